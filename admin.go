@@ -0,0 +1,328 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// admin.go defines an authenticated HTTP interface for managing a
+// running Clyde, so that operators of many bots can prune, decay,
+// merge, and replace chains and manage learning opt-outs
+// programmatically.
+
+package clyde
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// AdminConfig configures Clyde's administrative HTTP server. At least
+// one of Token and ClientCAFile must be set.
+type AdminConfig struct {
+	// Addr is the address to listen on, e.g. "localhost:8042".
+	Addr string
+	// Token, if set, must be presented by every request in an
+	// "Authorization: Bearer <Token>" header.
+	Token string
+	// CertFile and KeyFile, if set, are used to serve HTTPS.
+	CertFile, KeyFile string
+	// ClientCAFile, if set, is a PEM file of CA certificates; every
+	// request must present a client certificate signed by one of
+	// them. Requires CertFile and KeyFile.
+	ClientCAFile string
+}
+
+// maxUploadSize is the largest chain, in bytes, that can be uploaded
+// to the admin server.
+const maxUploadSize = 1 << 30
+
+// ServeAdmin serves Clyde's administrative HTTP endpoints according to
+// the given configuration. It blocks until the server fails, and
+// always returns a non-nil error. The endpoints are:
+//
+//	POST   /admin/prune?min=N          forget suffixes seen fewer than N times
+//	POST   /admin/decay?factor=F[&stale=D]
+//	                                   scale counts by F, optionally only for
+//	                                   prefixes not updated in duration D
+//	POST   /admin/merge                add an uploaded JSON chain to the chain
+//	POST   /admin/swap                 replace the chain with an uploaded one
+//	POST   /admin/forget               untrain the uploaded plain text from
+//	                                   every chain
+//	GET    /admin/optout               list users opted out of learning
+//	POST   /admin/optout?user=U        opt a user out of learning, and forget
+//	                                   their style model
+//	DELETE /admin/optout?user=U        opt a user back in to learning
+//
+// Responses are JSON objects.
+func (c *Clyde) ServeAdmin(conf AdminConfig) error {
+	if conf.Token == "" && conf.ClientCAFile == "" {
+		return errors.New("clyde: refusing to serve admin endpoints without a token or client CA")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/prune", c.adminPrune)
+	mux.HandleFunc("/admin/decay", c.adminDecay)
+	mux.HandleFunc("/admin/merge", c.adminMerge)
+	mux.HandleFunc("/admin/swap", c.adminSwap)
+	mux.HandleFunc("/admin/forget", c.adminForget)
+	mux.HandleFunc("/admin/optout", c.adminOptOut)
+
+	server := &http.Server{
+		Addr:    conf.Addr,
+		Handler: adminAuth(conf.Token, mux),
+	}
+
+	if conf.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(conf.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("clyde: no certificates found in %s", conf.ClientCAFile)
+		}
+		server.TLSConfig = &tls.Config{
+			ClientCAs:  pool,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+		}
+	}
+
+	log.Printf("Serving admin endpoints on %s", conf.Addr)
+	if conf.CertFile != "" || conf.KeyFile != "" {
+		return server.ListenAndServeTLS(conf.CertFile, conf.KeyFile)
+	}
+	if server.TLSConfig != nil {
+		return errors.New("clyde: client certificate authentication requires a server certificate")
+	}
+	return server.ListenAndServe()
+}
+
+// adminAuth wraps an http.Handler, rejecting requests that don't carry
+// the given bearer token (if it is non-empty).
+func adminAuth(token string, h http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			log.Printf("Rejected unauthenticated admin request from %s", r.RemoteAddr)
+			adminError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// adminReply writes v to an admin client as JSON.
+func adminReply(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// adminError writes an error message to an admin client as JSON.
+func adminError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// adminMethod checks that an admin request uses one of the given
+// methods, replying with an error if it doesn't.
+func adminMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	adminError(w, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+func (c *Clyde) adminPrune(w http.ResponseWriter, r *http.Request) {
+	if !adminMethod(w, r, "POST") {
+		return
+	}
+	min, err := strconv.Atoi(r.FormValue("min"))
+	if err != nil || min < 1 {
+		adminError(w, http.StatusBadRequest, "min must be a positive integer")
+		return
+	}
+
+	c.mu.Lock()
+	pruned := c.chain.Prune(min)
+	size := c.chain.Size()
+	c.mu.Unlock()
+
+	log.Printf("Admin pruned %d prefixes (min count %d)", pruned, min)
+	adminReply(w, map[string]int{"pruned": pruned, "size": size})
+}
+
+func (c *Clyde) adminDecay(w http.ResponseWriter, r *http.Request) {
+	if !adminMethod(w, r, "POST") {
+		return
+	}
+	factor, err := strconv.ParseFloat(r.FormValue("factor"), 64)
+	if err != nil || factor < 0 || factor > 1 {
+		adminError(w, http.StatusBadRequest, "factor must be between 0 and 1")
+		return
+	}
+	var stale time.Duration
+	if s := r.FormValue("stale"); s != "" {
+		stale, err = time.ParseDuration(s)
+		if err != nil {
+			adminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	c.mu.Lock()
+	var decayed int
+	if stale > 0 {
+		decayed = c.chain.DecayStale(time.Now().Add(-stale), factor)
+	} else {
+		decayed = c.chain.Decay(factor)
+	}
+	size := c.chain.Size()
+	c.mu.Unlock()
+
+	log.Printf("Admin decayed %d prefixes by %v", decayed, factor)
+	adminReply(w, map[string]int{"decayed": decayed, "size": size})
+}
+
+// adminUpload reads a chain uploaded in an admin request body.
+func adminUpload(w http.ResponseWriter, r *http.Request) (*markov.Chain, bool) {
+	upload := markov.NewChain(prefixLen)
+	err := upload.Decode(http.MaxBytesReader(w, r.Body, maxUploadSize))
+	if err != nil {
+		adminError(w, http.StatusBadRequest, fmt.Sprintf("bad chain: %v", err))
+		return nil, false
+	}
+	// Decode can't tell what prefix length the counts were learned
+	// with, and merging or swapping in the wrong one would garble
+	// Clyde's chain
+	if n := upload.LongestPrefix(); n != 0 && n != prefixLen {
+		adminError(w, http.StatusBadRequest, fmt.Sprintf("bad chain: prefix length %d, want %d", n, prefixLen))
+		return nil, false
+	}
+	return upload, true
+}
+
+func (c *Clyde) adminMerge(w http.ResponseWriter, r *http.Request) {
+	if !adminMethod(w, r, "POST") {
+		return
+	}
+	upload, ok := adminUpload(w, r)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	err := c.chain.Merge(upload)
+	size := c.chain.Size()
+	c.mu.Unlock()
+
+	if err != nil {
+		adminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("Admin merged %d prefixes", upload.Size())
+	adminReply(w, map[string]int{"merged": upload.Size(), "size": size})
+}
+
+func (c *Clyde) adminSwap(w http.ResponseWriter, r *http.Request) {
+	if !adminMethod(w, r, "POST") {
+		return
+	}
+	upload, ok := adminUpload(w, r)
+	if !ok {
+		return
+	}
+	upload.TrackUpdates(updateBucket)
+	upload.SetAllowlist(c.allowlist)
+	// The uploaded chain hasn't been saved where Clyde's is
+	upload.MarkDirty()
+
+	c.mu.Lock()
+	c.chain = upload
+	c.chains.Put(mainChain, false, upload)
+	c.mu.Unlock()
+
+	log.Printf("Admin swapped in a new chain with %d prefixes", upload.Size())
+	adminReply(w, map[string]int{"size": upload.Size()})
+}
+
+func (c *Clyde) adminForget(w http.ResponseWriter, r *http.Request) {
+	if !adminMethod(w, r, "POST") {
+		return
+	}
+	text, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadSize))
+	if err != nil {
+		adminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.mu.Lock()
+	body := c.learnable(string(text))
+	// Forget it from every chain it may have been learned into
+	forgotten := 0
+	remove := func(name string, chain *markov.Chain) {
+		// Reading a string can't fail
+		n, _ := chain.Remove(strings.NewReader(body))
+		forgotten += n
+	}
+	remove(mainChain, c.chain)
+	remove(mainChain, c.privateChain)
+	remove("zsig", c.zsigChain)
+	c.userChains.Each(remove)
+	c.classChains.Each(remove)
+	size := c.chain.Size()
+	c.mu.Unlock()
+
+	log.Printf("Admin untrained %d bytes, forgetting %d prefixes", len(text), forgotten)
+	adminReply(w, map[string]int{"forgotten": forgotten, "size": size})
+}
+
+func (c *Clyde) adminOptOut(w http.ResponseWriter, r *http.Request) {
+	if !adminMethod(w, r, "GET", "POST", "DELETE") {
+		return
+	}
+	user := r.FormValue("user")
+	if r.Method != "GET" && user == "" {
+		adminError(w, http.StatusBadRequest, "user is required")
+		return
+	}
+
+	c.mu.Lock()
+	switch r.Method {
+	case "POST":
+		c.optOut[user] = true
+		// Clyde saves the user chains to their own directory, so
+		// this deletes the saved chain too
+		if err := c.userChains.Delete(userKey(user)); err != nil {
+			c.mu.Unlock()
+			adminError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		log.Printf("Admin opted %s out of learning", user)
+	case "DELETE":
+		delete(c.optOut, user)
+		log.Printf("Admin opted %s back in to learning", user)
+	}
+	users := []string{}
+	for u := range c.optOut {
+		users = append(users, u)
+	}
+	c.mu.Unlock()
+
+	sort.Strings(users)
+	adminReply(w, map[string][]string{"optout": users})
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package clyde
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// testClyde returns a Clyde with no zephyr session, keeping its data
+// in a temporary directory.
+func testClyde(t *testing.T) *Clyde {
+	t.Helper()
+	c, err := newClyde(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ticker.Stop()
+	return c
+}
+
+// adminRequest calls an admin handler with a request body, failing the
+// test unless it succeeds.
+func adminRequest(t *testing.T, handler http.HandlerFunc, method, target, body string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s %s: %d %s", method, target, w.Code, w.Body)
+	}
+}
+
+func TestSwapSurvivesReload(t *testing.T) {
+	c := testClyde(t)
+	c.chain.Build(strings.NewReader("the old chain talks about cats"))
+	c.save()
+
+	swapped := markov.NewChain(prefixLen)
+	swapped.Build(strings.NewReader("the new chain talks about dogs"))
+	var buf bytes.Buffer
+	if err := swapped.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	adminRequest(t, c.adminSwap, "POST", "/admin/swap", buf.String())
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.chain.Generate("talks about", 1, 5); got != "talks about dogs" {
+		t.Errorf("after swapping and reloading, Generate(%q) = %q, want %q", "talks about", got, "talks about dogs")
+	}
+}
+
+func TestForgetScrubbed(t *testing.T) {
+	tests := []string{
+		"mail me at someone@example.org today",
+		"my number is 617-555-0123, call me",
+		"nothing to scrub here",
+	}
+	for _, text := range tests {
+		c := testClyde(t)
+		c.chain.Build(strings.NewReader(c.learnable(text)))
+		adminRequest(t, c.adminForget, "POST", "/admin/forget", text)
+		if got := c.chain.Size(); got != 0 {
+			t.Errorf("after learning and forgetting %q, chain has %d prefixes, want 0", text, got)
+		}
+	}
+}
+
+func TestForgetEverywhere(t *testing.T) {
+	const text = "the cat sat on the mat"
+	c := testClyde(t)
+	chains := map[string]*markov.Chain{
+		"main":    c.chain,
+		"private": c.privateChain,
+		"zsig":    c.zsigChain,
+		"user":    c.userChains.Route(userKey("someone"), false),
+		"class":   c.classChains.Route(classKey("help"), false),
+	}
+	for _, chain := range chains {
+		chain.Build(strings.NewReader(c.learnable(text)))
+	}
+	adminRequest(t, c.adminForget, "POST", "/admin/forget", text)
+	for name, chain := range chains {
+		if got := chain.Size(); got != 0 {
+			t.Errorf("after forgetting %q, %s chain has %d prefixes, want 0", text, name, got)
+		}
+	}
+}
+
+func TestOptOutDeletesSaved(t *testing.T) {
+	c := testClyde(t)
+	c.userChains.Route(userKey("someone"), false).Build(strings.NewReader("my style"))
+	c.save()
+	adminRequest(t, c.adminOptOut, "POST", "/admin/optout?user=someone", "")
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if chain := c.userChains.Lookup(userKey("someone"), false); chain != nil {
+		t.Errorf("after opting out and reloading, user chain has %d prefixes, want none", chain.Size())
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// adventure.go defines a choose-your-own-adventure game: Clyde
+// describes a scene and offers two choices, everyone votes, and the
+// winning choice leads to the next scene.
+
+package clyde
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zephyr-im/zephyr-go"
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/stringutil"
+	"github.com/sdukhovni/clyde-go/util"
+)
+
+// adventure is the state of a game in progress on one class and
+// instance.
+type adventure struct {
+	class, instance string
+	chain           *markov.Chain
+	options         [2]string
+	votes           map[string]int // sender -> option number
+	firstVote       time.Time
+	lastActivity    time.Time
+	turns           int
+}
+
+// adventureVoteWindow is how long voting stays open after the first
+// vote on a turn.
+const adventureVoteWindow = time.Minute
+
+// adventureTimeout is how long an adventure can go without any votes
+// before Clyde gives up on it.
+const adventureTimeout = 30 * time.Minute
+
+// adventureTurns is the maximum number of turns in an adventure.
+const adventureTurns = 10
+
+// adventureOptionWords is the maximum length of a choice.
+const adventureOptionWords = 12
+
+// adventureKey returns the key for the adventure on a class and
+// instance.
+func adventureKey(class, instance string) string {
+	return strings.ToLower(class) + "\x00" + strings.ToLower(instance)
+}
+
+// turn generates a scene continuing from the given seed, plus two new
+// options, and returns the text to send.
+func (a *adventure) turn(seed string) string {
+	scene := a.chain.Generate(seed, 2, maxWords)
+	for i := range a.options {
+		a.options[i] = strings.TrimRight(a.chain.Generate("You", 1, adventureOptionWords), ".!?")
+	}
+	a.votes = make(map[string]int)
+	a.firstVote = time.Time{}
+	a.lastActivity = time.Now()
+	a.turns++
+	return fmt.Sprintf("%s\n\nWhat do you do? Say 1 or 2.\n\n1) %s\n\n2) %s", scene, a.options[0], a.options[1])
+}
+
+var startAdventure = standardBehavior("clyde.? (let's|let us|can we) (play|have|go on) an? adventure( about (?P<topic>.*[^\\.\\?!]))?",
+	[]string{"topic"},
+	false,
+	func(c *Clyde, r zephyr.MessageReaderResult, kvs map[string]string) string {
+		class := r.Message.Header.Class
+		instance := r.Message.Header.Instance
+		if (class != homeClass || instance != homeInstance) && c.subs[class] != FULL {
+			return fmt.Sprintf("Let's go on an adventure over on -c %s -i %s instead!", homeClass, homeInstance)
+		}
+		key := adventureKey(class, instance)
+		if c.adventures[key] != nil {
+			return "We're already on an adventure!"
+		}
+
+		topic := kvs["topic"]
+		if topic == "" {
+			topic, _ = randomLine(c, "jobs")
+		}
+		a := &adventure{class: class, instance: instance, chain: c.chainFor(r)}
+		c.adventures[key] = a
+		log.Printf("Starting adventure on -c %s -i %s", class, instance)
+		return a.turn(fmt.Sprintf("You are %s %s. You", stringutil.Article(topic), topic))
+	})
+
+var stopAdventure = standardBehavior("clyde.? (stop|end|quit) (the |this |our )?adventure",
+	nil,
+	false,
+	func(c *Clyde, r zephyr.MessageReaderResult, kvs map[string]string) string {
+		key := adventureKey(r.Message.Header.Class, r.Message.Header.Instance)
+		if c.adventures[key] == nil {
+			return "We're not on an adventure right now."
+		}
+		delete(c.adventures, key)
+		return "And they all lived happily ever after. The end!"
+	})
+
+var adventureVote = regexp.MustCompile("^(clyde.? )?(i (choose|pick|vote)|choose|pick|vote)? ?(?P<choice>[12])[\\.!]?$")
+
+// voteAdventure is a behavior that counts votes in an adventure in
+// progress on a message's class and instance.
+func voteAdventure(c *Clyde, r zephyr.MessageReaderResult) bool {
+	a := c.adventures[adventureKey(r.Message.Header.Class, r.Message.Header.Instance)]
+	if a == nil {
+		return false
+	}
+	body := strings.ToLower(strings.Join(strings.Fields(util.MessageBody(r)), " "))
+	match := adventureVote.FindStringSubmatchIndex(body)
+	if match == nil {
+		return false
+	}
+	choice := string(adventureVote.ExpandString(nil, "$choice", body, match))
+	a.votes[shortSender(r)] = int(choice[0] - '0')
+	if a.firstVote.IsZero() {
+		a.firstVote = time.Now()
+	}
+	a.lastActivity = time.Now()
+	return true
+}
+
+// tickAdventures resolves adventure turns whose voting has closed,
+// and abandons adventures nobody is playing anymore.
+func (c *Clyde) tickAdventures() {
+	for key, a := range c.adventures {
+		if time.Since(a.lastActivity) > adventureTimeout {
+			log.Printf("Abandoning adventure on -c %s -i %s", a.class, a.instance)
+			delete(c.adventures, key)
+			c.send(a.class, a.instance, "I guess nobody wants to play anymore. The end.")
+			continue
+		}
+		if a.firstVote.IsZero() || time.Since(a.firstVote) < adventureVoteWindow {
+			continue
+		}
+
+		var tally [3]int
+		for _, v := range a.votes {
+			tally[v]++
+		}
+		choice := 1
+		if tally[2] > tally[1] || (tally[2] == tally[1] && rand.Intn(2) == 0) {
+			choice = 2
+		}
+		option := a.options[choice-1]
+
+		if a.turns >= adventureTurns {
+			delete(c.adventures, key)
+			c.send(a.class, a.instance, fmt.Sprintf("%s. %s\n\nThe end!", option, a.chain.Generate("", 1, maxWords)))
+			continue
+		}
+		c.send(a.class, a.instance, a.turn(option))
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// backup keeps timestamped copies of a bot's saved state, so that a
+// bad save (or a bad round of training) doesn't cost everything the
+// bot has learned. Snapshots are kept in a "backups" subdirectory of
+// the directory they're taken of, and the oldest are removed as new
+// ones are taken.
+
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dir is the name of the subdirectory snapshots are kept in.
+const Dir = "backups"
+
+// timeFormat names snapshots so that they sort in the order they were
+// taken, even if several are taken within a second. Snapshots taken
+// at the same time on a coarse clock get a sequence number as well.
+const timeFormat = "20060102-150405.000000000"
+
+// partialPrefix starts the names of snapshots still being copied,
+// which are renamed into place once complete.
+const partialPrefix = ".partial-"
+
+// Snapshot copies every file in dir (and its subdirectories, other
+// than Dir) into a new snapshot named for the current time, then
+// removes the oldest snapshots so that at most keep remain, along
+// with any left incomplete by a crash. It returns the new snapshot's
+// name. It does nothing if keep is less than 1 or dir doesn't exist
+// yet.
+//
+// Snapshot only reads dir, so it needn't hold up whatever saves the
+// files there, as long as they're replaced atomically (as
+// markov.Chain.Save does), and shouldn't: copying a large directory
+// takes a while.
+func Snapshot(dir string, keep int) (string, error) {
+	if keep < 1 {
+		return "", nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return "", nil
+	}
+	if err := os.MkdirAll(filepath.Join(dir, Dir), 0755); err != nil {
+		return "", err
+	}
+	partial, err := os.MkdirTemp(filepath.Join(dir, Dir), partialPrefix)
+	if err != nil {
+		return "", err
+	}
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel == Dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Skip files being written
+		if strings.HasPrefix(info.Name(), ".") && strings.Contains(info.Name(), ".tmp") {
+			return nil
+		}
+		return copyFile(p, filepath.Join(partial, rel))
+	})
+	var name string
+	if err == nil {
+		name, err = finish(dir, partial)
+	}
+	if err != nil {
+		os.RemoveAll(partial)
+		return "", err
+	}
+	return name, prune(dir, keep)
+}
+
+// finish renames a complete snapshot of dir into place, named for the
+// current time.
+func finish(dir, partial string) (string, error) {
+	base := time.Now().UTC().Format(timeFormat)
+	name := base
+	for seq := 1; ; seq++ {
+		snapshot := filepath.Join(dir, Dir, name)
+		if _, err := os.Stat(snapshot); os.IsNotExist(err) {
+			return name, os.Rename(partial, snapshot)
+		} else if err != nil {
+			return "", err
+		}
+		name = fmt.Sprintf("%s-%d", base, seq)
+	}
+}
+
+// List returns the names of the snapshots of dir, oldest first.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, Dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Restore copies the files in the named snapshot back into dir,
+// replacing their current versions. Files that were created since the
+// snapshot are left alone. The bot using dir must not be running.
+func Restore(dir, name string) error {
+	snapshot := filepath.Join(dir, Dir, name)
+	if _, err := os.Stat(snapshot); err != nil {
+		return err
+	}
+	return filepath.Walk(snapshot, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(snapshot, p)
+		if err != nil {
+			return err
+		}
+		return copyFile(p, filepath.Join(dir, rel))
+	})
+}
+
+// prune removes the oldest snapshots of dir so that at most keep
+// remain, and any incomplete snapshots older than an hour, which a
+// crash must have left behind.
+func prune(dir string, keep int) error {
+	entries, err := os.ReadDir(filepath.Join(dir, Dir))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), partialPrefix) {
+			continue
+		}
+		if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > time.Hour {
+			os.RemoveAll(filepath.Join(dir, Dir, e.Name()))
+		}
+	}
+
+	names, err := List(dir)
+	if err != nil {
+		return err
+	}
+	for len(names) > keep {
+		if err := os.RemoveAll(filepath.Join(dir, Dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// copyFile copies a file, with its permissions, creating the
+// destination's directory if necessary. The copy is written under a
+// temporary name and renamed into place, so an interrupted copy never
+// replaces a good file with part of one.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(out.Name(), info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Rename(out.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(out.Name())
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	write("chain.json", "v1")
+	write("users/alice.json", "alice")
+	// An incomplete snapshot left by a crash long ago
+	stale := filepath.Join(dir, Dir, partialPrefix+"crashed")
+	if err := os.MkdirAll(stale, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(stale, old, old)
+
+	// Snapshots taken in quick succession get names of their own,
+	// and only the newest are kept
+	var names []string
+	for i := 0; i < 4; i++ {
+		name, err := Snapshot(dir, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	write("chain.json", "v2")
+	list, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0] != names[1] || list[2] != names[3] {
+		t.Errorf("List = %q, want the last three of %q", list, names)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale incomplete snapshot wasn't removed")
+	}
+
+	if err := Restore(dir, list[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("chain.json"); got != "v1" {
+		t.Errorf("restored chain.json = %q, want v1", got)
+	}
+	if got := read("users/alice.json"); got != "alice" {
+		t.Errorf("restored users/alice.json = %q, want alice", got)
+	}
+}
+
+func TestSnapshotNothing(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		dir  string
+		keep int
+	}{
+		{dir, 0},
+		{filepath.Join(dir, "missing"), 5},
+	}
+	for _, tt := range tests {
+		if name, err := Snapshot(tt.dir, tt.keep); name != "" || err != nil {
+			t.Errorf("Snapshot(%s, %d) = %q, %v, want nothing", tt.dir, tt.keep, name, err)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"path"
 	"time"
 	"github.com/zephyr-im/zephyr-go"
+	"github.com/sdukhovni/clyde-go/markov"
 	"github.com/sdukhovni/clyde-go/stringutil"
 	"github.com/sdukhovni/clyde-go/util"
 	"github.com/sdukhovni/clyde-go/mood"
@@ -60,7 +61,10 @@ func standardBehavior(pattern string, keys []string, chain bool, resp func(*Clyd
 
 		response := resp(c, r, keyvals)
 		if chain {
-			response = c.chain.Generate(response, sentenceCounts[rand.Intn(len(sentenceCounts))], maxWords)
+			response = c.generateReply(r, response)
+			if response == "" {
+				return true
+			}
 		}
 
 		class := r.Message.Header.Class
@@ -92,6 +96,64 @@ const maxWords = 100
 // number of sentences is needed.
 var sentenceCounts = []int{1, 1, 1, 2, 2, 3}
 
+// skipConfidence is the chainer confidence below which Clyde would
+// rather say nothing at all, and hedgeConfidence is the confidence
+// below which he hedges what he says.
+const skipConfidence = 0.05
+const hedgeConfidence = 0.2
+
+// novelTries is the number of times to try generating a reply that
+// doesn't quote a training sentence verbatim.
+const novelTries = 3
+
+// hedges is a set of interjections for prefixing low-confidence
+// replies.
+var hedges = []string{"Hmm...", "Um...", "Uh,", "I dunno..."}
+
+// generateReply continues the given start string using the markov
+// chainer appropriate for replying to a zephyr. If the chainer isn't
+// confident in what it generated, the reply is hedged, or if it's
+// really unsure, generateReply returns "" to indicate that Clyde
+// shouldn't reply.
+func (c *Clyde) generateReply(r zephyr.MessageReaderResult, start string) string {
+	chain, done := c.replyChain(r)
+	defer done()
+	sentences := sentenceCounts[rand.Intn(len(sentenceCounts))]
+	var reply string
+	var confidence float64
+	// Try not to just quote someone back at themselves
+	for i := 0; i < novelTries; i++ {
+		reply, confidence = c.generate(chain, start, sentences)
+		// Class chains don't track sentences, but everything
+		// they've learned, chainFor's chain has too
+		if c.chainFor(r).IsNovel(reply) {
+			break
+		}
+		log.Printf("Regenerating unoriginal reply: %s", reply)
+	}
+	reply = c.filter.Apply(reply, func() string {
+		reply, confidence = c.generate(chain, start, sentences)
+		return reply
+	})
+	switch {
+	case confidence < skipConfidence:
+		log.Printf("Not replying, chainer confidence too low (%.2f)", confidence)
+		return ""
+	case confidence < hedgeConfidence:
+		log.Printf("Hedging reply, chainer confidence is low (%.2f)", confidence)
+		return fmt.Sprintf("%s %s", hedges[rand.Intn(len(hedges))], reply)
+	}
+	return reply
+}
+
+// generate generates a reply as GenerateConfidence does, and counts
+// the prefix lengths it used in Clyde's stats.
+func (c *Clyde) generate(chain *markov.Chain, start string, sentences int) (string, float64) {
+	r := chain.GenerateResult(start, sentences, maxWords)
+	c.stats.Add(r.Levels)
+	return r.Text, r.Confidence
+}
+
 // shortSender returns just the kerberos principal (with no realm) of
 // the sender of a zephyr.
 func shortSender(r zephyr.MessageReaderResult) string {
@@ -155,6 +217,7 @@ var behaviors = []behavior{
 	empathy,
 	addActLike,
 	actLike,
+	styleOf,
 	learnSecret,
 	tellSecret,
 	addSub,
@@ -162,15 +225,22 @@ var behaviors = []behavior{
 	getMood,
 	cheerup,
 	learnJob,
+	voteAdventure,
+	startAdventure,
+	stopAdventure,
+	longStory,
 	story,
 	fight,
 	fortune,
 	dice,
 	quip,
 	memSize,
+	recentlyLearned,
 	chainStats,
+	didYouSay,
 	ping,
 	chat,
+	interject,
 }
 
 
@@ -411,6 +481,25 @@ var learnJob = standardBehavior("clyde.? (?P<job>.+) is an? (job|profession|occu
 		return "That's what I wanna be when I grow up!"
 	})
 
+var longStory = standardBehavior("clyde.? tell me a (long )?story about (?P<topic>.*[^\\.\\?!])",
+	[]string{"topic"},
+	false,
+	func(c *Clyde, r zephyr.MessageReaderResult, kvs map[string]string) string {
+		story := c.chainFor(r).GenerateStory(markov.StoryConfig{
+			Topic:      stringutil.Capitalize(kvs["topic"]),
+			Chapters:   storyChapters,
+			Paragraphs: storyParagraphs,
+			Sentences:  2,
+			MaxWords:   maxWords,
+			Cast:       []string{shortSender(r)},
+		})
+		return fmt.Sprintf("A story about %s\n\n%s\n\nThe end.", kvs["topic"], story)
+	})
+
+// storyChapters and storyParagraphs set the length of long stories.
+const storyChapters = 2
+const storyParagraphs = 2
+
 var story = standardBehavior("tell me a story",
 	nil,
 	true,
@@ -459,7 +548,7 @@ var fortune = standardBehavior("fortune", []string{}, false,
 		}
 		var response []string
 		for _, intro := range intros {
-			response = append(response, c.chain.Generate(intro, 1, maxWords))
+			response = append(response, c.chainFor(r).GenerateNovel(intro, 1, maxWords, novelTries))
 		}
 		return strings.Join(response, " ")
 	})
@@ -549,9 +638,45 @@ var memSize = standardBehavior("how big is your memory", []string{}, false,
 		return fmt.Sprintf("I've got %d n-gram prefixes in my memory!", size)
 	})
 
+var recentlyLearned = standardBehavior("what have you learned (?P<period>today|this week|this month|recently)",
+	[]string{"period"},
+	false,
+	func(c *Clyde, r zephyr.MessageReaderResult, kvs map[string]string) string {
+		var since time.Duration
+		switch strings.ToLower(kvs["period"]) {
+		case "today":
+			since = 24 * time.Hour
+		case "this month":
+			since = 30 * 24 * time.Hour
+		default:
+			since = 7 * 24 * time.Hour
+		}
+
+		// Only report full-length prefixes from the middle of
+		// sentences; they're the most interesting
+		var learned []string
+		for _, prefix := range c.chain.UpdatedSince(time.Now().Add(-since)) {
+			if len(strings.Fields(prefix)) == prefixLen && !strings.Contains(prefix, "START") {
+				learned = append(learned, prefix)
+			}
+		}
+		if len(learned) == 0 {
+			return "Nothing new, I'm afraid."
+		}
+
+		var examples []string
+		for _, i := range rand.Perm(len(learned)) {
+			if len(examples) == 3 {
+				break
+			}
+			examples = append(examples, fmt.Sprintf("\"%s\"", learned[i]))
+		}
+		return fmt.Sprintf("I've learned %d phrases %s, like %s!", len(learned), strings.ToLower(kvs["period"]), strings.Join(examples, ", "))
+	})
+
 var chainStats = standardBehavior("how('s| is) your chainer", []string{}, false,
 	func(c *Clyde, r zephyr.MessageReaderResult, kvs map[string]string) string {
-		stats := c.chain.Stats()
+		stats := c.stats.Levels()
 		total := 0
 		for _, count := range stats {
 			total += count
@@ -572,6 +697,19 @@ var chainStats = standardBehavior("how('s| is) your chainer", []string{}, false,
 		return strings.Join(replyParts, ", ")
 	})
 
+var didYouSay = standardBehavior("clyde.? did you (really )?(say|write),? [\"'](?P<text>.+)[\"']\\??$",
+	[]string{"text"},
+	false,
+	func(c *Clyde, r zephyr.MessageReaderResult, kvs map[string]string) string {
+		if c.watermarks == nil {
+			return "I don't keep track of what I say, sorry."
+		}
+		if c.watermarks.Verify(kvs["text"]) {
+			return "Yup, that was me!"
+		}
+		return "Nope, that wasn't me!"
+	})
+
 var ping = standardBehavior("^clyde\\?$", []string{}, false,
 	func(c *Clyde, r zephyr.MessageReaderResult, kvs map[string]string) string {
 		return "Yes?"
@@ -0,0 +1,255 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// admin.go lets a bot's admins run it from chat, with commands like
+// "!save" and "!mute", handled by the core's triggers but refused to
+// anyone without the role each command requires (see Role).
+
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/corpus"
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// AdminPattern matches admin commands, for use with Admin.
+const AdminPattern = `^!(?P<command>save|stats|reload|mute|unmute|learn|forget|prune)\b\s*(?P<args>.*)$`
+
+var adminCommand = regexp.MustCompile("(?i)" + AdminPattern)
+
+// defaultPruneCount is the minimum count "!prune" keeps suffixes
+// with, unless told otherwise.
+const defaultPruneCount = 2
+
+// commandRoles are the roles needed to run each admin command. Only
+// owners can run commands that lose what the core has learned.
+var commandRoles = map[string]Role{
+	"stats":  RoleUser,
+	"save":   RoleAdmin,
+	"mute":   RoleAdmin,
+	"unmute": RoleAdmin,
+	"learn":  RoleAdmin,
+	"reload": RoleOwner,
+	"forget": RoleOwner,
+	"prune":  RoleOwner,
+}
+
+// AdminActions are what the admin commands that reach outside the
+// core do. They're called in their own goroutines, so they may use
+// the core (e.g. through Do); nil actions aren't offered.
+type AdminActions struct {
+	// Save saves the core's state, for "!save".
+	Save func()
+	// Reload reloads the core's chains from disk, for "!reload".
+	Reload func()
+}
+
+// SetAdminActions sets what the "!save" and "!reload" admin commands
+// do.
+func (c *Core) SetAdminActions(a AdminActions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = a
+}
+
+// AddAdminTriggers adds a trigger, above all others, running admin
+// commands (see Admin).
+func (c *Core) AddAdminTriggers() {
+	c.AddTrigger(Trigger{Pattern: AdminPattern, Priority: math.MaxInt32, Handler: Admin()})
+}
+
+// muted reports whether the core has been told to be quiet in a
+// channel.
+func (c *Core) muted(channel string) bool {
+	until, ok := c.mute[channel]
+	if ok && !until.IsZero() && time.Now().After(until) {
+		delete(c.mute, channel)
+		return false
+	}
+	return ok
+}
+
+// Admin returns a handler running the admin command in the "command"
+// group of a match, with the arguments in its "args" group, as in
+// AdminPattern, if the sender has the role the command requires (see
+// SetRoles):
+//
+//	!stats              report the size of the chains and what the
+//	                    core has learned and said (anyone)
+//	!save               save the core's state (admins; see
+//	                    AdminActions)
+//	!mute [duration]    stop replying in the channel, until unmuted or
+//	                    for the given duration, e.g. "30m" (admins)
+//	!unmute             start replying in the channel again (admins)
+//	!learn on|off       start or stop learning from messages (admins)
+//	!learn on|off here  start or stop learning in the channel (admins)
+//	!learn url [chain]  fetch a web page or feed and train the
+//	                    channel's chain, or the named one, on it
+//	                    (admins; see corpus.Fetch)
+//	!learn stop         give up on the pages and feeds being fetched
+//	                    to learn (admins)
+//	!reload             reload the core's chains (owners; see
+//	                    AdminActions)
+//	!forget text        untrain text from the channel's and global
+//	                    chains (owners; see markov.Chain.Remove)
+//	!prune [count]      forget suffixes seen fewer than count (default
+//	                    2) times from the channel's chain (owners; see
+//	                    markov.Chain.Prune)
+//
+// Admin commands are still run while the core is muted.
+func Admin() Handler {
+	return func(m *Match) string {
+		c := m.core
+		command := strings.ToLower(m.Groups["command"])
+		if c.role(m.user) < commandRoles[command] {
+			return fmt.Sprintf("Sorry, only %ss can do that.", commandRoles[command])
+		}
+		args := strings.Fields(strings.ToLower(m.Groups["args"]))
+		switch command {
+		case "save":
+			if c.actions.Save == nil {
+				return "I don't know how to save."
+			}
+			go c.actions.Save()
+			return "Saving."
+		case "reload":
+			if c.actions.Reload == nil {
+				return "I don't know how to reload."
+			}
+			go c.actions.Reload()
+			return "Reloading."
+		case "stats":
+			return c.stats(m.channel)
+		case "mute":
+			var until time.Time
+			if len(args) > 0 {
+				d, err := time.ParseDuration(args[0])
+				if err != nil || d <= 0 {
+					return "Mute for how long? Try e.g. \"!mute 30m\"."
+				}
+				until = time.Now().Add(d)
+			}
+			c.mute[m.channel] = until
+			if until.IsZero() {
+				return "OK, I'll be quiet here until unmuted."
+			}
+			return fmt.Sprintf("OK, I'll be quiet here until %s.", until.Format("15:04"))
+		case "unmute":
+			delete(c.mute, m.channel)
+			return "OK, I'll talk here again."
+		case "learn":
+			if raw := strings.Fields(m.Groups["args"]); len(raw) > 0 && isURL(raw[0]) {
+				name := m.channel
+				if len(raw) > 1 {
+					name = raw[1]
+				}
+				if c.fetching == nil {
+					c.fetching, c.stopFetching = context.WithCancel(context.Background())
+				}
+				go c.learnURL(c.fetching, raw[0], name)
+				return fmt.Sprintf("Fetching %s to learn.", raw[0])
+			}
+			if len(args) == 1 && args[0] == "stop" {
+				if c.fetching == nil {
+					return "I'm not fetching anything."
+				}
+				c.stopFetching()
+				c.fetching, c.stopFetching = nil, nil
+				return "OK, I've stopped fetching."
+			}
+			if len(args) == 0 || (args[0] != "on" && args[0] != "off") {
+				return "Usage: !learn on|off [here], !learn url [chain], or !learn stop"
+			}
+			on := args[0] == "on"
+			if len(args) > 1 && args[1] == "here" {
+				if on {
+					delete(c.learning.Channels, m.channel)
+				} else {
+					c.learning.Channels[m.channel] = true
+				}
+				return fmt.Sprintf("OK, learning here is %s.", args[0])
+			}
+			c.learning.Off = !on
+			return fmt.Sprintf("OK, learning is %s.", args[0])
+		case "forget":
+			if strings.TrimSpace(m.Groups["args"]) == "" {
+				return "Usage: !forget text"
+			}
+			// Remove the text as it would have been learned
+			text := c.learnable(m.Groups["args"])
+			// Reading a string can't fail
+			forgotten, _ := m.chain.Remove(strings.NewReader(text))
+			if global := c.chains.Get(GlobalChain); global != nil && global != m.chain {
+				global.Remove(strings.NewReader(text))
+			}
+			return fmt.Sprintf("OK, forgotten (%d prefixes).", forgotten)
+		case "prune":
+			count := defaultPruneCount
+			if len(args) > 0 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					return "Usage: !prune [count]"
+				}
+				count = n
+			}
+			return fmt.Sprintf("OK, pruned %d prefixes.", m.chain.Prune(count))
+		}
+		return ""
+	}
+}
+
+// isURL reports whether an admin command's argument is an HTTP URL.
+func isURL(s string) bool {
+	s = strings.ToLower(s)
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// learnURL fetches a web page or feed and trains a chain on it, for
+// "!learn url", unless ctx is done first ("!learn stop"). It's called
+// in its own goroutine, as fetching may take a while, so it only
+// reports the outcome in the log.
+func (c *Core) learnURL(ctx context.Context, url, name string) {
+	msgs, err := corpus.FetchContext(ctx, url, 0)
+	if err != nil {
+		log.Printf("Learning %s: %v", url, err)
+		return
+	}
+	c.Do(func(chains *markov.ChainSet) {
+		if ctx.Err() != nil {
+			log.Printf("Learning %s: %v", url, ctx.Err())
+			return
+		}
+		c.scrubMessages(msgs)
+		words, err := corpus.Build(chains.Chain(name), msgs, false)
+		if err != nil {
+			log.Printf("Learning %s: %v", url, err)
+		}
+		c.metrics.words.Add(uint64(words))
+		log.Printf("Learned %d words from %s into %s", words, url, name)
+	})
+}
+
+// stats reports on the core's chains and counters, for "!stats".
+func (c *Core) stats(channel string) string {
+	size := 0
+	if chain := c.chains.Get(channel); chain != nil {
+		size = chain.Size()
+	}
+	s := fmt.Sprintf("I have %d chains; this channel's has %d prefixes", c.chains.Len(), size)
+	if global := c.chains.Get(GlobalChain); global != nil {
+		s += fmt.Sprintf(" and the global one %d", global.Size())
+	}
+	return s + fmt.Sprintf(". Since starting, I've learned %d messages (%d words) and sent %d replies.",
+		c.metrics.learned.Value(), c.metrics.words.Value(), c.metrics.replies.Value())
+}
@@ -0,0 +1,42 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package bot
+
+import (
+	"testing"
+
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/moderation"
+)
+
+func TestForget(t *testing.T) {
+	tests := []string{
+		"the cat sat on the mat",
+		"mail the cat at cat@example.com today",
+		"the cat said “hello” to the mat",
+	}
+	id := Identity{Network: "test", ID: "bot", Name: "clyde"}
+	for _, text := range tests {
+		c := NewCore(markov.NewChainSet(2))
+		scrubber, err := moderation.NewScrubber([]string{"email"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetScrubber(scrubber)
+		c.SetRoles(map[string]Role{"test/owner": RoleOwner})
+		c.AddAdminTriggers()
+		c.Handle(id, Message{Channel: "c", Sender: "u", Text: text})
+		if chain := c.chains.Get("test/c"); chain == nil || chain.Size() == 0 {
+			t.Fatalf("didn't learn %q", text)
+		}
+		c.Handle(id, Message{Channel: "c", Sender: "owner", Text: "!forget " + text})
+		for _, name := range []string{"test/c", GlobalChain} {
+			if chain := c.chains.Get(name); chain != nil && chain.Size() != 0 {
+				t.Errorf("after !forget %q, %s has %d prefixes, want 0", text, name, chain.Size())
+			}
+		}
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// bot defines the interface between chat network frontends and a
+// shared bot core, which learns from the messages frontends receive
+// and generates replies for them to send. Frontends for new networks
+// only need to implement Frontend to use the core.
+
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/corpus"
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/moderation"
+	"github.com/sdukhovni/clyde-go/stringutil"
+	"github.com/sdukhovni/clyde-go/watermark"
+)
+
+// GlobalChain is the name of the chain a Core trains on every message
+// it sees, from every frontend (unless Routing.NoGlobal is set).
+const GlobalChain = "global"
+
+// maxWords is the maximum number of words in a reply.
+const maxWords = 100
+
+// contextMessages is the number of recent messages in a channel whose
+// topic replies without a seed or keyword of their own take up.
+const contextMessages = 3
+
+// A Message is a message received by a frontend.
+type Message struct {
+	// Channel identifies where the message was sent (a room, a
+	// chat, a correspondent, ...), in a form the frontend's Send
+	// accepts for replying there.
+	Channel string
+	// Sender identifies who sent the message.
+	Sender string
+	Text   string
+	// Addressed is set if the message was addressed to the bot by
+	// the network's own means, e.g. a direct message or a reply to
+	// one of the bot's messages. Messages that address the bot by
+	// name (see stringutil.Address) are treated as addressed
+	// regardless.
+	Addressed bool
+}
+
+// Identity describes who a frontend's bot is on its network.
+type Identity struct {
+	// Network names the frontend's network, e.g. "matrix"; it
+	// must be unique among the frontends sharing a Core.
+	Network string
+	// ID is the bot's own user ID, so the core can ignore its own
+	// messages.
+	ID string
+	// Name is the name users address the bot by.
+	Name string
+}
+
+// A Frontend connects a bot to a chat network.
+type Frontend interface {
+	// Identity returns who the bot is on the network.
+	Identity() Identity
+	// Messages returns a channel of received messages, which is
+	// closed when the frontend disconnects.
+	Messages() <-chan Message
+	// Send sends text to a channel, as named in a Message.
+	Send(channel, text string) error
+}
+
+// Core is the shared part of a bot: it learns from the messages its
+// frontends receive (see SetLearning), with a chain for each channel
+// (named "<network>/<channel>") as well as the global chain (see
+// SetRouting), and replies to messages addressed to it, or
+// occasionally others (see SetPolicy), using the channel's chain.
+type Core struct {
+	mu       sync.Mutex
+	chains   *markov.ChainSet
+	triggers []Trigger
+	karma    map[string]int
+	policy   Policy
+	// lastSpoke is when the core last replied in each channel.
+	lastSpoke map[string]time.Time
+	// recent holds the last few messages in each channel.
+	recent map[string][]string
+	// languages holds the language last seen in each channel (see
+	// Routing.Languages).
+	languages map[string]string
+	learning  learning
+	flood     floodState
+	// said registers everything the core has said, so it isn't
+	// learned back.
+	said     *watermark.Registry
+	filter   *moderation.Filter
+	scrubber *moderation.Scrubber
+	routing  Routing
+	metrics  coreMetrics
+	// levels totals the prefix lengths used to generate replies.
+	levels markov.Stats
+	// roles are users' roles for admin commands, and mute holds
+	// when the core was told to be quiet in each channel until (zero
+	// for until unmuted).
+	roles   map[string]Role
+	actions AdminActions
+	mute    map[string]time.Time
+	shadow  bool
+	// feeds holds the IDs of the entries of each feed the core has
+	// learned, by the feed's URL (see PollFeed).
+	feeds map[string]map[string]bool
+	// fetching is done when "!learn stop" gives up on the pages
+	// being fetched to learn, and is nil if none have been since.
+	fetching     context.Context
+	stopFetching context.CancelFunc
+}
+
+// NewCore returns a Core using the given chains. The chains must not
+// be used elsewhere while the core is running, except through Do.
+func NewCore(chains *markov.ChainSet) *Core {
+	// A key only fails to generate if the system's random number
+	// generator is broken, and then there's nothing else to use
+	key, err := watermark.NewKey()
+	if err != nil {
+		panic(err)
+	}
+	return &Core{
+		chains:    chains,
+		karma:     make(map[string]int),
+		lastSpoke: make(map[string]time.Time),
+		recent:    make(map[string][]string),
+		languages: make(map[string]string),
+		said:      watermark.NewRegistry(key),
+		metrics:   newCoreMetrics(),
+		roles:     make(map[string]Role),
+		mute:      make(map[string]time.Time),
+		feeds:     make(map[string]map[string]bool),
+		learning: learning{
+			Channels: make(map[string]bool),
+			Users:    make(map[string]bool),
+		},
+	}
+}
+
+// SetFilter sets a filter for what the core says (see package
+// moderation), or removes it if filter is nil.
+func (c *Core) SetFilter(filter *moderation.Filter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filter = filter
+}
+
+// SetScrubber sets a scrubber masking personal information and
+// secrets in everything the core learns, from messages, feeds, and
+// "!learn url", or removes it if scrubber is nil.
+func (c *Core) SetScrubber(scrubber *moderation.Scrubber) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scrubber = scrubber
+}
+
+// scrub returns text masked by the core's scrubber, if any.
+func (c *Core) scrub(text string) string {
+	return c.scrubber.Scrub(text)
+}
+
+// learnable returns the part of a message's text that the core learns:
+// its punctuation normalized, anything the core said itself stripped
+// (see Registry.Strip), and the rest scrubbed. Anything that removes
+// learned text must prepare it the same way, or it won't match.
+func (c *Core) learnable(text string) string {
+	return c.scrub(c.said.Strip(stringutil.NormalizePunctuation(text)))
+}
+
+// scrubMessages masks the text of messages from a corpus with the
+// core's scrubber, if any.
+func (c *Core) scrubMessages(msgs []corpus.Message) {
+	for i := range msgs {
+		msgs[i].Text = c.scrub(msgs[i].Text)
+	}
+}
+
+// Do calls f with the core's chains while no messages are being
+// handled, e.g. to save them.
+func (c *Core) Do(f func(chains *markov.ChainSet)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f(c.chains)
+}
+
+// LoadRegistry replaces the registry of what the core has said (see
+// package watermark) with one using the key in keyFile, created if
+// missing, and loaded from filename, if it exists. Otherwise the core
+// only remembers what it said since it started.
+func (c *Core) LoadRegistry(keyFile, filename string) error {
+	key, err := watermark.LoadKey(keyFile)
+	if err != nil {
+		return err
+	}
+	said := watermark.NewRegistry(key)
+	if err := said.Load(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.said = said
+	return nil
+}
+
+// SaveRegistry saves the registry of what the core has said to a file.
+func (c *Core) SaveRegistry(filename string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.said.Save(filename)
+}
+
+// Run handles the messages a frontend receives, sending its replies
+// through the frontend (or only logging them, in shadow mode; see
+// SetShadow), until the frontend's Messages channel is closed. Run may
+// be called concurrently for several frontends.
+func (c *Core) Run(f Frontend) {
+	id := f.Identity()
+	for m := range f.Messages() {
+		reply, why := c.handle(id, m)
+		if reply == "" {
+			continue
+		}
+		if c.shadowed() {
+			log.Printf("Shadow: would say in %s %s (%s): %q", id.Network, m.Channel, why, reply)
+			continue
+		}
+		if err := f.Send(m.Channel, reply); err != nil {
+			log.Printf("Error sending to %s %s: %v", id.Network, m.Channel, err)
+		}
+	}
+}
+
+// Handle learns from a message received by the frontend with the
+// given identity, unless told not to (see SetLearning), except for
+// anything the core said itself, and returns a reply if the message
+// triggers one of the core's triggers (see AddTrigger), is addressed
+// to the bot, or is chosen for an unprompted reply by the core's
+// policy (see SetPolicy), or "" if not, the bot has nothing to say,
+// or it has been muted in the channel (see Admin).
+func (c *Core) Handle(id Identity, m Message) string {
+	reply, _ := c.handle(id, m)
+	return reply
+}
+
+// handle implements Handle, additionally returning why the core
+// replied: which trigger matched, or what the reply was generated
+// from.
+func (c *Core) handle(id Identity, m Message) (reply, why string) {
+	if m.Sender == id.ID || strings.TrimSpace(m.Text) == "" {
+		return "", ""
+	}
+	c.metrics.received.Inc()
+	text := stringutil.NormalizePunctuation(m.Text)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name := id.Network + "/" + m.Channel
+	user := id.Network + "/" + m.Sender
+	lang := c.language(name, text)
+	chain := c.chains.Chain(languageChain(name, lang))
+	seed, addressed := stringutil.Address(text, id.Name)
+	addressed = addressed || m.Addressed
+	if addressed {
+		request := seed
+		if request == "" {
+			request = text
+		}
+		if reply, ok := c.learningCommand(user, request); ok {
+			return reply, "learning command"
+		}
+	}
+
+	// Pick the keyword before learning the message, so its own words
+	// don't seem commoner than they are
+	keyword := c.keyword(chain, text, id.Name)
+	topic := c.keyword(chain, strings.Join(c.recent[name], " "), id.Name)
+	// Don't learn back anything the bot said, e.g. echoed by a bridge,
+	// or admin commands
+	if learned := c.learnable(m.Text); learned != "" && c.learns(name, user) && !adminCommand.MatchString(m.Text) && !c.dampen(user, learned) {
+		chain.Build(strings.NewReader(learned))
+		c.metrics.learned.Inc()
+		c.metrics.words.Add(uint64(len(strings.Fields(learned))))
+		if !c.routing.NoGlobal {
+			c.chains.Chain(languageChain(GlobalChain, lang)).Build(strings.NewReader(learned))
+		}
+		recent := append(c.recent[name], learned)
+		if len(recent) > contextMessages {
+			recent = recent[len(recent)-contextMessages:]
+		}
+		c.recent[name] = recent
+	}
+
+	chain, done := c.replyChain(chain, name, lang)
+	defer done()
+	// Only admin commands work while muted, so the core can be
+	// unmuted
+	if c.muted(name) && !adminCommand.MatchString(m.Text) {
+		return "", ""
+	}
+	reply, t := c.trigger(m, name, user, addressed, chain)
+	var regenerate func() string
+	if t != nil {
+		why = "trigger " + t.String()
+	} else {
+		reason := "addressed"
+		if !addressed {
+			if !c.volunteer(name) {
+				return "", ""
+			}
+			reason = "unprompted"
+		}
+		if !chain.Ready(c.policy.MinTokens) {
+			if !addressed {
+				return "", ""
+			}
+			reply, why = c.policy.warmup(), "addressed, warming up"
+		} else {
+			regenerate = func() string {
+				start := time.Now()
+				defer func() { c.metrics.latency.Observe(time.Since(start).Seconds()) }()
+				c.metrics.generated.Inc()
+				reply, from := c.generate(chain, name, seed, keyword, topic)
+				why = reason + ", from " + from
+				return reply
+			}
+			reply = regenerate()
+		}
+	}
+	// Handlers may have side effects, so only generated replies are
+	// regenerated
+	reply = c.filter.Apply(reply, regenerate)
+	if reply != "" {
+		c.metrics.replies.Inc()
+		c.lastSpoke[name] = time.Now()
+		c.said.Record(reply)
+	}
+	return reply, why
+}
+
+// keyword returns the most distinctive word of a message (see
+// markov.Chain.Keywords) other than the bot's name, or "" if there
+// isn't one.
+func (c *Core) keyword(chain *markov.Chain, text, name string) string {
+	for _, w := range chain.Keywords(text) {
+		if w != strings.ToLower(strings.TrimPrefix(name, "@")) {
+			return w
+		}
+	}
+	return ""
+}
+
+// generate generates a reply in a channel, continuing seed if the
+// chain can, or else starting from keyword, or else continuing the
+// last message there, or else starting from topic (the most
+// distinctive word of the last few messages), or else from scratch,
+// rather than just repeating what was said. It also returns a
+// description of which it did.
+func (c *Core) generate(chain *markov.Chain, channel, seed, keyword, topic string) (string, string) {
+	if c.policy.Spelling > 0 {
+		seed = chain.CorrectSpelling(seed, c.policy.Spelling)
+	}
+	for i, start := range []string{seed, keyword} {
+		if start == "" {
+			continue
+		}
+		reply := c.generateFrom(chain, start)
+		if reply != strings.Join(strings.Fields(start), " ") {
+			return reply, fmt.Sprintf("%s %q", []string{"seed", "keyword"}[i], start)
+		}
+	}
+
+	if recent := c.recent[channel]; len(recent) > 0 {
+		last := strings.Fields(recent[len(recent)-1])
+		words := strings.Fields(c.generateFrom(chain, strings.Join(last, " ")))
+		if len(words) > len(last) {
+			return strings.Join(words[len(last):], " "), "conversation"
+		}
+	}
+	if topic != "" {
+		if reply := c.generateFrom(chain, topic); reply != topic {
+			return reply, fmt.Sprintf("topic %q", topic)
+		}
+	}
+	return c.generateFrom(chain, ""), "scratch"
+}
+
+// generateFrom generates a sentence from a chain, continuing start,
+// and counts the prefix lengths it used in the core's levels.
+func (c *Core) generateFrom(chain *markov.Chain, start string) string {
+	r := chain.GenerateResult(start, 1, maxWords)
+	c.levels.Add(r.Levels)
+	return r.Text
+}
+
+// Complete returns up to n distinct completions of the given text,
+// generated from the global chain.
+func (c *Core) Complete(text string, n int) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chain := c.chains.Get(GlobalChain)
+	if chain == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var completions []string
+	// Allow some extra tries for duplicates
+	for i := 0; i < 2*n && len(completions) < n; i++ {
+		s := chain.Generate(text, 1, maxWords)
+		if c.filter.Matches(s) {
+			continue
+		}
+		if s != "" && s != strings.TrimSpace(text) && !seen[s] {
+			seen[s] = true
+			completions = append(completions, s)
+			// Users send completions as their own messages
+			c.said.Record(s)
+		}
+	}
+	return completions
+}
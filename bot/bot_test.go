@@ -0,0 +1,38 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+func TestGenerate(t *testing.T) {
+	chain := markov.NewChain(2)
+	chain.Build(strings.NewReader("zebras eat grass. the weather is nice."))
+	c := NewCore(markov.NewChainSet(2))
+
+	tests := []struct {
+		seed, keyword, topic string
+		recent               []string
+		from                 string
+	}{
+		{"zebras", "weather", "", nil, `seed "zebras"`},
+		{"", "weather", "zebras", nil, `keyword "weather"`},
+		{"", "", "", []string{"the weather"}, "conversation"},
+		{"", "", "zebras", []string{"i saw zebras", "nice."}, `topic "zebras"`},
+		{"", "", "", nil, "scratch"},
+	}
+	for _, tt := range tests {
+		c.recent["c"] = tt.recent
+		reply, from := c.generate(chain, "c", tt.seed, tt.keyword, tt.topic)
+		if from != tt.from || reply == "" {
+			t.Errorf("generate(%q, %q, %q) with %q = %q, from %s, want from %s", tt.seed, tt.keyword, tt.topic, tt.recent, reply, from, tt.from)
+		}
+	}
+}
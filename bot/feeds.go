@@ -0,0 +1,201 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// feeds.go lets a Core learn from RSS and Atom feeds, polling them for
+// new entries, and post a daily summary riffing on their headlines.
+
+package bot
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/corpus"
+)
+
+// summaryEvery is how often a feed's summary is posted.
+const summaryEvery = 24 * time.Hour
+
+// summaryHeadlines is the most headlines a summary riffs on.
+const summaryHeadlines = 3
+
+// A Feed is an RSS or Atom feed for the core to learn from (see
+// PollFeed).
+type Feed struct {
+	URL string
+	// Chain is the chain new entries are learned into, or
+	// GlobalChain if it's empty.
+	Chain string
+	// Every is how often the feed is polled.
+	Every time.Duration
+	// Summary is a channel, as named in the frontend's Messages, to
+	// post a summary of the day's headlines in, if it isn't empty.
+	Summary string
+}
+
+// PollFeed polls a feed every feed.Every until stop is closed, from
+// now, learning the text of entries it hasn't seen before (see
+// LoadFeeds), whether or not the core learns from messages. If
+// feed.Summary is set, once a day it also posts text generated from
+// the headlines of the entries learned since the last summary there,
+// through the frontend, as Run would a reply (so not while muted, and
+// only logged in shadow mode).
+func (c *Core) PollFeed(f Frontend, feed Feed, stop <-chan struct{}) {
+	if feed.Chain == "" {
+		feed.Chain = GlobalChain
+	}
+	var headlines []string
+	lastSummary := time.Now()
+	tick := time.NewTicker(feed.Every)
+	defer tick.Stop()
+	for {
+		headlines = append(headlines, c.pollFeed(feed)...)
+		if feed.Summary != "" && len(headlines) > 0 && time.Since(lastSummary) >= summaryEvery {
+			c.postSummary(f, feed, headlines)
+			headlines, lastSummary = nil, time.Now()
+		}
+		select {
+		case <-tick.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// pollFeed fetches a feed and learns the entries it hasn't seen
+// before, returning their headlines.
+func (c *Core) pollFeed(feed Feed) []string {
+	items, err := corpus.FetchFeed(feed.URL, 0)
+	if err != nil {
+		log.Printf("Polling %s: %v", feed.URL, err)
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := c.feeds[feed.URL]
+	// Only remember the entries still in the feed, as older ones
+	// won't come back
+	c.feeds[feed.URL] = make(map[string]bool)
+	chain := c.chains.Chain(feed.Chain)
+	var headlines []string
+	words := 0
+	for _, item := range items {
+		c.feeds[feed.URL][item.ID] = true
+		if seen[item.ID] {
+			continue
+		}
+		msgs := item.Messages()
+		c.scrubMessages(msgs)
+		n, err := corpus.Build(chain, msgs, false)
+		if err != nil {
+			log.Printf("Learning %s: %v", feed.URL, err)
+		}
+		words += n
+		if item.Title != "" {
+			headlines = append(headlines, item.Title)
+		}
+	}
+	if words > 0 {
+		c.metrics.words.Add(uint64(words))
+		log.Printf("Learned %d words from %s into %s", words, feed.URL, feed.Chain)
+	}
+	return headlines
+}
+
+// postSummary posts text generated from a feed's headlines: a sentence
+// for each of the last few, starting from its most distinctive word.
+func (c *Core) postSummary(f Frontend, feed Feed, headlines []string) {
+	id := f.Identity()
+	name := id.Network + "/" + feed.Summary
+	if len(headlines) > summaryHeadlines {
+		headlines = headlines[len(headlines)-summaryHeadlines:]
+	}
+
+	c.mu.Lock()
+	if c.muted(name) {
+		c.mu.Unlock()
+		return
+	}
+	chain := c.chains.Chain(feed.Chain)
+	var sentences []string
+	for _, h := range headlines {
+		keyword := c.keyword(chain, h, id.Name)
+		if keyword == "" {
+			continue
+		}
+		generate := func() string { return chain.Generate(keyword, 1, maxWords) }
+		s := c.filter.Apply(generate(), generate)
+		if s != "" && s != keyword {
+			sentences = append(sentences, s)
+		}
+	}
+	summary := ""
+	if len(sentences) > 0 {
+		summary = "In the news: " + strings.Join(sentences, " ")
+		c.said.Record(summary)
+		c.lastSpoke[name] = time.Now()
+	}
+	shadow := c.shadow
+	c.mu.Unlock()
+
+	switch {
+	case summary == "":
+	case shadow:
+		log.Printf("Shadow: would say in %s %s (summary of %s): %q", id.Network, feed.Summary, feed.URL, summary)
+	default:
+		if err := f.Send(feed.Summary, summary); err != nil {
+			log.Printf("Error sending to %s %s: %v", id.Network, feed.Summary, err)
+		}
+	}
+}
+
+// LoadFeeds attempts to load which feed entries the core has seen, in
+// JSON format, from a file, so that restarting doesn't learn them
+// again.
+func (c *Core) LoadFeeds(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	feeds := make(map[string][]string)
+	if err := json.NewDecoder(f).Decode(&feeds); err != nil {
+		return err
+	}
+	for url, ids := range feeds {
+		c.feeds[url] = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			c.feeds[url][id] = true
+		}
+	}
+	return nil
+}
+
+// SaveFeeds saves which feed entries the core has seen to a file in
+// JSON format.
+func (c *Core) SaveFeeds(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	feeds := make(map[string][]string, len(c.feeds))
+	for url, seen := range c.feeds {
+		for id := range seen {
+			feeds[url] = append(feeds[url], id)
+		}
+	}
+	return json.NewEncoder(f).Encode(feeds)
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// flood.go limits how much a Core learns from floods of messages, so
+// that copypasta and bot spam don't come to dominate what it says.
+
+package bot
+
+import (
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// Flood limits how much a Core learns from any one user, or any one
+// message, in each window of time. Messages over the limits are still
+// replied to as usual; they just aren't learned.
+type Flood struct {
+	// Window is the length of the windows the limits apply in.
+	// There are no limits if it's zero.
+	Window time.Duration
+	// User is the most messages learned from a user in a window,
+	// across all channels, or 0 for no limit.
+	User int
+	// Repeats is the most times the same message (ignoring case and
+	// spacing) is learned in a window, from anyone, anywhere, or 0
+	// for no limit.
+	Repeats int
+}
+
+// floodState counts what a Core has learned in the current window.
+type floodState struct {
+	Flood
+	start   time.Time
+	users   map[string]int
+	repeats map[uint64]int
+}
+
+// SetFlood sets how much the core learns from floods of messages.
+func (c *Core) SetFlood(f Flood) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flood = floodState{Flood: f}
+}
+
+// dampen reports whether a user's message shouldn't be learned for
+// going over the core's flood limits (see SetFlood), counting it
+// against them if not.
+func (c *Core) dampen(user, text string) bool {
+	f := &c.flood
+	if f.Window <= 0 {
+		return false
+	}
+	// The windows are fixed rather than sliding, so that the counts
+	// don't grow without bound
+	if now := time.Now(); now.Sub(f.start) >= f.Window {
+		f.start = now
+		f.users = make(map[string]int)
+		f.repeats = make(map[uint64]int)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToLower(strings.Join(strings.Fields(text), " "))))
+	key := h.Sum64()
+	if (f.User > 0 && f.users[user] >= f.User) || (f.Repeats > 0 && f.repeats[key] >= f.Repeats) {
+		c.metrics.dampened.Inc()
+		return true
+	}
+	f.users[user]++
+	f.repeats[key]++
+	return false
+}
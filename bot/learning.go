@@ -0,0 +1,135 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// learning.go controls what a Core learns from: learning can be
+// turned off entirely, in particular channels, or for particular
+// users, who can ask the bot not to learn from them.
+
+package bot
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// learning records where a Core doesn't learn. Off is set for the
+// life of the core only, and isn't saved.
+type learning struct {
+	Off bool `json:"-"`
+	// Channels and Users are the channels and users not to learn
+	// from, named "<network>/<channel>" and "<network>/<sender>".
+	Channels map[string]bool
+	Users    map[string]bool
+}
+
+// optOutCommand and optInCommand match requests from users, addressed
+// to the bot, not to learn from them, or to learn from them again.
+// Opting out is easy, so anything that sounds like it counts, but
+// opting back in takes the command on its own, so that e.g. "you
+// can't learn from me" doesn't.
+var optOutCommand = regexp.MustCompile("(?i)\\b(don'?t|do not|stop|never) learn(ing)? from me\\b")
+var optInCommand = regexp.MustCompile("(?i)^(please )?(start learning|learn|you can learn) from me( again)?( please)?[.!]*$")
+
+// SetLearning sets whether the core learns from messages at all. It
+// still replies to them.
+func (c *Core) SetLearning(on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.learning.Off = !on
+}
+
+// SetChannelLearning sets whether the core learns from messages in a
+// channel, named "<network>/<channel>" like its chain.
+func (c *Core) SetChannelLearning(channel string, on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if on {
+		delete(c.learning.Channels, channel)
+	} else {
+		c.learning.Channels[channel] = true
+	}
+}
+
+// SetUserLearning sets whether the core learns from messages sent by a
+// user, named "<network>/<sender>". Users can also ask the bot
+// themselves, by telling it "don't learn from me", or just "learn
+// from me again".
+func (c *Core) SetUserLearning(user string, on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setUserLearning(user, on)
+}
+
+// setUserLearning implements SetUserLearning, with the lock held.
+func (c *Core) setUserLearning(user string, on bool) {
+	if on {
+		delete(c.learning.Users, user)
+	} else {
+		c.learning.Users[user] = true
+	}
+}
+
+// learns reports whether the core learns from a user's messages in a
+// channel.
+func (c *Core) learns(channel, user string) bool {
+	return !c.learning.Off && !c.learning.Channels[channel] && !c.learning.Users[user]
+}
+
+// learningCommand handles a user's request, addressed to the bot, to
+// stop or start learning from them, returning the reply and whether
+// the message was such a request. The request is text, less any
+// leading address (see stringutil.Address).
+func (c *Core) learningCommand(user, text string) (string, bool) {
+	switch {
+	case optOutCommand.MatchString(text):
+		c.setUserLearning(user, false)
+		return "OK, I won't learn from you anymore.", true
+	case optInCommand.MatchString(strings.TrimSpace(text)):
+		c.setUserLearning(user, true)
+		return "OK, I'll learn from you again.", true
+	}
+	return "", false
+}
+
+// LoadLearning attempts to load the core's learning settings in JSON
+// format from a file.
+func (c *Core) LoadLearning(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&c.learning); err != nil {
+		return err
+	}
+	if c.learning.Channels == nil {
+		c.learning.Channels = make(map[string]bool)
+	}
+	if c.learning.Users == nil {
+		c.learning.Users = make(map[string]bool)
+	}
+	return nil
+}
+
+// SaveLearning saves the core's learning settings to a file in JSON
+// format.
+func (c *Core) SaveLearning(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	enc := json.NewEncoder(f)
+	return enc.Encode(c.learning)
+}
@@ -0,0 +1,61 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package bot
+
+import (
+	"testing"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+func TestLearningCommand(t *testing.T) {
+	tests := []struct {
+		before bool // whether the core learned from the user before
+		text   string
+		ok     bool
+		after  bool
+	}{
+		{true, "don't learn from me", true, false},
+		{true, "please never learn from me", true, false},
+		{true, "stop learning from me!", true, false},
+		{false, "learn from me again", true, true},
+		{false, "You can learn from me.", true, true},
+		{false, "start learning from me again please", true, true},
+		{false, "you can't learn from me", false, false},
+		{false, "you cannot learn from me", false, false},
+		{false, "I won't let you learn from me", false, false},
+		{false, "nobody should learn from me", false, false},
+		{true, "what did you learn from me?", false, true},
+	}
+	for _, tt := range tests {
+		c := NewCore(markov.NewChainSet(2))
+		c.setUserLearning("test/u", tt.before)
+		_, ok := c.learningCommand("test/u", tt.text)
+		if after := c.learns("test/c", "test/u"); ok != tt.ok || after != tt.after {
+			t.Errorf("learningCommand(%q) = %v, learning %v, want %v, %v", tt.text, ok, after, tt.ok, tt.after)
+		}
+	}
+}
+
+func TestChannelLearning(t *testing.T) {
+	c := NewCore(markov.NewChainSet(2))
+	c.SetChannelLearning("test/quiet", false)
+	id := Identity{Network: "test", ID: "bot", Name: "clyde"}
+	c.Handle(id, Message{Channel: "quiet", Sender: "u", Text: "secret plans"})
+	c.Handle(id, Message{Channel: "loud", Sender: "u", Text: "public plans"})
+	if chain := c.chains.Get("test/quiet"); chain != nil && chain.Size() > 0 {
+		t.Errorf("learned in a channel with learning off")
+	}
+	if chain := c.chains.Get("test/loud"); chain == nil || chain.Size() == 0 {
+		t.Errorf("didn't learn in a channel with learning on")
+	}
+
+	c.SetChannelLearning("test/quiet", true)
+	c.Handle(id, Message{Channel: "quiet", Sender: "u", Text: "new plans"})
+	if chain := c.chains.Get("test/quiet"); chain == nil || chain.Size() == 0 {
+		t.Errorf("didn't learn in a channel with learning back on")
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// metrics.go counts what a Core does, for monitoring long-running
+// bots (see package metrics).
+
+package bot
+
+import "github.com/sdukhovni/clyde-go/metrics"
+
+// coreMetrics are the counters a Core keeps whether or not they're
+// exported.
+type coreMetrics struct {
+	received  *metrics.Counter
+	learned   *metrics.Counter
+	dampened  *metrics.Counter
+	words     *metrics.Counter
+	replies   *metrics.Counter
+	generated *metrics.Counter
+	latency   *metrics.Histogram
+}
+
+func newCoreMetrics() coreMetrics {
+	return coreMetrics{
+		received:  metrics.NewCounter("clyde_messages_received_total", "Number of messages received from all frontends."),
+		learned:   metrics.NewCounter("clyde_messages_learned_total", "Number of messages learned."),
+		dampened:  metrics.NewCounter("clyde_messages_dampened_total", "Number of messages not learned for going over flood limits."),
+		words:     metrics.NewCounter("clyde_words_learned_total", "Number of words learned."),
+		replies:   metrics.NewCounter("clyde_replies_total", "Number of replies sent, generated or not."),
+		generated: metrics.NewCounter("clyde_generations_total", "Number of replies generated from a chain."),
+		latency:   metrics.NewHistogram("clyde_generation_seconds", "Time taken to generate a reply.", nil),
+	}
+}
+
+// RegisterMetrics adds the core's counters, and gauges describing its
+// chains and the replies generated from them (see
+// metrics.ChainGauges), to a registry.
+func (c *Core) RegisterMetrics(r *metrics.Registry) {
+	m := c.metrics
+	r.Register(m.received, m.learned, m.dampened, m.words, m.replies, m.generated, m.latency)
+	r.Register(metrics.ChainGauges("clyde_", c.Do, &c.levels)...)
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// policy.go decides when a Core speaks up in a channel without being
+// addressed.
+
+package bot
+
+import (
+	"math/rand"
+	"time"
+)
+
+// A Policy says how often a Core replies to messages that aren't
+// addressed to it. Addressed messages always get a reply, if the core
+// has anything to say.
+type Policy struct {
+	// Chance is the probability of replying to each unaddressed
+	// message.
+	Chance float64
+	// Channels overrides Chance for particular channels, named
+	// "<network>/<channel>" like their chains.
+	Channels map[string]float64
+	// Cooldown is the minimum time after the core says something in
+	// a channel before it replies there unprompted.
+	Cooldown time.Duration
+	// MinTokens is how many words the chain a reply would come from
+	// must have learned (see markov.Chain.Ready) before the core
+	// generates replies from it. Until then, the core doesn't reply
+	// unprompted, and answers addressed messages with one of Warmup,
+	// if there are any, rather than the odd word or two the chain
+	// knows. Triggers work as usual.
+	MinTokens int
+	Warmup    []string
+	// Spelling is the most edits by which to correct words of a
+	// message the chain doesn't know before continuing it (see
+	// markov.Chain.CorrectSpelling), or 0 to leave them as they are.
+	Spelling int
+}
+
+// SetPolicy sets the policy for unprompted replies. By default, the
+// core never replies unprompted.
+func (c *Core) SetPolicy(p Policy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policy = p
+}
+
+// chance returns the probability of replying unprompted in a channel.
+func (p *Policy) chance(channel string) float64 {
+	if chance, ok := p.Channels[channel]; ok {
+		return chance
+	}
+	return p.Chance
+}
+
+// warmup returns a canned response for when a chain hasn't learned
+// enough to reply from, or "" if there are none.
+func (p *Policy) warmup() string {
+	if len(p.Warmup) == 0 {
+		return ""
+	}
+	return p.Warmup[rand.Intn(len(p.Warmup))]
+}
+
+// volunteer reports whether to reply unprompted in a channel now.
+func (c *Core) volunteer(channel string) bool {
+	if time.Since(c.lastSpoke[channel]) < c.policy.Cooldown {
+		return false
+	}
+	return rand.Float64() < c.policy.chance(channel)
+}
@@ -0,0 +1,63 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// roles.go maps the users of a Core's frontends to roles, which
+// decide what admin commands they may run, so that an ordinary
+// channel member can't make the bot forget what it has learned.
+
+package bot
+
+import "fmt"
+
+// A Role is what a user may do with a Core. Each role may do
+// everything the roles below it may.
+type Role int
+
+const (
+	// RoleUser is the role of everyone not given another.
+	RoleUser Role = iota
+	// RoleAdmin may run the bot day to day: save, mute, and stop
+	// learning.
+	RoleAdmin
+	// RoleOwner may also run commands that lose what the bot has
+	// learned.
+	RoleOwner
+)
+
+var roleNames = []string{"user", "admin", "owner"}
+
+// String returns the role's name.
+func (r Role) String() string {
+	if r < 0 || int(r) >= len(roleNames) {
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+	return roleNames[r]
+}
+
+// ParseRole returns the role with the given name.
+func ParseRole(name string) (Role, error) {
+	for i, n := range roleNames {
+		if n == name {
+			return Role(i), nil
+		}
+	}
+	return 0, fmt.Errorf("bot: unknown role %q", name)
+}
+
+// SetRoles sets the roles of users, named "<network>/<sender>",
+// replacing any set before. Everyone else is a RoleUser.
+func (c *Core) SetRoles(roles map[string]Role) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roles = make(map[string]Role, len(roles))
+	for user, role := range roles {
+		c.roles[user] = role
+	}
+}
+
+// role returns a user's role.
+func (c *Core) role(user string) Role {
+	return c.roles[user]
+}
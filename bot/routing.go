@@ -0,0 +1,113 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// routing.go decides which chains a Core learns from and replies
+// with, so that each channel can develop a voice of its own while
+// still borrowing from the others until it has learned enough.
+
+package bot
+
+import (
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// channelChainSize is the number of prefixes a channel's chain needs
+// before the core replies there using it alone; smaller chains lean
+// on the global chain proportionally more.
+const channelChainSize = 5000
+
+// Routing says how a Core shares what it learns between channels. By
+// default, every message also trains the global chain, which replies
+// in channels lean on until their own chains are big enough.
+type Routing struct {
+	// Isolated restricts replies in each channel to the channel's
+	// own chain, however little it has learned.
+	Isolated bool
+	// NoGlobal stops the core training the global chain, which
+	// leaves replies isolated and the core without completions (see
+	// Complete).
+	NoGlobal bool
+	// Languages gives each channel, and the global chain, a chain
+	// per language, named like "<network>/<channel>#<language>" and
+	// "global#<language>" (see stringutil.DetectLanguage), so that
+	// replies in bilingual channels don't mix languages. Messages
+	// whose language can't be told are taken to be in the language
+	// last seen in their channel. Chains learned before Languages
+	// was set keep their names, and replies lean on them until the
+	// chains for each language have learned enough.
+	Languages bool
+}
+
+// SetRouting sets how the core shares what it learns between
+// channels.
+func (c *Core) SetRouting(r Routing) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.routing = r
+}
+
+// language returns the language of a message in a channel, for
+// routing by language, or "" if the core doesn't route by language or
+// the language isn't known.
+func (c *Core) language(channel, text string) string {
+	if !c.routing.Languages {
+		return ""
+	}
+	lang := stringutil.DetectLanguage(text)
+	if lang == "" {
+		return c.languages[channel]
+	}
+	c.languages[channel] = lang
+	return lang
+}
+
+// languageChain returns the name of a chain's chain for a language
+// (see Routing.Languages), or of the chain itself if lang is "".
+func languageChain(name, lang string) string {
+	if lang == "" {
+		return name
+	}
+	return name + "#" + lang
+}
+
+// replyChain returns the chain to reply in a channel with: the
+// channel's chain for a language, with the global chain for the same
+// language mixed in until the channel's chain is big enough, unless
+// replies are isolated. Chains for a language also lean on the chains
+// of the same names without one, learned before the core routed by
+// language (see Routing.Languages), until they're big enough
+// themselves, so turning on language routing doesn't start a bot from
+// scratch. The returned function must be called when the core is done
+// with the chain.
+func (c *Core) replyChain(chain *markov.Chain, name, lang string) (*markov.Chain, func()) {
+	// Each chain leans on the next, if there is one
+	var fallbacks []*markov.Chain
+	if lang != "" {
+		fallbacks = append(fallbacks, c.chains.Get(name))
+	}
+	if !c.routing.Isolated && !c.routing.NoGlobal {
+		fallbacks = append(fallbacks, c.chains.Get(languageChain(GlobalChain, lang)))
+		if lang != "" {
+			fallbacks = append(fallbacks, c.chains.Get(GlobalChain))
+		}
+	}
+	var undo []func()
+	used := map[*markov.Chain]bool{chain: true}
+	last := chain
+	for _, fallback := range fallbacks {
+		if fallback == nil || used[fallback] {
+			continue
+		}
+		undo = append(undo, last.SetFallbackUntil(fallback, channelChainSize))
+		used[fallback] = true
+		last = fallback
+	}
+	return chain, func() {
+		for _, f := range undo {
+			f()
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+func TestReplyChainLanguages(t *testing.T) {
+	tests := []struct {
+		routing Routing
+		learned map[string]string
+		seed    string
+		want    string
+	}{
+		// The channel's chain from before language routing
+		{Routing{Languages: true}, map[string]string{"m/c": "zebras eat grass"}, "zebras", "zebras eat grass"},
+		// The global chain from before language routing
+		{Routing{Languages: true}, map[string]string{"global": "zebras eat grass"}, "zebras", "zebras eat grass"},
+		// ... but not if replies are isolated
+		{Routing{Languages: true, Isolated: true}, map[string]string{"global": "zebras eat grass"}, "zebras", "zebras"},
+		{Routing{Languages: true}, map[string]string{"global#en": "zebras eat grass"}, "zebras", "zebras eat grass"},
+		{Routing{}, map[string]string{"global": "zebras eat grass"}, "zebras", "zebras eat grass"},
+		{Routing{Isolated: true}, map[string]string{"global": "zebras eat grass"}, "zebras", "zebras"},
+	}
+	for _, test := range tests {
+		c := NewCore(markov.NewChainSet(2))
+		c.SetRouting(test.routing)
+		for name, text := range test.learned {
+			c.chains.Chain(name).Build(strings.NewReader(text))
+		}
+		lang := ""
+		if test.routing.Languages {
+			lang = "en"
+		}
+		chain, done := c.replyChain(c.chains.Chain(languageChain("m/c", lang)), "m/c", lang)
+		got := chain.Generate(test.seed, 1, 10)
+		done()
+		if got != test.want {
+			t.Errorf("with %+v and %v, Generate(%q) = %q, want %q", test.routing, test.learned, test.seed, got, test.want)
+		}
+		for name := range test.learned {
+			if chain := c.chains.Get(name); chain.Generate(test.seed, 1, 10) != test.learned[name] {
+				t.Errorf("with %+v, chain %s still has a fallback", test.routing, name)
+			}
+		}
+	}
+}
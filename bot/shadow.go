@@ -0,0 +1,28 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// shadow.go lets a Core run without speaking, logging what it would
+// have said instead, so that settings like the reply chance can be
+// tuned on a live channel without anyone noticing.
+
+package bot
+
+// SetShadow sets whether the core runs in shadow mode, in which Run
+// still learns from messages but only logs the replies it would have
+// sent, and why (which trigger matched, or what a reply was generated
+// from). The core otherwise behaves as if it had sent them, e.g.
+// waiting out its cooldown (see Policy) after each.
+func (c *Core) SetShadow(on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shadow = on
+}
+
+// shadowed reports whether the core is in shadow mode.
+func (c *Core) shadowed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.shadow
+}
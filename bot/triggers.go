@@ -0,0 +1,263 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// triggers.go lets a Core respond to particular messages in
+// particular ways (canned responses, seeded replies, dice rolls,
+// karma), which is most of what makes a bot feel like a character
+// rather than a raw text generator.
+
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// A Trigger maps messages matching a pattern or containing a keyword
+// to a handler. When a Core receives a message, it tries its triggers
+// in order of priority (highest first, then in the order they were
+// added), and calls the handler of the first that matches, instead of
+// its usual reply.
+type Trigger struct {
+	// Pattern, if set, is a regular expression (matched
+	// case-insensitively) that messages may match. Its named
+	// capturing groups are passed to the handler.
+	Pattern string
+	// Keywords, if set, are words (matched case-insensitively) that
+	// messages may contain.
+	Keywords []string
+	Priority int
+	// Addressed restricts the trigger to messages addressed to the
+	// bot.
+	Addressed bool
+	Handler   Handler
+
+	rex *regexp.Regexp
+}
+
+// A Match is a message that matched a trigger.
+type Match struct {
+	Message Message
+	// Groups are the named groups of the trigger's pattern.
+	Groups map[string]string
+	// Addressed is set if the message was addressed to the bot.
+	Addressed bool
+
+	core  *Core
+	chain *markov.Chain
+	// channel and user name the message's channel and sender as
+	// "<network>/<channel>" and "<network>/<sender>".
+	channel, user string
+}
+
+// A Handler handles a message matching a trigger, returning a reply, or
+// "" to say nothing.
+type Handler func(m *Match) string
+
+// Generate generates a reply continuing seed from the chain for the
+// message's channel.
+func (m *Match) Generate(seed string) string {
+	return m.chain.Generate(seed, 1, maxWords)
+}
+
+// Expand replaces $name and ${name} in s with the match's groups.
+func (m *Match) Expand(s string) string {
+	return os.Expand(s, func(name string) string { return m.Groups[name] })
+}
+
+// AddTrigger adds a trigger to the core, returning an error if its
+// pattern is invalid or it has no handler.
+func (c *Core) AddTrigger(t Trigger) error {
+	if t.Pattern == "" && len(t.Keywords) == 0 {
+		return fmt.Errorf("bot: trigger needs a pattern or keywords")
+	}
+	if t.Handler == nil {
+		return fmt.Errorf("bot: trigger %v needs a handler", &t)
+	}
+	if t.Pattern != "" {
+		rex, err := regexp.Compile("(?i)" + t.Pattern)
+		if err != nil {
+			return err
+		}
+		t.rex = rex
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.triggers = append(c.triggers, t)
+	sort.SliceStable(c.triggers, func(i, j int) bool {
+		return c.triggers[i].Priority > c.triggers[j].Priority
+	})
+	return nil
+}
+
+// match returns the groups of the trigger's pattern in a message, and
+// whether the message matches the trigger.
+func (t *Trigger) match(text string) (map[string]string, bool) {
+	groups := make(map[string]string)
+	if t.rex != nil {
+		if m := t.rex.FindStringSubmatch(text); m != nil {
+			for i, name := range t.rex.SubexpNames() {
+				if name != "" {
+					groups[name] = m[i]
+				}
+			}
+			return groups, true
+		}
+	}
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		w = strings.Trim(w, ".,;:!?\"'()")
+		for _, k := range t.Keywords {
+			if w == strings.ToLower(k) {
+				return groups, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// String describes the trigger by its pattern or keywords.
+func (t *Trigger) String() string {
+	if t.Pattern != "" {
+		return "/" + t.Pattern + "/"
+	}
+	return "keywords " + strings.Join(t.Keywords, ", ")
+}
+
+// trigger calls the handler of the first trigger matching a message
+// sent by user in channel, returning its reply and the trigger, or
+// nil if none matched.
+func (c *Core) trigger(m Message, channel, user string, addressed bool, chain *markov.Chain) (string, *Trigger) {
+	for i := range c.triggers {
+		t := &c.triggers[i]
+		if t.Addressed && !addressed {
+			continue
+		}
+		groups, ok := t.match(m.Text)
+		if !ok {
+			continue
+		}
+		return t.Handler(&Match{Message: m, Groups: groups, Addressed: addressed, core: c, chain: chain, channel: channel, user: user}), t
+	}
+	return "", nil
+}
+
+// Canned returns a handler replying with one of the given responses,
+// chosen at random, with the match's groups expanded (see
+// Match.Expand), or nil (which AddTrigger rejects) if there are none.
+func Canned(responses ...string) Handler {
+	if len(responses) == 0 {
+		return nil
+	}
+	responses = append([]string(nil), responses...)
+	return func(m *Match) string {
+		return m.Expand(responses[rand.Intn(len(responses))])
+	}
+}
+
+// Reply returns a handler replying with generated text continuing
+// seed, with the match's groups expanded (see Match.Expand); e.g.
+// Reply("I love $thing") with the pattern "do you like (?P<thing>\w+)".
+func Reply(seed string) Handler {
+	return func(m *Match) string {
+		return m.Generate(m.Expand(seed))
+	}
+}
+
+// DicePattern matches dice rolls in standard notation ("roll 2d6"), for
+// use with Dice.
+const DicePattern = `\broll (?P<count>\d*)d(?P<sides>\d+)\b`
+
+// maxDice limits the number of dice and sides a roll can have.
+const maxDice = 100
+
+// Dice returns a handler rolling the dice described by the "count"
+// (default 1) and "sides" groups of a match, as in DicePattern.
+func Dice() Handler {
+	return func(m *Match) string {
+		count := 1
+		if s := m.Groups["count"]; s != "" {
+			count, _ = strconv.Atoi(s)
+		}
+		sides, _ := strconv.Atoi(m.Groups["sides"])
+		if count < 1 || count > maxDice || sides < 1 || sides > maxDice {
+			return "I don't have dice like that."
+		}
+
+		rolls := make([]string, count)
+		total := 0
+		for i := range rolls {
+			roll := rand.Intn(sides) + 1
+			rolls[i] = strconv.Itoa(roll)
+			total += roll
+		}
+		if count == 1 {
+			return fmt.Sprintf("You rolled %d.", total)
+		}
+		return fmt.Sprintf("You rolled %s, for a total of %d.", strings.Join(rolls, " + "), total)
+	}
+}
+
+// KarmaPattern matches karma updates ("pizza++", "mondays--"), for use
+// with Karma.
+const KarmaPattern = `(?P<thing>[\w.-]+)(?P<op>\+\+|--)`
+
+// Karma returns a handler updating the core's karma for the "thing"
+// group of a match, according to its "op" group ("++" or "--"), as in
+// KarmaPattern.
+func Karma() Handler {
+	return func(m *Match) string {
+		thing := strings.ToLower(m.Groups["thing"])
+		if m.Groups["op"] == "--" {
+			m.core.karma[thing]--
+		} else {
+			m.core.karma[thing]++
+		}
+		return fmt.Sprintf("%s now has %d karma.", m.Groups["thing"], m.core.karma[thing])
+	}
+}
+
+// AddStandardTriggers adds triggers for dice rolls (see Dice) and
+// karma updates (see Karma).
+func (c *Core) AddStandardTriggers() {
+	c.AddTrigger(Trigger{Pattern: DicePattern, Handler: Dice()})
+	c.AddTrigger(Trigger{Pattern: KarmaPattern, Handler: Karma()})
+}
+
+// LoadKarma attempts to load karma in JSON format from a file.
+func (c *Core) LoadKarma(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dec := json.NewDecoder(f)
+	return dec.Decode(&c.karma)
+}
+
+// SaveKarma saves karma to a file in JSON format.
+func (c *Core) SaveKarma(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	enc := json.NewEncoder(f)
+	return enc.Encode(c.karma)
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package bot
+
+import (
+	"testing"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+func TestAddTrigger(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger Trigger
+		ok      bool
+	}{
+		{"pattern", Trigger{Pattern: "hello", Handler: Canned("hi")}, true},
+		{"keywords", Trigger{Keywords: []string{"hello"}, Handler: Reply("")}, true},
+		{"no pattern or keywords", Trigger{Handler: Canned("hi")}, false},
+		{"bad pattern", Trigger{Pattern: "(", Handler: Canned("hi")}, false},
+		{"no handler", Trigger{Pattern: "hello"}, false},
+		{"no responses", Trigger{Pattern: "hello", Handler: Canned()}, false},
+	}
+	for _, tt := range tests {
+		c := NewCore(markov.NewChainSet(2))
+		err := c.AddTrigger(tt.trigger)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: AddTrigger = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	c := NewCore(markov.NewChainSet(2))
+	c.AddTrigger(Trigger{Pattern: `hello (?P<name>\w+)`, Handler: Canned("hi, $name")})
+	c.AddTrigger(Trigger{Keywords: []string{"ping"}, Priority: 1, Handler: Canned("pong")})
+	c.AddTrigger(Trigger{Keywords: []string{"secret"}, Addressed: true, Handler: Canned("shh")})
+	id := Identity{Network: "test", ID: "bot", Name: "clyde"}
+
+	tests := []struct {
+		text, want string
+	}{
+		{"hello alice", "hi, alice"},
+		{"Ping!", "pong"},
+		{"hello bob, ping", "pong"},
+		{"a secret", ""},
+		{"clyde: a secret", "shh"},
+	}
+	for _, tt := range tests {
+		if got := c.Handle(id, Message{Channel: "c", Sender: "u", Text: tt.text}); got != tt.want {
+			t.Errorf("Handle(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// classes.go gives each zephyr class Clyde hears its own chain, so he
+// picks up the local dialect of each class he replies on, and lets
+// operators configure extra classes and instances for Clyde to watch
+// and learn from.
+
+package clyde
+
+import (
+	"os"
+	"strings"
+
+	"github.com/zephyr-im/zephyr-go"
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// classChainSize is the number of prefixes a class's chain needs
+// before Clyde replies on that class using it alone; smaller chains
+// lean on the main chain proportionally more.
+const classChainSize = 5000
+
+// classChainMinSize is the number of prefixes a class's chain needs
+// before Clyde uses it at all.
+const classChainMinSize = 50
+
+// classKey normalizes a zephyr class for use as the key of a class
+// chain, since zephyr classes are case-insensitive.
+func classKey(class string) string {
+	return strings.ToLower(class)
+}
+
+// replyChain returns the chain Clyde should generate replies to a
+// zephyr with: the chain for the zephyr's class, with the main chain
+// mixed in until the class chain is big enough, or just chainFor's
+// chain for private zephyrs and classes Clyde hasn't heard enough
+// from. The returned function must be called when Clyde is done with
+// the chain.
+func (c *Clyde) replyChain(r zephyr.MessageReaderResult) (*markov.Chain, func()) {
+	main := c.chainFor(r)
+	chain := c.classChains.Lookup(classKey(r.Message.Header.Class), c.isPrivate(r))
+	if chain == nil || chain.Size() < classChainMinSize {
+		return main, func() {}
+	}
+	return chain, chain.SetFallbackUntil(main, classChainSize)
+}
+
+// loadWatches subscribes Clyde to the classes and instances listed in
+// his watch file, one "class [instance]" per line, for him to learn
+// from without replying (unless he's also subscribed to the class in
+// the usual way; see watchOnly).
+func (c *Clyde) loadWatches() error {
+	if _, err := os.Stat(c.path(watchFile)); err != nil {
+		c.watches = nil
+		return err
+	}
+	lines, err := allLines(c, watchFile)
+	if err != nil {
+		return err
+	}
+
+	var subList []zephyr.Subscription
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		instance := "*"
+		if len(fields) > 1 {
+			instance = fields[1]
+		}
+		subList = append(subList, zephyr.Subscription{Class: fields[0], Instance: instance, Recipient: ""})
+	}
+
+	c.watches = subList
+	if !c.sandbox {
+		c.session.SendSubscribeNoDefaults(c.ctx, subList)
+	}
+
+	return nil
+}
+
+// watchOnly returns whether Clyde only hears a zephyr because he's
+// watching its class and instance, in which case he learns from it but
+// mustn't reply. Personal zephyrs, his home instance, and classes he's
+// subscribed to in the usual way are never watch-only.
+func (c *Clyde) watchOnly(r zephyr.MessageReaderResult) bool {
+	h := r.Message.Header
+	if h.Recipient != "" || (h.Class == homeClass && h.Instance == homeInstance) || c.subs[h.Class] != 0 {
+		return false
+	}
+	for _, w := range c.watches {
+		if strings.EqualFold(w.Class, h.Class) && (w.Instance == "*" || strings.EqualFold(w.Instance, h.Instance)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package clyde
+
+import (
+	"testing"
+
+	"github.com/zephyr-im/zephyr-go"
+)
+
+func TestWatchOnly(t *testing.T) {
+	c := &Clyde{
+		subs: map[string]classPolicy{"sipb": FULL},
+		watches: []zephyr.Subscription{
+			{Class: "Help", Instance: "*"},
+			{Class: "sipb", Instance: "*"},
+			{Class: "ztoys", Instance: "*"},
+			{Class: "games", Instance: "chess"},
+		},
+	}
+	tests := []struct {
+		class, instance, recipient string
+		want                       bool
+	}{
+		{"help", "anything", "", true},
+		{"HELP", "x", "", true},
+		{"games", "Chess", "", true},
+		{"games", "go", "", false},
+		{"sipb", "x", "", false}, // subscribed in the usual way
+		{homeClass, homeInstance, "", false},
+		{homeClass, "other", "", true},
+		{"help", "x", "someone", false}, // personal
+		{"other", "x", "", false},
+	}
+	for _, tt := range tests {
+		r := zephyr.MessageReaderResult{Message: &zephyr.Message{Header: zephyr.Header{
+			Class: tt.class, Instance: tt.instance, Recipient: tt.recipient,
+		}}}
+		if got := c.watchOnly(r); got != tt.want {
+			t.Errorf("watchOnly(-c %s -i %s -r %q) = %v, want %v", tt.class, tt.instance, tt.recipient, got, tt.want)
+		}
+	}
+}
@@ -28,11 +28,14 @@ import (
 	"fmt"
 	"github.com/zephyr-im/krb5-go"
 	"github.com/zephyr-im/zephyr-go"
+	"github.com/sdukhovni/clyde-go/backup"
 	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/moderation"
 	"github.com/sdukhovni/clyde-go/mood"
 	"github.com/sdukhovni/clyde-go/cat"
 	"github.com/sdukhovni/clyde-go/stringutil"
 	"github.com/sdukhovni/clyde-go/util"
+	"github.com/sdukhovni/clyde-go/watermark"
 )
 
 // Clyde (the struct) holds all of the internal state needed for Clyde
@@ -52,21 +55,44 @@ type Clyde struct {
 	cat cat.Cat
 	shutdown chan struct{}
 	wg sync.WaitGroup
+	allowlist []string
+	filter *moderation.Filter
+	scrubber *moderation.Scrubber
+	optOut map[string]bool
+	mu sync.Mutex // held while handling messages, ticks, and admin requests
+	sandbox bool // if set, there is no zephyr session and nothing is really sent
+	sandboxSent int
+	privateChain *markov.Chain
+	chains *markov.ChainSet // routes zephyrs to chain or privateChain
+	userChains *markov.ChainSet
+	classChains *markov.ChainSet
+	watches []zephyr.Subscription // classes and instances to learn from only
+	privateClasses map[string]bool
+	interjections map[string]*interjectionCounts
+	extraInterjections []string
+	interjectLimiter rateLimiter
+	watermarks *watermark.Registry
+	adventures map[string]*adventure
+	stats markov.Stats // prefix lengths used to generate replies
+	outgoing chan pendingZephyr // zephyrs waiting to be sent, once running
+}
+
+// A pendingZephyr is a zephyr waiting to be sent after a delay (see
+// sendLoop).
+type pendingZephyr struct {
+	msg *zephyr.Message
+	delay time.Duration
 }
 
 // LoadClyde initializes a Clyde by loading data files found in the
 // given directory, returning an error if the directory does not
 // exist and cannot be created.
 func LoadClyde(dir string) (*Clyde, error) {
-	err := os.MkdirAll(dir, 0755)
+	c, err := newClyde(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	c := &Clyde{}
-
-	c.homeDir = dir
-
 	// Set up zephyr session
 	c.session, err = zephyr.DialSystemDefault()
 	if err != nil {
@@ -79,12 +105,45 @@ func LoadClyde(dir string) (*Clyde, error) {
 		return nil, err
 	}
 
+	c.session.SendSubscribeNoDefaults(c.ctx, []zephyr.Subscription{{Class: homeClass, Instance: homeInstance, Recipient: ""}})
+	err = c.loadSubs()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	err = c.loadWatches()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newClyde initializes a Clyde's internal state by loading data files
+// found in the given directory, without connecting to zephyr.
+func newClyde(dir string) (*Clyde, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Clyde{}
+
+	c.homeDir = dir
+
 	// Create markov chain, and try to load saved chain
 	c.chain = markov.NewChain(prefixLen)
 	err = c.chain.Load(c.path(chainFile))
 	if err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
+	err = c.chain.LoadUpdates(c.path(chainUpdatesFile), updateBucket)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	err = c.chain.LoadSentences(c.path(chainSentencesFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
 
 	// Create zsig markov chain, and try to load saved chain
 	c.zsigChain = markov.NewChain(zsigPrefixLen)
@@ -93,12 +152,133 @@ func LoadClyde(dir string) (*Clyde, error) {
 		return nil, err
 	}
 
-	c.session.SendSubscribeNoDefaults(c.ctx, []zephyr.Subscription{{Class: homeClass, Instance: homeInstance, Recipient: ""}})
+	// Create a separate chain for private traffic, and load the
+	// list of private classes
+	c.privateChain = markov.NewChain(prefixLen)
+	err = c.privateChain.Load(c.path(privateChainFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	err = c.privateChain.LoadSentences(c.path(privateSentencesFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	// Route zephyrs to the main or private chain
+	c.chains = markov.NewChainSet(prefixLen)
+	c.chains.SetIsolation(privateIsolation)
+	c.chains.Put(mainChain, false, c.chain)
+	c.chains.Put(mainChain, true, c.privateChain)
+
+	// Load the per-user style models, which are only trained on
+	// public traffic
+	c.userChains = markov.NewChainSet(prefixLen)
+	c.userChains.SetIsolation(markov.PublicOnly)
+	c.userChains.SetSetup(func(name string, chain *markov.Chain) {
+		chain.SetAllowlist(c.allowlist)
+	})
+	err = c.userChains.Load(c.path(userChainsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	// Likewise for the per-class chains
+	c.classChains = markov.NewChainSet(prefixLen)
+	c.classChains.SetIsolation(markov.PublicOnly)
+	c.classChains.SetSetup(func(name string, chain *markov.Chain) {
+		chain.SetAllowlist(c.allowlist)
+	})
+	err = c.classChains.Load(c.path(classChainsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	c.privateClasses = make(map[string]bool)
+	if _, err := os.Stat(c.path(privateClassesFile)); err == nil {
+		classes, err := allLines(c, privateClassesFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, class := range classes {
+			c.privateClasses[strings.ToLower(class)] = true
+		}
+	}
+
+	// In strict environments, only generate words from a curated
+	// allowlist, if one exists
+	if _, err := os.Stat(c.path(allowlistFile)); err == nil {
+		c.allowlist, err = allLines(c, allowlistFile)
+		if err != nil {
+			return nil, err
+		}
+		c.chain.SetAllowlist(c.allowlist)
+		c.privateChain.SetAllowlist(c.allowlist)
+		c.zsigChain.SetAllowlist(c.allowlist)
+		c.userChains.Each(func(name string, chain *markov.Chain) {
+			chain.SetAllowlist(c.allowlist)
+		})
+		c.classChains.Each(func(name string, chain *markov.Chain) {
+			chain.SetAllowlist(c.allowlist)
+		})
+	}
+
+	// Keep filtered terms out of anything Clyde says, if there's a
+	// filter
+	if _, err := os.Stat(c.path(filterFile)); err == nil {
+		c.filter, err = moderation.LoadFilter(c.path(filterFile), filterStrategy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Mask personal information and secrets before learning them
+	var patterns []string
+	if _, err := os.Stat(c.path(scrubFile)); err == nil {
+		patterns, err = allLines(c, scrubFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.scrubber, err = moderation.NewScrubber(scrubKinds, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the list of users who don't want Clyde learning from them
+	c.optOut = make(map[string]bool)
+	err = loadJSON(c.path(optOutFile), &(c.optOut))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
 	c.subs = make(map[string]classPolicy)
-	err = c.loadSubs()
+
+	// Load learned interjections, and any extra ones provided
+	c.interjections = make(map[string]*interjectionCounts)
+	err = loadJSON(c.path(interjectionCountsFile), &(c.interjections))
 	if err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
+	if _, err := os.Stat(c.path(interjectionsFile)); err == nil {
+		c.extraInterjections, err = allLines(c, interjectionsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.interjectLimiter.interval = interjectCooldown
+
+	// Load the registry of everything Clyde has said
+	if watermarkOutput {
+		key, err := watermark.LoadKey(c.path(watermarkKeyFile))
+		if err != nil {
+			return nil, err
+		}
+		c.watermarks = watermark.NewRegistry(key)
+		err = c.watermarks.Load(c.path(watermarksFile))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	c.adventures = make(map[string]*adventure)
 
 	c.mood = mood.Ok
 
@@ -120,7 +300,9 @@ func LoadClyde(dir string) (*Clyde, error) {
 // to clock ticks. After Clyde.Run() is called, Clyde.Shutdown() must
 // be called before exiting.
 func (c *Clyde) Run() {
-	c.wg.Add(1)
+	c.outgoing = make(chan pendingZephyr, sendQueueLen)
+	c.wg.Add(2)
+	go c.sendLoop()
 	go func() {
 		defer c.handleShutdown()
 		for {
@@ -133,9 +315,19 @@ func (c *Clyde) Run() {
 			}
 			select {
 			case t := <-c.ticker.C:
+				c.mu.Lock()
+				due := c.saveDue()
+				c.mu.Unlock()
+				if due {
+					c.backup()
+				}
+				c.mu.Lock()
 				c.handleTick(t)
+				c.mu.Unlock()
 			case r := <-c.session.Messages():
+				c.mu.Lock()
 				c.handleMessage(r)
+				c.mu.Unlock()
 			case <-c.shutdown:
 				return
 			}
@@ -154,6 +346,44 @@ func (c *Clyde) Shutdown() {
 	c.session.Close() // Moved here to avoid lingering internal event loop issue
 }
 
+// Reload saves Clyde's state, then reloads his chains, opt-outs,
+// watermarks, and configuration files (allowlist, filter, scrub
+// patterns, private classes, interjections, and watched classes) from
+// his home directory, without closing his zephyr session, so that
+// edited files take effect without a restart. If anything fails to load, Clyde
+// carries on as he was.
+func (c *Clyde) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Save first, so that nothing learned since the last save is
+	// lost
+	c.save()
+	fresh, err := newClyde(c.homeDir)
+	if err != nil {
+		return err
+	}
+	fresh.ticker.Stop()
+
+	c.chain = fresh.chain
+	c.zsigChain = fresh.zsigChain
+	c.privateChain = fresh.privateChain
+	c.chains = fresh.chains
+	c.userChains = fresh.userChains
+	c.classChains = fresh.classChains
+	c.allowlist = fresh.allowlist
+	c.filter = fresh.filter
+	c.scrubber = fresh.scrubber
+	c.privateClasses = fresh.privateClasses
+	c.extraInterjections = fresh.extraInterjections
+	c.optOut = fresh.optOut
+	c.watermarks = fresh.watermarks
+	err = c.loadWatches()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 
 type classPolicy uint8
 
@@ -168,7 +398,9 @@ func (c *Clyde) subscribe(class string, policy classPolicy) {
 	if c.subs[class] != 0 {
 		return
 	}
-	c.session.SendSubscribeNoDefaults(c.ctx, []zephyr.Subscription{{Class: class, Instance: "*", Recipient: ""}})
+	if !c.sandbox {
+		c.session.SendSubscribeNoDefaults(c.ctx, []zephyr.Subscription{{Class: class, Instance: "*", Recipient: ""}})
+	}
 	c.subs[class] = policy
 }
 
@@ -179,11 +411,18 @@ func (c *Clyde) send(class, instance, body string) {
 	preformatted := false
 
 	log.Printf("Sending message to -c %s -i %s: %s", class, instance, body)
+	delay := time.Duration(len(body))*sendDelayFactor*time.Millisecond
 
-	time.Sleep(time.Duration(len(body))*sendDelayFactor*time.Millisecond)
+	body = stringutil.FixAgreement(body, agreementFixes)
+
+	body = c.filter.Apply(body, nil)
+	if body == "" {
+		log.Printf("Not sending message, nothing left after filtering")
+		return
+	}
 
 	if !preformatted {
-		body = stringutil.BreakLines(body, stringutil.MaxLine)
+		body = stringutil.BreakParagraphs(body, stringutil.MaxLine)
 	}
 
 	if rand.Intn(10) == 0 {
@@ -205,12 +444,10 @@ func (c *Clyde) send(class, instance, body string) {
 		}
 		body = fmt.Sprintf(format, body)
 		if breaklines && !preformatted {
-			body = stringutil.BreakLines(body, stringutil.MaxLine)
+			body = stringutil.BreakParagraphs(body, stringutil.MaxLine)
 		}
 	}
 
-	uid := c.session.MakeUID(time.Now())
-
 	var zsig string
 	if zsigUseChainer {
 		zsig = c.zsigChain.Generate("", 1, rand.Intn(6)+2)
@@ -218,10 +455,18 @@ func (c *Clyde) send(class, instance, body string) {
 		zsig = "Clyde"
 	}
 
+	if c.watermarks != nil {
+		c.watermarks.Record(body)
+	}
+
+	if c.sandbox {
+		c.sandboxSent++
+		return
+	}
+
 	msg := &zephyr.Message{
 		Header: zephyr.Header{
 			Kind:	zephyr.ACKED,
-			UID:	uid,
 			Port:	c.session.Port(),
 			Class:	class, Instance: instance,
 			OpCode: "AUTO",
@@ -234,9 +479,25 @@ func (c *Clyde) send(class, instance, body string) {
 		},
 		Body: []string{zsig, body},
 	}
-	_, err := c.session.SendMessageUnauth(msg)
-	if err != nil {
-		log.Printf("Send error: %v", err)
+	select {
+	case c.outgoing <- pendingZephyr{msg, delay}:
+	default:
+		log.Printf("Not sending message, too many waiting to be sent")
+	}
+}
+
+// sendLoop sends queued zephyrs in order, each after a delay as if
+// Clyde were typing it. It doesn't hold c.mu, so Clyde keeps learning
+// and answering admin requests while he types. It returns once the
+// queue is closed and empty, when Clyde shuts down.
+func (c *Clyde) sendLoop() {
+	defer c.wg.Done()
+	for z := range c.outgoing {
+		time.Sleep(z.delay)
+		z.msg.Header.UID = c.session.MakeUID(time.Now())
+		if _, err := c.session.SendMessageUnauth(z.msg); err != nil {
+			log.Printf("Send error: %v", err)
+		}
 	}
 }
 
@@ -250,15 +511,68 @@ const homeInstance = "clyde"
 
 const chainFile = "chain.json"
 const zsigChainFile = "zsigChain.json"
+const chainSentencesFile = "chainSentences.json"
+const privateChainFile = "privateChain.json"
+const privateSentencesFile = "privateSentences.json"
+const privateClassesFile = "private" // one private class per line
+const userChainsDir = "users"
+const classChainsDir = "classes"
+const watchFile = "watch" // one "class [instance]" to learn from per line
+const chainUpdatesFile = "chainUpdates.json"
 const subsFile = "subs.json"
+const allowlistFile = "allowlist" // one allowed word per line
+const filterFile = "filter" // one term Clyde mustn't say per line
+const scrubFile = "scrub" // one regular expression to mask in what Clyde learns per line
+const optOutFile = "optout.json"
+const interjectionsFile = "interjections" // one extra interjection per line
+const interjectionCountsFile = "interjectionCounts.json"
+const watermarkKeyFile = "watermarkKey"
+const watermarksFile = "watermarks.json"
 
 const sender = "clyde"
 const prefixLen = 2
+const updateBucket = time.Hour // granularity of prefix update times
+
+// privateIsolation is how Clyde routes personal zephyrs and private
+// classes (see markov.Isolation): with markov.SeparatePrivate, he
+// learns from them using a separate chain, which is only used to
+// generate replies to private traffic, so nothing said in private can
+// ever turn up in a public reply. The per-user and per-class chains
+// never learn from private traffic. It must be markov.Shared or
+// markov.SeparatePrivate, as Clyde needs a chain to reply with.
+const privateIsolation = markov.SeparatePrivate
+
+// mainChain is the name chain and privateChain are routed under in
+// Clyde's chains.
+const mainChain = "main"
+
+// If watermarkOutput is set, Clyde keeps a registry of keyed hashes
+// of everything he says, so he can later vouch for (or disown) text
+// attributed to him.
+const watermarkOutput = true
+
+// filterStrategy is what Clyde does with sentences he generates that
+// contain terms from his filter file.
+const filterStrategy = moderation.Regenerate
+
+// scrubKinds are the kinds of personal information and secrets Clyde
+// masks in everything he learns (see moderation.Scrubber), along with
+// anything matching the patterns in his scrub file.
+var scrubKinds = moderation.ScrubKinds
+
+// agreementFixes is how aggressively Clyde corrects agreement errors
+// ("they is", "a apple") in what he says. The rules still misfire on
+// some constructions, so it's off by default.
+const agreementFixes = stringutil.AgreementOff
 
 const zsigUseChainer = false
 const zsigPrefixLen = 1 // Be more creative with less input data
 
+const saveInterval = 30 * time.Minute // how often to save persistent state
+const backupCount = 5 // number of backups of Clyde's home directory to keep
+
 const sendDelayFactor = 20 // milliseconds to wait per character in a message before sending
+const sendQueueLen = 64 // most messages waiting to be sent before more are dropped
 
 func (c *Clyde) handleMessage(r zephyr.MessageReaderResult) {
 	// Ignore our own messages
@@ -273,8 +587,31 @@ func (c *Clyde) handleMessage(r zephyr.MessageReaderResult) {
 
 	log.Printf("received message on -c %s -i %s: %s", r.Message.Header.Class, r.Message.Header.Instance, util.MessageBody(r))
 
-	c.chain.Build(strings.NewReader(util.MessageBody(r)))
-	c.zsigChain.Build(strings.NewReader(util.MessageZSig(r)))
+	body := c.learnable(util.MessageBody(r))
+
+	if c.optOut[shortSender(r)] {
+		log.Printf("Not learning from %s, who opted out", shortSender(r))
+	} else {
+		private := c.isPrivate(r)
+		c.chainFor(r).Build(strings.NewReader(body))
+		if chain := c.userChains.Route(userKey(shortSender(r)), private); chain != nil {
+			chain.Build(strings.NewReader(body))
+		}
+		if chain := c.classChains.Route(classKey(r.Message.Header.Class), private); chain != nil {
+			chain.Build(strings.NewReader(body))
+		}
+		// The zsig chain and interjection counts aren't routed, so
+		// they only learn from private traffic if it's shared
+		if !private || privateIsolation == markov.Shared {
+			c.zsigChain.Build(strings.NewReader(c.learnable(util.MessageZSig(r))))
+			c.learnInterjection(r)
+		}
+	}
+
+	// Watched classes are only for learning
+	if c.watchOnly(r) {
+		return
+	}
 
 	// Perform the first behavior that triggers, and exit
 	for i, b := range behaviors {
@@ -286,14 +623,84 @@ func (c *Clyde) handleMessage(r zephyr.MessageReaderResult) {
 	}
 }
 
-func (c *Clyde) handleTick(t time.Time) {
-	if time.Since(c.lastSaved) > 30*time.Minute {
-		log.Println("Saving data")
+// learnable returns text the way Clyde learns it: with its punctuation
+// normalized, anything he said stripped, so he doesn't learn it back
+// when e.g. another bot relays it, and personal information scrubbed.
+// Text to forget must be prepared the same way to match what was
+// learned.
+func (c *Clyde) learnable(text string) string {
+	text = stringutil.NormalizePunctuation(text)
+	if c.watermarks != nil {
+		text = c.watermarks.Strip(text)
+	}
+	return c.scrubber.Scrub(text)
+}
+
+// isPrivate returns a boolean indicating whether a zephyr is private:
+// either a personal zephyr, or a zephyr on a private class.
+func (c *Clyde) isPrivate(r zephyr.MessageReaderResult) bool {
+	return r.Message.Header.Recipient != "" || c.privateClasses[strings.ToLower(r.Message.Header.Class)]
+}
+
+// chainFor returns the chain that Clyde should learn from and generate
+// replies with for a given zephyr, as routed by Clyde's chains. All
+// learning and generation on behalf of incoming zephyrs must go
+// through chainFor, or route through Clyde's other chain sets, which
+// is what keeps private traffic isolated.
+func (c *Clyde) chainFor(r zephyr.MessageReaderResult) *markov.Chain {
+	return c.chains.Route(mainChain, c.isPrivate(r))
+}
+
+// backup backs up Clyde's last save (see package backup), before he
+// saves again. It only reads his home directory, where every file is
+// replaced atomically when saved, so it's called without c.mu held,
+// rather than holding up messages while the whole directory is copied.
+func (c *Clyde) backup() {
+	if _, err := backup.Snapshot(c.homeDir, backupCount); err != nil {
+		log.Printf("Backup error: %v", err)
+	}
+}
+
+// saveDue returns whether it's time for Clyde to save again.
+func (c *Clyde) saveDue() bool {
+	return time.Since(c.lastSaved) > saveInterval
+}
+
+// save saves Clyde's persistent state, which should be backed up
+// first. Chains are only saved if they changed since they were loaded
+// or last saved, and are written atomically, so a crash mid-save
+// leaves the previous version intact.
+func (c *Clyde) save() {
+	if c.chain.Dirty() {
 		c.chain.Save(c.path(chainFile))
+		c.chain.SaveUpdates(c.path(chainUpdatesFile))
+		c.chain.SaveSentences(c.path(chainSentencesFile))
+	}
+	if c.privateChain.Dirty() {
+		c.privateChain.Save(c.path(privateChainFile))
+		c.privateChain.SaveSentences(c.path(privateSentencesFile))
+	}
+	if c.zsigChain.Dirty() {
 		c.zsigChain.Save(c.path(zsigChainFile))
-		c.saveSubs()
-		c.lastSaved = time.Now()
 	}
+	c.userChains.Save(c.path(userChainsDir))
+	c.classChains.Save(c.path(classChainsDir))
+	c.saveSubs()
+	saveJSON(c.path(optOutFile), c.optOut)
+	saveJSON(c.path(interjectionCountsFile), c.interjections)
+	if c.watermarks != nil {
+		c.watermarks.Save(c.path(watermarksFile))
+	}
+	c.lastSaved = time.Now()
+}
+
+func (c *Clyde) handleTick(t time.Time) {
+	if c.saveDue() {
+		log.Println("Saving data")
+		c.save()
+	}
+
+	c.tickAdventures()
 
 	aloneDuration := time.Since(c.lastInteraction)
 
@@ -346,13 +753,15 @@ func (c *Clyde) handleTick(t time.Time) {
 
 func (c *Clyde) handleShutdown() {
 	log.Println("Shutting down")
+	c.backup()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.ticker.Stop()
-	c.chain.Save(c.path(chainFile))
-	c.zsigChain.Save(c.path(zsigChainFile))
-	c.saveSubs()
+	c.save()
 	c.session.SendCancelSubscriptions(c.ctx)
 	c.ctx.Free()
 	// c.session.Close()
+	close(c.outgoing) // sendLoop finishes sending what's queued
 	c.wg.Done()
 }
 
@@ -386,17 +795,42 @@ func (c *Clyde) loadSubs() error {
 // saveSubs saves Clyde's subscriptions to a file in JSON format in
 // Clyde's home directory.
 func (c *Clyde) saveSubs() error {
-	f, err := os.Create(c.path(subsFile))
+	return saveJSON(c.path(subsFile), c.subs)
+}
+
+// loadJSON decodes a file in JSON format into v.
+func loadJSON(filename string, v interface{}) error {
+	f, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	enc := json.NewEncoder(f)
-	err = enc.Encode(c.subs)
+	dec := json.NewDecoder(f)
+	return dec.Decode(v)
+}
+
+// saveJSON saves v to a file in JSON format. Like chains, the file is
+// written under a temporary name (which backups skip) and renamed into
+// place, so that a backup taken meanwhile, or a crash, never sees it
+// half-written.
+func saveJSON(filename string, v interface{}) error {
+	f, err := os.CreateTemp(path.Dir(filename), "."+path.Base(filename)+".tmp*")
 	if err != nil {
 		return err
 	}
-
-	return nil
+	err = json.NewEncoder(f).Encode(v)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
 }
@@ -0,0 +1,41 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package clyde
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSaveJSON(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, "state.json")
+	tests := []struct {
+		v       interface{}
+		want    string
+		wantErr bool
+	}{
+		{[]string{"a"}, "[\"a\"]\n", false},
+		{map[string]int{"b": 2}, "{\"b\":2}\n", false},
+		// A value that can't be encoded leaves the last save
+		{make(chan int), "{\"b\":2}\n", true},
+	}
+	for _, test := range tests {
+		err := saveJSON(filename, test.v)
+		if (err != nil) != test.wantErr {
+			t.Errorf("saveJSON(%v) = %v, want error: %v", test.v, err, test.wantErr)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil || string(data) != test.want {
+			t.Errorf("after saveJSON(%v), file = %q, %v, want %q", test.v, data, err, test.want)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) != 1 {
+			t.Errorf("after saveJSON(%v), directory has %d files, %v, want 1", test.v, len(entries), err)
+		}
+	}
+}
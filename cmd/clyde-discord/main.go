@@ -0,0 +1,120 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-discord serves a Discord application's interactions endpoint,
+// answering /say and /stats with per-guild and per-channel chains, and
+// connects to Discord's gateway to learn from channel messages.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+	"github.com/sdukhovni/clyde-go/backup"
+	"github.com/sdukhovni/clyde-go/discord"
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/moderation"
+)
+
+const feedbackFile = "feedback.json"
+
+func main() {
+	addr := flag.String("addr", "localhost:8044", "address to listen on")
+	dir := flag.String("dir", "", "directory of chains saved as a set (created if missing)")
+	prefixLen := flag.Int("prefix", 2, "prefix length of the chains")
+	saveEvery := flag.Duration("save", 10*time.Minute, "how often to save chains and feedback")
+	backups := flag.Int("backups", 5, "number of backups of the chains directory to keep, taken before each save")
+	scrub := flag.String("scrub", "", "comma-separated kinds of information to mask before learning: email, phone, secret")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s -dir chains [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "The application's public key must be in $DISCORD_PUBLIC_KEY. If $DISCORD_TOKEN\n")
+		fmt.Fprintf(os.Stderr, "is set, the bot connects to the gateway to learn from messages and reactions,\n")
+		fmt.Fprintf(os.Stderr, "and if $DISCORD_APP_ID is set too, the slash commands are registered at startup.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	publicKey := os.Getenv("DISCORD_PUBLIC_KEY")
+	if *dir == "" || publicKey == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	rand.Seed(time.Now().UnixNano())
+
+	if appID, token := os.Getenv("DISCORD_APP_ID"), os.Getenv("DISCORD_TOKEN"); appID != "" && token != "" {
+		if err := discord.RegisterCommands(appID, token); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	chains := markov.NewChainSet(*prefixLen)
+	if err := chains.Load(*dir); err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	bot, err := discord.NewBot(publicKey, chains)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *scrub != "" {
+		scrubber, err := moderation.NewScrubber(strings.Split(*scrub, ","), nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		bot.SetScrubber(scrubber)
+	}
+	err = bot.LoadFeedback(path.Join(*dir, feedbackFile))
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	save := func() {
+		if _, err := backup.Snapshot(*dir, *backups); err != nil {
+			log.Println(err)
+		}
+		bot.Do(func(chains *markov.ChainSet) {
+			if err := chains.Save(*dir); err != nil {
+				log.Println(err)
+			}
+		})
+		if err := bot.SaveFeedback(path.Join(*dir, feedbackFile)); err != nil {
+			log.Println(err)
+		}
+	}
+
+	go func() {
+		log.Printf("Serving Discord interactions on %s", *addr)
+		log.Fatal(http.ListenAndServe(*addr, bot))
+	}()
+	stop := make(chan struct{})
+	if token := os.Getenv("DISCORD_TOKEN"); token != "" {
+		go func() {
+			if err := bot.RunGateway(token, stop); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	// Save periodically, and once more on SIGINT or SIGTERM
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	tick := time.NewTicker(*saveEvery).C
+	for {
+		select {
+		case <-tick:
+			save()
+		case <-c:
+			close(stop)
+			save()
+			return
+		}
+	}
+}
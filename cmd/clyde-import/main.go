@@ -0,0 +1,71 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-import imports clyde.pl's brain (see
+// markov.Chain.ImportLegacy), or a table of suffix counts (see
+// markov.Chain.ImportTable), into a Clyde home directory's chain.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// Must match Clyde's prefix length
+const prefixLen = 2
+
+func main() {
+	dir := flag.String("dir", "", "Clyde home directory to import into (stop Clyde first)")
+	in := flag.String("in", "", "brain or table of suffix counts to import (default: stdin)")
+	format := flag.String("format", "legacy", "format of the input: \"legacy\" for clyde.pl's brain, or \"table\" for tab-separated suffix counts")
+	replace := flag.Bool("replace", false, "replace the existing chain instead of adding to it")
+	flag.Parse()
+
+	if *dir == "" {
+		log.Fatal("-dir is required")
+	}
+	var importer func(*markov.Chain, io.Reader) (int, error)
+	switch *format {
+	case "legacy":
+		importer = (*markov.Chain).ImportLegacy
+	case "table":
+		importer = (*markov.Chain).ImportTable
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+	chainFile := path.Join(*dir, "chain.json")
+
+	chain := markov.NewChain(prefixLen)
+	if !*replace {
+		err := chain.Load(chainFile)
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	n, err := importer(chain, r)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := chain.Save(chainFile); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Imported %d entries; chain now has %d prefixes\n", n, chain.Size())
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-inspect loads a model and reports on what it has learned, for
+// debugging training and spotting problems in a corpus.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// A command is a subcommand of clyde-inspect.
+type command struct {
+	summary string
+	run     func(args []string)
+}
+
+var commands = map[string]command{
+	"dot":    {"write the model as a GraphViz DOT graph", dot},
+	"report": {"summarize the model's vocabulary and most common prefixes", report},
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s command [options] model\n\ncommands:\n", os.Args[0])
+		var names []string
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].summary)
+		}
+		fmt.Fprintf(os.Stderr, "\nRun %s command -h for a command's options.\n", os.Args[0])
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: unknown command %q\n", os.Args[0], flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd.run(flag.Args()[1:])
+}
+
+// newFlags returns a flag set for a command taking a model, with a
+// -prefix flag for its prefix length.
+func newFlags(name string) (*flag.FlagSet, *int) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	prefixLen := fs.Int("prefix", 2, "prefix length the model was trained with")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s %s [options] model\n", os.Args[0], name)
+		fs.PrintDefaults()
+	}
+	return fs, prefixLen
+}
+
+// loadModel loads the model named by a command's arguments.
+func loadModel(fs *flag.FlagSet, prefixLen int) *markov.Chain {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	chain := markov.NewChain(prefixLen)
+	if err := chain.Load(fs.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+	return chain
+}
+
+// dot writes a model as a DOT graph (see markov.Chain.WriteDOT).
+func dot(args []string) {
+	fs, prefixLen := newFlags("dot")
+	minCount := fs.Int("min", 1, "leave out words seen fewer than this many times after a prefix")
+	out := fs.String("o", "", "file to write the graph to (default: stdout)")
+	fs.Parse(args)
+	chain := loadModel(fs, *prefixLen)
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := chain.WriteDOT(w, *minCount); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// report prints a summary of a model (see markov.Chain.Report).
+func report(args []string) {
+	fs, prefixLen := newFlags("report")
+	n := fs.Int("n", 10, "number of prefixes and words to list of each kind")
+	fs.Parse(args)
+	if *n < 0 {
+		log.Fatal("-n must not be negative")
+	}
+	chain := loadModel(fs, *prefixLen)
+
+	r := chain.Report(*n)
+	fmt.Printf("vocabulary: %d words\n", r.Vocabulary)
+	fmt.Printf("tokens:     %d\n", r.Tokens)
+	fmt.Printf("prefixes:   %d\n", r.Prefixes)
+	printTallies("most frequent prefixes (words after them, share of tokens)", r.TopPrefixes, r.Tokens)
+	printTallies("most varied prefixes (distinct words after them)", r.VariedPrefixes, 0)
+	printTallies("most frequent words (count, share of tokens)", r.TopWords, r.Tokens)
+}
+
+// printTallies prints a list of tallies under a heading, with each
+// count's share of total, if it's positive.
+func printTallies(heading string, tallies []markov.Tally, total int) {
+	fmt.Printf("\n%s:\n", heading)
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	for _, t := range tallies {
+		if total > 0 {
+			fmt.Fprintf(w, "%d\t%.1f%%\t  %s\n", t.Count, 100*float64(t.Count)/float64(total), t.Text)
+		} else {
+			fmt.Fprintf(w, "%d\t  %s\n", t.Count, t.Text)
+		}
+	}
+	w.Flush()
+}
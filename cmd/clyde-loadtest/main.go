@@ -0,0 +1,67 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-loadtest feeds synthetic traffic through a sandboxed Clyde,
+// which isn't connected to zephyr, and reports how he holds up.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"github.com/sdukhovni/clyde-go"
+)
+
+func main() {
+	dir := flag.String("dir", "", "Clyde home directory to test against (use a scratch copy)")
+	transcript := flag.String("transcript", "", "file of messages to replay, one per line (default: generate messages)")
+	messages := flag.Int("n", 10000, "number of messages to send")
+	rate := flag.Float64("rate", 0, "messages per second (default: as fast as possible)")
+	class := flag.String("class", "", "class to send messages on (default: Clyde's home class)")
+	instance := flag.String("instance", "", "instance to send messages on")
+	verbose := flag.Bool("v", false, "show Clyde's log output")
+	flag.Parse()
+
+	if *dir == "" {
+		log.Fatal("-dir is required")
+	}
+
+	conf := clyde.LoadTestConfig{
+		Messages: *messages,
+		Rate: *rate,
+		Class: *class,
+		Instance: *instance,
+	}
+
+	if *transcript != "" {
+		f, err := os.Open(*transcript)
+		if err != nil {
+			log.Fatal(err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				conf.Transcript = append(conf.Transcript, line)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	c, err := clyde.NewSandboxClyde(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	fmt.Println(c.LoadTest(conf))
+}
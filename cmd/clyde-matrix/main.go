@@ -0,0 +1,39 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-matrix runs Clyde's chainer as a Matrix bot, with a chain per
+// room.
+package main
+
+import (
+	"log"
+	"github.com/sdukhovni/clyde-go/bot"
+	"github.com/sdukhovni/clyde-go/config"
+	"github.com/sdukhovni/clyde-go/matrix"
+)
+
+func main() {
+	config.Main(config.Command{
+		Usage: "The bot logs in to $MATRIX_HOMESERVER as $MATRIX_USER_ID with the access\n" +
+			"token in $MATRIX_TOKEN, unless they're in the configuration file.\n",
+		// The environment variables the bot has always read; the
+		// CLYDE_ variables for every setting work too (see
+		// config.SetEnv)
+		Env: map[string]string{
+			"MATRIX_HOMESERVER": "matrix.homeserver",
+			"MATRIX_USER_ID":    "matrix.user_id",
+			"MATRIX_TOKEN":      "matrix.token",
+		},
+		Ready: func(cfg *config.Config) bool {
+			return cfg.Matrix.Homeserver != "" && cfg.Matrix.UserID != "" && cfg.Matrix.Token != ""
+		},
+		Start: func(cfg *config.Config, core *bot.Core, stop <-chan struct{}) (bot.Frontend, <-chan error, error) {
+			frontend := matrix.NewBot(cfg.Matrix.Homeserver, cfg.Matrix.UserID, cfg.Matrix.Token)
+			go frontend.Run(stop)
+			log.Printf("Running as %s on %s", cfg.Matrix.UserID, cfg.Matrix.Homeserver)
+			return frontend, nil, nil
+		},
+	})
+}
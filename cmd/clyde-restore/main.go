@@ -0,0 +1,63 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-restore lists the backups of a Clyde home directory or a bot's
+// chains directory (see package backup), and restores one of them.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"github.com/sdukhovni/clyde-go/backup"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory to restore into (stop the bot first)")
+	list := flag.Bool("list", false, "list the backups instead of restoring one")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s -dir dir [-list] [backup]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Restores the given backup, or the latest one.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *dir == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	names, err := backup.List(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *list {
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	var name string
+	switch {
+	case flag.NArg() == 1:
+		name = flag.Arg(0)
+	case len(names) > 0:
+		name = names[len(names)-1]
+	default:
+		log.Fatalf("No backups in %s", *dir)
+	}
+	// Take a backup of the current state first, in case the
+	// restored one turns out to be worse
+	current, err := backup.Snapshot(*dir, len(names)+1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := backup.Restore(*dir, name); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Restored %s (previous state saved as %s)\n", name, current)
+}
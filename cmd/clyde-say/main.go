@@ -0,0 +1,167 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-say loads a model and prints text generated from it, for
+// piping into fortune, MOTDs, and the like.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+	"github.com/sdukhovni/clyde-go/config"
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+func main() {
+	format := flag.String("format", "auto", "model format: auto (json, compact, or frozen, possibly gzipped), or proto")
+	prefixLen := flag.Int("prefix", 2, "prefix length the model was trained with")
+	n := flag.Int("n", 1, "number of outputs to print")
+	sentences := flag.Int("sentences", 1, "number of sentences per output")
+	maxWords := flag.Int("max-words", 50, "maximum number of words per output")
+	beam := flag.Int("beam", 0, "generate the most likely sentence by beam search with this beam width, instead of sampling")
+	candidates := flag.Int("candidates", 1, "generate this many candidates per output and print the best")
+	allowlist := flag.String("allowlist", "", "file of words (one per line) to restrict output to")
+	temperature := flag.Float64("temp", 1, "sampling temperature; lower is more predictable, higher more surprising")
+	smoothing := flag.Float64("smoothing", 0, "add-k smoothing constant, to occasionally choose words unseen after a prefix")
+	kneserNey := flag.Float64("kn", 0, "discount for Kneser-Ney smoothing, less than 1 (0.75 is typical); 0 turns it off")
+	fuzzy := flag.Int("fuzzy", 0, "match unknown prefix words with known ones at most this many edits away")
+	correct := flag.Int("correct", 0, "correct seed words the model doesn't know that are at most this many edits from ones it does")
+	thesaurus := flag.String("thesaurus", "", "file of synonyms for unknown seed words (see markov.ReadSynonyms), or \"builtin\"")
+	skipGrams := flag.Bool("skip-grams", false, "continue unknown prefixes from prefixes differing in one word")
+	interactive := flag.Bool("i", false, "read seed text and commands from standard input interactively")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [options] model [seed text...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	rand.Seed(time.Now().UnixNano())
+	model := flag.Arg(0)
+	seed := strings.Join(flag.Args()[1:], " ")
+
+	if *format == "auto" && isFrozen(model) {
+		if *beam > 0 || *candidates > 1 || *allowlist != "" || *temperature != 1 || *smoothing != 0 || *kneserNey != 0 || *fuzzy != 0 || *correct != 0 || *thesaurus != "" || *skipGrams || *interactive {
+			log.Fatal("-beam, -candidates, -allowlist, -temp, -smoothing, -kn, -fuzzy, -correct, -thesaurus, -skip-grams, and -i aren't supported with frozen models")
+		}
+		fc, err := markov.OpenFrozen(model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer fc.Close()
+		for i := 0; i < *n; i++ {
+			fmt.Println(fc.Generate(seed, *sentences, *maxWords))
+		}
+		return
+	}
+
+	chain := markov.NewChain(*prefixLen)
+	var err error
+	switch *format {
+	case "auto":
+		err = chain.Load(model)
+	case "proto":
+		var data []byte
+		data, err = ioutil.ReadFile(model)
+		if err == nil {
+			err = chain.UnmarshalBinary(data)
+		}
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *allowlist != "" {
+		words, err := readLines(*allowlist)
+		if err != nil {
+			log.Fatal(err)
+		}
+		chain.SetAllowlist(words)
+	}
+	if *temperature <= 0 {
+		log.Fatal("-temp must be positive")
+	}
+	chain.SetTemperature(*temperature)
+	if *smoothing < 0 {
+		log.Fatal("-smoothing must not be negative")
+	}
+	chain.SetSmoothing(*smoothing)
+	if *kneserNey < 0 || *kneserNey >= 1 {
+		log.Fatal("-kn must be at least 0 and less than 1")
+	}
+	chain.SetKneserNey(*kneserNey)
+	synonyms, err := config.LoadThesaurus(*thesaurus)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if synonyms != nil {
+		chain.SetThesaurus(synonyms)
+	}
+	chain.SetFuzzy(*fuzzy)
+	chain.SetSkipGrams(*skipGrams)
+	seed = chain.CorrectSpelling(seed, *correct)
+
+	if *interactive {
+		if err := repl(chain, *prefixLen, *sentences, *maxWords, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	for i := 0; i < *n; i++ {
+		switch {
+		case *beam > 0:
+			fmt.Println(chain.GenerateBeam(seed, *beam, *maxWords))
+		case *candidates > 1:
+			fmt.Println(chain.GenerateN(seed, *candidates, *sentences, *maxWords, nil)[0])
+		default:
+			r := chain.GenerateResult(seed, *sentences, *maxWords)
+			if r.Err != nil {
+				log.Fatal(r.Err)
+			}
+			fmt.Println(r.Text)
+		}
+	}
+}
+
+// isFrozen reports whether a file is a frozen model.
+func isFrozen(filename string) bool {
+	fc, err := markov.OpenFrozen(filename)
+	if err != nil {
+		return false
+	}
+	fc.Close()
+	return true
+}
+
+// readLines returns the non-empty lines of a file.
+func readLines(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// repl.go implements clyde-say's interactive mode, for exploring how a
+// model behaves.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+const replHelp = `Type some text to continue it, or a command:
+  :temp T        set the sampling temperature (1 is normal)
+  :context N     use at most N words of context (-1 for all)
+  :dist [text]   show the next-word distribution after text
+                 (default: the last output)
+  :explain [text]
+                 continue text, showing how each word was chosen
+  :sentences N   set the number of sentences per output
+  :words N       set the maximum number of words per output
+  :help          show this message
+  :quit          exit
+`
+
+// repl reads lines from in, printing continuations of them, or the
+// results of commands, to out, until in is exhausted or the user quits.
+func repl(chain *markov.Chain, prefixLen, sentences, maxWords int, in io.Reader, out io.Writer) error {
+	last := ""
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(out, "> ")
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, ":") {
+			last = chain.Generate(line, sentences, maxWords)
+			fmt.Fprintln(out, last)
+			fmt.Fprint(out, "> ")
+			continue
+		}
+
+		fields := strings.Fields(line)
+		cmd, args := fields[0], fields[1:]
+		var err error
+		switch cmd {
+		case ":temp":
+			var t float64
+			if t, err = floatArg(args); err == nil {
+				if t <= 0 {
+					err = fmt.Errorf("temperature must be positive")
+				} else {
+					chain.SetTemperature(t)
+				}
+			}
+		case ":context":
+			var n int
+			if n, err = intArg(args); err == nil {
+				chain.SetContext(n)
+			}
+		case ":dist":
+			text := last
+			if len(args) > 0 {
+				text = strings.Join(args, " ")
+			}
+			printDistribution(out, chain.Distribution(tailPrefix(text, prefixLen)))
+		case ":explain":
+			r := chain.GenerateTrace(strings.Join(args, " "), sentences, maxWords)
+			last = r.Text
+			fmt.Fprintln(out, last)
+			printTrace(out, r.Trace)
+		case ":sentences":
+			sentences, err = intArg(args)
+		case ":words":
+			maxWords, err = intArg(args)
+		case ":help":
+			fmt.Fprint(out, replHelp)
+		case ":quit":
+			return nil
+		default:
+			err = fmt.Errorf("unknown command %s (try :help)", cmd)
+		}
+		if err != nil {
+			fmt.Fprintln(out, err)
+		}
+		fmt.Fprint(out, "> ")
+	}
+	fmt.Fprintln(out)
+	return scanner.Err()
+}
+
+// tailPrefix returns the prefix generation would continue text from.
+func tailPrefix(text string, prefixLen int) markov.Prefix {
+	words := strings.Fields(text)
+	if len(words) > prefixLen {
+		words = words[len(words)-prefixLen:]
+	}
+	p := markov.NewPrefix(prefixLen)
+	for _, w := range words {
+		p.Shift(w)
+	}
+	return p
+}
+
+// printDistribution prints the most likely words of a distribution.
+func printDistribution(out io.Writer, dist []markov.WordProb) {
+	const maxShown = 20
+	if len(dist) == 0 {
+		fmt.Fprintln(out, "(no words)")
+		return
+	}
+	fmt.Fprintf(out, "%d words, using %d words of context:\n", len(dist), dist[0].Level)
+	for i, wp := range dist {
+		if i == maxShown {
+			fmt.Fprintf(out, "  ... and %d more\n", len(dist)-maxShown)
+			break
+		}
+		fmt.Fprintf(out, "  %6.2f%%  %s\n", 100*wp.Prob, wp.Word)
+	}
+}
+
+// printTrace prints how each word of a text was chosen.
+func printTrace(out io.Writer, trace []markov.TraceStep) {
+	for _, st := range trace {
+		fmt.Fprintf(out, "  %6.2f%% of %-5d %-20q after %q\n", 100*st.Prob, st.Candidates, st.Word, st.Prefix)
+	}
+}
+
+// intArg parses a command's single integer argument.
+func intArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected one argument")
+	}
+	return strconv.Atoi(args[0])
+}
+
+// floatArg parses a command's single numeric argument.
+func floatArg(args []string) (float64, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected one argument")
+	}
+	return strconv.ParseFloat(args[0], 64)
+}
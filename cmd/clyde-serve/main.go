@@ -0,0 +1,117 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-serve serves generation and training endpoints over HTTP, and
+// optionally gRPC, for a directory of chains, for services that want
+// to use Clyde's models without linking Go code.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"github.com/sdukhovni/clyde-go/backup"
+	"github.com/sdukhovni/clyde-go/httpapi"
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/metrics"
+	"github.com/sdukhovni/clyde-go/rpcapi"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8043", "address to listen on")
+	grpcAddr := flag.String("grpc-addr", "", "address to serve gRPC on, over unencrypted HTTP/2 (default: don't)")
+	dir := flag.String("dir", "", "directory of chains saved as a set (created if missing)")
+	prefixLen := flag.Int("prefix", 2, "prefix length of the chains")
+	saveEvery := flag.Duration("save", 10*time.Minute, "how often to save trained chains (0 to never save)")
+	backups := flag.Int("backups", 5, "number of backups of the chains directory to keep, taken before each save")
+	debug := flag.Bool("debug", false, "also serve profiles and chain sizes under /debug/")
+	tokenFile := flag.String("train-token-file", "", "file holding a token that /train requests must present as a bearer token (default: only accept /train from localhost)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s -dir chains [options]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *dir == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	rand.Seed(time.Now().UnixNano())
+
+	chains := markov.NewChainSet(*prefixLen)
+	if err := chains.Load(*dir); err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	server := httpapi.NewServer(chains)
+	if *tokenFile != "" {
+		token, err := ioutil.ReadFile(*tokenFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.SetTrainToken(strings.TrimSpace(string(token)))
+	}
+	var handler http.Handler = server
+	if *debug {
+		mux := http.NewServeMux()
+		mux.Handle("/", server)
+		mux.Handle("/debug/", metrics.DebugHandler(server.Do))
+		handler = mux
+	}
+	save := func() {
+		if *saveEvery == 0 {
+			return
+		}
+		if _, err := backup.Snapshot(*dir, *backups); err != nil {
+			log.Println(err)
+		}
+		server.Do(func(chains *markov.ChainSet) {
+			if err := chains.Save(*dir); err != nil {
+				log.Println(err)
+			}
+		})
+	}
+
+	go func() {
+		log.Printf("Serving %d chains on %s", chains.Len(), *addr)
+		log.Fatal(http.ListenAndServe(*addr, handler))
+	}()
+	if *grpcAddr != "" {
+		grpcServer := &http.Server{
+			Addr:      *grpcAddr,
+			Handler:   rpcapi.NewServer(server),
+			Protocols: new(http.Protocols),
+		}
+		grpcServer.Protocols.SetUnencryptedHTTP2(true)
+		go func() {
+			log.Printf("Serving gRPC on %s", *grpcAddr)
+			log.Fatal(grpcServer.ListenAndServe())
+		}()
+	}
+
+	// Save periodically, and once more on SIGINT or SIGTERM
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	var tick <-chan time.Time
+	if *saveEvery > 0 {
+		tick = time.NewTicker(*saveEvery).C
+	}
+	for {
+		select {
+		case <-tick:
+			save()
+		case <-c:
+			save()
+			return
+		}
+	}
+}
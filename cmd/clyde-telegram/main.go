@@ -0,0 +1,63 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-telegram runs Clyde's chainer as a Telegram bot, with a chain
+// per chat, receiving updates by long polling or by webhook.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"github.com/sdukhovni/clyde-go/bot"
+	"github.com/sdukhovni/clyde-go/config"
+	"github.com/sdukhovni/clyde-go/telegram"
+)
+
+func main() {
+	flag.String("webhook", "", "public URL to receive updates at, instead of polling")
+	flag.String("addr", "localhost:8045", "address to serve the webhook on")
+	config.Main(config.Command{
+		Usage: "The bot token must be in $TELEGRAM_TOKEN and the bot's username in\n" +
+			"$TELEGRAM_USERNAME, unless they're in the configuration file. With\n" +
+			"-webhook, $TELEGRAM_WEBHOOK_SECRET, if set, is required of every update.\n",
+		Flags: map[string]string{
+			"webhook": "telegram.webhook",
+			"addr":    "telegram.addr",
+		},
+		// The environment variables the bot has always read; the
+		// CLYDE_ variables for every setting work too (see
+		// config.SetEnv)
+		Env: map[string]string{
+			"TELEGRAM_TOKEN":          "telegram.token",
+			"TELEGRAM_USERNAME":       "telegram.username",
+			"TELEGRAM_WEBHOOK_SECRET": "telegram.webhook_secret",
+		},
+		Ready: func(cfg *config.Config) bool {
+			return cfg.Telegram.Token != "" && cfg.Telegram.Username != ""
+		},
+		Start: func(cfg *config.Config, core *bot.Core, stop <-chan struct{}) (bot.Frontend, <-chan error, error) {
+			frontend := telegram.NewBot(cfg.Telegram.Token, cfg.Telegram.Username)
+			frontend.Complete = core.Complete
+			if cfg.Telegram.Webhook != "" {
+				if err := frontend.SetWebhook(cfg.Telegram.Webhook, cfg.Telegram.WebhookSecret); err != nil {
+					return nil, nil, err
+				}
+				go func() {
+					log.Printf("Serving Telegram webhook on %s", cfg.Telegram.Addr)
+					log.Fatal(http.ListenAndServe(cfg.Telegram.Addr, frontend))
+				}()
+				return frontend, nil, nil
+			}
+			// Polling only works with no webhook set
+			if err := frontend.SetWebhook("", ""); err != nil {
+				return nil, nil, err
+			}
+			go frontend.Poll(stop)
+			log.Printf("Polling for Telegram updates as @%s", cfg.Telegram.Username)
+			return frontend, nil, nil
+		},
+	})
+}
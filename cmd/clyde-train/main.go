@@ -0,0 +1,353 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-train trains a chain on corpus files and saves it, so models
+// can be built from scripts and cron jobs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"github.com/sdukhovni/clyde-go/corpus"
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/moderation"
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+func main() {
+	out := flag.String("o", "", "output model file (.gz to compress)")
+	format := flag.String("format", "json", "output format: json, compact, proto, or frozen")
+	prefixLen := flag.Int("prefix", 2, "prefix length")
+	appendTo := flag.Bool("append", false, "add to the existing output model instead of replacing it")
+	match := flag.String("match", "", "comma-separated patterns that files in directories must match, e.g. \"*.txt,*.log\"")
+	normalize := flag.Bool("normalize", true, "normalize quotes, dashes, and full-width punctuation")
+	sentenceMarkers := flag.Bool("sentences", false, "treat each sentence as a separate block of text")
+	weight := flag.Int("weight", 1, "count each word this many times")
+	tag := flag.String("tag", "", "source tag to record the corpus under")
+	input := flag.String("input", "text", "format of the corpus files: text, html, gutenberg (Project Gutenberg ebooks), irc (IRC logs), slack (a Slack export's directory), or discord (DiscordChatExporter JSON)")
+	tagSenders := flag.Bool("tag-senders", false, "record each message of a chat log under its sender's name as a source tag")
+	tagsFile := flag.String("tags", "", "file to save source tags' counts in (see -tag and -tag-senders), which aren't saved in the model")
+	dedup := flag.String("dedup", "", "skip lines or sentences already trained on (see -seen): lines or sentences")
+	seenFile := flag.String("seen", "", "file to save the hashes of what was trained on with -dedup in, loaded with -append")
+	urls := flag.String("urls", "keep", "what to learn for URLs: keep, drop, or placeholder (\""+markov.URLPlaceholder+"\")")
+	mentions := flag.String("mentions", "keep", "what to learn for @mentions and nick prefixes: keep, strip (the @), or drop")
+	caseFolding := flag.String("case", "fold", "how to treat the case of words: fold, preserve, or restore (fold, but write words as usually cased)")
+	scrub := flag.String("scrub", "", "comma-separated kinds of information to mask before training: email, phone, secret")
+	fromURL := flag.Bool("url", false, "fetch the arguments as URLs of web pages, feeds, or plain text instead of reading files")
+	maxSize := flag.Int64("max-size", corpus.DefaultMaxFetchSize, "largest page or feed to fetch, in bytes (see -url)")
+	quiet := flag.Bool("q", false, "don't report progress")
+	showProgress := flag.Bool("progress", false, "show how many words and bytes have been trained on as training goes, for large corpora")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s -o model [options] file|dir|glob...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -o model -url [options] url...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *out == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var patterns []string
+	if *match != "" {
+		patterns = strings.Split(*match, ",")
+	}
+
+	chain := markov.NewChain(*prefixLen)
+	chain.SetSentenceMarkers(*sentenceMarkers)
+	urlPolicy, err := markov.ParseURLPolicy(*urls)
+	if err != nil {
+		log.Fatal(err)
+	}
+	chain.SetURLPolicy(urlPolicy)
+	mentionPolicy, err := markov.ParseMentionPolicy(*mentions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	chain.SetMentionPolicy(mentionPolicy)
+	folding, err := markov.ParseCaseFolding(*caseFolding)
+	if err != nil {
+		log.Fatal(err)
+	}
+	chain.SetCaseFolding(folding)
+	if *appendTo {
+		err := load(chain, *out, *format)
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		if *tagsFile != "" {
+			err := chain.LoadTags(*tagsFile)
+			if err != nil && !os.IsNotExist(err) {
+				log.Fatal(err)
+			}
+		}
+		if *seenFile != "" {
+			err := chain.LoadTrained(*seenFile)
+			if err != nil && !os.IsNotExist(err) {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	files := flag.Args()
+	if !*fromURL {
+		files, err = corpusFiles(flag.Args(), patterns)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	opts := []markov.BuildOption{markov.Weight(*weight)}
+	if *tag != "" {
+		opts = append(opts, markov.Tag(*tag))
+	}
+	if *showProgress && !*quiet {
+		opts = append(opts, markov.Progress(func(p markov.BuildProgress) {
+			fmt.Fprintf(os.Stderr, "\r%d words, %.1f MB", p.Words, float64(p.Bytes)/(1<<20))
+		}))
+	}
+	switch *dedup {
+	case "":
+	case "lines":
+		opts = append(opts, markov.DedupLines())
+	case "sentences":
+		opts = append(opts, markov.DedupSentences())
+	default:
+		log.Fatalf("unknown -dedup %q", *dedup)
+	}
+	t := &trainer{
+		chain:      chain,
+		input:      *input,
+		normalize:  *normalize,
+		tagSenders: *tagSenders,
+		opts:       opts,
+	}
+	if *scrub != "" {
+		if t.scrubber, err = moderation.NewScrubber(strings.Split(*scrub, ","), nil); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *input == "slack" && !*fromURL {
+		if t.slackUsers, err = slackUsers(files); err != nil {
+			log.Fatal(err)
+		}
+	}
+	total, failed := 0, 0
+	for i, file := range files {
+		var words int
+		if *fromURL {
+			var msgs []corpus.Message
+			msgs, err = corpus.Fetch(file, *maxSize)
+			if err == nil {
+				words, err = t.build(msgs)
+			}
+		} else {
+			var text []byte
+			if text, err = ioutil.ReadFile(file); err == nil {
+				words, err = t.train(file, string(text))
+			}
+		}
+		if err != nil {
+			log.Printf("%s: %v", file, err)
+			failed++
+			continue
+		}
+		total += words
+		if !*quiet {
+			if *showProgress {
+				// Overwrite the progress line
+				fmt.Fprintf(os.Stderr, "\r%40s\r", "")
+			}
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s: %d words\n", i+1, len(files), file, words)
+		}
+	}
+
+	if err := save(chain, *out, *format); err != nil {
+		log.Fatal(err)
+	}
+	if *tagsFile != "" {
+		if err := chain.SaveTags(*tagsFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *seenFile != "" {
+		if err := chain.SaveTrained(*seenFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if !*quiet {
+		fmt.Fprintf(os.Stderr, "Trained on %d words from %d files (%d failed); %s has %d prefixes\n",
+			total, len(files)-failed, failed, *out, chain.Size())
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// A trainer trains a chain on corpus files in one input format.
+type trainer struct {
+	chain      *markov.Chain
+	input      string
+	normalize  bool
+	tagSenders bool
+	opts       []markov.BuildOption
+	// slackUsers maps a Slack export's user IDs to usernames.
+	slackUsers map[string]string
+	// scrubber masks information in the corpus, if set.
+	scrubber *moderation.Scrubber
+}
+
+// train trains the chain on the text of a corpus file, returning the
+// number of words trained on.
+func (t *trainer) train(file, text string) (int, error) {
+	var msgs []corpus.Message
+	var err error
+	switch t.input {
+	case "text", "gutenberg":
+		if t.input == "gutenberg" {
+			text = corpus.CleanGutenberg(text)
+		}
+		if t.normalize {
+			text = stringutil.NormalizePunctuation(text)
+		}
+		if t.scrubber != nil {
+			text = t.scrubber.Scrub(text)
+		}
+		return t.chain.Build(strings.NewReader(text), t.opts...)
+	case "html":
+		// Train on each block of text separately, as BuildHTML
+		// does, but normalized
+		for _, p := range stringutil.HTMLText(text) {
+			msgs = append(msgs, corpus.Message{Text: p})
+		}
+	case "irc":
+		msgs, err = corpus.ReadIRC(strings.NewReader(text))
+	case "slack":
+		if corpus.SlackMetadata[filepath.Base(file)] {
+			return 0, nil
+		}
+		msgs, err = corpus.ReadSlack(strings.NewReader(text), t.slackUsers)
+	case "discord":
+		msgs, err = corpus.ReadDiscord(strings.NewReader(text))
+	default:
+		return 0, fmt.Errorf("unknown input format %q", t.input)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return t.build(msgs)
+}
+
+// build trains the chain on messages read from a corpus file,
+// scrubbed if need be, returning the number of words trained on.
+func (t *trainer) build(msgs []corpus.Message) (int, error) {
+	if t.scrubber != nil {
+		for i := range msgs {
+			msgs[i].Text = t.scrubber.Scrub(msgs[i].Text)
+		}
+	}
+	return corpus.Build(t.chain, msgs, t.tagSenders, t.opts...)
+}
+
+// slackUsers reads the usernames of a Slack export's users from the
+// export's users.json, if it's among the corpus files.
+func slackUsers(files []string) (map[string]string, error) {
+	for _, file := range files {
+		if filepath.Base(file) != "users.json" {
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return corpus.ReadSlackUsers(f)
+	}
+	return nil, nil
+}
+
+// corpusFiles expands the given files, directories, and glob patterns
+// into a list of files, including the files in directories (and their
+// subdirectories) whose names match one of the patterns, if any.
+func corpusFiles(args []string, patterns []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no such file", arg)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			// Files named explicitly are always used
+			if !info.IsDir() {
+				if info.Mode().IsRegular() {
+					files = append(files, match)
+				}
+				continue
+			}
+			var walkErr error
+			err = markov.WalkCorpus(match, patterns, func(path string, err error) {
+				if err != nil && walkErr == nil {
+					walkErr = err
+				}
+				files = append(files, path)
+			})
+			if err == nil {
+				err = walkErr
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+// load loads a model in the given format.
+func load(chain *markov.Chain, filename, format string) error {
+	switch format {
+	case "json", "compact":
+		return chain.Load(filename)
+	case "proto":
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		return chain.UnmarshalBinary(data)
+	case "frozen":
+		return fmt.Errorf("can't add to a frozen model")
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// save saves a model in the given format.
+func save(chain *markov.Chain, filename, format string) error {
+	switch format {
+	case "json":
+		return chain.Save(filename)
+	case "compact":
+		return chain.SaveCompact(filename)
+	case "proto":
+		data, err := chain.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filename, data, 0644)
+	case "frozen":
+		return chain.SaveFrozen(filename)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
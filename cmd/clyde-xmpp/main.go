@@ -0,0 +1,63 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// clyde-xmpp runs Clyde's chainer as an XMPP bot, with a chain per
+// room and per correspondent.
+package main
+
+import (
+	"flag"
+	"github.com/sdukhovni/clyde-go/bot"
+	"github.com/sdukhovni/clyde-go/config"
+	"github.com/sdukhovni/clyde-go/xmpp"
+)
+
+func main() {
+	flag.String("server", "", "server address (host:port), if not port 5222 of the JID's domain")
+	flag.String("nick", "clyde", "nickname in rooms")
+	flag.String("rooms", "", "comma-separated rooms to join, e.g. \"chat@conference.example.org\"")
+	config.Main(config.Command{
+		Usage: "The bot logs in as $XMPP_JID with the password in $XMPP_PASSWORD, unless\n" +
+			"they're in the configuration file.\n",
+		Flags: map[string]string{
+			"server": "xmpp.server",
+			"nick":   "xmpp.nick",
+			"rooms":  "xmpp.rooms",
+		},
+		// The environment variables the bot has always read; the
+		// CLYDE_ variables for every setting work too (see
+		// config.SetEnv)
+		Env: map[string]string{
+			"XMPP_JID":      "xmpp.jid",
+			"XMPP_PASSWORD": "xmpp.password",
+		},
+		Ready: func(cfg *config.Config) bool {
+			return cfg.XMPP.JID != ""
+		},
+		Start: func(cfg *config.Config, core *bot.Core, stop <-chan struct{}) (bot.Frontend, <-chan error, error) {
+			frontend, err := xmpp.NewBot(cfg.XMPP.JID, cfg.XMPP.Password, cfg.XMPP.Nick)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := frontend.Connect(cfg.XMPP.Server); err != nil {
+				return nil, nil, err
+			}
+			for _, room := range cfg.XMPP.Rooms {
+				if err := frontend.Join(room); err != nil {
+					return nil, nil, err
+				}
+			}
+			done := make(chan error, 1)
+			go func() {
+				done <- frontend.Run()
+			}()
+			go func() {
+				<-stop
+				frontend.Close()
+			}()
+			return frontend, done, nil
+		},
+	})
+}
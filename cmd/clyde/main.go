@@ -30,6 +30,15 @@ func main() {
 	}
 	clydeDir := path.Join(curUser.HomeDir, ".clyde")
 
+	// Configure the admin server, if requested
+	adminConf := clyde.AdminConfig{
+		Addr: os.Getenv("CLYDE_ADMIN_ADDR"),
+		Token: os.Getenv("CLYDE_ADMIN_TOKEN"),
+		CertFile: os.Getenv("CLYDE_ADMIN_CERT"),
+		KeyFile: os.Getenv("CLYDE_ADMIN_KEY"),
+		ClientCAFile: os.Getenv("CLYDE_ADMIN_CLIENT_CA"),
+	}
+
 	// Load Clyde
 	clyde, err := clyde.LoadClyde(clydeDir)
 	if err != nil {
@@ -40,8 +49,24 @@ func main() {
 	// Start Clyde's main goroutine
 	clyde.Run()
 
-	// Keep listening until a SIGINT or SIGTERM.
+	if adminConf.Addr != "" {
+		go func() {
+			log.Println(clyde.ServeAdmin(adminConf))
+		}()
+	}
+
+	// Keep listening until a SIGINT or SIGTERM, which save Clyde's
+	// state on the way out (see Shutdown). A SIGHUP saves it and
+	// reloads it along with his configuration files instead.
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	<-c
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range c {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		log.Println("Reloading")
+		if err := clyde.Reload(); err != nil {
+			log.Printf("Reload error: %v", err)
+		}
+	}
 }
@@ -0,0 +1,688 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// config defines the full configuration of a bot built on package bot
+// (its chains, replies, triggers, and frontend), which can be loaded
+// from a TOML or JSON file instead of being spread over flags and
+// hard-coded settings.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/bot"
+	"github.com/sdukhovni/clyde-go/corpus"
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/metrics"
+	"github.com/sdukhovni/clyde-go/moderation"
+)
+
+// Config is a bot's configuration. Fields left out of a configuration
+// file keep their values from Default.
+type Config struct {
+	// Dir is the directory the bot's chains and other state are
+	// saved in. It's required.
+	Dir string `json:"dir"`
+	// Save is how often to save the bot's state.
+	Save Duration `json:"save"`
+	// Backups is the number of backups of Dir to keep, taken before
+	// each save (see package backup).
+	Backups int `json:"backups"`
+	// Metrics is an address to serve the bot's metrics on, at
+	// /metrics (see package metrics). They aren't served if it's
+	// empty.
+	Metrics string `json:"metrics"`
+	// Debug additionally serves the Go runtime's profiles and a dump
+	// of the chains' sizes on the metrics address, under /debug/
+	// (see metrics.DebugHandler).
+	Debug bool `json:"debug"`
+	// Shadow runs the bot without sending anything, logging what it
+	// would have said instead (see bot.Core.SetShadow).
+	Shadow bool `json:"shadow"`
+
+	Chains   Chains   `json:"chains"`
+	Sampling Sampling `json:"sampling"`
+	Replies  Replies  `json:"replies"`
+	Filter   Filter   `json:"filter"`
+	Flood    Flood    `json:"flood"`
+	Scrub    Scrub    `json:"scrub"`
+	// Channels overrides settings for particular channels, named
+	// "<network>/<channel>" like their chains.
+	Channels map[string]Channel `json:"channels"`
+	Triggers []Trigger          `json:"triggers"`
+	Feeds    []Feed             `json:"feeds"`
+
+	Matrix   Matrix   `json:"matrix"`
+	Telegram Telegram `json:"telegram"`
+	XMPP     XMPP     `json:"xmpp"`
+}
+
+// Chains configures how the bot's chains are built.
+type Chains struct {
+	// PrefixLen is the prefix length of the chains.
+	PrefixLen int `json:"prefix"`
+	// Learn says whether the bot learns from messages at all.
+	Learn bool `json:"learn"`
+	// Isolated restricts replies in each channel to what was learned
+	// there (see bot.Routing).
+	Isolated bool `json:"isolated"`
+	// Languages gives each channel a chain per language (see
+	// bot.Routing).
+	Languages bool `json:"languages"`
+	// URLs is what the chains learn for URLs: "keep", "drop", or
+	// "placeholder" (see markov.URLPolicy).
+	URLs string `json:"urls"`
+	// Mentions is what the chains learn for mentions of users and
+	// nick prefixes: "keep", "strip", or "drop" (see
+	// markov.MentionPolicy).
+	Mentions string `json:"mentions"`
+	// Case is how the chains treat the case of words: "fold",
+	// "preserve", or "restore" (see markov.CaseFolding).
+	Case string `json:"case"`
+	// Starter is a corpus to build the global chain from on the
+	// bot's first run (see SeedChains): "builtin" for
+	// corpus.Starter, or a directory of text files and models (see
+	// corpus.BuildFS).
+	Starter string `json:"starter"`
+}
+
+// Sampling configures how the chains generate text (see
+// markov.Chain.SetTemperature, SetContext, and SetSmoothing). Zero
+// values leave the chains' defaults.
+type Sampling struct {
+	Temperature float64 `json:"temperature"`
+	Context     int     `json:"context"`
+	Smoothing   float64 `json:"smoothing"`
+	// KneserNey is the discount for Kneser-Ney smoothing, if any
+	// (see markov.Chain.SetKneserNey).
+	KneserNey float64 `json:"kneser_ney"`
+	// Stem is the language to match prefixes by their stems in, if
+	// any (see markov.Chain.SetStemming).
+	Stem string `json:"stem"`
+	// Thesaurus is where to find synonyms for unknown prefix words,
+	// if anywhere: "builtin" for markov.BuiltinThesaurus, or a file
+	// of synonyms (see markov.ReadSynonyms).
+	Thesaurus string `json:"thesaurus"`
+	// Fuzzy is the most edits by which to match unknown prefix words
+	// with known ones, if any (see markov.Chain.SetFuzzy).
+	Fuzzy int `json:"fuzzy"`
+	// SkipGrams has prefixes match those differing in one word when
+	// nothing else does (see markov.Chain.SetSkipGrams).
+	SkipGrams bool `json:"skip_grams"`
+}
+
+// Replies configures unprompted replies (see bot.Policy).
+type Replies struct {
+	Chance   float64  `json:"chance"`
+	Cooldown Duration `json:"cooldown"`
+	// MinTokens and Warmup hold off replies until a chain has learned
+	// enough (see bot.Policy).
+	MinTokens int      `json:"min_tokens"`
+	Warmup    []string `json:"warmup"`
+	// Spelling is the most edits by which to correct misspelled words
+	// before continuing a message (see bot.Policy).
+	Spelling int `json:"spelling"`
+}
+
+// Filter configures a filter for what the bot says (see package
+// moderation).
+type Filter struct {
+	// File is a file of terms, one per line. There's no filter if
+	// it's empty.
+	File string `json:"file"`
+	// Strategy is "drop", "regenerate", or "mask".
+	Strategy string `json:"strategy"`
+}
+
+// Flood limits how much the bot learns from floods of messages (see
+// bot.Flood). There are no limits unless Window is set.
+type Flood struct {
+	Window  Duration `json:"window"`
+	User    int      `json:"user"`
+	Repeats int      `json:"repeats"`
+}
+
+// Scrub configures masking personal information and secrets in what
+// the bot learns (see moderation.Scrubber). Nothing is masked if both
+// lists are empty.
+type Scrub struct {
+	// Kinds are kinds of information to mask: "email", "phone", or
+	// "secret".
+	Kinds []string `json:"kinds"`
+	// Patterns are regular expressions matching anything else to
+	// mask.
+	Patterns []string `json:"patterns"`
+}
+
+// Channel overrides settings for a channel. Unset fields leave the
+// global settings.
+type Channel struct {
+	Chance *float64 `json:"chance"`
+	Learn  *bool    `json:"learn"`
+}
+
+// Trigger configures a trigger (see bot.Trigger) replying with one of
+// a list of canned responses (see bot.Canned) or with generated text
+// continuing a seed (see bot.Reply).
+type Trigger struct {
+	Pattern   string   `json:"pattern"`
+	Keywords  []string `json:"keywords"`
+	Priority  int      `json:"priority"`
+	Addressed bool     `json:"addressed"`
+	Responses []string `json:"responses"`
+	Reply     string   `json:"reply"`
+}
+
+// Feed configures an RSS or Atom feed for the bot to learn from (see
+// bot.Feed). Every defaults to an hour.
+type Feed struct {
+	URL     string   `json:"url"`
+	Chain   string   `json:"chain"`
+	Every   Duration `json:"every"`
+	Summary string   `json:"summary"`
+}
+
+// defaultFeedEvery is how often feeds are polled unless configured
+// otherwise.
+const defaultFeedEvery = time.Hour
+
+// Roles lists the users of a frontend given roles for admin commands
+// (see bot.Role and bot.Admin), by their IDs on its network.
+type Roles struct {
+	Admins []string `json:"admins"`
+	Owners []string `json:"owners"`
+}
+
+// Matrix configures the Matrix frontend. Roles are given to user IDs.
+type Matrix struct {
+	Homeserver string `json:"homeserver"`
+	UserID     string `json:"user_id"`
+	Token      string `json:"token"`
+	Roles
+}
+
+// Telegram configures the Telegram frontend. If Webhook is set,
+// updates are received there, served on Addr, instead of by polling.
+// Roles are given to numeric user IDs.
+type Telegram struct {
+	Token         string `json:"token"`
+	Username      string `json:"username"`
+	Webhook       string `json:"webhook"`
+	WebhookSecret string `json:"webhook_secret"`
+	Addr          string `json:"addr"`
+	Roles
+}
+
+// XMPP configures the XMPP frontend. Server defaults to port 5222 of
+// the JID's domain. Roles are given to bare JIDs, with no resource,
+// which only match in rooms that reveal occupants' real JIDs to the bot
+// (see xmpp.Bot).
+type XMPP struct {
+	JID      string   `json:"jid"`
+	Password string   `json:"password"`
+	Server   string   `json:"server"`
+	Nick     string   `json:"nick"`
+	Rooms    []string `json:"rooms"`
+	Roles
+}
+
+// Duration is a time.Duration written as a string like "10m" in
+// configuration files.
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10m\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Default returns the default configuration, which is complete except
+// for Dir and the frontend's credentials.
+func Default() *Config {
+	return &Config{
+		Save:    Duration(10 * time.Minute),
+		Backups: 5,
+		Chains: Chains{
+			PrefixLen: 2,
+			Learn:     true,
+			URLs:      markov.KeepURLs.String(),
+			Mentions:  markov.KeepMentions.String(),
+			Case:      markov.FoldCase.String(),
+		},
+		Replies: Replies{
+			Cooldown: Duration(5 * time.Minute),
+		},
+		Filter: Filter{
+			Strategy: moderation.Regenerate.String(),
+		},
+		Telegram: Telegram{
+			Addr: "localhost:8045",
+		},
+		XMPP: XMPP{
+			Nick: "clyde",
+		},
+	}
+}
+
+// Load loads a configuration from a file over the defaults, as in
+// LoadFile, and validates it.
+func Load(filename string) (*Config, error) {
+	c := Default()
+	if err := c.LoadFile(filename); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("config: %s: %v", filename, err)
+	}
+	return c, nil
+}
+
+// LoadFile loads settings from a file, in TOML format, or JSON if the
+// filename ends in ".json", over the configuration's current ones,
+// without validating the result, so that more settings can be layered
+// over it (see Set). Unknown settings are errors, to catch typos.
+// Relative paths in the file are taken relative to its directory.
+func (c *Config) LoadFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(filename, ".json") {
+		doc, err := parseTOML(string(data))
+		if err != nil {
+			return fmt.Errorf("config: %s: %v", filename, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return err
+		}
+	}
+
+	// The settings naming files or directories, and the names some
+	// take for what's built in instead
+	paths := []*string{&c.Dir, &c.Filter.File, &c.Chains.Starter, &c.Sampling.Thesaurus}
+	builtin := map[*string]string{&c.Chains.Starter: builtinStarter, &c.Sampling.Thesaurus: builtinThesaurus}
+	old := make([]string, len(paths))
+	for i, p := range paths {
+		old[i] = *p
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("config: %s: %s", filename, strings.TrimPrefix(err.Error(), "json: "))
+	}
+	for i, p := range paths {
+		if *p != old[i] && *p != builtin[p] && !filepath.IsAbs(*p) {
+			*p = filepath.Join(filepath.Dir(filename), *p)
+		}
+	}
+	return nil
+}
+
+// Validate returns an error describing the first problem with the
+// configuration, if any.
+func (c *Config) Validate() error {
+	switch {
+	case c.Dir == "":
+		return fmt.Errorf("dir is required")
+	case c.Save <= 0:
+		return fmt.Errorf("save must be positive")
+	case c.Backups < 0:
+		return fmt.Errorf("backups must not be negative")
+	case c.Debug && c.Metrics == "":
+		return fmt.Errorf("debug requires a metrics address")
+	case c.Chains.PrefixLen < 1:
+		return fmt.Errorf("chains.prefix must be at least 1")
+	case c.Sampling.Temperature < 0:
+		return fmt.Errorf("sampling.temperature must not be negative")
+	case c.Sampling.Context < 0:
+		return fmt.Errorf("sampling.context must not be negative")
+	case c.Sampling.Fuzzy < 0:
+		return fmt.Errorf("sampling.fuzzy must not be negative")
+	case c.Sampling.Smoothing < 0:
+		return fmt.Errorf("sampling.smoothing must not be negative")
+	case c.Sampling.KneserNey < 0 || c.Sampling.KneserNey >= 1:
+		return fmt.Errorf("sampling.kneser_ney must be at least 0 and less than 1")
+	case c.Replies.Cooldown < 0:
+		return fmt.Errorf("replies.cooldown must not be negative")
+	case c.Replies.MinTokens < 0:
+		return fmt.Errorf("replies.min_tokens must not be negative")
+	case c.Replies.Spelling < 0:
+		return fmt.Errorf("replies.spelling must not be negative")
+	case c.Flood.Window < 0 || c.Flood.User < 0 || c.Flood.Repeats < 0:
+		return fmt.Errorf("flood settings must not be negative")
+	}
+	if err := checkChance("replies.chance", c.Replies.Chance); err != nil {
+		return err
+	}
+	if _, err := moderation.ParseStrategy(c.Filter.Strategy); err != nil {
+		return err
+	}
+	if _, err := markov.ParseURLPolicy(c.Chains.URLs); err != nil {
+		return err
+	}
+	if _, err := markov.ParseMentionPolicy(c.Chains.Mentions); err != nil {
+		return err
+	}
+	if _, err := markov.ParseCaseFolding(c.Chains.Case); err != nil {
+		return err
+	}
+	if _, err := moderation.NewScrubber(c.Scrub.Kinds, c.Scrub.Patterns); err != nil {
+		return fmt.Errorf("scrub: %v", err)
+	}
+	for name, ch := range c.Channels {
+		if ch.Chance != nil {
+			if err := checkChance("channels."+name+".chance", *ch.Chance); err != nil {
+				return err
+			}
+		}
+	}
+	for i, t := range c.Triggers {
+		switch {
+		case t.Pattern == "" && len(t.Keywords) == 0:
+			return fmt.Errorf("triggers[%d] needs a pattern or keywords", i)
+		case len(t.Responses) == 0 && t.Reply == "":
+			return fmt.Errorf("triggers[%d] needs responses or a reply", i)
+		case len(t.Responses) > 0 && t.Reply != "":
+			return fmt.Errorf("triggers[%d] can't have both responses and a reply", i)
+		}
+		if t.Pattern != "" {
+			if _, err := regexp.Compile(t.Pattern); err != nil {
+				return fmt.Errorf("triggers[%d]: %v", i, err)
+			}
+		}
+	}
+	// Occupant JIDs ("room@conference.example.org/nick") name whoever
+	// has the nickname, so XMPP roles must be bare JIDs
+	for _, jids := range [][]string{c.XMPP.Admins, c.XMPP.Owners} {
+		for _, jid := range jids {
+			if strings.Contains(jid, "/") {
+				return fmt.Errorf("xmpp roles must be bare JIDs, not %q", jid)
+			}
+		}
+	}
+	for i, f := range c.Feeds {
+		switch {
+		case !strings.HasPrefix(f.URL, "http://") && !strings.HasPrefix(f.URL, "https://"):
+			return fmt.Errorf("feeds[%d] needs an HTTP url", i)
+		case f.Every < 0:
+			return fmt.Errorf("feeds[%d].every must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// builtinStarter is the Chains.Starter setting for corpus.Starter.
+const builtinStarter = "builtin"
+
+// SeedChains builds the global chain from the starter corpus, if one is
+// configured, so that a new bot has something to say. It's meant for
+// the bot's first run, when there are no saved chains to load.
+func (c *Config) SeedChains(chains *markov.ChainSet) error {
+	var fsys fs.FS
+	switch c.Chains.Starter {
+	case "":
+		return nil
+	case builtinStarter:
+		fsys = corpus.Starter
+	default:
+		fsys = os.DirFS(c.Chains.Starter)
+	}
+	words, err := corpus.BuildFS(chains.Chain(bot.GlobalChain), fsys)
+	if err != nil {
+		return fmt.Errorf("chains.starter: %v", err)
+	}
+	log.Printf("Learned %d words from the starter corpus", words)
+	return nil
+}
+
+// checkChance returns an error if a probability is out of range.
+func checkChance(name string, p float64) error {
+	if p < 0 || p > 1 {
+		return fmt.Errorf("%s must be between 0 and 1", name)
+	}
+	return nil
+}
+
+// SetupChains applies the sampling, URL, mention, and case settings to
+// a set of chains, and to the chains it creates or loads from now on.
+// The configuration must be valid. It returns an error if the
+// thesaurus can't be read.
+func (c *Config) SetupChains(chains *markov.ChainSet) error {
+	thesaurus, err := c.thesaurus()
+	if err != nil {
+		return err
+	}
+	urls, _ := markov.ParseURLPolicy(c.Chains.URLs)
+	mentions, _ := markov.ParseMentionPolicy(c.Chains.Mentions)
+	folding, _ := markov.ParseCaseFolding(c.Chains.Case)
+	setup := func(name string, chain *markov.Chain) {
+		chain.SetURLPolicy(urls)
+		chain.SetMentionPolicy(mentions)
+		chain.SetCaseFolding(folding)
+		if c.Sampling.Temperature > 0 {
+			chain.SetTemperature(c.Sampling.Temperature)
+		}
+		if c.Sampling.Context > 0 {
+			chain.SetContext(c.Sampling.Context)
+		}
+		if c.Sampling.Smoothing > 0 {
+			chain.SetSmoothing(c.Sampling.Smoothing)
+		}
+		if c.Sampling.KneserNey > 0 {
+			chain.SetKneserNey(c.Sampling.KneserNey)
+		}
+		if c.Sampling.Stem != "" {
+			chain.SetStemming(c.Sampling.Stem)
+		}
+		if thesaurus != nil {
+			chain.SetThesaurus(thesaurus)
+		}
+		if c.Sampling.Fuzzy > 0 {
+			chain.SetFuzzy(c.Sampling.Fuzzy)
+		}
+		if c.Sampling.SkipGrams {
+			chain.SetSkipGrams(true)
+		}
+	}
+	chains.SetSetup(setup)
+	chains.Each(setup)
+	return nil
+}
+
+// builtinThesaurus is the Sampling.Thesaurus setting for
+// markov.BuiltinThesaurus.
+const builtinThesaurus = "builtin"
+
+// thesaurus returns the thesaurus the sampling settings name, or nil
+// if there isn't one.
+func (c *Config) thesaurus() (markov.Thesaurus, error) {
+	thesaurus, err := LoadThesaurus(c.Sampling.Thesaurus)
+	if err != nil {
+		return nil, fmt.Errorf("sampling.thesaurus: %v", err)
+	}
+	return thesaurus, nil
+}
+
+// LoadThesaurus returns the thesaurus a Sampling.Thesaurus setting
+// names: nil for "", markov.BuiltinThesaurus for "builtin", or else
+// the synonyms read from the named file (see markov.ReadSynonyms).
+func LoadThesaurus(name string) (markov.Thesaurus, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case builtinThesaurus:
+		return markov.BuiltinThesaurus(), nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	synonyms, err := markov.ReadSynonyms(f)
+	if err != nil {
+		return nil, err
+	}
+	return synonyms, nil
+}
+
+// SetupCore applies the reply, shadow, routing, learning, flood,
+// filter, scrub, admin, and trigger settings to a core, after the standard
+// triggers (see bot.Core.AddStandardTriggers). Learning settings for channels
+// override any saved with bot.Core.SaveLearning, so it should be
+// called after bot.Core.LoadLearning.
+func (c *Config) SetupCore(core *bot.Core) error {
+	policy := bot.Policy{
+		Chance:    c.Replies.Chance,
+		Cooldown:  time.Duration(c.Replies.Cooldown),
+		Channels:  make(map[string]float64),
+		MinTokens: c.Replies.MinTokens,
+		Warmup:    c.Replies.Warmup,
+		Spelling:  c.Replies.Spelling,
+	}
+	for name, ch := range c.Channels {
+		if ch.Chance != nil {
+			policy.Channels[name] = *ch.Chance
+		}
+		if ch.Learn != nil {
+			core.SetChannelLearning(name, *ch.Learn)
+		}
+	}
+	core.SetPolicy(policy)
+	core.SetShadow(c.Shadow)
+	core.SetRouting(bot.Routing{Isolated: c.Chains.Isolated, Languages: c.Chains.Languages})
+	core.SetFlood(bot.Flood{
+		Window:  time.Duration(c.Flood.Window),
+		User:    c.Flood.User,
+		Repeats: c.Flood.Repeats,
+	})
+	if !c.Chains.Learn {
+		core.SetLearning(false)
+	}
+
+	if c.Filter.File != "" {
+		strategy, err := moderation.ParseStrategy(c.Filter.Strategy)
+		if err != nil {
+			return err
+		}
+		filter, err := moderation.LoadFilter(c.Filter.File, strategy)
+		if err != nil {
+			return err
+		}
+		core.SetFilter(filter)
+	}
+
+	if len(c.Scrub.Kinds) > 0 || len(c.Scrub.Patterns) > 0 {
+		scrubber, err := moderation.NewScrubber(c.Scrub.Kinds, c.Scrub.Patterns)
+		if err != nil {
+			return err
+		}
+		core.SetScrubber(scrubber)
+	}
+
+	roles := make(map[string]bot.Role)
+	for network, r := range map[string]Roles{
+		"matrix":   c.Matrix.Roles,
+		"telegram": c.Telegram.Roles,
+		"xmpp":     c.XMPP.Roles,
+	} {
+		for _, id := range r.Admins {
+			roles[network+"/"+id] = bot.RoleAdmin
+		}
+		// Owners outrank admins if listed as both
+		for _, id := range r.Owners {
+			roles[network+"/"+id] = bot.RoleOwner
+		}
+	}
+	if len(roles) > 0 {
+		core.SetRoles(roles)
+		core.AddAdminTriggers()
+	}
+
+	core.AddStandardTriggers()
+	for _, t := range c.Triggers {
+		handler := bot.Reply(t.Reply)
+		if len(t.Responses) > 0 {
+			handler = bot.Canned(t.Responses...)
+		}
+		err := core.AddTrigger(bot.Trigger{
+			Pattern:   t.Pattern,
+			Keywords:  t.Keywords,
+			Priority:  t.Priority,
+			Addressed: t.Addressed,
+			Handler:   handler,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeMetrics starts serving a core's metrics (see
+// bot.Core.RegisterMetrics), and its debugging endpoints if enabled,
+// on the configured address, if any.
+func (c *Config) ServeMetrics(core *bot.Core) error {
+	if c.Metrics == "" {
+		return nil
+	}
+	l, err := net.Listen("tcp", c.Metrics)
+	if err != nil {
+		return err
+	}
+	registry := metrics.NewRegistry()
+	core.RegisterMetrics(registry)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	if c.Debug {
+		mux.Handle("/debug/", metrics.DebugHandler(core.Do))
+	}
+	go func() {
+		log.Fatal(http.Serve(l, mux))
+	}()
+	return nil
+}
+
+// PollFeeds starts polling the configured feeds for a core (see
+// bot.Core.PollFeed), posting any summaries through the frontend,
+// until stop is closed, or for good if it's nil.
+func (c *Config) PollFeeds(core *bot.Core, f bot.Frontend, stop <-chan struct{}) {
+	for _, feed := range c.Feeds {
+		every := time.Duration(feed.Every)
+		if every == 0 {
+			every = defaultFeedEvery
+		}
+		go core.PollFeed(f, bot.Feed{
+			URL:     feed.URL,
+			Chain:   feed.Chain,
+			Every:   every,
+			Summary: feed.Summary,
+		}, stop)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		text  string
+		err   string
+		check func(c *Config) bool
+	}{
+		{"clyde.toml", "dir = \"chains\"\n[chains]\nprefix = 3\n", "", func(c *Config) bool {
+			return c.Dir == filepath.Join(dir, "chains") && c.Chains.PrefixLen == 3 && c.Chains.Learn
+		}},
+		{"abs.toml", "dir = \"/var/clyde\"\n[filter]\nfile = \"terms.txt\"\n", "", func(c *Config) bool {
+			return c.Dir == "/var/clyde" && c.Filter.File == filepath.Join(dir, "terms.txt")
+		}},
+		{"starter.toml", "[chains]\nstarter = \"corpus\"\n", "", func(c *Config) bool {
+			return c.Chains.Starter == filepath.Join(dir, "corpus")
+		}},
+		{"builtin.toml", "[chains]\nstarter = \"builtin\"\n[filter]\nfile = \"\"\n[sampling]\nthesaurus = \"builtin\"\n", "", func(c *Config) bool {
+			return c.Chains.Starter == "builtin" && c.Filter.File == "" && c.Sampling.Thesaurus == "builtin"
+		}},
+		{"thesaurus.toml", "[sampling]\nthesaurus = \"synonyms.txt\"\n", "", func(c *Config) bool {
+			return c.Sampling.Thesaurus == filepath.Join(dir, "synonyms.txt")
+		}},
+		{"clyde.json", `{"save": "1h", "replies": {"chance": 0.5}}`, "", func(c *Config) bool {
+			return c.Save == Duration(time.Hour) && c.Replies.Chance == 0.5 && c.Dir == ""
+		}},
+		{"triggers.toml", "[[triggers]]\nkeywords = [\"hi\"]\nresponses = [\"hello\"]\n", "", func(c *Config) bool {
+			return len(c.Triggers) == 1 && c.Triggers[0].Responses[0] == "hello"
+		}},
+		{"typo.toml", "[chains]\nprefx = 3\n", "unknown field", nil},
+		{"bad.toml", "dir = \n", "bad.toml", nil},
+		{"duration.json", `{"save": 600}`, "duration must be a string", nil},
+	}
+	for _, test := range tests {
+		filename := filepath.Join(dir, test.name)
+		if err := os.WriteFile(filename, []byte(test.text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c := Default()
+		err := c.LoadFile(filename)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("LoadFile(%q) = %v, want no error", test.name, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("LoadFile(%q) = %v, want an error containing %q", test.name, err, test.err)
+		case test.check != nil && !test.check(c):
+			t.Errorf("LoadFile(%q) loaded %+v", test.name, c)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	half, double := 0.5, 2.0
+	tests := []struct {
+		set  func(c *Config)
+		want string
+	}{
+		{func(c *Config) {}, ""},
+		{func(c *Config) { c.Dir = "" }, "dir is required"},
+		{func(c *Config) { c.Save = 0 }, "save must be positive"},
+		{func(c *Config) { c.Debug = true }, "debug requires a metrics address"},
+		{func(c *Config) { c.Chains.PrefixLen = 0 }, "chains.prefix must be at least 1"},
+		{func(c *Config) { c.Chains.URLs = "sometimes" }, "sometimes"},
+		{func(c *Config) { c.Replies.Chance = 1.5 }, "replies.chance must be between 0 and 1"},
+		{func(c *Config) { c.Filter.Strategy = "ignore" }, "ignore"},
+		{func(c *Config) { c.Sampling.KneserNey = 0.75 }, ""},
+		{func(c *Config) { c.Sampling.KneserNey = 1 }, "sampling.kneser_ney must be at least 0 and less than 1"},
+		{func(c *Config) { c.Channels = map[string]Channel{"matrix/!a": {Chance: &half}} }, ""},
+		{func(c *Config) { c.Channels = map[string]Channel{"matrix/!a": {Chance: &double}} }, "channels.matrix/!a.chance"},
+		{func(c *Config) { c.Triggers = []Trigger{{Reply: "hi"}} }, "triggers[0] needs a pattern or keywords"},
+		{func(c *Config) { c.Triggers = []Trigger{{Pattern: "(", Reply: "hi"}} }, "triggers[0]"},
+		{func(c *Config) { c.Triggers = []Trigger{{Keywords: []string{"hi"}}} }, "triggers[0] needs responses or a reply"},
+		{func(c *Config) { c.Feeds = []Feed{{URL: "ftp://example.org/feed"}} }, "feeds[0] needs an HTTP url"},
+		{func(c *Config) { c.XMPP.Owners = []string{"me@example.org"} }, ""},
+		{func(c *Config) { c.XMPP.Admins = []string{"chat@conference.example.org/me"} }, "xmpp roles must be bare JIDs"},
+		{func(c *Config) { c.Matrix.Admins = []string{"@me:example.org/x"} }, ""},
+	}
+	for i, test := range tests {
+		c := Default()
+		c.Dir = "chains"
+		test.set(c)
+		err := c.Validate()
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("test %d: Validate() = %v, want no error", i, err)
+		case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+			t.Errorf("test %d: Validate() = %v, want an error containing %q", i, err, test.want)
+		}
+	}
+}
+
+func TestLoadThesaurus(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "synonyms.txt")
+	if err := os.WriteFile(file, []byte("big, large, huge\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		word    string
+		wantNil bool
+		wantErr bool
+	}{
+		{"", "", true, false},
+		{"builtin", "big", false, false},
+		{file, "large", false, false},
+		{filepath.Join(dir, "missing.txt"), "", true, true},
+	}
+	for _, test := range tests {
+		thesaurus, err := LoadThesaurus(test.name)
+		if (err != nil) != test.wantErr || (thesaurus == nil) != test.wantNil {
+			t.Errorf("LoadThesaurus(%q) = %v, %v, want nil %v and error %v", test.name, thesaurus, err, test.wantNil, test.wantErr)
+			continue
+		}
+		if test.word != "" && len(thesaurus.Synonyms(test.word)) == 0 {
+			t.Errorf("LoadThesaurus(%q) has no synonyms for %q", test.name, test.word)
+		}
+	}
+}
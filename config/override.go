@@ -0,0 +1,193 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// override.go sets individual settings from strings, so that
+// environment variables and flags can be layered over a configuration
+// file, and secrets needn't be written in one.
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(Duration(0))
+
+// Set sets the setting with the given key, the path of names of its
+// tables and itself as in a configuration file, joined by dots (e.g.
+// "chains.prefix" or "telegram.token"), from a string. Durations are
+// written as in time.ParseDuration, and lists as comma-separated
+// values. Settings in maps and lists of tables, such as channels and
+// triggers, can only be set in a file.
+func (c *Config) Set(key, value string) error {
+	v := reflect.ValueOf(c).Elem()
+	for _, name := range strings.Split(key, ".") {
+		f, ok := field(v, name)
+		if !ok {
+			return fmt.Errorf("config: unknown setting %q", key)
+		}
+		v = f
+	}
+	if err := setString(v, value); err != nil {
+		return fmt.Errorf("config: %s: %v", key, err)
+	}
+	return nil
+}
+
+// field returns the field of a struct with the given JSON name,
+// looking in embedded structs as encoding/json does.
+func field(v reflect.Value, name string) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Anonymous {
+			if f, ok := field(v.Field(i), name); ok {
+				return f, true
+			}
+			continue
+		}
+		if jsonName(t.Field(i)) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// jsonName returns the name of a struct field in configuration files.
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// setString sets a value from a string, according to its type.
+func setString(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("can only be set in a file")
+		}
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("can only be set in a file")
+	}
+	return nil
+}
+
+// Keys returns the keys of every setting that Set can set.
+func Keys() []string {
+	return keys(reflect.TypeOf(Config{}), "")
+}
+
+// keys returns the keys of the settable fields of a struct type, with
+// the given prefix.
+func keys(t reflect.Type, prefix string) []string {
+	var ks []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := prefix + jsonName(f)
+		switch {
+		case f.Anonymous:
+			ks = append(ks, keys(f.Type, prefix)...)
+		case f.Type == durationType:
+			ks = append(ks, key)
+		case f.Type.Kind() == reflect.Struct:
+			ks = append(ks, keys(f.Type, key+".")...)
+		case f.Type.Kind() == reflect.Map || f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() != reflect.String:
+			// Only settable in files
+		default:
+			ks = append(ks, key)
+		}
+	}
+	return ks
+}
+
+// EnvName returns the name of the environment variable that SetEnv
+// reads a setting from: the prefix, then the key in upper case with
+// dots replaced by underscores, e.g. CLYDE_TELEGRAM_TOKEN for the key
+// "telegram.token" with prefix "CLYDE_".
+func EnvName(prefix, key string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// SetEnv sets every setting whose environment variable (see EnvName)
+// is set, e.g. to keep secrets out of configuration files.
+func (c *Config) SetEnv(prefix string) error {
+	vars := make(map[string]string)
+	for _, key := range Keys() {
+		vars[EnvName(prefix, key)] = key
+	}
+	return c.SetEnvVars(vars)
+}
+
+// SetEnvVars sets settings from the given environment variables, if
+// they're set, mapped to the keys of the settings, e.g. for variables
+// with names older than SetEnv's.
+func (c *Config) SetEnvVars(vars map[string]string) error {
+	for name, key := range vars {
+		if value, ok := os.LookupEnv(name); ok {
+			if err := c.Set(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetFlags sets settings from the flags in a flag set that were set on
+// the command line, mapped to the keys of the settings.
+func (c *Config) SetFlags(fs *flag.FlagSet, flags map[string]string) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := flags[f.Name]; ok && err == nil {
+			err = c.Set(key, f.Value.String())
+		}
+	})
+	return err
+}
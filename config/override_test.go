@@ -0,0 +1,99 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package config
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		key, value string
+		get        func(c *Config) interface{}
+		want       interface{}
+	}{
+		{"dir", "chains", func(c *Config) interface{} { return c.Dir }, "chains"},
+		{"chains.prefix", "3", func(c *Config) interface{} { return c.Chains.PrefixLen }, 3},
+		{"chains.learn", "false", func(c *Config) interface{} { return c.Chains.Learn }, false},
+		{"replies.chance", "0.25", func(c *Config) interface{} { return c.Replies.Chance }, 0.25},
+		{"save", "1h", func(c *Config) interface{} { return c.Save }, Duration(time.Hour)},
+		{"xmpp.rooms", "a@muc.example.org,b@muc.example.org", func(c *Config) interface{} { return c.XMPP.Rooms }, []string{"a@muc.example.org", "b@muc.example.org"}},
+		{"telegram.token", "123:abc", func(c *Config) interface{} { return c.Telegram.Token }, "123:abc"},
+	}
+	for _, test := range tests {
+		c := Default()
+		if err := c.Set(test.key, test.value); err != nil {
+			t.Errorf("Set(%q, %q) = %v", test.key, test.value, err)
+			continue
+		}
+		if got := test.get(c); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Set(%q, %q) set %v, want %v", test.key, test.value, got, test.want)
+		}
+	}
+
+	bad := []struct{ key, value string }{
+		{"chains.prefx", "3"},
+		{"chains.prefix", "three"},
+		{"save", "soon"},
+		{"chains", "3"},
+	}
+	for _, test := range bad {
+		if err := Default().Set(test.key, test.value); err == nil {
+			t.Errorf("Set(%q, %q) = nil, want an error", test.key, test.value)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"dir", "CLYDE_DIR"},
+		{"chains.prefix", "CLYDE_CHAINS_PREFIX"},
+		{"telegram.webhook_secret", "CLYDE_TELEGRAM_WEBHOOK_SECRET"},
+	}
+	for _, test := range tests {
+		if got := EnvName("CLYDE_", test.key); got != test.want {
+			t.Errorf("EnvName(%q) = %q, want %q", test.key, got, test.want)
+		}
+	}
+}
+
+func TestLayering(t *testing.T) {
+	t.Setenv("CLYDE_CHAINS_PREFIX", "3")
+	t.Setenv("CLYDE_DIR", "from-env")
+	t.Setenv("MATRIX_TOKEN", "secret")
+	c := Default()
+	if err := c.SetEnvVars(map[string]string{"MATRIX_TOKEN": "matrix.token", "MATRIX_USER_ID": "matrix.user_id"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetEnv("CLYDE_"); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("clyde", flag.ContinueOnError)
+	fs.String("dir", "", "")
+	fs.Int("prefix", 2, "")
+	if err := fs.Parse([]string{"-dir", "from-flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetFlags(fs, map[string]string{"dir": "dir", "prefix": "chains.prefix"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Flags override the environment, but only where they're set
+	if c.Dir != "from-flag" {
+		t.Errorf("Dir = %q, want %q", c.Dir, "from-flag")
+	}
+	if c.Chains.PrefixLen != 3 {
+		t.Errorf("Chains.PrefixLen = %d, want 3", c.Chains.PrefixLen)
+	}
+	if c.Matrix.Token != "secret" || c.Matrix.UserID != "" {
+		t.Errorf("Matrix = %+v, want only the token set", c.Matrix)
+	}
+}
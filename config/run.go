@@ -0,0 +1,254 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// run.go is the main function the bundled frontends' commands share:
+// layering the configuration file, environment, and flags, loading the
+// chains and the rest of the bot's state, serving metrics, polling
+// feeds, and saving periodically and on the way out.
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/backup"
+	"github.com/sdukhovni/clyde-go/bot"
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// The files the bot's state is saved in, in Config.Dir alongside the
+// chains.
+const (
+	karmaFile        = "karma.json"
+	learningFile     = "learning.json"
+	feedsFile        = "feeds.json"
+	watermarkKeyFile = "watermarkKey"
+	watermarksFile   = "watermarks.json"
+)
+
+// commonFlags maps the flags every command has to the settings they
+// override (see Set).
+var commonFlags = map[string]string{
+	"dir":             "dir",
+	"prefix":          "chains.prefix",
+	"save":            "save",
+	"backups":         "backups",
+	"chance":          "replies.chance",
+	"isolated":        "chains.isolated",
+	"learn":           "chains.learn",
+	"filter":          "filter.file",
+	"filter-strategy": "filter.strategy",
+	"cooldown":        "replies.cooldown",
+	"metrics":         "metrics",
+	"debug":           "debug",
+	"shadow":          "shadow",
+}
+
+// A Command describes a frontend's command for Main.
+type Command struct {
+	// Usage explains where the frontend's credentials come from,
+	// for the usage message.
+	Usage string
+	// Flags maps the frontend's own flags, which the command defines
+	// on flag.CommandLine before calling Main, to the settings they
+	// override.
+	Flags map[string]string
+	// Env maps the environment variables the frontend has always
+	// read to the settings they set (see SetEnvVars).
+	Env map[string]string
+	// Ready reports whether the configuration has everything the
+	// frontend needs to connect.
+	Ready func(c *Config) bool
+	// Start connects the frontend and starts it receiving messages
+	// for the core until stop is closed. It returns the frontend,
+	// and a channel that receives an error if the frontend
+	// disconnects by itself, which may be nil if it never does.
+	Start func(c *Config, core *bot.Core, stop <-chan struct{}) (bot.Frontend, <-chan error, error)
+}
+
+// Main runs a bot with the given frontend: it parses the command line,
+// layers the configuration file, the environment, and the flags over
+// the defaults, sets up the chains and core, starts the frontend, and
+// saves the bot's state periodically and once more on SIGINT or
+// SIGTERM, or when the frontend disconnects. It exits on any error
+// setting up.
+func Main(cmd Command) {
+	configFile := flag.String("config", "", "configuration file (TOML, or JSON if named *.json), which the environment and other flags override")
+	flag.String("dir", "", "directory of chains saved as a set (created if missing)")
+	flag.Int("prefix", 2, "prefix length of the chains")
+	flag.Duration("save", 10*time.Minute, "how often to save chains")
+	flag.Int("backups", 5, "number of backups of the chains directory to keep, taken before each save")
+	flag.Float64("chance", 0, "probability of replying to messages not addressed to the bot")
+	flag.Bool("isolated", false, "reply in each channel using only what was learned there")
+	flag.Bool("learn", true, "learn from messages at all")
+	flag.String("filter", "", "file of terms (one per line) the bot mustn't say")
+	flag.String("filter-strategy", "regenerate", "what to do with sentences containing filtered terms: drop, regenerate, or mask")
+	flag.Duration("cooldown", 5*time.Minute, "minimum time between unprompted replies in a channel")
+	flag.String("metrics", "", "address to serve metrics for Prometheus on, at /metrics")
+	flag.Bool("debug", false, "also serve profiles and chain sizes on the metrics address, under /debug/")
+	flag.Bool("shadow", false, "learn, but only log what the bot would say instead of sending it")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [-config file] [-dir chains] [options]\n", os.Args[0])
+		fmt.Fprint(os.Stderr, cmd.Usage)
+		fmt.Fprintf(os.Stderr, "Any setting can be set in the environment as $CLYDE_<SETTING>, e.g.\n")
+		fmt.Fprintf(os.Stderr, "$CLYDE_CHAINS_PREFIX, overriding the file; flags override both.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Layer the configuration file, the environment, and the flags
+	// over the defaults, in that order
+	cfg := Default()
+	if *configFile != "" {
+		if err := cfg.LoadFile(*configFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := cfg.SetEnvVars(cmd.Env); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.SetEnv("CLYDE_"); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.SetFlags(flag.CommandLine, commonFlags); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.SetFlags(flag.CommandLine, cmd.Flags); err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Dir == "" || !cmd.Ready(cfg) || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	rand.Seed(time.Now().UnixNano())
+
+	core, err := cfg.newCore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	save := cfg.saver(core)
+	core.SetAdminActions(bot.AdminActions{
+		Save: save,
+		Reload: func() {
+			core.Do(func(chains *markov.ChainSet) {
+				if err := chains.Load(cfg.Dir); err != nil {
+					log.Println(err)
+				}
+			})
+		},
+	})
+
+	stop := make(chan struct{})
+	frontend, done, err := cmd.Start(cfg, core, stop)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go core.Run(frontend)
+	cfg.PollFeeds(core, frontend, stop)
+
+	// Save periodically, and once more on SIGINT, SIGTERM, or
+	// disconnection
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	tick := time.NewTicker(time.Duration(cfg.Save)).C
+	for {
+		select {
+		case <-tick:
+			save()
+		case <-c:
+			close(stop)
+			save()
+			return
+		case err := <-done:
+			save()
+			log.Fatal(err)
+		}
+	}
+}
+
+// newCore loads the chains, or seeds them on the first run, and the
+// rest of the bot's saved state into a new core set up as configured,
+// and starts serving its metrics.
+func (c *Config) newCore() (*bot.Core, error) {
+	chains := markov.NewChainSet(c.Chains.PrefixLen)
+	if err := c.SetupChains(chains); err != nil {
+		return nil, err
+	}
+	if err := chains.Load(c.Dir); os.IsNotExist(err) {
+		if err := c.SeedChains(chains); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	core := bot.NewCore(chains)
+	for _, load := range []struct {
+		file string
+		load func(string) error
+	}{
+		{karmaFile, core.LoadKarma},
+		{learningFile, core.LoadLearning},
+		{feedsFile, core.LoadFeeds},
+	} {
+		err := load.load(path.Join(c.Dir, load.file))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if err := c.SetupCore(core); err != nil {
+		return nil, err
+	}
+	if err := c.ServeMetrics(core); err != nil {
+		return nil, err
+	}
+	// The registry's key is created on first run
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := core.LoadRegistry(path.Join(c.Dir, watermarkKeyFile), path.Join(c.Dir, watermarksFile)); err != nil {
+		return nil, err
+	}
+	return core, nil
+}
+
+// saver returns a function that backs up the bot's state and saves a
+// core's chains and state over it, logging any errors.
+func (c *Config) saver(core *bot.Core) func() {
+	return func() {
+		if _, err := backup.Snapshot(c.Dir, c.Backups); err != nil {
+			log.Println(err)
+		}
+		core.Do(func(chains *markov.ChainSet) {
+			if err := chains.Save(c.Dir); err != nil {
+				log.Println(err)
+			}
+		})
+		for _, save := range []struct {
+			file string
+			save func(string) error
+		}{
+			{karmaFile, core.SaveKarma},
+			{learningFile, core.SaveLearning},
+			{feedsFile, core.SaveFeeds},
+			{watermarksFile, core.SaveRegistry},
+		} {
+			if err := save.save(path.Join(c.Dir, save.file)); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,439 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// toml.go parses the subset of TOML that configuration files need:
+// tables, arrays of tables, strings, numbers, booleans, arrays, and
+// inline tables, but not dates or multi-line strings. It's written by
+// hand, since the subset is small and stable.
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlParser is the state of a TOML parse.
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+// parseTOML parses a TOML document into nested maps, with arrays as
+// slices, integers as int64, and floats as float64.
+func parseTOML(s string) (map[string]interface{}, error) {
+	p := &tomlParser{s: s, line: 1}
+	root := make(map[string]interface{})
+	table := root
+	// Tables defined by headers, which may not be defined twice
+	defined := make(map[string]bool)
+
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return root, nil
+		}
+		var err error
+		switch {
+		case strings.HasPrefix(p.s[p.pos:], "[["):
+			p.pos += 2
+			table, err = p.arrayTableHeader(root)
+		case p.s[p.pos] == '[':
+			p.pos++
+			table, err = p.tableHeader(root, defined)
+		default:
+			err = p.keyValue(table)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// errorf returns an error at the current line.
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+// skipSpace skips spaces, tabs, and comments, and newlines too if
+// newlines is set.
+func (p *tomlParser) skipSpace(newlines bool) {
+	for !p.eof() {
+		switch c := p.s[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && newlines:
+			p.pos++
+			p.line++
+		case c == '#':
+			for !p.eof() && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endLine checks that nothing but a comment follows on the line.
+func (p *tomlParser) endLine() error {
+	p.skipSpace(false)
+	if p.eof() {
+		return nil
+	}
+	if p.s[p.pos] != '\n' {
+		return p.errorf("unexpected %q after value", p.rest())
+	}
+	return nil
+}
+
+// rest returns the rest of the current line, for error messages.
+func (p *tomlParser) rest() string {
+	end := strings.IndexByte(p.s[p.pos:], '\n')
+	if end < 0 {
+		return p.s[p.pos:]
+	}
+	return p.s[p.pos : p.pos+end]
+}
+
+// expect consumes the given byte, after any spaces.
+func (p *tomlParser) expect(c byte) error {
+	p.skipSpace(false)
+	if p.eof() || p.s[p.pos] != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// tableHeader parses the rest of a "[table]" header, returning the
+// table.
+func (p *tomlParser) tableHeader(root map[string]interface{}, defined map[string]bool) (map[string]interface{}, error) {
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+	name := strings.Join(keys, "\x00")
+	if defined[name] {
+		return nil, p.errorf("table %s defined twice", strings.Join(keys, "."))
+	}
+	defined[name] = true
+	return p.descend(root, keys)
+}
+
+// arrayTableHeader parses the rest of a "[[array]]" header, returning
+// the new table appended to the array.
+func (p *tomlParser) arrayTableHeader(root map[string]interface{}) (map[string]interface{}, error) {
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+	parent, err := p.descend(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	table := make(map[string]interface{})
+	switch v := parent[last].(type) {
+	case nil:
+		parent[last] = []interface{}{table}
+	case []interface{}:
+		parent[last] = append(v, table)
+	default:
+		return nil, p.errorf("%s is not an array of tables", strings.Join(keys, "."))
+	}
+	return table, nil
+}
+
+// descend returns the table named by the given keys, creating any
+// missing tables on the way. Keys naming arrays of tables descend into
+// their last table.
+func (p *tomlParser) descend(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for i, k := range keys {
+		switch v := table[k].(type) {
+		case nil:
+			next := make(map[string]interface{})
+			table[k] = next
+			table = next
+		case map[string]interface{}:
+			table = v
+		case []interface{}:
+			var last map[string]interface{}
+			if len(v) > 0 {
+				last, _ = v[len(v)-1].(map[string]interface{})
+			}
+			if last == nil {
+				return nil, p.errorf("%s is not a table", strings.Join(keys[:i+1], "."))
+			}
+			table = last
+		default:
+			return nil, p.errorf("%s is not a table", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return table, nil
+}
+
+// keyValue parses a "key = value" line into the given table.
+func (p *tomlParser) keyValue(table map[string]interface{}) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if err := p.expect('='); err != nil {
+		return err
+	}
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	table, err = p.descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := table[last]; ok {
+		return p.errorf("%s defined twice", strings.Join(keys, "."))
+	}
+	table[last] = v
+	return nil
+}
+
+// key parses a possibly dotted key, of bare or quoted parts.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace(false)
+		if p.eof() {
+			return nil, p.errorf("expected key")
+		}
+		var k string
+		var err error
+		switch p.s[p.pos] {
+		case '"':
+			k, err = p.basicString()
+		case '\'':
+			k, err = p.literalString()
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.s[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected key, found %q", p.rest())
+			}
+			k = p.s[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+		p.skipSpace(false)
+		if p.eof() || p.s[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value parses a value.
+func (p *tomlParser) value() (interface{}, error) {
+	p.skipSpace(false)
+	if p.eof() {
+		return nil, p.errorf("expected value")
+	}
+	switch c := p.s[p.pos]; {
+	case strings.HasPrefix(p.s[p.pos:], `"""`) || strings.HasPrefix(p.s[p.pos:], "'''"):
+		return nil, p.errorf("multi-line strings aren't supported")
+	case c == '"':
+		return p.basicString()
+	case c == '\'':
+		return p.literalString()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	case strings.HasPrefix(p.s[p.pos:], "true"):
+		p.pos += len("true")
+		return true, nil
+	case strings.HasPrefix(p.s[p.pos:], "false"):
+		p.pos += len("false")
+		return false, nil
+	}
+	return p.number()
+}
+
+// number parses an integer or float.
+func (p *tomlParser) number() (interface{}, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("+-0123456789_.eExabcdfinoABCDF", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	text := p.s[start:p.pos]
+	if text == "" {
+		return nil, p.errorf("expected value, found %q", p.rest())
+	}
+	if strings.Contains(text, "__") || strings.HasPrefix(text, "_") || strings.HasSuffix(text, "_") {
+		return nil, p.errorf("bad number %q", text)
+	}
+	clean := strings.ReplaceAll(text, "_", "")
+	if i, err := strconv.ParseInt(clean, 0, 64); err == nil {
+		return i, nil
+	}
+	switch clean {
+	case "inf", "+inf", "-inf", "nan", "+nan", "-nan":
+		return nil, p.errorf("bad number %q", text)
+	}
+	f, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return nil, p.errorf("bad value %q", text)
+	}
+	return f, nil
+}
+
+// basicString parses a double-quoted string with escapes.
+func (p *tomlParser) basicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() || p.s[p.pos] == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.s[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated string")
+			}
+			e := p.s[p.pos]
+			p.pos++
+			switch e {
+			case 'b':
+				b.WriteByte('\b')
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'f':
+				b.WriteByte('\f')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(e)
+			case 'u', 'U':
+				n := 4
+				if e == 'U' {
+					n = 8
+				}
+				if p.pos+n > len(p.s) {
+					return "", p.errorf("bad escape in string")
+				}
+				r, err := strconv.ParseUint(p.s[p.pos:p.pos+n], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(r)) {
+					return "", p.errorf("bad escape in string")
+				}
+				b.WriteRune(rune(r))
+				p.pos += n
+			default:
+				return "", p.errorf("bad escape \\%c in string", e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// literalString parses a single-quoted string, without escapes.
+func (p *tomlParser) literalString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.s[p.pos:], "'\n")
+	if end < 0 || p.s[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.s[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+// array parses an array, which may span lines.
+func (p *tomlParser) array() ([]interface{}, error) {
+	p.pos++
+	a := []interface{}{}
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.s[p.pos] == ']' {
+			p.pos++
+			return a, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+		p.skipSpace(true)
+		if !p.eof() && p.s[p.pos] == ',' {
+			p.pos++
+		} else if p.eof() || p.s[p.pos] != ']' {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// inlineTable parses a "{key = value, ...}" table on one line.
+func (p *tomlParser) inlineTable() (map[string]interface{}, error) {
+	p.pos++
+	table := make(map[string]interface{})
+	p.skipSpace(false)
+	if !p.eof() && p.s[p.pos] == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		if p.eof() {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// corpus reads training text out of chat logs and exports, keeping
+// only what people said, so that a chain can be bootstrapped from a
+// community's history without learning timestamps, nicks, and
+// join/part noise.
+
+package corpus
+
+import (
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// A Message is something someone said, read from a log or export.
+type Message struct {
+	Sender string
+	Text   string
+}
+
+// Build adds the text of each message to the chain as a separate block
+// of text, with its punctuation normalized (see
+// stringutil.NormalizePunctuation), and returns the number of words
+// added, and the first error training on a message, after which the
+// rest are still added. If tagSenders is set, each message is also
+// recorded under its sender's name as a source tag (see markov.Tag),
+// for per-user models.
+func Build(c *markov.Chain, msgs []Message, tagSenders bool, opts ...markov.BuildOption) (int, error) {
+	words := 0
+	var err error
+	for _, m := range msgs {
+		text := stringutil.NormalizePunctuation(m.Text)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		o := opts
+		if tagSenders && m.Sender != "" {
+			o = append(o[:len(o):len(o)], markov.Tag(m.Sender))
+		}
+		n, e := c.Build(strings.NewReader(text), o...)
+		words += n
+		if err == nil {
+			err = e
+		}
+	}
+	return words, err
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// discord.go reads messages from Discord channels exported as JSON by
+// DiscordChatExporter.
+
+package corpus
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// discordExport is a channel exported by DiscordChatExporter.
+type discordExport struct {
+	Messages []struct {
+		Type    string `json:"type"`
+		Content string `json:"content"`
+		Author  struct {
+			Name  string `json:"name"`
+			IsBot bool   `json:"isBot"`
+		} `json:"author"`
+	} `json:"messages"`
+}
+
+// discordMarkup matches the markup Discord's clients display
+// specially: custom emoji ("<:name:123>"), mentions ("<@123>",
+// "<#123>", "<@&123>"), and timestamps ("<t:1700000000:R>").
+var discordMarkup = regexp.MustCompile(`<a?(:\w+:)\d+>|<(@[!&]?|#|t:)\d+(:\w)?>`)
+
+// ReadDiscord reads the messages from a channel exported as JSON by
+// DiscordChatExporter, naming senders by their usernames. Messages
+// from bots and events such as joins and pins are skipped, custom
+// emoji are replaced by their names, and raw mentions are removed.
+func ReadDiscord(r io.Reader) ([]Message, error) {
+	var export discordExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+	var msgs []Message
+	for _, m := range export.Messages {
+		if m.Author.IsBot || (m.Type != "Default" && m.Type != "Reply") {
+			continue
+		}
+		text := discordMarkup.ReplaceAllStringFunc(m.Content, func(s string) string {
+			return discordMarkup.FindStringSubmatch(s)[1]
+		})
+		if text = strings.TrimSpace(text); text != "" {
+			msgs = append(msgs, Message{Sender: m.Author.Name, Text: text})
+		}
+	}
+	return msgs, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// feed.go reads the entries of RSS and Atom feeds.
+
+package corpus
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// A FeedItem is an entry in an RSS or Atom feed.
+type FeedItem struct {
+	// ID identifies the entry: its GUID or ID, or else its link, or
+	// else its title.
+	ID    string
+	Title string
+	Link  string
+	// Text is the entry's content or summary, as plain text.
+	Text string
+}
+
+// feedXML covers the elements of RSS 2.0 and Atom documents that
+// ReadFeed uses; RSS 1.0 (RDF) items are at the top level.
+type feedXML struct {
+	XMLName xml.Name
+	Items   []feedEntry `xml:"channel>item"`
+	RDF     []feedEntry `xml:"item"`
+	Entries []feedEntry `xml:"entry"`
+}
+
+type feedEntry struct {
+	Title       string `xml:"title"`
+	GUID        string `xml:"guid"`
+	ID          string `xml:"id"`
+	Description string `xml:"description"`
+	Encoded     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Summary     string `xml:"summary"`
+	Content     string `xml:"content"`
+	Links       []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"link"`
+}
+
+// ReadFeed reads the entries of an RSS (0.9x, 1.0, or 2.0) or Atom
+// feed, in the order they appear. Entries' content is converted from
+// HTML to plain text (see stringutil.HTMLText).
+func ReadFeed(r io.Reader) ([]FeedItem, error) {
+	var feed feedXML
+	dec := xml.NewDecoder(r)
+	// Feeds in other encodings are rare enough to treat as UTF-8
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := dec.Decode(&feed); err != nil {
+		return nil, err
+	}
+	switch feed.XMLName.Local {
+	case "rss", "RDF", "feed":
+	default:
+		return nil, fmt.Errorf("corpus: not a feed: <%s>", feed.XMLName.Local)
+	}
+
+	var items []FeedItem
+	for _, e := range append(append(feed.Items, feed.RDF...), feed.Entries...) {
+		item := FeedItem{Title: strings.TrimSpace(e.Title)}
+		for _, l := range e.Links {
+			if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+				item.Link = l.Href
+				break
+			} else if l.Href == "" && strings.TrimSpace(l.Text) != "" {
+				item.Link = strings.TrimSpace(l.Text)
+				break
+			}
+		}
+		item.ID = strings.TrimSpace(e.GUID + e.ID)
+		if item.ID == "" {
+			item.ID = item.Link
+		}
+		if item.ID == "" {
+			item.ID = item.Title
+		}
+		for _, content := range []string{e.Encoded, e.Content, e.Description, e.Summary} {
+			if strings.TrimSpace(content) != "" {
+				item.Text = strings.Join(stringutil.HTMLText(content), "\n\n")
+				break
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Messages returns the paragraphs of an entry's text as separate
+// messages without senders, for Build.
+func (item FeedItem) Messages() []Message {
+	var msgs []Message
+	for _, p := range strings.Split(item.Text, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			msgs = append(msgs, Message{Text: p})
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// fetch.go fetches web pages and feeds to train on.
+
+package corpus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// DefaultMaxFetchSize is the largest page or feed Fetch reads, unless
+// told otherwise.
+const DefaultMaxFetchSize = 5 << 20
+
+// fetchTimeout is how long Fetch waits for a page or feed.
+const fetchTimeout = 30 * time.Second
+
+var fetchClient = &http.Client{Timeout: fetchTimeout}
+
+// Fetch fetches an HTTP or HTTPS URL and returns its text: the
+// paragraphs of an HTML page (see stringutil.HTMLText), the entries
+// of an RSS or Atom feed (see ReadFeed), or the paragraphs of plain
+// text, as separate messages without senders. Other content types,
+// and responses larger than maxSize bytes (DefaultMaxFetchSize if
+// maxSize is 0), are refused.
+func Fetch(rawurl string, maxSize int64) ([]Message, error) {
+	return FetchContext(context.Background(), rawurl, maxSize)
+}
+
+// FetchContext is like Fetch, but gives up when ctx is done.
+func FetchContext(ctx context.Context, rawurl string, maxSize int64) ([]Message, error) {
+	kind, body, err := fetch(ctx, rawurl, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	var paragraphs []string
+	switch kind {
+	case "html":
+		paragraphs = stringutil.HTMLText(string(body))
+	case "feed":
+		items, err := ReadFeed(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		var msgs []Message
+		for _, item := range items {
+			msgs = append(msgs, item.Messages()...)
+		}
+		return msgs, nil
+	case "text":
+		paragraphs = strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n\n")
+	}
+	var msgs []Message
+	for _, p := range paragraphs {
+		if p = strings.TrimSpace(p); p != "" {
+			msgs = append(msgs, Message{Text: p})
+		}
+	}
+	return msgs, nil
+}
+
+// FetchFeed fetches an RSS or Atom feed from an HTTP or HTTPS URL and
+// returns its entries, with the same limits as Fetch.
+func FetchFeed(rawurl string, maxSize int64) ([]FeedItem, error) {
+	kind, body, err := fetch(context.Background(), rawurl, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if kind != "feed" {
+		return nil, fmt.Errorf("corpus: %s is not a feed", rawurl)
+	}
+	return ReadFeed(bytes.NewReader(body))
+}
+
+// fetch fetches a URL for Fetch and FetchFeed, returning what kind of
+// text it is (see fetchKind) and its body.
+func fetch(ctx context.Context, rawurl string, maxSize int64) (string, []byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFetchSize
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", nil, fmt.Errorf("corpus: can't fetch %s: not an HTTP URL", rawurl)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("corpus: can't fetch %s: %s", rawurl, resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return "", nil, fmt.Errorf("corpus: can't fetch %s: larger than %d bytes", rawurl, maxSize)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", nil, fmt.Errorf("corpus: can't fetch %s: bad content type: %v", rawurl, err)
+	}
+	kind := fetchKind(mediaType)
+	if kind == "" {
+		return "", nil, fmt.Errorf("corpus: can't fetch %s: unsupported content type %s", rawurl, mediaType)
+	}
+
+	// Read one byte more than allowed, to tell if there was more
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return "", nil, fmt.Errorf("corpus: can't fetch %s: larger than %d bytes", rawurl, maxSize)
+	}
+	return kind, body, nil
+}
+
+// fetchKind returns what kind of text Fetch reads from a media type:
+// "html", "feed", or "text", or "" if it doesn't read it.
+func fetchKind(mediaType string) string {
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		return "html"
+	case "application/rss+xml", "application/atom+xml", "application/rdf+xml",
+		"application/xml", "text/xml":
+		return "feed"
+	case "text/plain":
+		return "text"
+	}
+	return ""
+}
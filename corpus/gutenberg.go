@@ -0,0 +1,67 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// gutenberg.go cleans up Project Gutenberg's plain-text ebooks, whose
+// license boilerplate, hard-wrapped lines, and chapter headings would
+// otherwise be learned along with the book.
+
+package corpus
+
+import (
+	"regexp"
+	"strings"
+)
+
+// gutenbergStart and gutenbergEnd match the lines marking the start
+// and end of a book's text, in current and older ebooks.
+var gutenbergStart = regexp.MustCompile(`(?im)^\*\*\* ?START OF (THE|THIS) PROJECT GUTENBERG.*$`)
+var gutenbergEnd = regexp.MustCompile(`(?im)^(\*\*\* ?END OF (THE|THIS) PROJECT GUTENBERG|End of (the )?Project Gutenberg'?s?).*$`)
+
+// gutenbergHeading matches paragraphs that are only headings, like
+// "CHAPTER XII.", "Chapter 3: The Storm", "PART THE FIRST", "ACT II.
+// SCENE 1.", or a bare numeral: a heading word and a number, possibly
+// followed by punctuation and a short title or another heading.
+var gutenbergHeading = regexp.MustCompile(`(?i)^((chapter|book|part|volume|section|act|scene|letter|canto|stave) (the )?` + gutenbergNumber + `\b(\s*[.:-]\s*[^.!?]{1,60}?[.!]?|\.)?|[IVXLC]+\.?|\d+\.?|the end\.?)$`)
+
+// gutenbergNumber matches the numbers of chapters and parts, in
+// numerals or words.
+const gutenbergNumber = `(\d+|[IVXLC]+|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|(twenty|thirty|forty|fifty)(-\w+)?|\w+teen|first|second|third|fourth|fifth|sixth|seventh|eighth|ninth|tenth|last)`
+
+// gutenbergNotes matches paragraphs of notes about the ebook rather
+// than its text.
+var gutenbergNotes = regexp.MustCompile(`(?i)^(produced by|transcriber'?s? notes?|\[illustration|e-text prepared by|this ebook was)`)
+
+// gutenbergBrackets matches bracketed editorial insertions like
+// "[Illustration: ...]" and "[Footnote 1: ...]".
+var gutenbergBrackets = regexp.MustCompile(`\[(Illustration|Footnote|Sidenote)[^\]]*\]`)
+
+// CleanGutenberg returns the text of a Project Gutenberg plain-text
+// ebook without the license header and footer, notes about the
+// ebook, illustrations, footnotes, or chapter headings, and with each
+// paragraph unwrapped onto a single line, with blank lines between
+// paragraphs. Text without Project Gutenberg's markers is kept whole.
+func CleanGutenberg(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if loc := gutenbergStart.FindStringIndex(text); loc != nil {
+		text = text[loc[1]:]
+	}
+	if loc := gutenbergEnd.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+	text = gutenbergBrackets.ReplaceAllString(text, "")
+
+	var paragraphs []string
+	for _, p := range strings.Split(text, "\n\n") {
+		// Unwrap the paragraph, dropping the underscores marking
+		// italics
+		p = strings.Join(strings.Fields(p), " ")
+		p = strings.ReplaceAll(p, "_", "")
+		if p == "" || gutenbergHeading.MatchString(p) || gutenbergNotes.MatchString(p) {
+			continue
+		}
+		paragraphs = append(paragraphs, p)
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,80 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// irc.go reads messages from IRC logs in the formats written by common
+// clients and bouncers.
+
+package corpus
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ircTimestamp matches the timestamps that start log lines: "12:34",
+// "[12:34:56]", "2016-01-02 12:34:56", "Jan 02 12:34:56", and so on.
+var ircTimestamp = regexp.MustCompile(`^\[?(\d{4}-\d\d-\d\d[ T]|[A-Z][a-z]{2} \d\d )?\d\d:\d\d(:\d\d)?([.,]\d+)?([+-]\d\d:?\d\d|Z)?\]?\s+`)
+
+// ircMessage matches a message after its timestamp: "<nick> text",
+// with the nick possibly prefixed by a mode character (or a space
+// where there isn't one, as irssi writes).
+var ircMessage = regexp.MustCompile(`^<[ @+%~&!]?([^>\s]+)> ?(.*)$`)
+
+// ircModes are the mode characters clients put in front of nicks.
+const ircModes = "@+%~&!"
+
+// ircFormatting matches IRC's text formatting codes: bold, color (with
+// optional foreground and background numbers), reset, reverse,
+// italic, strikethrough, monospace, and underline.
+var ircFormatting = regexp.MustCompile("\x02|\x03(\\d\\d?(,\\d\\d?)?)?|[\x0f\x16\x1d\x1e\x11\x1f]")
+
+// weechatEvents are the prefixes WeeChat writes in place of a nick for
+// lines that aren't messages: joins, parts, quits, network notices,
+// and actions.
+var weechatEvents = map[string]bool{
+	"-->": true, "<--": true, "--": true, "=!=": true, "*": true, " *": true, "": true,
+}
+
+// ReadIRC reads the messages from an IRC log, in any of the formats
+// written by irssi, WeeChat, HexChat/XChat, mIRC, ZNC, and similar
+// clients: lines of "<nick> text" with or without a leading
+// timestamp, or WeeChat's tab-separated "time\tnick\ttext". Joins,
+// parts, quits, mode changes, actions ("* nick waves"), and other
+// events are skipped, formatting codes are removed, and mode prefixes
+// like "@" are stripped from nicks.
+func ReadIRC(r io.Reader) ([]Message, error) {
+	var msgs []Message
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if m, ok := parseIRCLine(strings.TrimRight(scanner.Text(), "\r")); ok {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs, scanner.Err()
+}
+
+// parseIRCLine parses a line of an IRC log, reporting whether it was
+// a message.
+func parseIRCLine(line string) (Message, bool) {
+	var m Message
+	if fields := strings.SplitN(line, "\t", 3); len(fields) == 3 {
+		// WeeChat
+		if weechatEvents[fields[1]] {
+			return m, false
+		}
+		m = Message{Sender: strings.TrimLeft(fields[1], ircModes), Text: fields[2]}
+	} else {
+		match := ircMessage.FindStringSubmatch(ircTimestamp.ReplaceAllString(line, ""))
+		if match == nil {
+			return m, false
+		}
+		m = Message{Sender: match[1], Text: match[2]}
+	}
+	m.Text = strings.TrimSpace(ircFormatting.ReplaceAllString(m.Text, ""))
+	return m, m.Sender != "" && m.Text != ""
+}
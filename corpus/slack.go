@@ -0,0 +1,166 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// slack.go reads messages from a Slack workspace export: a directory
+// with a users.json listing the workspace's members and a directory
+// per channel of JSON files, one per day.
+
+package corpus
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SlackMetadata are the files at the top of a Slack export that
+// describe the workspace rather than holding messages.
+var SlackMetadata = map[string]bool{
+	"users.json":            true,
+	"channels.json":         true,
+	"groups.json":           true,
+	"dms.json":              true,
+	"mpims.json":            true,
+	"integration_logs.json": true,
+	"canvases.json":         true,
+}
+
+// slackMessage is a message in a Slack export's day file.
+type slackMessage struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+	User    string `json:"user"`
+	BotID   string `json:"bot_id"`
+	Text    string `json:"text"`
+	Profile struct {
+		Name string `json:"name"`
+	} `json:"user_profile"`
+}
+
+// slackUser is a member in a Slack export's users.json.
+type slackUser struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// slackLink matches Slack's markup for mentions, channels, and links:
+// "<@U123>", "<#C123|general>", "<!here>", "<https://...|label>".
+var slackLink = regexp.MustCompile(`<([@#!]?)([^>|]*)(\|([^>]*))?>`)
+
+// ReadSlackUsers reads a Slack export's users.json, returning a map
+// of user IDs to usernames.
+func ReadSlackUsers(r io.Reader) (map[string]string, error) {
+	var users []slackUser
+	if err := json.NewDecoder(r).Decode(&users); err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+	return names, nil
+}
+
+// ReadSlack reads the messages from one of a Slack export's day
+// files, naming senders and mentioned users by the given map of user
+// IDs to usernames (see ReadSlackUsers), or by the name in the
+// message's user profile, or else by ID. Messages from bots and
+// events such as joins are skipped, and Slack's markup is replaced by
+// the text it displays as, except that bare links are removed.
+func ReadSlack(r io.Reader, users map[string]string) ([]Message, error) {
+	var day []slackMessage
+	if err := json.NewDecoder(r).Decode(&day); err != nil {
+		return nil, err
+	}
+	var msgs []Message
+	for _, m := range day {
+		if m.Type != "message" || m.BotID != "" || (m.Subtype != "" && m.Subtype != "thread_broadcast") {
+			continue
+		}
+		sender := users[m.User]
+		if sender == "" {
+			sender = m.Profile.Name
+		}
+		if sender == "" {
+			sender = m.User
+		}
+		text := strings.TrimSpace(slackText(m.Text, users))
+		if text != "" {
+			msgs = append(msgs, Message{Sender: sender, Text: text})
+		}
+	}
+	return msgs, nil
+}
+
+// slackText replaces Slack's markup in a message's text with what it
+// displays as.
+func slackText(text string, users map[string]string) string {
+	text = slackLink.ReplaceAllStringFunc(text, func(link string) string {
+		m := slackLink.FindStringSubmatch(link)
+		kind, target, label := m[1], m[2], m[4]
+		switch kind {
+		case "@":
+			if label == "" {
+				label = users[target]
+			}
+			if label == "" {
+				return ""
+			}
+			return "@" + label
+		case "#":
+			if label == "" {
+				return ""
+			}
+			return "#" + label
+		case "!":
+			// Special mentions like <!here>, or dates with fallback
+			// text
+			if label != "" {
+				return label
+			}
+			return "@" + target
+		}
+		return label
+	})
+	return html.UnescapeString(text)
+}
+
+// ReadSlackExport reads the messages from every channel of an
+// unzipped Slack export, in the order of its files' names.
+func ReadSlackExport(dir string) ([]Message, error) {
+	users := make(map[string]string)
+	if f, err := os.Open(filepath.Join(dir, "users.json")); err == nil {
+		users, err = ReadSlackUsers(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var msgs []Message
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		day, err := ReadSlack(f, users)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, day...)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// starter.go bundles a small corpus of general chatter into binaries,
+// and builds chains from corpora and models embedded in binaries, so
+// that a freshly deployed bot has something sensible to say before it
+// has learned anything.
+
+package corpus
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+//go:embed starter/*.txt
+var starterFiles embed.FS
+
+// Starter is a small corpus of general, friendly chatter for BuildFS,
+// for bots that have nothing else to start from.
+var Starter fs.FS
+
+func init() {
+	// The directory is embedded, so it's always there
+	Starter, _ = fs.Sub(starterFiles, "starter")
+}
+
+// BuildFS adds every file in fsys, e.g. an embed.FS bundling a corpus
+// or model into a binary, to the chain. Files named *.txt are text,
+// with a message on each line (see Build); other files are models
+// saved by markov.Chain.Save, which are merged into the chain. It
+// returns the number of words of text added.
+func BuildFS(c *markov.Chain, fsys fs.FS) (int, error) {
+	words := 0
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if path.Ext(name) == ".txt" {
+			var msgs []Message
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				msgs = append(msgs, Message{Text: scanner.Text()})
+			}
+			if err := scanner.Err(); err != nil {
+				return err
+			}
+			n, err := Build(c, msgs, false)
+			words += n
+			return err
+		}
+		model := markov.NewChain(c.PrefixLen())
+		if err := model.Decode(f); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return c.Merge(model)
+	})
+	return words, err
+}
@@ -0,0 +1,477 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// discord lets a Markov chain bot live on Discord, with a chain per
+// guild and per channel. It serves Discord's HTTP interactions
+// endpoint: the /say and /stats slash commands, and thumbs up/down
+// buttons on generated messages for collecting feedback. Ordinary
+// channel messages and reactions only arrive over Discord's gateway
+// websocket, which Bot.RunGateway connects to (see gateway.go).
+
+package discord
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/moderation"
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// apiBase is the base URL of Discord's REST API.
+const apiBase = "https://discord.com/api/v10"
+
+// channelChainSize is the number of prefixes a channel's chain needs
+// before the bot generates from it alone; smaller chains lean on the
+// guild's chain proportionally more.
+const channelChainSize = 5000
+
+// maxWords is the maximum number of words in a generated message.
+const maxWords = 100
+
+// maxInteractionSize is the largest interaction request body, in
+// bytes, the bot will read.
+const maxInteractionSize = 1 << 20
+
+// Interaction and response types, from Discord's API documentation.
+const (
+	interactionPing      = 1
+	interactionCommand   = 2
+	interactionComponent = 3
+	responsePong         = 1
+	responseMessage      = 4
+	componentRow         = 1
+	componentButton      = 2
+	buttonSecondary      = 2
+	messageEphemeral     = 1 << 6
+)
+
+// Feedback tallies the ratings of one generated message, by button or
+// reaction. Each user counts once, with their latest rating.
+type Feedback struct {
+	Chain string         `json:"chain"`
+	Text  string         `json:"text"`
+	Up    int            `json:"up"`
+	Down  int            `json:"down"`
+	Votes map[string]int `json:"votes,omitempty"` // user ID to +1 or -1
+
+	// The interaction that asked for the message, and the message's
+	// ID once the gateway reports it, for matching up reactions
+	Interaction string `json:"interaction,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+// vote records a user's rating of +1 or -1, replacing any earlier
+// rating of theirs, or withdraws it if v is 0. It returns whether the
+// tally changed.
+func (f *Feedback) vote(user string, v int) bool {
+	old := f.Votes[user]
+	if old == v {
+		return false
+	}
+	switch old {
+	case 1:
+		f.Up--
+	case -1:
+		f.Down--
+	}
+	switch v {
+	case 1:
+		f.Up++
+	case -1:
+		f.Down++
+	}
+	if f.Votes == nil {
+		f.Votes = make(map[string]int)
+	}
+	if v == 0 {
+		delete(f.Votes, user)
+	} else {
+		f.Votes[user] = v
+	}
+	return true
+}
+
+// Bot handles Discord interactions for a set of chains. Chains are
+// named "<guild>" for a whole guild, and "<guild>/<channel>" for one
+// channel in it; direct messages use the guild name "dm".
+type Bot struct {
+	mu            sync.Mutex
+	publicKey     ed25519.PublicKey
+	chains        *markov.ChainSet
+	feedback      map[string]*Feedback
+	byInteraction map[string]string // interaction ID to feedback ID
+	byMessage     map[string]string // message ID to feedback ID
+	nextID        int
+	userID        string // the bot's own user ID, once connected
+	scrubber      *moderation.Scrubber
+}
+
+// NewBot returns a Bot for the given chains that accepts interactions
+// signed with the application's public key (hex-encoded, as shown in
+// the Discord developer portal). The chains must not be used elsewhere
+// while the bot is running, except through Do.
+func NewBot(publicKey string, chains *markov.ChainSet) (*Bot, error) {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("discord: bad public key %q", publicKey)
+	}
+	return &Bot{
+		publicKey:     ed25519.PublicKey(key),
+		chains:        chains,
+		feedback:      make(map[string]*Feedback),
+		byInteraction: make(map[string]string),
+		byMessage:     make(map[string]string),
+	}, nil
+}
+
+// Do calls f with the bot's chains while no interactions are using
+// them, e.g. to save them.
+func (b *Bot) Do(f func(chains *markov.ChainSet)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f(b.chains)
+}
+
+// chainNames returns the names of the guild and channel chains for a
+// message.
+func chainNames(guild, channel string) (string, string) {
+	if guild == "" {
+		guild = "dm"
+	}
+	return guild, guild + "/" + channel
+}
+
+// SetScrubber sets a scrubber masking personal information and
+// secrets in the messages the bot learns, or removes it if scrubber is
+// nil.
+func (b *Bot) SetScrubber(scrubber *moderation.Scrubber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.scrubber = scrubber
+}
+
+// Learn trains the guild and channel chains on a message, masked by
+// the bot's scrubber, if any.
+func (b *Bot) Learn(guild, channel, text string) {
+	text = stringutil.NormalizePunctuation(text)
+	guildName, channelName := chainNames(guild, channel)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	text = b.scrubber.Scrub(text)
+	b.chains.Chain(guildName).Build(strings.NewReader(text))
+	b.chains.Chain(channelName).Build(strings.NewReader(text))
+}
+
+// Say generates text continuing seed in the style of a channel: from
+// the channel's chain, with its guild's chain mixed in until the
+// channel chain is big enough.
+func (b *Bot) Say(guild, channel, seed string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.say(guild, channel, seed)
+}
+
+func (b *Bot) say(guild, channel, seed string) string {
+	guildName, channelName := chainNames(guild, channel)
+	guildChain := b.chains.Get(guildName)
+	if guildChain == nil {
+		return ""
+	}
+	chain := b.chains.Get(channelName)
+	if chain == nil {
+		return guildChain.Generate(seed, 1, maxWords)
+	}
+	defer chain.SetFallbackUntil(guildChain, channelChainSize)()
+	return chain.Generate(seed, 1, maxWords)
+}
+
+// user is the part of a Discord user the bot uses.
+type user struct {
+	ID  string `json:"id"`
+	Bot bool   `json:"bot"`
+}
+
+// interaction is the part of a Discord interaction the bot uses.
+type interaction struct {
+	ID        string `json:"id"`
+	Type      int    `json:"type"`
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	Member    struct {
+		User user `json:"user"`
+	} `json:"member"` // in guilds
+	User user `json:"user"` // in direct messages
+	Data struct {
+		Name     string `json:"name"`
+		CustomID string `json:"custom_id"`
+		Options  []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+// ServeHTTP serves Discord's interactions endpoint; configure its URL
+// as the application's Interactions Endpoint URL.
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxInteractionSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Discord requires every interaction's signature to be checked
+	sig, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+	msg := append([]byte(r.Header.Get("X-Signature-Timestamp")), body...)
+	if err != nil || !ed25519.Verify(b.publicKey, msg, sig) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+		return
+	}
+
+	var in interaction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var resp interface{}
+	switch in.Type {
+	case interactionPing:
+		resp = map[string]int{"type": responsePong}
+	case interactionCommand:
+		resp = b.command(in)
+	case interactionComponent:
+		resp = b.component(in)
+	default:
+		http.Error(w, "unknown interaction type", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// message returns an interaction response with a message.
+func message(content string, flags int, components []interface{}) interface{} {
+	data := map[string]interface{}{"content": content}
+	if flags != 0 {
+		data["flags"] = flags
+	}
+	if components != nil {
+		data["components"] = components
+	}
+	return map[string]interface{}{"type": responseMessage, "data": data}
+}
+
+// command handles a slash command.
+func (b *Bot) command(in interaction) interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch in.Data.Name {
+	case "say":
+		seed := ""
+		for _, opt := range in.Data.Options {
+			if s, ok := opt.Value.(string); ok && opt.Name == "seed" {
+				seed = s
+			}
+		}
+		text := b.say(in.GuildID, in.ChannelID, seed)
+		if text == "" {
+			return message("I haven't heard enough here to say anything yet.", messageEphemeral, nil)
+		}
+		_, channelName := chainNames(in.GuildID, in.ChannelID)
+		b.nextID++
+		id := strconv.Itoa(b.nextID)
+		b.feedback[id] = &Feedback{Chain: channelName, Text: text, Interaction: in.ID}
+		b.byInteraction[in.ID] = id
+		return message(text, 0, []interface{}{
+			map[string]interface{}{
+				"type": componentRow,
+				"components": []interface{}{
+					feedbackButton("up:"+id, "👍"),
+					feedbackButton("down:"+id, "👎"),
+				},
+			},
+		})
+	case "stats":
+		guildName, channelName := chainNames(in.GuildID, in.ChannelID)
+		size := func(name string) int {
+			if c := b.chains.Get(name); c != nil {
+				return c.Size()
+			}
+			return 0
+		}
+		up, down := 0, 0
+		for _, f := range b.feedback {
+			if f.Chain == channelName {
+				up += f.Up
+				down += f.Down
+			}
+		}
+		return message(fmt.Sprintf("This channel's chain has %d prefixes, and this server's has %d. Messages I've said here got %d 👍 and %d 👎.",
+			size(channelName), size(guildName), up, down), messageEphemeral, nil)
+	}
+	return message(fmt.Sprintf("I don't know the command /%s.", in.Data.Name), messageEphemeral, nil)
+}
+
+// feedbackButton returns a button for rating a generated message.
+func feedbackButton(customID, emoji string) interface{} {
+	return map[string]interface{}{
+		"type":      componentButton,
+		"style":     buttonSecondary,
+		"custom_id": customID,
+		"emoji":     map[string]string{"name": emoji},
+	}
+}
+
+// component handles a click on a feedback button.
+func (b *Bot) component(in interaction) interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	parts := strings.SplitN(in.Data.CustomID, ":", 2)
+	if len(parts) != 2 || b.feedback[parts[1]] == nil {
+		return message("I don't remember saying that, sorry.", messageEphemeral, nil)
+	}
+	userID := in.Member.User.ID
+	if userID == "" {
+		userID = in.User.ID
+	}
+	v := 1
+	if parts[0] == "down" {
+		v = -1
+	}
+	if !b.vote(parts[1], userID, v) {
+		return message("You've already rated that.", messageEphemeral, nil)
+	}
+	return message("Thanks for the feedback!", messageEphemeral, nil)
+}
+
+// vote records a user's rating of a generated message (see
+// Feedback.vote), returning whether it changed anything.
+func (b *Bot) vote(id, userID string, v int) bool {
+	f := b.feedback[id]
+	if f == nil || userID == "" || !f.vote(userID, v) {
+		return false
+	}
+	log.Printf("Feedback on %q in %s: %d up, %d down", f.Text, f.Chain, f.Up, f.Down)
+	return true
+}
+
+// Feedback returns the feedback collected on each generated message,
+// in no particular order.
+func (b *Bot) Feedback() []Feedback {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var all []Feedback
+	for _, f := range b.feedback {
+		all = append(all, *f)
+	}
+	return all
+}
+
+// LoadFeedback attempts to load feedback in JSON format from a file,
+// replacing any already collected.
+func (b *Bot) LoadFeedback(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&b.feedback); err != nil {
+		return err
+	}
+	b.byInteraction = make(map[string]string)
+	b.byMessage = make(map[string]string)
+	for id, fb := range b.feedback {
+		if n, err := strconv.Atoi(id); err == nil && n > b.nextID {
+			b.nextID = n
+		}
+		if fb.Message != "" {
+			b.byMessage[fb.Message] = id
+		} else if fb.Interaction != "" {
+			b.byInteraction[fb.Interaction] = id
+		}
+	}
+	return nil
+}
+
+// SaveFeedback saves the collected feedback to a file in JSON format.
+func (b *Bot) SaveFeedback(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	enc := json.NewEncoder(f)
+	return enc.Encode(b.feedback)
+}
+
+// commands describes the bot's slash commands to Discord.
+var commands = []interface{}{
+	map[string]interface{}{
+		"name":        "say",
+		"description": "Say something in the style of this channel",
+		"options": []interface{}{
+			map[string]interface{}{
+				"type":        3, // string
+				"name":        "seed",
+				"description": "Text to start with",
+			},
+		},
+	},
+	map[string]interface{}{
+		"name":        "stats",
+		"description": "Show how much I've learned here",
+	},
+}
+
+// RegisterCommands registers the bot's slash commands globally for the
+// given application, authenticating with its bot token.
+func RegisterCommands(appID, token string) error {
+	body, err := json.Marshal(commands)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/applications/%s/commands", apiBase, appID), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("discord: registering commands: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package discord
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/moderation"
+)
+
+func newTestBot(t *testing.T) *Bot {
+	b, err := NewBot(strings.Repeat("00", 32), markov.NewChainSet(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestFeedbackVote(t *testing.T) {
+	tests := []struct {
+		votes    []int // alternating users a and b, starting with a
+		up, down int
+	}{
+		{[]int{1}, 1, 0},
+		{[]int{1, 1}, 2, 0},
+		{[]int{1, 0, 1}, 1, 0}, // a again
+		{[]int{1, 0, -1}, 0, 1},
+		{[]int{1, 0, 0}, 0, 0},
+		{[]int{-1, -1, -1, -1}, 0, 2},
+	}
+	for _, tt := range tests {
+		var f Feedback
+		for i, v := range tt.votes {
+			f.vote([]string{"a", "b"}[i%2], v)
+		}
+		if f.Up != tt.up || f.Down != tt.down {
+			t.Errorf("votes %v: %d up, %d down, want %d, %d", tt.votes, f.Up, f.Down, tt.up, tt.down)
+		}
+	}
+}
+
+func TestComponentDedupe(t *testing.T) {
+	b := newTestBot(t)
+	b.feedback["1"] = &Feedback{Chain: "g/c", Text: "hi"}
+	click := func(customID, userID string) {
+		var in interaction
+		in.Data.CustomID = customID
+		in.Member.User.ID = userID
+		b.component(in)
+	}
+	click("up:1", "alice")
+	click("up:1", "alice")
+	click("up:1", "bob")
+	click("down:1", "bob")
+	if f := b.feedback["1"]; f.Up != 1 || f.Down != 1 {
+		t.Errorf("got %d up, %d down, want 1, 1", f.Up, f.Down)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	b := newTestBot(t)
+	b.feedback["1"] = &Feedback{Chain: "g/c", Text: "hi", Interaction: "i1"}
+	b.byInteraction["i1"] = "1"
+
+	events := []struct {
+		event, data string
+	}{
+		{"READY", `{"user": {"id": "me"}}`},
+		{"MESSAGE_CREATE", `{"id": "m1", "guild_id": "g", "channel_id": "c", "author": {"id": "me", "bot": true}, "content": "hi", "interaction_metadata": {"id": "i1"}}`},
+		{"MESSAGE_CREATE", `{"id": "m2", "guild_id": "g", "channel_id": "c", "author": {"id": "alice"}, "content": "the cat sat on the mat"}`},
+		{"MESSAGE_CREATE", `{"id": "m3", "guild_id": "g", "channel_id": "c", "author": {"id": "other", "bot": true}, "content": "beep boop"}`},
+		{"MESSAGE_REACTION_ADD", `{"user_id": "alice", "message_id": "m1", "emoji": {"name": "👍"}}`},
+		{"MESSAGE_REACTION_ADD", `{"user_id": "bob", "message_id": "m1", "emoji": {"name": "👎"}}`},
+		{"MESSAGE_REACTION_ADD", `{"user_id": "bob", "message_id": "m1", "emoji": {"name": "🎉"}}`},
+		{"MESSAGE_REACTION_ADD", `{"user_id": "me", "message_id": "m1", "emoji": {"name": "👍"}}`},
+		{"MESSAGE_REACTION_ADD", `{"user_id": "carol", "message_id": "m2", "emoji": {"name": "👍"}}`},
+		{"MESSAGE_REACTION_REMOVE", `{"user_id": "bob", "message_id": "m1", "emoji": {"name": "👎"}}`},
+	}
+	for _, ev := range events {
+		b.dispatch(ev.event, json.RawMessage(ev.data))
+	}
+
+	f := b.feedback["1"]
+	if f.Message != "m1" || b.byMessage["m1"] != "1" {
+		t.Errorf("message ID = %q, want m1", f.Message)
+	}
+	if f.Up != 1 || f.Down != 0 {
+		t.Errorf("got %d up, %d down, want 1, 0", f.Up, f.Down)
+	}
+	for _, name := range []string{"g", "g/c"} {
+		c := b.chains.Get(name)
+		if c == nil {
+			t.Errorf("chain %s wasn't created", name)
+			continue
+		}
+		if got := c.Generate("the cat", 1, 10); !strings.Contains(got, "mat") {
+			t.Errorf("chain %s generated %q, want it to have learned from alice", name, got)
+		}
+		if got := c.Generate("beep", 1, 10); strings.Contains(got, "boop") {
+			t.Errorf("chain %s learned from a bot", name)
+		}
+	}
+}
+
+func TestSocketFrames(t *testing.T) {
+	tests := []int{0, 5, 125, 126, 1000, 70000}
+	for _, n := range tests {
+		client, server := net.Pipe()
+		s := &socket{conn: client}
+		r := &socket{r: bufio.NewReader(server)}
+		msg := bytes.Repeat([]byte("x"), n)
+		go func() {
+			s.writeText(msg)
+			client.Close()
+		}()
+		fin, op, payload, err := r.readFrame()
+		if err != nil || !fin || op != opText || !bytes.Equal(payload, msg) {
+			t.Errorf("frame of %d bytes: read %v, %d, %d bytes, %v", n, fin, op, len(payload), err)
+		}
+		server.Close()
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455
+	if got, want := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("acceptKey = %q, want %q", got, want)
+	}
+}
+
+func TestLearnScrubbed(t *testing.T) {
+	b := newTestBot(t)
+	scrubber, err := moderation.NewScrubber([]string{"email"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetScrubber(scrubber)
+	b.Learn("g", "c", "write to sam@example.org")
+	if got := b.chains.Get("g/c").Generate("write to", 1, 10); strings.Contains(got, "sam@") {
+		t.Errorf("Generate = %q, want the address masked", got)
+	}
+}
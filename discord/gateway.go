@@ -0,0 +1,305 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// gateway.go connects a Bot to Discord's gateway, which is the only
+// way Discord delivers ordinary channel messages and reactions: the
+// bot learns from the messages, and counts 👍 and 👎 reactions to what
+// it said as feedback, alongside the buttons.
+
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// gatewayURL is the URL of Discord's gateway.
+const gatewayURL = "wss://gateway.discord.gg/?v=10&encoding=json"
+
+// retryDelay is how long to wait before reconnecting to the gateway.
+const retryDelay = 10 * time.Second
+
+// Gateway opcodes, from Discord's API documentation.
+const (
+	gatewayDispatch       = 0
+	gatewayHeartbeat      = 1
+	gatewayIdentify       = 2
+	gatewayReconnect      = 7
+	gatewayInvalidSession = 9
+	gatewayHello          = 10
+	gatewayHeartbeatAck   = 11
+)
+
+// gatewayIntents are the events the bot asks for: guild and direct
+// messages and their reactions. Message content is a privileged
+// intent, which must be enabled for the application in the Discord
+// developer portal.
+const gatewayIntents = 1<<9 | 1<<10 | 1<<12 | 1<<13 | 1<<15
+
+// fatalCloseCodes are the gateway close codes that reconnecting won't
+// fix, such as a bad token or intents the application may not use.
+var fatalCloseCodes = map[int]bool{4004: true, 4010: true, 4011: true, 4012: true, 4013: true, 4014: true}
+
+// gatewayPayload is a message to or from the gateway.
+type gatewayPayload struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d"`
+	Seq  *int64          `json:"s,omitempty"`
+	Type string          `json:"t,omitempty"`
+}
+
+// RunGateway connects to Discord's gateway with the application's bot
+// token, learning from messages and counting reactions, until stop is
+// closed. It reconnects whenever the connection fails, and only
+// returns an error if Discord refuses the token or intents.
+func (b *Bot) RunGateway(token string, stop <-chan struct{}) error {
+	for {
+		err := b.runGateway(token, stop)
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		var closed *closeError
+		if errors.As(err, &closed) && fatalCloseCodes[closed.code] {
+			return err
+		}
+		log.Printf("Discord gateway error: %v", err)
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// runGateway runs a single gateway session.
+func (b *Bot) runGateway(token string, stop <-chan struct{}) error {
+	s, err := dialSocket(gatewayURL)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		s.close()
+	}()
+
+	var hello gatewayPayload
+	if err := readPayload(s, &hello); err != nil {
+		return err
+	}
+	var h struct {
+		Interval int64 `json:"heartbeat_interval"`
+	}
+	if err := json.Unmarshal(hello.Data, &h); hello.Op != gatewayHello || err != nil || h.Interval <= 0 {
+		return errors.New("discord: expected hello from gateway")
+	}
+
+	identify := map[string]interface{}{
+		"token":   token,
+		"intents": gatewayIntents,
+		"properties": map[string]string{
+			"os":      runtime.GOOS,
+			"browser": "clyde-go",
+			"device":  "clyde-go",
+		},
+	}
+	if err := writePayload(s, gatewayIdentify, identify); err != nil {
+		return err
+	}
+
+	// Heartbeat until the session ends, with the sequence number of
+	// the last event (or null before the first), giving up on the
+	// connection if Discord stops acknowledging them
+	var seq atomic.Int64
+	seq.Store(-1)
+	heartbeat := func() {
+		var last *int64
+		if n := seq.Load(); n >= 0 {
+			last = &n
+		}
+		writePayload(s, gatewayHeartbeat, last)
+	}
+	acks := make(chan struct{}, 1)
+	beatNow := make(chan struct{}, 1)
+	go func() {
+		tick := time.NewTicker(time.Duration(h.Interval) * time.Millisecond)
+		defer tick.Stop()
+		acked := true
+		for {
+			select {
+			case <-done:
+				return
+			case <-beatNow:
+				heartbeat()
+				continue
+			case <-acks:
+				acked = true
+				continue
+			case <-tick.C:
+			}
+			if !acked {
+				log.Printf("Discord gateway stopped acknowledging heartbeats")
+				s.conn.Close()
+				return
+			}
+			acked = false
+			heartbeat()
+		}
+	}()
+
+	for {
+		var p gatewayPayload
+		if err := readPayload(s, &p); err != nil {
+			return err
+		}
+		switch p.Op {
+		case gatewayDispatch:
+			if p.Seq != nil {
+				seq.Store(*p.Seq)
+			}
+			b.dispatch(p.Type, p.Data)
+		case gatewayHeartbeat:
+			select {
+			case beatNow <- struct{}{}:
+			default:
+			}
+		case gatewayHeartbeatAck:
+			select {
+			case acks <- struct{}{}:
+			default:
+			}
+		case gatewayReconnect:
+			return errors.New("discord: gateway asked for a reconnect")
+		case gatewayInvalidSession:
+			return errors.New("discord: gateway session invalidated")
+		}
+	}
+}
+
+// readPayload reads a message from the gateway.
+func readPayload(s *socket, p *gatewayPayload) error {
+	msg, err := s.readMessage()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(msg, p); err != nil {
+		return fmt.Errorf("discord: bad gateway message: %v", err)
+	}
+	return nil
+}
+
+// writePayload sends a message to the gateway.
+func writePayload(s *socket, op int, data interface{}) error {
+	d, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	msg, err := json.Marshal(gatewayPayload{Op: op, Data: d})
+	if err != nil {
+		return err
+	}
+	return s.writeText(msg)
+}
+
+// gatewayMessage is the part of a MESSAGE_CREATE event the bot uses.
+type gatewayMessage struct {
+	ID          string `json:"id"`
+	GuildID     string `json:"guild_id"`
+	ChannelID   string `json:"channel_id"`
+	WebhookID   string `json:"webhook_id"`
+	Author      user   `json:"author"`
+	Content     string `json:"content"`
+	Interaction *struct {
+		ID string `json:"id"`
+	} `json:"interaction_metadata"`
+}
+
+// gatewayReaction is the part of a MESSAGE_REACTION_ADD or
+// MESSAGE_REACTION_REMOVE event the bot uses.
+type gatewayReaction struct {
+	UserID    string `json:"user_id"`
+	MessageID string `json:"message_id"`
+	Emoji     struct {
+		Name string `json:"name"`
+	} `json:"emoji"`
+}
+
+// reactionVotes are the reactions that count as feedback.
+var reactionVotes = map[string]int{"👍": 1, "👎": -1}
+
+// dispatch handles a gateway event.
+func (b *Bot) dispatch(event string, data json.RawMessage) {
+	switch event {
+	case "READY":
+		var ready struct {
+			User user `json:"user"`
+		}
+		if err := json.Unmarshal(data, &ready); err != nil {
+			log.Printf("Bad READY event: %v", err)
+			return
+		}
+		b.mu.Lock()
+		b.userID = ready.User.ID
+		b.mu.Unlock()
+		log.Printf("Connected to the Discord gateway as %s", ready.User.ID)
+	case "MESSAGE_CREATE":
+		var m gatewayMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			log.Printf("Bad MESSAGE_CREATE event: %v", err)
+			return
+		}
+		b.mu.Lock()
+		own := m.Author.ID != "" && m.Author.ID == b.userID
+		if own && m.Interaction != nil {
+			// Remember which message answered a /say, to match
+			// up its reactions
+			if id, ok := b.byInteraction[m.Interaction.ID]; ok {
+				delete(b.byInteraction, m.Interaction.ID)
+				b.feedback[id].Message = m.ID
+				b.byMessage[m.ID] = id
+			}
+		}
+		b.mu.Unlock()
+		if own || m.Author.Bot || m.WebhookID != "" || m.Content == "" {
+			return
+		}
+		b.Learn(m.GuildID, m.ChannelID, m.Content)
+	case "MESSAGE_REACTION_ADD", "MESSAGE_REACTION_REMOVE":
+		var r gatewayReaction
+		if err := json.Unmarshal(data, &r); err != nil {
+			log.Printf("Bad %s event: %v", event, err)
+			return
+		}
+		v, ok := reactionVotes[r.Emoji.Name]
+		if !ok {
+			return
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		id, ok := b.byMessage[r.MessageID]
+		if !ok || r.UserID == b.userID {
+			return
+		}
+		if event == "MESSAGE_REACTION_REMOVE" {
+			// Only withdraw the rating if it's still this one
+			if b.feedback[id].Votes[r.UserID] != v {
+				return
+			}
+			v = 0
+		}
+		b.vote(id, r.UserID, v)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// websocket.go is just enough of a WebSocket client (RFC 6455) to talk
+// to Discord's gateway: text messages over TLS, with pings answered
+// and fragmented messages reassembled.
+
+package discord
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes.
+const (
+	opContinuation = 0
+	opText         = 1
+	opBinary       = 2
+	opClose        = 8
+	opPing         = 9
+	opPong         = 10
+)
+
+// maxSocketMessage is the largest message the bot will read from the
+// gateway, in bytes.
+const maxSocketMessage = 16 << 20
+
+// dialTimeout limits how long connecting to the gateway may take.
+const dialTimeout = 30 * time.Second
+
+// websocketGUID is appended to the handshake key to compute the
+// accept header, as RFC 6455 specifies.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// A closeError is returned by readMessage when the server closes the
+// connection, with the status code it gave, if any.
+type closeError struct {
+	code int
+}
+
+func (e *closeError) Error() string {
+	if e.code == 0 {
+		return "discord: websocket closed"
+	}
+	return fmt.Sprintf("discord: websocket closed with code %d", e.code)
+}
+
+// A socket is a client WebSocket connection.
+type socket struct {
+	conn net.Conn
+	r    *bufio.Reader
+	wmu  sync.Mutex // held while writing frames
+}
+
+// dialSocket opens a WebSocket connection to a ws or wss URL.
+func dialSocket(rawurl string) (*socket, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "ws" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		} else {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		conn, err = dialer.Dial("tcp", host)
+	case "wss":
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("discord: bad websocket URL %q", rawurl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s, err := handshake(conn, u)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// handshake upgrades a connection to a WebSocket.
+func handshake(conn net.Conn, u *url.URL) (*socket, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{
+		Method: "GET",
+		URL:    u,
+		Host:   u.Host,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("discord: websocket handshake: %s", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, errors.New("discord: websocket handshake: bad accept key")
+	}
+	conn.SetDeadline(time.Time{})
+	return &socket{conn: conn, r: r}, nil
+}
+
+// acceptKey returns the accept header a server must answer key with.
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// readMessage reads the next text or binary message, answering pings
+// along the way.
+func (s *socket) readMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := s.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := s.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			s.writeFrame(opClose, payload)
+			err := &closeError{}
+			if len(payload) >= 2 {
+				err.code = int(binary.BigEndian.Uint16(payload))
+			}
+			return nil, err
+		}
+		if len(msg)+len(payload) > maxSocketMessage {
+			return nil, errors.New("discord: websocket message too large")
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads a single frame.
+func (s *socket) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(s.r, head[:]); err != nil {
+		return
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0f
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(s.r, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(s.r, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxSocketMessage {
+		err = errors.New("discord: websocket frame too large")
+		return
+	}
+	var mask [4]byte
+	masked := head[1]&0x80 != 0
+	if masked {
+		if _, err = io.ReadFull(s.r, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(s.r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// writeFrame writes a single, final frame, masked as clients must.
+func (s *socket) writeFrame(op byte, payload []byte) error {
+	frame := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame = append(frame, mask[:]...)
+	for i, c := range payload {
+		frame = append(frame, c^mask[i%4])
+	}
+
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	_, err := s.conn.Write(frame)
+	return err
+}
+
+// writeText sends a text message.
+func (s *socket) writeText(msg []byte) error {
+	return s.writeFrame(opText, msg)
+}
+
+// close closes the connection, telling the server first.
+func (s *socket) close() error {
+	s.writeFrame(opClose, []byte{0x03, 0xe8}) // normal closure
+	return s.conn.Close()
+}
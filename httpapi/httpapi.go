@@ -0,0 +1,315 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// httpapi serves text generation and training over HTTP, so that
+// other services and chat frontends can use a set of chains without
+// linking Go code.
+
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/markov"
+	"github.com/sdukhovni/clyde-go/metrics"
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// Defaults and limits for requests.
+const (
+	defaultSentences = 1
+	defaultMaxWords  = 50
+	maxMaxWords      = 1000
+	maxRequestSize   = 16 << 20
+)
+
+// Errors returned by Generate and Train wrap one of these, according
+// to what's wrong with the request.
+var (
+	// ErrBadRequest means the request's parameters are invalid.
+	ErrBadRequest = errors.New("bad request")
+	// ErrNoChain means the request named a chain that doesn't exist.
+	ErrNoChain = errors.New("no such chain")
+)
+
+// A GenerateRequest is the body of a POST /generate request.
+type GenerateRequest struct {
+	// Chain is the name of the chain to generate from.
+	Chain string `json:"chain"`
+	// Seed is text to continue, if any.
+	Seed string `json:"seed"`
+	// Sentences and MaxWords limit the length of the output. They
+	// default to 1 and 50 respectively.
+	Sentences int `json:"sentences"`
+	MaxWords  int `json:"maxWords"`
+	// Temperature, if set, overrides the chain's sampling
+	// temperature (see markov.Chain.SetTemperature).
+	Temperature float64 `json:"temperature"`
+	// Context, if set, limits the number of words of context the
+	// chain uses (see markov.Chain.SetContext).
+	Context int `json:"context"`
+}
+
+// A TrainRequest is the body of a POST /train request.
+type TrainRequest struct {
+	// Chain is the name of the chain to train, which is created if
+	// it doesn't exist.
+	Chain string `json:"chain"`
+	// Text is the text to learn. Its punctuation is normalized
+	// first, as Clyde does for chat messages.
+	Text string `json:"text"`
+	// Weight, if positive, counts each word that many times.
+	Weight int `json:"weight"`
+	// Tag, if set, additionally records the text under a source
+	// tag (see markov.Tag).
+	Tag string `json:"tag"`
+}
+
+// A TrainResponse is the reply to a POST /train request.
+type TrainResponse struct {
+	Words int `json:"words"`
+	Size  int `json:"size"`
+}
+
+// Server is an http.Handler serving generation and training endpoints
+// over a ChainSet:
+//
+//	POST /generate   generate text from a chain; takes a GenerateRequest
+//	                 and replies with a markov.Structured
+//	POST /train      train a chain; takes a TrainRequest and replies
+//	                 with a TrainResponse
+//	GET /metrics     export metrics for Prometheus (see package
+//	                 metrics)
+//
+// Request and response bodies are JSON; errors are JSON objects with an
+// "error" field. Training changes what the chains say to everyone, so
+// POST /train requires the token set with SetTrainToken, or, without
+// one, only accepts requests from the local host.
+type Server struct {
+	mu         sync.Mutex
+	chains     *markov.ChainSet
+	mux        *http.ServeMux
+	trainToken string
+
+	trained   *metrics.Counter
+	words     *metrics.Counter
+	generated *metrics.Counter
+	latency   *metrics.Histogram
+	stats     markov.Stats
+}
+
+// NewServer returns a Server for the given chains. The chains must not
+// be used elsewhere while the server is running, except through Do.
+func NewServer(chains *markov.ChainSet) *Server {
+	s := &Server{
+		chains:    chains,
+		mux:       http.NewServeMux(),
+		trained:   metrics.NewCounter("clyde_train_requests_total", "Number of texts trained on."),
+		words:     metrics.NewCounter("clyde_words_learned_total", "Number of words learned."),
+		generated: metrics.NewCounter("clyde_generations_total", "Number of texts generated."),
+		latency:   metrics.NewHistogram("clyde_generation_seconds", "Time taken to generate a text.", nil),
+	}
+	registry := metrics.NewRegistry()
+	registry.Register(s.trained, s.words, s.generated, s.latency)
+	registry.Register(metrics.ChainGauges("clyde_", s.Do, &s.stats)...)
+	s.mux.HandleFunc("/generate", s.generate)
+	s.mux.HandleFunc("/train", s.train)
+	s.mux.Handle("/metrics", registry)
+	return s
+}
+
+// SetTrainToken sets a token that POST /train requests must present in
+// an "Authorization: Bearer <token>" header, from any address.
+func (s *Server) SetTrainToken(token string) {
+	s.trainToken = token
+}
+
+// Authorized reports whether a client may train the chains, according
+// to the server's train token (see SetTrainToken).
+func (s *Server) Authorized(r *http.Request) bool {
+	if s.trainToken != "" {
+		want := []byte("Bearer " + s.trainToken)
+		return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) == 1
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Do calls f with the server's chains while no requests are using
+// them, e.g. to save them.
+func (s *Server) Do(f func(chains *markov.ChainSet)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.chains)
+}
+
+// reply writes v to a client as JSON.
+func reply(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// replyError writes an error message to a client as JSON.
+func replyError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// decode reads a POST request's JSON body into v, replying with an
+// error if it can't.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != "POST" {
+		replyError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(v)
+	if err != nil {
+		replyError(w, http.StatusBadRequest, fmt.Sprintf("bad request: %v", err))
+		return false
+	}
+	return true
+}
+
+// replyErr writes an error from Generate or Train to a client, with
+// the status the error calls for.
+func replyErr(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrNoChain):
+		status = http.StatusNotFound
+	}
+	replyError(w, status, err.Error())
+}
+
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) {
+	var req GenerateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	res, err := s.Generate(req)
+	if err != nil {
+		replyErr(w, err)
+		return
+	}
+	reply(w, res)
+}
+
+// Generate generates text as a POST /generate request does, for
+// serving the same API over other protocols (see package rpcapi).
+func (s *Server) Generate(req GenerateRequest) (markov.Structured, error) {
+	if req.Sentences == 0 {
+		req.Sentences = defaultSentences
+	}
+	if req.MaxWords == 0 {
+		req.MaxWords = defaultMaxWords
+	}
+	if req.Sentences < 0 || req.MaxWords < 0 || req.MaxWords > maxMaxWords {
+		return markov.Structured{}, fmt.Errorf("%w: sentences must be positive, and maxWords between 1 and %d", ErrBadRequest, maxMaxWords)
+	}
+	if req.Temperature < 0 || req.Context < 0 {
+		return markov.Structured{}, fmt.Errorf("%w: temperature and context must be positive", ErrBadRequest)
+	}
+
+	s.mu.Lock()
+	chain := s.chains.Get(req.Chain)
+	if chain == nil {
+		s.mu.Unlock()
+		return markov.Structured{}, fmt.Errorf("%w named %q", ErrNoChain, req.Chain)
+	}
+	// Override the chain's sampling settings just for this request
+	temperature, context := chain.Temperature(), chain.Context()
+	if req.Temperature > 0 {
+		chain.SetTemperature(req.Temperature)
+	}
+	if req.Context > 0 {
+		chain.SetContext(req.Context)
+	}
+	start := time.Now()
+	res := chain.GenerateStructured(req.Seed, req.Sentences, req.MaxWords)
+	s.latency.Observe(time.Since(start).Seconds())
+	s.generated.Inc()
+	levels := make([]int, chain.PrefixLen()+1)
+	for _, tok := range res.Tokens {
+		if !tok.Seed {
+			levels[tok.Level]++
+		}
+	}
+	s.stats.Add(levels)
+	chain.SetTemperature(temperature)
+	chain.SetContext(context)
+	s.mu.Unlock()
+	return res, nil
+}
+
+func (s *Server) train(w http.ResponseWriter, r *http.Request) {
+	if !s.Authorized(r) {
+		log.Printf("Rejected unauthorized training request from %s", r.RemoteAddr)
+		replyError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req TrainRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	// Stop if the client goes away, rather than hold up other
+	// requests
+	res, err := s.Train(r.Context(), req)
+	if err != nil {
+		return
+	}
+	reply(w, res)
+}
+
+// Train trains a chain as a POST /train request does, stopping early
+// if the context is done, for serving the same API over other
+// protocols (see package rpcapi). Callers must check that the client
+// is Authorized first.
+func (s *Server) Train(ctx context.Context, req TrainRequest) (TrainResponse, error) {
+	opts := []markov.BuildOption{}
+	if req.Weight > 0 {
+		opts = append(opts, markov.Weight(req.Weight))
+	}
+	if req.Tag != "" {
+		opts = append(opts, markov.Tag(req.Tag))
+	}
+	text := stringutil.NormalizePunctuation(req.Text)
+
+	s.mu.Lock()
+	chain := s.chains.Chain(req.Chain)
+	words, err := chain.BuildContext(ctx, strings.NewReader(text), opts...)
+	size := chain.Size()
+	s.mu.Unlock()
+	if err != nil {
+		log.Printf("Training chain %q: %v after %d words", req.Chain, err, words)
+		return TrainResponse{}, err
+	}
+
+	s.trained.Inc()
+	s.words.Add(uint64(words))
+	log.Printf("Trained chain %q on %d words", req.Chain, words)
+	return TrainResponse{Words: words, Size: size}, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+func TestTrainAuthorization(t *testing.T) {
+	tests := []struct {
+		token      string // the server's train token
+		remoteAddr string
+		auth       string // the request's Authorization header
+		want       int
+	}{
+		{"", "127.0.0.1:5000", "", http.StatusOK},
+		{"", "[::1]:5000", "", http.StatusOK},
+		{"", "192.0.2.1:5000", "", http.StatusUnauthorized},
+		{"", "192.0.2.1:5000", "Bearer ", http.StatusUnauthorized},
+		{"secret", "192.0.2.1:5000", "Bearer secret", http.StatusOK},
+		{"secret", "127.0.0.1:5000", "", http.StatusUnauthorized},
+		{"secret", "192.0.2.1:5000", "Bearer wrong", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		s := NewServer(markov.NewChainSet(2))
+		s.SetTrainToken(test.token)
+		r := httptest.NewRequest("POST", "/train", strings.NewReader(`{"chain": "c", "text": "hello there"}`))
+		r.RemoteAddr = test.remoteAddr
+		if test.auth != "" {
+			r.Header.Set("Authorization", test.auth)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != test.want {
+			t.Errorf("token %q, from %s with %q: status %d, want %d", test.token, test.remoteAddr, test.auth, w.Code, test.want)
+		}
+		trained := false
+		s.Do(func(chains *markov.ChainSet) { trained = chains.Get("c") != nil })
+		if trained != (test.want == http.StatusOK) {
+			t.Errorf("token %q, from %s with %q: trained = %v", test.token, test.remoteAddr, test.auth, trained)
+		}
+	}
+}
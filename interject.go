@@ -0,0 +1,139 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// interject.go teaches Clyde the filler words and interjections
+// ("hmm", "lol", "ok") that are popular on each class, so he can
+// chime in with them the way everyone else does.
+
+package clyde
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zephyr-im/zephyr-go"
+	"github.com/sdukhovni/clyde-go/util"
+)
+
+// interjectionCounts counts how often each interjection has been seen
+// as an entire message on a class, and how many messages have been
+// seen in total.
+type interjectionCounts struct {
+	Words    map[string]int
+	Messages int
+}
+
+// maxInterjectionWords and maxInterjectionLength bound the size of a
+// message that can count as an interjection.
+const maxInterjectionWords = 2
+const maxInterjectionLength = 16
+
+// minInterjectionCount is the number of times an interjection must
+// have been seen before Clyde will use it.
+const minInterjectionCount = 3
+
+// interjectionScale scales the fraction of messages on a class that
+// are interjections to get the chance that Clyde interjects.
+const interjectionScale = 0.25
+
+// interjectCooldown is the minimum time between interjections.
+const interjectCooldown = 15 * time.Minute
+
+// rateLimiter allows an action at most once per interval.
+type rateLimiter struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// Allow reports whether the action may happen now, and if so records
+// that it did.
+func (l *rateLimiter) Allow() bool {
+	if time.Since(l.last) < l.interval {
+		return false
+	}
+	l.last = time.Now()
+	return true
+}
+
+// interjectionKey returns the normalized form of a message if it
+// looks like an interjection, or "" if it doesn't.
+func interjectionKey(body string) string {
+	words := strings.Fields(strings.ToLower(body))
+	key := strings.Join(words, " ")
+	if len(words) == 0 || len(words) > maxInterjectionWords || utf8.RuneCountInString(key) > maxInterjectionLength {
+		return ""
+	}
+	if strings.HasPrefix(key, sender) || strings.IndexFunc(key, unicode.IsLetter) < 0 {
+		return ""
+	}
+	return key
+}
+
+// learnInterjection updates the interjection counts for a message's
+// class.
+func (c *Clyde) learnInterjection(r zephyr.MessageReaderResult) {
+	class := r.Message.Header.Class
+	counts := c.interjections[class]
+	if counts == nil {
+		counts = &interjectionCounts{Words: make(map[string]int)}
+		c.interjections[class] = counts
+	}
+	counts.Messages++
+	if key := interjectionKey(util.MessageBody(r)); key != "" {
+		counts.Words[key]++
+	}
+}
+
+// interject is a behavior that occasionally replies to a message with
+// one of the interjections popular on its class, more often on
+// classes where interjections are more common. Interjections listed in
+// the "interjections" file in Clyde's home directory are always
+// candidates.
+func interject(c *Clyde, r zephyr.MessageReaderResult) bool {
+	counts := c.interjections[r.Message.Header.Class]
+	if counts == nil || counts.Messages == 0 {
+		return false
+	}
+
+	candidates := make(map[string]int)
+	total, seen := 0, 0
+	for w, n := range counts.Words {
+		seen += n
+		if n >= minInterjectionCount {
+			candidates[w] = n
+			total += n
+		}
+	}
+	for _, w := range c.extraInterjections {
+		candidates[w] += minInterjectionCount
+		total += minInterjectionCount
+	}
+	if total == 0 {
+		return false
+	}
+
+	chance := interjectionScale * float64(seen) / float64(counts.Messages)
+	if rand.Float64() >= chance || !c.interjectLimiter.Allow() {
+		return false
+	}
+
+	n := rand.Intn(total)
+	var word string
+	for w, count := range candidates {
+		n -= count
+		if n < 0 {
+			word = w
+			break
+		}
+	}
+
+	return standardBehavior("", nil, false,
+		func(c *Clyde, r zephyr.MessageReaderResult, kvs map[string]string) string {
+			return word
+		})(c, r)
+}
@@ -0,0 +1,174 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// loadtest.go defines a sandboxed Clyde that isn't connected to
+// zephyr, and a load test that feeds synthetic traffic through
+// Clyde's full message-handling pipeline to measure how he holds up.
+
+package clyde
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/zephyr-im/zephyr-go"
+)
+
+// NewSandboxClyde initializes a Clyde by loading data files found in
+// the given directory, like LoadClyde, but without connecting to
+// zephyr: messages Clyde sends are counted and discarded. A sandbox
+// Clyde is meant for load testing, and should not be Run. Behaviors
+// that write data files will still write them to the directory, so
+// it's best to test against a scratch copy of a real home directory.
+func NewSandboxClyde(dir string) (*Clyde, error) {
+	c, err := newClyde(dir)
+	if err != nil {
+		return nil, err
+	}
+	c.sandbox = true
+
+	err = loadJSON(c.path(subsFile), &(c.subs))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// LoadTestConfig describes the synthetic traffic for a load test.
+type LoadTestConfig struct {
+	// Transcript is a list of message bodies to replay in order,
+	// wrapping around if needed. If it is empty, message bodies
+	// are generated using Clyde's own chain.
+	Transcript []string
+	// Messages is the total number of messages to send.
+	Messages int
+	// Rate is the number of messages to send per second; if it is
+	// zero, messages are sent as fast as Clyde handles them.
+	Rate float64
+	// Class and Instance are the destination of the messages;
+	// they default to Clyde's home class and instance.
+	Class, Instance string
+	// Senders is the number of distinct fake users sending the
+	// messages; it defaults to 10.
+	Senders int
+}
+
+// LoadTestReport summarizes the results of a load test.
+type LoadTestReport struct {
+	Messages   int
+	Replies    int
+	Duration   time.Duration
+	Throughput float64 // messages handled per second
+	P50        time.Duration
+	P90        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+	HeapGrowth int64 // bytes
+	SizeGrowth int   // chain prefixes
+}
+
+// String formats a load test report for humans.
+func (r LoadTestReport) String() string {
+	return fmt.Sprintf("%d messages (%d replies) in %v: %.1f msg/s\n"+
+		"latency: p50 %v, p90 %v, p99 %v, max %v\n"+
+		"heap growth: %d bytes, chain growth: %d prefixes",
+		r.Messages, r.Replies, r.Duration, r.Throughput,
+		r.P50, r.P90, r.P99, r.Max,
+		r.HeapGrowth, r.SizeGrowth)
+}
+
+// One in every loadTestAddressRate generated load test messages is
+// addressed to Clyde, to exercise his replying behaviors.
+const loadTestAddressRate = 5
+
+// LoadTest feeds synthetic traffic through Clyde's message handling
+// pipeline, and reports throughput, latency percentiles, and memory
+// growth. It is meant to be used with a sandbox Clyde.
+func (c *Clyde) LoadTest(conf LoadTestConfig) LoadTestReport {
+	if conf.Class == "" {
+		conf.Class, conf.Instance = homeClass, homeInstance
+	}
+	if conf.Senders <= 0 {
+		conf.Senders = 10
+	}
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	sizeBefore := c.chain.Size()
+	sentBefore := c.sandboxSent
+
+	latencies := make([]time.Duration, 0, conf.Messages)
+	start := time.Now()
+	for i := 0; i < conf.Messages; i++ {
+		if conf.Rate > 0 {
+			due := start.Add(time.Duration(float64(i) / conf.Rate * float64(time.Second)))
+			time.Sleep(time.Until(due))
+		}
+
+		var body string
+		if len(conf.Transcript) > 0 {
+			body = conf.Transcript[i%len(conf.Transcript)]
+		} else {
+			body = c.chain.Generate("", 1, maxWords)
+			if rand.Intn(loadTestAddressRate) == 0 {
+				body = fmt.Sprintf("clyde, %s", body)
+			}
+		}
+
+		r := zephyr.MessageReaderResult{
+			Message: &zephyr.Message{
+				Header: zephyr.Header{
+					Kind:     zephyr.ACKED,
+					Class:    conf.Class,
+					Instance: conf.Instance,
+					OpCode:   "AUTO",
+					Sender:   fmt.Sprintf("loadtest%d", i%conf.Senders),
+					Charset:  zephyr.CharsetUTF8,
+				},
+				Body: []string{"load test", body},
+			},
+			AuthStatus: zephyr.AuthYes,
+		}
+
+		t := time.Now()
+		c.mu.Lock()
+		c.handleMessage(r)
+		c.mu.Unlock()
+		latencies = append(latencies, time.Since(t))
+	}
+	duration := time.Since(start)
+
+	var after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	report := LoadTestReport{
+		Messages:   conf.Messages,
+		Replies:    c.sandboxSent - sentBefore,
+		Duration:   duration,
+		HeapGrowth: int64(after.HeapAlloc) - int64(before.HeapAlloc),
+		SizeGrowth: c.chain.Size() - sizeBefore,
+	}
+	if duration > 0 {
+		report.Throughput = float64(conf.Messages) / duration.Seconds()
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		percentile := func(p int) time.Duration {
+			return latencies[(len(latencies)-1)*p/100]
+		}
+		report.P50 = percentile(50)
+		report.P90 = percentile(90)
+		report.P99 = percentile(99)
+		report.Max = latencies[len(latencies)-1]
+	}
+	return report
+}
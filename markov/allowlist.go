@@ -0,0 +1,51 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// allowlist.go lets a Chain restrict generation to a curated
+// vocabulary, for deployments where anything outside a known-safe set
+// of words must never be said.
+
+package markov
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SetAllowlist restricts generation to suffixes whose words appear in
+// the given list. Words are compared case-insensitively and ignoring
+// surrounding punctuation, so allowing "hello" also allows "Hello,"
+// and "hello!"; suffixes made up entirely of punctuation, and the end
+// of the text, are always allowed. Training is unaffected. Passing an empty list removes the
+// restriction.
+func (c *Chain) SetAllowlist(words []string) {
+	if len(words) == 0 {
+		c.allowlist = nil
+		return
+	}
+	c.allowlist = make(map[string]bool)
+	for _, w := range words {
+		c.allowlist[allowKey(w)] = true
+	}
+}
+
+// allowed reports whether a suffix may be generated under the
+// Chain's allowlist, and isn't banned (see Banned). End is always
+// allowed, so that generation can still stop.
+func (c *Chain) allowed(w string) bool {
+	if w == End || c.allowlist == nil && c.banned == nil {
+		return true
+	}
+	key := allowKey(w)
+	if key == "" {
+		return true
+	}
+	return !c.banned[key] && (c.allowlist == nil || c.allowlist[key])
+}
+
+// allowKey normalizes a word for comparison against an allowlist.
+func allowKey(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, unicode.IsPunct))
+}
@@ -0,0 +1,35 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllowlistEnds(t *testing.T) {
+	tests := []struct {
+		text  string
+		allow []string
+		opts  []GenerateOption
+		want  string
+	}{
+		{"hello there", []string{"hello", "there"}, nil, "hello there"},
+		{"Hello there. Goodbye now.", []string{"hello", "there"}, nil, "Hello there."},
+		// "end" itself isn't allowed, but the end of the text is
+		{"hello there", []string{"hello", "there"}, []GenerateOption{Banned("end")}, "hello there"},
+		{"hello there", nil, []GenerateOption{Banned("end")}, "hello there"},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		c.Build(strings.NewReader(test.text))
+		c.SetAllowlist(test.allow)
+		opts := append([]GenerateOption{Start(""), MaxWords(20)}, test.opts...)
+		if got := c.GenerateWith(opts...).Text; got != test.want {
+			t.Errorf("after Build(%q), SetAllowlist(%q), GenerateWith = %q, want %q", test.text, test.allow, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// atomic.go writes files so that a crash or full disk mid-save leaves
+// the previous version in place, rather than a truncated file that
+// can't be loaded.
+
+package markov
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is a file being written under a temporary name, which
+// replaces the real file when it's closed.
+type atomicFile struct {
+	*os.File
+	name string
+}
+
+// createAtomic creates a temporary file in the same directory as the
+// named file (so that it can be renamed over it), to be renamed to
+// the named file when closed.
+func createAtomic(filename string) (*atomicFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{f, filename}, nil
+}
+
+// Close flushes the file to disk and renames it to its real name,
+// removing it instead if anything fails.
+func (af *atomicFile) Close() error {
+	err := af.File.Sync()
+	if cerr := af.File.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// Keep the usual permissions of a created file, rather
+		// than the temporary file's private ones
+		err = os.Chmod(af.File.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(af.File.Name(), af.name)
+	}
+	if err != nil {
+		os.Remove(af.File.Name())
+	}
+	return err
+}
+
+// Abort closes and removes the file, leaving the real file as it was.
+func (af *atomicFile) Abort() {
+	af.File.Close()
+	os.Remove(af.File.Name())
+}
+
+// saveAtomic creates the named file as in createAtomic, writes to it
+// with the given encoding function, and closes it, returning the
+// first error. If anything fails, any existing file is left as it
+// was.
+func saveAtomic(filename string, encode func(w io.Writer) error) error {
+	f, err := createAtomic(filename)
+	if err != nil {
+		return err
+	}
+	if err := encode(f); err != nil {
+		f.Abort()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// beam.go generates text by beam search rather than random sampling:
+// several continuations are explored in parallel, and the most likely
+// complete sentence wins. The output is less surprising than sampled
+// text, but more coherent, which suits headlines and fortunes.
+
+package markov
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// hypothesis is a partial text being explored by beam search.
+type hypothesis struct {
+	words []string
+	p     Prefix
+	score float64 // total log-likelihood of the generated words
+	n     int     // number of generated words
+}
+
+// normalized returns the hypothesis's average log-likelihood per
+// generated word, so that long and short texts compete fairly.
+func (h hypothesis) normalized() float64 {
+	if h.n == 0 {
+		return math.Inf(-1)
+	}
+	return h.score / float64(h.n)
+}
+
+// GenerateBeam continues the start string with the single sentence
+// that beam search finds most likely, keeping the beamWidth best
+// partial sentences at each step and generating at most maxWords
+// words. A sentence is complete when it ends with sentence-ending
+// punctuation or the chain produces End. If no sentence is completed,
+// because the word limit is reached or every partial sentence runs
+// into a prefix the chain knows no words after, the most likely
+// fragment is returned. Only if the chain can't continue the start
+// string at all is it returned as is.
+func (c *Chain) GenerateBeam(start string, beamWidth, maxWords int) string {
+	words := strings.Fields(start)
+	p := NewPrefix(c.prefixLen)
+	lastWordsStart := len(words) - c.prefixLen
+	if lastWordsStart < 0 {
+		lastWordsStart = 0
+	}
+	for _, w := range words[lastWordsStart:] {
+		c.shift(p, w)
+	}
+
+	beam := []hypothesis{{words: words, p: p}}
+	var best, fragment *hypothesis
+	for step := 0; step < maxWords && len(beam) > 0; step++ {
+		var next []hypothesis
+		for _, h := range beam {
+			for _, w := range c.topSuffixes(h.p, beamWidth) {
+				nh := hypothesis{
+					words: h.words,
+					p:     append(Prefix(nil), h.p...),
+					score: h.score + math.Log(c.wordProb(h.p, w)),
+					n:     h.n + 1,
+				}
+				if w != End {
+					nh.words = append(append([]string(nil), h.words...), c.caser().restore(h.p, w))
+					c.shift(nh.p, w)
+				}
+				if w == End || stringutil.EndsSentence(w) {
+					if best == nil || nh.normalized() > best.normalized() {
+						best = &nh
+					}
+					continue
+				}
+				next = append(next, nh)
+			}
+		}
+		sort.Slice(next, func(i, j int) bool { return next[i].score > next[j].score })
+		if len(next) > beamWidth {
+			next = next[:beamWidth]
+		}
+		for i := range next {
+			// On a tie, the longer fragment is more use
+			if fragment == nil || next[i].normalized() >= fragment.normalized() {
+				fragment = &next[i]
+			}
+		}
+		beam = next
+	}
+
+	if best == nil {
+		best = fragment
+	}
+	if best == nil {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(best.words, " ")
+}
+
+// topSuffixes returns up to k allowed suffixes of the longest tail of
+// the given prefix that the chain knows, most frequent first.
+func (c *Chain) topSuffixes(p Prefix, k int) []string {
+	for i := 0; i <= c.prefixLen; i++ {
+		suffixes := c.chain[strings.Join(p[i:], " ")]
+		var result []string
+		for w := range suffixes {
+			if c.allowed(w) {
+				result = append(result, w)
+			}
+		}
+		if len(result) == 0 {
+			continue
+		}
+		sort.Slice(result, func(a, b int) bool {
+			if suffixes[result[a]] != suffixes[result[b]] {
+				return suffixes[result[a]] > suffixes[result[b]]
+			}
+			return result[a] < result[b]
+		})
+		if len(result) > k {
+			result = result[:k]
+		}
+		return result
+	}
+	return nil
+}
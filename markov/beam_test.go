@@ -0,0 +1,52 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateBeam(t *testing.T) {
+	tests := []struct {
+		corpus   string
+		start    string
+		maxWords int
+		want     string
+	}{
+		// A complete sentence wins
+		{"the cat sat down. the cat sat down.", "", 10, "the cat sat down."},
+		// The word limit cuts the sentence short
+		{"the cat sat on the mat.", "", 3, "the cat sat"},
+		// No sentence ends in time, so the most likely fragment is
+		// returned rather than just the start
+		{"the cat sat on", "the", 3, "the cat sat on"},
+		// Nothing follows the start at all
+		{"", "zebra crossing", 10, "zebra crossing"},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		if strings.HasSuffix(test.corpus, ".") {
+			c.Build(strings.NewReader(test.corpus))
+		} else {
+			// Leave no End for the fragment to finish with
+			addWords(c, test.corpus)
+		}
+		if got := c.GenerateBeam(test.start, 3, test.maxWords); got != test.want {
+			t.Errorf("GenerateBeam(%q) on %q = %q, want %q", test.start, test.corpus, got, test.want)
+		}
+	}
+}
+
+// addWords adds the words of text to a chain, like Build but without
+// End.
+func addWords(c *Chain, text string) {
+	p := NewPrefix(c.prefixLen)
+	for _, w := range strings.Fields(text) {
+		c.Add(p, w)
+		p.Shift(w)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var errRead = errors.New("read failed")
+
+// failingReader returns a reader of text that fails once it's read.
+func failingReader(text string) io.Reader {
+	return io.MultiReader(strings.NewReader(text), iotest.ErrReader(errRead))
+}
+
+func TestBuildErrors(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name  string
+		build func(c *Chain) (int, error)
+		words int
+		err   error
+	}{
+		{"Build", func(c *Chain) (int, error) {
+			return c.Build(strings.NewReader("the cat sat"))
+		}, 3, nil},
+		// What was read before the error is still learned
+		{"Build failing", func(c *Chain) (int, error) {
+			return c.Build(failingReader("the cat sat "))
+		}, 3, errRead},
+		{"Build failing deduplicated", func(c *Chain) (int, error) {
+			return c.Build(failingReader("the cat sat\n"), DedupLines())
+		}, 3, errRead},
+		{"BuildContext canceled", func(c *Chain) (int, error) {
+			return c.BuildContext(canceled, strings.NewReader("the cat sat"))
+		}, 0, context.Canceled},
+		{"BuildParallel failing", func(c *Chain) (int, error) {
+			err := c.BuildParallel([]io.Reader{strings.NewReader("the cat sat"), failingReader("a dog")}, 2)
+			return 5, err
+		}, 5, errRead},
+		{"Remove failing", func(c *Chain) (int, error) {
+			c.Build(strings.NewReader("the cat sat"))
+			_, err := c.Remove(failingReader("the cat"))
+			return 1, err
+		}, 1, errRead},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		words, err := test.build(c)
+		if words != test.words || !errors.Is(err, test.err) {
+			t.Errorf("%s = %d, %v, want %d, %v", test.name, words, err, test.words, test.err)
+		}
+	}
+}
+
+func TestBuildParallelLearnsDespiteErrors(t *testing.T) {
+	c := NewChain(1)
+	c.BuildParallel([]io.Reader{failingReader("zebras graze "), strings.NewReader("lions hunt")}, 2)
+	for _, word := range []string{"zebras", "lions"} {
+		if got := c.Generate(word, 1, 2); got == word {
+			t.Errorf("Generate(%q) = %q, want a continuation", word, got)
+		}
+	}
+}
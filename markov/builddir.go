@@ -0,0 +1,94 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// builddir.go trains a Chain on every file in a directory tree.
+
+package markov
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// A FileReport describes the outcome of training on one file.
+type FileReport struct {
+	Path string
+	// Words is the number of words read from the file.
+	Words int
+	// Err is the error opening or reading the file, if any; Words
+	// were still learned from what was read before an error reading
+	// it.
+	Err error
+}
+
+// BuildDir adds the text of every regular file under the given
+// directory to the chain as Build would, in lexical order. If any
+// patterns are given, only files whose base names match one of them
+// (as in filepath.Match, e.g. "*.txt") are used. It returns a report
+// for each file it tried to train on, including any it couldn't read;
+// the error is only non-nil if the directory itself can't be walked
+// or a pattern is malformed.
+func (c *Chain) BuildDir(dir string, patterns []string, opts ...BuildOption) ([]FileReport, error) {
+	var reports []FileReport
+	err := WalkCorpus(dir, patterns, func(path string, err error) {
+		if err != nil {
+			reports = append(reports, FileReport{Path: path, Err: err})
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			reports = append(reports, FileReport{Path: path, Err: err})
+			return
+		}
+		defer f.Close()
+		words, err := c.build(f, opts)
+		reports = append(reports, FileReport{Path: path, Words: words, Err: err})
+	})
+	return reports, err
+}
+
+// WalkCorpus calls f with the path of every regular file under the
+// given directory that BuildDir would train on, in lexical order, or
+// with the path and error for anything under it that can't be read.
+// The error is only non-nil if the directory itself can't be walked or
+// a pattern is malformed, in which case f isn't called.
+func WalkCorpus(dir string, patterns []string, f func(path string, err error)) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			f(path, err)
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() && matchAny(patterns, info.Name()) {
+			f(path, nil)
+		}
+		return nil
+	})
+}
+
+// matchAny reports whether a name matches any of the given patterns,
+// or whether there are no patterns.
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
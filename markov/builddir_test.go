@@ -0,0 +1,57 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWalkCorpus(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.log", "sub/c.txt", "sub/d.md"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("some text"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{nil, []string{"a.txt", "b.log", "sub/c.txt", "sub/d.md"}},
+		{[]string{"*.txt"}, []string{"a.txt", "sub/c.txt"}},
+		{[]string{"*.txt", "*.md"}, []string{"a.txt", "sub/c.txt", "sub/d.md"}},
+		{[]string{"*.go"}, nil},
+	}
+	for _, test := range tests {
+		var got []string
+		err := WalkCorpus(dir, test.patterns, func(path string, err error) {
+			if err != nil {
+				t.Errorf("WalkCorpus(%q): %s: %v", test.patterns, path, err)
+			}
+			rel, _ := filepath.Rel(dir, path)
+			got = append(got, filepath.ToSlash(rel))
+		})
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("WalkCorpus(%q) = %q, %v; want %q", test.patterns, got, err, test.want)
+		}
+	}
+	if err := WalkCorpus(dir, []string{"["}, func(string, error) {}); err == nil {
+		t.Errorf("WalkCorpus with a malformed pattern succeeded")
+	}
+
+	c := NewChain(2)
+	reports, err := c.BuildDir(dir, []string{"*.txt"})
+	if err != nil || len(reports) != 2 || reports[0].Words != 2 {
+		t.Errorf("BuildDir = %+v, %v", reports, err)
+	}
+}
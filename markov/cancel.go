@@ -0,0 +1,34 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// cancel.go lets training on a long stream be stopped part way, e.g.
+// when whoever asked for it gives up.
+
+package markov
+
+import (
+	"context"
+	"io"
+)
+
+// BuildContext is like Build, but stops reading once ctx is done,
+// returning the number of words added until then along with ctx's
+// error. What was read before that stays learned.
+func (c *Chain) BuildContext(ctx context.Context, r io.Reader, opts ...BuildOption) (int, error) {
+	return c.build(&ctxReader{ctx: ctx, r: r}, opts)
+}
+
+// ctxReader reads from r until ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *ctxReader) Read(b []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(b)
+}
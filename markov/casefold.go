@@ -0,0 +1,188 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// casefold.go controls whether a Chain's prefixes ignore case, and how
+// generated words are capitalized, since folding case garbles
+// acronyms and names in technical corpora.
+
+package markov
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// A CaseFolding is how a Chain treats the case of prefix words.
+type CaseFolding int
+
+const (
+	// FoldCase lowercases prefix words, so "NASA" and "nasa" are the
+	// same prefix. Words chosen without any context (when the chain
+	// backs off to the empty prefix) are lowercased too, except at
+	// the start of a sentence.
+	FoldCase CaseFolding = iota
+	// PreserveCase keeps prefix words as they were written, and
+	// generated words as they were learned.
+	PreserveCase
+	// RestoreCase folds prefix words as FoldCase does, but writes
+	// each generated word in the case it was most often learned in
+	// mid-sentence, e.g. "NASA" or "Linux", capitalizing it at the
+	// start of a sentence.
+	RestoreCase
+)
+
+var caseFoldingNames = []string{"fold", "preserve", "restore"}
+
+// String returns the case folding's name.
+func (f CaseFolding) String() string {
+	if f < 0 || int(f) >= len(caseFoldingNames) {
+		return fmt.Sprintf("CaseFolding(%d)", int(f))
+	}
+	return caseFoldingNames[f]
+}
+
+// ParseCaseFolding returns the case folding with the given name
+// ("fold", "preserve", or "restore").
+func ParseCaseFolding(name string) (CaseFolding, error) {
+	for i, n := range caseFoldingNames {
+		if strings.EqualFold(name, n) {
+			return CaseFolding(i), nil
+		}
+	}
+	return 0, fmt.Errorf("markov: unknown case folding %q", name)
+}
+
+// SetCaseFolding sets how the chain treats the case of prefix words.
+// It should be set before the chain is built or loaded, since
+// prefixes built with one case folding won't match those generated
+// with another. RestoreCase learns the case of words from the chain's
+// counts, whose suffixes are kept as written, when it's set and
+// whenever the chain is loaded, and from text added by Build.
+func (c *Chain) SetCaseFolding(f CaseFolding) {
+	c.caseFolding = f
+	if f == RestoreCase && c.cases == nil {
+		c.learnCases()
+	} else if f != RestoreCase {
+		c.cases = nil
+	}
+}
+
+// A caser applies a case folding to generated text.
+type caser struct {
+	folding CaseFolding
+	// cases counts the forms of each lowercased word learned
+	// mid-sentence, for RestoreCase.
+	cases map[string]map[string]int
+}
+
+// caser returns the chain's caser.
+func (c *Chain) caser() caser {
+	return caser{folding: c.caseFolding, cases: c.cases}
+}
+
+// shift shifts a word into a prefix, folding its case unless the
+// chain preserves case.
+func (c *Chain) shift(p Prefix, w string) {
+	c.caser().shift(p, w)
+}
+
+// shift implements Chain.shift.
+func (cs caser) shift(p Prefix, w string) {
+	if cs.folding == PreserveCase {
+		copy(p, p[1:])
+		p[len(p)-1] = w
+		return
+	}
+	p.Shift(w)
+}
+
+// fixCase adjusts the capitalization of a word chosen with no context,
+// because the chain doesn't recognize the tail word of the prefix, to
+// at least suit the position the word is in.
+func (cs caser) fixCase(p Prefix, word string) string {
+	if word == End {
+		return word
+	}
+	if startsSentence(p) {
+		return stringutil.Capitalize(word)
+	}
+	if cs.folding == FoldCase {
+		return strings.ToLower(word)
+	}
+	return word
+}
+
+// restore returns a generated word in the case it was most often
+// learned in, for RestoreCase, capitalized if it starts a sentence.
+// Escaped words are unescaped (see escapeEnd).
+func (cs caser) restore(p Prefix, word string) string {
+	if cs.folding != RestoreCase || word == End {
+		return unescapeEnd(word)
+	}
+	best, most := "", 0
+	for form, n := range cs.cases[strings.ToLower(word)] {
+		if n > most || (n == most && form < best) {
+			best, most = form, n
+		}
+	}
+	if best == "" {
+		return unescapeEnd(word)
+	}
+	if startsSentence(p) {
+		return stringutil.Capitalize(unescapeEnd(best))
+	}
+	return unescapeEnd(best)
+}
+
+// learnCase records the form of a word learned after a prefix, for
+// RestoreCase. Words starting sentences are skipped, as they're
+// capitalized whatever their usual case.
+func (c *Chain) learnCase(p Prefix, word string) {
+	c.learnCaseN(p, word, 1)
+}
+
+// learnCaseN implements learnCase for a word learned n times.
+func (c *Chain) learnCaseN(p Prefix, word string, n int) {
+	if c.cases == nil || word == End || startsSentence(p) {
+		return
+	}
+	r, _ := utf8.DecodeRuneInString(word)
+	if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return
+	}
+	key := strings.ToLower(word)
+	if c.cases[key] == nil {
+		c.cases[key] = make(map[string]int)
+	}
+	c.cases[key][word] += n
+}
+
+// learnCases relearns the case of words for RestoreCase from the
+// suffixes of the chain's full-length prefixes, which count each
+// word's occurrences once, except at the start of the input, where it
+// starts a sentence anyway.
+func (c *Chain) learnCases() {
+	c.cases = make(map[string]map[string]int)
+	for key, suffixes := range c.chain {
+		p := Prefix(strings.Split(key, " "))
+		if key == "" || len(p) != c.prefixLen {
+			continue
+		}
+		for s, freq := range suffixes {
+			c.learnCaseN(p, s, freq)
+		}
+	}
+}
+
+// startsSentence reports whether the next word after a prefix starts
+// a sentence.
+func startsSentence(p Prefix) bool {
+	last := p[len(p)-1]
+	return last == "START" || stringutil.EndsSentence(last)
+}
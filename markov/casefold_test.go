@@ -0,0 +1,51 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRestoreCaseRelearned(t *testing.T) {
+	const text = "we work at NASA on Linux. nasa said so. Linux runs at NASA today."
+	built := NewChain(2)
+	built.SetCaseFolding(RestoreCase)
+	built.Build(strings.NewReader(text))
+
+	var saved bytes.Buffer
+	if err := built.Encode(&saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewChain(2)
+	loaded.SetCaseFolding(RestoreCase)
+	if err := loaded.Decode(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	// A chain built before case was restored
+	enabled := NewChain(2)
+	enabled.Build(strings.NewReader(text))
+	enabled.SetCaseFolding(RestoreCase)
+
+	tests := []struct {
+		name  string
+		chain *Chain
+	}{
+		{"loaded", loaded},
+		{"enabled", enabled},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.chain.cases, built.cases) {
+			t.Errorf("%s chain learned cases %v, want %v", test.name, test.chain.cases, built.cases)
+		}
+		if got := test.chain.Generate("we work at", 1, 10); !strings.HasPrefix(got, "we work at NASA") {
+			t.Errorf("%s chain generated %q, want it to continue with %q", test.name, got, "NASA")
+		}
+	}
+}
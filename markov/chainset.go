@@ -0,0 +1,197 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// chainset.go defines ChainSet, a registry of named chains (per
+// channel, per user, per persona, ...) that can be saved to and loaded
+// from a directory together.
+
+package markov
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// A ChainSet manages a set of named chains with the same prefix
+// length, creating them on demand.
+type ChainSet struct {
+	prefixLen int
+	chains    map[string]*Chain
+	setup     func(name string, c *Chain)
+	isolation Isolation
+	// dir is the directory the set was last loaded from or saved
+	// to, where its clean chains are already saved.
+	dir string
+}
+
+// chainSetIndex is the name of the file in a ChainSet's directory
+// mapping chain names to the files they're saved in.
+const chainSetIndex = "index.json"
+
+// NewChainSet returns a new, empty ChainSet whose chains have prefixes
+// of prefixLen words.
+func NewChainSet(prefixLen int) *ChainSet {
+	return &ChainSet{
+		prefixLen: prefixLen,
+		chains:    make(map[string]*Chain),
+	}
+}
+
+// SetSetup sets a function to be called on every chain the set creates
+// or loads from now on, before it's used, e.g. to set an allowlist or
+// start tracking updates.
+func (s *ChainSet) SetSetup(setup func(name string, c *Chain)) {
+	s.setup = setup
+}
+
+// Get returns the chain with the given name, or nil if there is none.
+func (s *ChainSet) Get(name string) *Chain {
+	return s.chains[name]
+}
+
+// Chain returns the chain with the given name, creating an empty one
+// if there is none.
+func (s *ChainSet) Chain(name string) *Chain {
+	c := s.chains[name]
+	if c == nil {
+		c = s.newChain(name)
+		s.chains[name] = c
+	}
+	return c
+}
+
+// newChain creates an empty chain for the set.
+func (s *ChainSet) newChain(name string) *Chain {
+	c := NewChain(s.prefixLen)
+	c.private = isPrivateKey(name)
+	if s.setup != nil {
+		s.setup(name, c)
+	}
+	return c
+}
+
+// Delete removes the chain with the given name from the set, and from
+// the directory the set was last loaded from or saved to, if any, so
+// that it's gone for good rather than loaded again.
+func (s *ChainSet) Delete(name string) error {
+	delete(s.chains, name)
+	if s.dir == "" {
+		return nil
+	}
+	index, err := readIndex(s.dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	file, ok := index[name]
+	if !ok {
+		return nil
+	}
+	// Drop it from the index first, so the set can still be loaded
+	// if removing the file fails
+	delete(index, name)
+	if err := writeIndex(s.dir, index); err != nil {
+		return err
+	}
+	return os.Remove(path.Join(s.dir, file))
+}
+
+// Names returns the names of the chains in the set, in sorted order.
+func (s *ChainSet) Names() []string {
+	names := make([]string, 0, len(s.chains))
+	for name := range s.chains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Len returns the number of chains in the set.
+func (s *ChainSet) Len() int {
+	return len(s.chains)
+}
+
+// Each calls f with each chain in the set and its name, in sorted
+// order of name.
+func (s *ChainSet) Each(f func(name string, c *Chain)) {
+	for _, name := range s.Names() {
+		f(name, s.chains[name])
+	}
+}
+
+// Load attempts to load the chains saved in the given directory by
+// Save, adding them to the set (and replacing any with the same
+// names).
+func (s *ChainSet) Load(dir string) error {
+	index, err := readIndex(dir)
+	if err != nil {
+		return err
+	}
+	for name, file := range index {
+		c := s.newChain(name)
+		if err := c.Load(path.Join(dir, file)); err != nil {
+			return err
+		}
+		s.chains[name] = c
+	}
+	s.dir = dir
+	return nil
+}
+
+// Save saves every chain in the set to its own file in the given
+// directory, creating the directory if necessary, along with an index
+// of the chains' names. If the set was last loaded from or saved to
+// the same directory, only dirty chains (see Chain.Dirty) are saved.
+func (s *ChainSet) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	index := make(map[string]string)
+	for name, c := range s.chains {
+		file := stringutil.Escape(name) + ".chain.json"
+		index[name] = file
+		if dir == s.dir && !c.Dirty() {
+			continue
+		}
+		if err := c.Save(path.Join(dir, file)); err != nil {
+			return err
+		}
+	}
+
+	if err := writeIndex(dir, index); err != nil {
+		return err
+	}
+	s.dir = dir
+	return nil
+}
+
+// readIndex reads the index of the chains saved in a directory,
+// mapping their names to their files.
+func readIndex(dir string) (map[string]string, error) {
+	f, err := os.Open(path.Join(dir, chainSetIndex))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var index map[string]string
+	if err := json.NewDecoder(f).Decode(&index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// writeIndex replaces the index of the chains saved in a directory.
+func writeIndex(dir string, index map[string]string) error {
+	return saveAtomic(path.Join(dir, chainSetIndex), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(index)
+	})
+}
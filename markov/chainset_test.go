@@ -0,0 +1,56 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		saved  []string // chains saved before deleting
+		added  []string // chains added since
+		delete string
+		want   []string // chains loaded again afterwards
+	}{
+		{[]string{"alice", "bob"}, nil, "alice", []string{"bob"}},
+		{[]string{"alice", "bob"}, []string{"carol"}, "carol", []string{"alice", "bob"}},
+		{[]string{"alice"}, nil, "nobody", []string{"alice"}},
+		{[]string{"alice"}, nil, "alice", []string{}},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		s := NewChainSet(2)
+		for _, name := range test.saved {
+			s.Chain(name).Build(strings.NewReader("hello there"))
+		}
+		if err := s.Save(dir); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range test.added {
+			s.Chain(name).Build(strings.NewReader("hello there"))
+		}
+		if err := s.Delete(test.delete); err != nil {
+			t.Errorf("Delete(%q) = %v", test.delete, err)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, test.delete+".chain.json")); !os.IsNotExist(err) {
+			t.Errorf("after Delete(%q), its file is still there", test.delete)
+		}
+		loaded := NewChainSet(2)
+		if err := loaded.Load(dir); err != nil {
+			t.Errorf("after Delete(%q), Load = %v", test.delete, err)
+			continue
+		}
+		if got := loaded.Names(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("after Delete(%q), Load gives %q, want %q", test.delete, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// compact.go defines a compact JSON representation of a Chain, with a
+// shared word table and arrays of word IDs and counts instead of
+// nested maps, which is several times smaller and faster to parse.
+
+package markov
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// compactFormat identifies the compact representation.
+const compactFormat = "clyde-compact"
+
+// compactMagic is how every compact chain file starts, so Decode can
+// tell the formats apart without parsing twice.
+var compactMagic = []byte(`{"format":"` + compactFormat + `"`)
+
+// compactChain is the compact representation of a Chain. Prefixes[i]
+// is the list of word IDs (indices into Words) making up the i-th
+// prefix, and Suffixes[i] alternates word IDs and counts of the
+// suffixes following it.
+type compactChain struct {
+	Format    string   `json:"format"`
+	PrefixLen int      `json:"prefixLen"`
+	Words     []string `json:"words"`
+	Prefixes  [][]int  `json:"prefixes"`
+	Suffixes  [][]int  `json:"suffixes"`
+}
+
+// EncodeCompact writes the chain's suffix frequency map to the given
+// Writer in the compact format, which Decode also understands.
+func (c *Chain) EncodeCompact(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	return enc.Encode(c.compact())
+}
+
+// compact returns the chain's compact representation.
+func (c *Chain) compact() compactChain {
+	ids := make(map[string]int)
+	cc := compactChain{Format: compactFormat, PrefixLen: c.prefixLen}
+	id := func(w string) int {
+		i, ok := ids[w]
+		if !ok {
+			i = len(cc.Words)
+			ids[w] = i
+			cc.Words = append(cc.Words, w)
+		}
+		return i
+	}
+
+	// Write prefixes and suffixes in sorted order, so the same
+	// chain always encodes the same way
+	keys := make([]string, 0, len(c.chain))
+	for key := range c.chain {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prefix := []int{}
+		for _, w := range strings.Fields(key) {
+			prefix = append(prefix, id(w))
+		}
+		suffixes := make([]string, 0, len(c.chain[key]))
+		for s := range c.chain[key] {
+			suffixes = append(suffixes, s)
+		}
+		sort.Strings(suffixes)
+		counts := make([]int, 0, 2*len(suffixes))
+		for _, s := range suffixes {
+			counts = append(counts, id(s), c.chain[key][s])
+		}
+		cc.Prefixes = append(cc.Prefixes, prefix)
+		cc.Suffixes = append(cc.Suffixes, counts)
+	}
+	return cc
+}
+
+// SaveCompact saves a chain's suffix frequency map to the given file
+// in the compact format, compressed with gzip if the filename ends in
+// ".gz", with a header as in Save.
+func (c *Chain) SaveCompact(filename string) error {
+	if err := saveModel(filename, c.EncodeCompact); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// isCompact reports whether buffered input is in the compact format,
+// without consuming any of it.
+func isCompact(br *bufio.Reader) bool {
+	magic, _ := br.Peek(len(compactMagic))
+	return bytes.Equal(magic, compactMagic)
+}
+
+// decodeCompact reads a suffix frequency map in the compact format.
+func (c *Chain) decodeCompact(r io.Reader) error {
+	var cc compactChain
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&cc); err != nil {
+		return jsonError(err)
+	}
+	return c.loadCompact(cc)
+}
+
+// loadCompact replaces the chain's suffix frequency map with the one
+// in the given compact representation.
+func (c *Chain) loadCompact(cc compactChain) error {
+	if cc.PrefixLen != c.prefixLen {
+		return fmt.Errorf("markov: can't load chain with prefix length %d into chain with prefix length %d", cc.PrefixLen, c.prefixLen)
+	}
+	if len(cc.Prefixes) != len(cc.Suffixes) {
+		return corruptf("markov: compact chain has %d prefixes but %d suffix lists", len(cc.Prefixes), len(cc.Suffixes))
+	}
+
+	word := func(i int) (string, error) {
+		if i < 0 || i >= len(cc.Words) {
+			return "", corruptf("markov: compact chain has bad word ID %d", i)
+		}
+		return cc.Words[i], nil
+	}
+	chain := make(map[string]map[string]int, len(cc.Prefixes))
+	for i, prefix := range cc.Prefixes {
+		words := make([]string, len(prefix))
+		for j, id := range prefix {
+			w, err := word(id)
+			if err != nil {
+				return err
+			}
+			words[j] = w
+		}
+		counts := cc.Suffixes[i]
+		if len(counts)%2 != 0 {
+			return corruptf("markov: compact chain has an odd-length suffix list")
+		}
+		suffixes := make(map[string]int, len(counts)/2)
+		for j := 0; j < len(counts); j += 2 {
+			s, err := word(counts[j])
+			if err != nil {
+				return err
+			}
+			if counts[j+1] < 1 {
+				return corruptf("markov: compact chain has bad count %d", counts[j+1])
+			}
+			suffixes[s] = counts[j+1]
+		}
+		chain[strings.Join(words, " ")] = suffixes
+	}
+	c.chain = chain
+	c.reindex()
+	c.dirty = false
+	return nil
+}
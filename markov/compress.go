@@ -0,0 +1,94 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// compress.go handles gzip-compressed chain files, which are a small
+// fraction of the size of uncompressed ones, since chat-trained
+// chains repeat the same words endlessly.
+
+package markov
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+// gzipMagic is how every gzip stream starts.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns a buffered reader for the given input, which is
+// transparently decompressed if it's gzipped.
+func decompress(r io.Reader) (*bufio.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(gzipMagic))
+	if !bytes.Equal(magic, gzipMagic) {
+		return br, nil
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewReader(zr), nil
+}
+
+// compressedFile is a file being written through a gzip compressor.
+type compressedFile struct {
+	*gzip.Writer
+	f *atomicFile
+}
+
+// Close flushes the compressor and closes the file.
+func (cf compressedFile) Close() error {
+	err := cf.Writer.Close()
+	if err != nil {
+		cf.f.Abort()
+		return err
+	}
+	return cf.f.Close()
+}
+
+// Abort abandons the file.
+func (cf compressedFile) Abort() {
+	cf.f.Abort()
+}
+
+// abortWriteCloser is a file being written that can be abandoned,
+// leaving any existing file in its place.
+type abortWriteCloser interface {
+	io.WriteCloser
+	Abort()
+}
+
+// create creates the named file for writing, compressing whatever is
+// written to it with gzip if the name ends in ".gz". The file only
+// replaces any existing one when it's closed without error (see
+// createAtomic).
+func create(filename string) (abortWriteCloser, error) {
+	f, err := createAtomic(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(filename, ".gz") {
+		return f, nil
+	}
+	return compressedFile{gzip.NewWriter(f), f}, nil
+}
+
+// saveWith creates the named file as in create, writes to it with the
+// given encoding function, and closes it, returning the first error.
+// If anything fails, any existing file is left as it was.
+func saveWith(filename string, encode func(w io.Writer) error) error {
+	w, err := create(filename)
+	if err != nil {
+		return err
+	}
+	if err := encode(w); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
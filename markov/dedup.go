@@ -0,0 +1,147 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// dedup.go optionally skips text a Chain was already trained on, so
+// that re-importing overlapping log archives doesn't count the same
+// messages twice.
+
+package markov
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// dedupMode is what a Build with deduplication compares: lines or
+// sentences.
+type dedupMode int
+
+const (
+	noDedup dedupMode = iota
+	dedupLines
+	dedupSentences
+)
+
+// DedupLines returns a BuildOption that skips each line of the text
+// the chain was already trained on with deduplication (see
+// LoadTrained), and records the hashes of the rest. Lines are
+// compared as in IsNovel, ignoring case, punctuation, and spacing,
+// and lines too short to be distinctive, like "lol", are always
+// added, since people really do say them again.
+func DedupLines() BuildOption {
+	return func(o *buildOptions) {
+		o.dedup = dedupLines
+	}
+}
+
+// DedupSentences is like DedupLines, but compares the text's
+// sentences (see stringutil.SplitSentences) instead of its lines.
+func DedupSentences() BuildOption {
+	return func(o *buildOptions) {
+		o.dedup = dedupSentences
+	}
+}
+
+// dedup returns a reader of the text from r without the lines or
+// sentences the chain was already trained on, which records the rest as
+// trained as it goes.
+func (c *Chain) dedup(r io.Reader, mode dedupMode) io.Reader {
+	if c.trained == nil {
+		c.trained = make(map[uint64]bool)
+	}
+	return &dedupReader{c: c, mode: mode, in: bufio.NewReader(r)}
+}
+
+// dedupReader reads text a line at a time, passing on the lines or
+// sentences its chain wasn't already trained on, so that deduplicating
+// a large corpus doesn't mean holding all of it in memory.
+type dedupReader struct {
+	c    *Chain
+	mode dedupMode
+	in   *bufio.Reader
+	// pending is the last sentence read, which may continue on the
+	// next line
+	pending string
+	out     []byte // text passed on but not yet read
+	err     error
+}
+
+func (d *dedupReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		line, err := d.in.ReadString('\n')
+		d.err = err
+		d.add(line, err != nil)
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// add passes on what's new in a line of text, holding back a sentence
+// that may be continued on the next, unless it's the last line.
+func (d *dedupReader) add(line string, last bool) {
+	units := []string{strings.TrimSuffix(line, "\n")}
+	if d.mode == dedupSentences {
+		units = stringutil.SplitSentences(d.pending + " " + line)
+		d.pending = ""
+		if !last && len(units) > 0 {
+			d.pending = units[len(units)-1]
+			units = units[:len(units)-1]
+		}
+	}
+	for _, u := range units {
+		if h, ok := sentenceHash(u); ok {
+			if d.c.trained[h] {
+				continue
+			}
+			d.c.trained[h] = true
+		}
+		d.out = append(d.out, u...)
+		d.out = append(d.out, '\n')
+	}
+}
+
+// LoadTrained attempts to load the hashes of the text the chain was
+// trained on with deduplication (see DedupLines), in JSON format, from
+// the given file. They aren't saved with the chain itself.
+func (c *Chain) LoadTrained(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var hashes []uint64
+	if err := json.NewDecoder(f).Decode(&hashes); err != nil {
+		return err
+	}
+	if c.trained == nil {
+		c.trained = make(map[uint64]bool, len(hashes))
+	}
+	for _, h := range hashes {
+		c.trained[h] = true
+	}
+	return nil
+}
+
+// SaveTrained saves the hashes of the text the chain was trained on
+// with deduplication to the given file in JSON format.
+func (c *Chain) SaveTrained(filename string) error {
+	hashes := make([]uint64, 0, len(c.trained))
+	for h := range c.trained {
+		hashes = append(hashes, h)
+	}
+	return saveAtomic(filename, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(hashes)
+	})
+}
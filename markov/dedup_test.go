@@ -0,0 +1,46 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDedup(t *testing.T) {
+	tests := []struct {
+		opt   BuildOption
+		texts []string
+		words []int
+	}{
+		// Repeated lines are skipped, even across builds, but short
+		// ones are always added
+		{DedupLines(), []string{"the cat sat on the mat\nlol\n", "The cat sat on the mat!\nlol\nthe dog ate my homework"}, []int{7, 6}},
+		// Sentences are compared even if they span lines
+		{DedupSentences(), []string{"The cat sat\non the mat. The dog\nate my homework.", "Once more. The cat sat on the mat."}, []int{11, 2}},
+		{DedupSentences(), []string{"The cat sat on the mat. The cat sat on the mat"}, []int{6}},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		for i, text := range test.texts {
+			// Read a byte at a time, to test reassembling lines
+			r := iotest.OneByteReader(strings.NewReader(text))
+			if got, _ := c.Build(r, test.opt); got != test.words[i] {
+				t.Errorf("Build(%q) = %d, want %d", text, got, test.words[i])
+			}
+		}
+	}
+}
+
+func TestCloneTrained(t *testing.T) {
+	c := NewChain(2)
+	c.Build(strings.NewReader("the cat sat on the mat"), DedupLines())
+	clone := c.Clone()
+	if got, _ := clone.Build(strings.NewReader("the cat sat on the mat"), DedupLines()); got != 0 {
+		t.Errorf("Build on clone = %d, want 0", got)
+	}
+}
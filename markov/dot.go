@@ -0,0 +1,76 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// dot.go exports a Chain as a graph in GraphViz's DOT language, to
+// see the shape of what it has learned: where its text can go from
+// each prefix, and how often it did.
+
+package markov
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteDOT writes the chain to the given Writer as a DOT graph, which
+// GraphViz's dot command can draw. Each node is a prefix the chain
+// starts a word from, its longest tail there: a full prefix, or a
+// shorter one beginning with "START" at the start of input. Each edge
+// is a word the chain learned after a prefix, leading to the prefix
+// that word makes, or to an END node for End, and is labeled with the
+// word and how often it was seen. Only edges seen at least minCount
+// times are included, along with the nodes they join, since even
+// small chains make unreadably large graphs.
+func (c *Chain) WriteDOT(w io.Writer, minCount int) error {
+	var keys []string
+	for key := range c.chain {
+		if key != "" && (strings.Count(key, " ")+1 == c.prefixLen || strings.HasPrefix(key, "START")) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph chain {")
+	fmt.Fprintln(bw, "\tnode [shape=box];")
+	ended := false
+	for _, key := range keys {
+		suffixes := c.chain[key]
+		words := make([]string, 0, len(suffixes))
+		for s, freq := range suffixes {
+			if freq >= minCount {
+				words = append(words, s)
+			}
+		}
+		sort.Strings(words)
+		for _, s := range words {
+			next := End
+			if s == End {
+				ended = true
+			} else {
+				prefix := strings.Split(key, " ")
+				p := make(Prefix, c.prefixLen)
+				copy(p[c.prefixLen-len(prefix):], prefix)
+				c.shift(p, s)
+				next = strings.TrimSpace(strings.Join(p, " "))
+			}
+			fmt.Fprintf(bw, "\t%s -> %s [label=%s];\n", dotQuote(key), dotQuote(next), dotQuote(fmt.Sprintf("%s (%d)", s, suffixes[s])))
+		}
+	}
+	if ended {
+		fmt.Fprintf(bw, "\t%s [shape=doublecircle];\n", dotQuote(End))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a DOT quoted string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
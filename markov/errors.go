@@ -0,0 +1,79 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// errors.go defines the errors callers can check for with errors.Is,
+// to tell a chain with nothing to say, or a damaged model file, from
+// other failures.
+
+package markov
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrEmptyChain is returned when generating from a chain that
+	// hasn't learned anything.
+	ErrEmptyChain = errors.New("markov: chain is empty")
+	// ErrUnknownPrefix is returned when generating after a prefix
+	// the chain doesn't know any words to follow, even backing off
+	// to shorter prefixes, e.g. because an allowlist rules them all
+	// out.
+	ErrUnknownPrefix = errors.New("markov: no words follow prefix")
+	// ErrCorruptModel matches (with errors.Is) the errors returned
+	// when loading a model that's truncated or malformed.
+	ErrCorruptModel = errors.New("markov: corrupt model")
+)
+
+// A corruptError describes what's wrong with a corrupt model.
+type corruptError struct {
+	msg string
+}
+
+func (e *corruptError) Error() string {
+	return e.msg
+}
+
+// Is makes corrupt errors match ErrCorruptModel.
+func (e *corruptError) Is(target error) bool {
+	return target == ErrCorruptModel
+}
+
+// corruptf returns a corrupt model error with a formatted message.
+func corruptf(format string, args ...interface{}) error {
+	return &corruptError{msg: fmt.Sprintf(format, args...)}
+}
+
+// jsonError returns an error decoding a model's JSON, as a corrupt
+// model error if the JSON was malformed.
+func jsonError(err error) error {
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	if errors.As(err, &syntax) || errors.As(err, &typ) || err == io.ErrUnexpectedEOF {
+		return corruptf("markov: corrupt model: %v", err)
+	}
+	return err
+}
+
+// wordError returns the error for when there's no word to generate
+// after a prefix.
+func (c *Chain) wordError() error {
+	if len(c.chain) == 0 && (c.fallback == nil || len(c.fallback.chain) == 0) {
+		return ErrEmptyChain
+	}
+	return ErrUnknownPrefix
+}
+
+// Next is like NextWord, but returns ErrEmptyChain or ErrUnknownPrefix
+// instead of "" if there's no word to choose.
+func (c *Chain) Next(p Prefix) (string, error) {
+	if w := c.NextWord(p); w != "" {
+		return w, nil
+	}
+	return "", c.wordError()
+}
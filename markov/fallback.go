@@ -0,0 +1,54 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// fallback.go lets a small Chain lean on a larger one while
+// generating, so that a model trained on only a little text (say, one
+// user's messages) still produces fluent output.
+
+package markov
+
+// SetFallback makes generation draw each word from the fallback chain
+// with probability weight (between 0 and 1), when the fallback
+// recognizes at least the last word of the prefix, and from this chain
+// otherwise, falling back anyway whenever this chain has no word to
+// offer. Passing a nil fallback removes it, and so does passing a
+// private fallback for a public chain (see Chain.Private).
+func (c *Chain) SetFallback(fallback *Chain, weight float64) {
+	if fallback == c || fallback != nil && fallback.private && !c.private {
+		fallback = nil
+	}
+	c.fallback = fallback
+	c.fallbackWeight = weight
+}
+
+// SetFallbackUntil sets a fallback (see SetFallback) whose weight
+// shrinks as this chain grows, from 1 for an empty chain to 0 once it
+// has size prefixes, so that a chain leans on the fallback only until
+// it has learned enough to stand on its own. It returns a function
+// removing the fallback again, for when the caller is done generating.
+func (c *Chain) SetFallbackUntil(fallback *Chain, size int) func() {
+	weight := 1 - float64(c.Size())/float64(size)
+	if weight < 0 {
+		weight = 0
+	}
+	c.SetFallback(fallback, weight)
+	return func() { c.SetFallback(nil, 0) }
+}
+
+// nextWordFallback implements nextWord for a chain with a fallback.
+func (c *Chain) nextWordFallback(p Prefix) step {
+	// Only take the fallback's word if it knows something about
+	// the prefix; a word chosen with no context at all would derail
+	// the text
+	if c.float64() < c.fallbackWeight {
+		if st := c.fallback.nextWord(p); st.word != "" && st.level > 0 {
+			return st
+		}
+	}
+	if st := c.ownNextWord(p); st.word != "" {
+		return st
+	}
+	return c.fallback.nextWord(p)
+}
@@ -0,0 +1,37 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetFallbackUntil(t *testing.T) {
+	fallback := NewChain(2)
+	fallback.Build(strings.NewReader("the cat sat on the mat."))
+	tests := []struct {
+		text     string
+		size     int
+		min, max float64 // the weight's bounds
+	}{
+		{"", 10, 1, 1},
+		{"a b c d e", 100, 0.5, 0.99},
+		{"a b c d e f g h i j k l m n", 10, 0, 0},
+	}
+	for _, tt := range tests {
+		c := NewChain(2)
+		c.Build(strings.NewReader(tt.text))
+		done := c.SetFallbackUntil(fallback, tt.size)
+		if c.fallback != fallback || c.fallbackWeight < tt.min || c.fallbackWeight > tt.max {
+			t.Errorf("chain of size %d, until %d: weight %v, want %v to %v", c.Size(), tt.size, c.fallbackWeight, tt.min, tt.max)
+		}
+		done()
+		if c.fallback != nil {
+			t.Errorf("fallback still set after done")
+		}
+	}
+}
@@ -0,0 +1,449 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// frozen.go defines FrozenChain, a read-only chain stored in a flat
+// binary file that is memory-mapped rather than parsed, for serving
+// generation from a large model with near-zero startup time and with
+// memory shared between processes.
+
+package markov
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A frozen chain file is laid out as follows, with all integers
+// little-endian uint32s:
+//
+//	header      magic, prefix length, flags, word count, prefix
+//	            count, suffix count, word blob length
+//	words       word count + 1 offsets into the word blob, then the
+//	            blob itself (the words in sorted order), padded to a
+//	            multiple of 4 bytes
+//	prefixes    for each prefix, in sorted order: prefix length word
+//	            IDs (noWord for the missing leading words of shorter
+//	            prefixes), the index of its first suffix, its suffix
+//	            count, and its total frequency
+//	suffixes    for each suffix: its word ID and frequency
+const frozenMagic = "CLYDEFZ1"
+
+const frozenHeaderLen = len(frozenMagic) + 6*4
+
+// frozenSentenceMarkers is the header flag recording that the chain
+// was built with sentence markers.
+const frozenSentenceMarkers = 1
+
+// frozenPreserveCase is the header flag recording that the chain was
+// built with PreserveCase.
+const frozenPreserveCase = 2
+
+// noWord fills the unused leading word IDs of a short prefix.
+const noWord = ^uint32(0)
+
+// A FrozenChain is a read-only Chain backed by a memory-mapped file
+// written by SaveFrozen.
+type FrozenChain struct {
+	data            []byte
+	prefixLen       int
+	sentenceMarkers bool
+	preserveCase    bool
+	words           int // number of words
+	prefixes        int // number of prefixes
+	wordOffsets     int // offset of the word offset table
+	blob            int // offset of the word blob
+	prefixTable     int // offset of the prefix table
+	suffixTable     int // offset of the suffix table
+	unmap           func([]byte) error
+}
+
+// WriteFrozen writes the chain to the given Writer in the frozen chain
+// format read by OpenFrozen.
+func (c *Chain) WriteFrozen(w io.Writer) error {
+	seen := make(map[string]bool)
+	var keys []string
+	for key, suffixes := range c.chain {
+		keys = append(keys, key)
+		for _, word := range strings.Fields(key) {
+			seen[word] = true
+		}
+		for s := range suffixes {
+			seen[s] = true
+		}
+	}
+	words := make([]string, 0, len(seen))
+	for word := range seen {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	ids := make(map[string]uint32, len(words))
+	for i, word := range words {
+		ids[word] = uint32(i)
+	}
+
+	// Sort prefixes by their word IDs, the order FrozenChain
+	// searches them in
+	prefixIDs := make(map[string][]uint32, len(keys))
+	for _, key := range keys {
+		prefixIDs[key] = frozenPrefix(c.prefixLen, strings.Fields(key), ids)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return comparePrefixes(prefixIDs[keys[i]], prefixIDs[keys[j]]) < 0
+	})
+
+	blobLen := 0
+	for _, word := range words {
+		blobLen += len(word)
+	}
+	suffixCount := 0
+	for _, suffixes := range c.chain {
+		suffixCount += len(suffixes)
+	}
+	flags := uint32(0)
+	if c.sentenceMarkers {
+		flags |= frozenSentenceMarkers
+	}
+	if c.caseFolding == PreserveCase {
+		flags |= frozenPreserveCase
+	}
+
+	bw := bufio.NewWriter(w)
+	put := func(v uint32) {
+		binary.Write(bw, binary.LittleEndian, v)
+	}
+	bw.WriteString(frozenMagic)
+	for _, v := range []int{c.prefixLen, int(flags), len(words), len(keys), suffixCount, blobLen} {
+		put(uint32(v))
+	}
+	offset := 0
+	for _, word := range words {
+		put(uint32(offset))
+		offset += len(word)
+	}
+	put(uint32(offset))
+	for _, word := range words {
+		bw.WriteString(word)
+	}
+	for ; blobLen%4 != 0; blobLen++ {
+		bw.WriteByte(0)
+	}
+
+	// Suffixes are written in the same order as their prefixes,
+	// and in sorted order within each prefix
+	first := 0
+	for _, key := range keys {
+		for _, id := range prefixIDs[key] {
+			put(id)
+		}
+		mass := 0
+		for _, freq := range c.chain[key] {
+			mass += freq
+		}
+		put(uint32(first))
+		put(uint32(len(c.chain[key])))
+		put(uint32(mass))
+		first += len(c.chain[key])
+	}
+	for _, key := range keys {
+		suffixes := make([]string, 0, len(c.chain[key]))
+		for s := range c.chain[key] {
+			suffixes = append(suffixes, s)
+		}
+		sort.Strings(suffixes)
+		for _, s := range suffixes {
+			put(ids[s])
+			put(uint32(c.chain[key][s]))
+		}
+	}
+	return bw.Flush()
+}
+
+// SaveFrozen saves the chain to the given file in the frozen chain
+// format read by OpenFrozen.
+func (c *Chain) SaveFrozen(filename string) error {
+	return saveAtomic(filename, c.WriteFrozen)
+}
+
+// frozenPrefix returns the word IDs of a prefix, padded at the start
+// with noWord to the given prefix length. It returns nil if any word
+// has no ID.
+func frozenPrefix(prefixLen int, words []string, ids map[string]uint32) []uint32 {
+	prefix := make([]uint32, prefixLen)
+	pad := prefixLen - len(words)
+	for i := range prefix {
+		if i < pad {
+			prefix[i] = noWord
+			continue
+		}
+		id, ok := ids[words[i-pad]]
+		if !ok {
+			return nil
+		}
+		prefix[i] = id
+	}
+	return prefix
+}
+
+// comparePrefixes compares two prefixes of word IDs lexicographically.
+func comparePrefixes(a, b []uint32) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// OpenFrozen opens a chain saved by SaveFrozen, memory-mapping it
+// where the operating system allows and reading it into memory
+// otherwise. The FrozenChain must be closed when no longer needed.
+func OpenFrozen(filename string) (*FrozenChain, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, unmap, err := mapFile(f)
+	if err != nil {
+		return nil, err
+	}
+	fc, err := newFrozenChain(data)
+	if err != nil {
+		unmap(data)
+		return nil, err
+	}
+	fc.unmap = unmap
+	return fc, nil
+}
+
+// errBadFrozen is returned when opening a file that isn't a valid
+// frozen chain.
+var errBadFrozen error = &corruptError{msg: "markov: not a valid frozen chain file"}
+
+// newFrozenChain checks the header and tables of frozen chain data,
+// and returns a FrozenChain reading from it.
+func newFrozenChain(data []byte) (*FrozenChain, error) {
+	if len(data) < frozenHeaderLen || string(data[:len(frozenMagic)]) != frozenMagic {
+		return nil, errBadFrozen
+	}
+	header := func(i int) int {
+		return int(binary.LittleEndian.Uint32(data[len(frozenMagic)+4*i:]))
+	}
+	fc := &FrozenChain{
+		data:            data,
+		prefixLen:       header(0),
+		sentenceMarkers: header(1)&frozenSentenceMarkers != 0,
+		preserveCase:    header(1)&frozenPreserveCase != 0,
+		words:           header(2),
+		prefixes:        header(3),
+	}
+	suffixes, blobLen := header(4), header(5)
+	// Check each table fits before computing the next offset, so that
+	// huge counts can't overflow
+	if fc.prefixLen < 1 || fc.prefixLen > len(data) || fc.words < 0 || fc.prefixes < 0 || suffixes < 0 || blobLen < 0 {
+		return nil, errBadFrozen
+	}
+	fc.wordOffsets = frozenHeaderLen
+	if fc.words >= (len(data)-fc.wordOffsets)/4 {
+		return nil, errBadFrozen
+	}
+	fc.blob = fc.wordOffsets + 4*(fc.words+1)
+	if blobLen > len(data)-fc.blob {
+		return nil, errBadFrozen
+	}
+	fc.prefixTable = fc.blob + (blobLen+3)/4*4
+	entryLen := 4 * (fc.prefixLen + 3)
+	if fc.prefixTable > len(data) || fc.prefixes > (len(data)-fc.prefixTable)/entryLen {
+		return nil, errBadFrozen
+	}
+	fc.suffixTable = fc.prefixTable + entryLen*fc.prefixes
+	if suffixes > (len(data)-fc.suffixTable)/8 || fc.suffixTable+8*suffixes != len(data) {
+		return nil, errBadFrozen
+	}
+	if !fc.valid(blobLen, suffixes) {
+		return nil, errBadFrozen
+	}
+	return fc, nil
+}
+
+// valid reports whether the frozen chain's word offsets, word IDs and
+// suffix ranges all lie within their tables, so that reading the chain
+// can't go out of bounds.
+func (fc *FrozenChain) valid(blobLen, suffixes int) bool {
+	prev := uint32(0)
+	for i := 0; i <= fc.words; i++ {
+		offset := fc.u32(fc.wordOffsets + 4*i)
+		if offset < prev || int64(offset) > int64(blobLen) {
+			return false
+		}
+		prev = offset
+	}
+	entryLen := 4 * (fc.prefixLen + 3)
+	for i := 0; i < fc.prefixes; i++ {
+		entry := fc.prefixTable + i*entryLen
+		for j := 0; j < fc.prefixLen; j++ {
+			if id := fc.u32(entry + 4*j); id != noWord && int64(id) >= int64(fc.words) {
+				return false
+			}
+		}
+		first := int64(fc.u32(entry + 4*fc.prefixLen))
+		count := int64(fc.u32(entry + 4*fc.prefixLen + 4))
+		if first+count > int64(suffixes) {
+			return false
+		}
+	}
+	for i := 0; i < suffixes; i++ {
+		if int64(fc.u32(fc.suffixTable+8*i)) >= int64(fc.words) {
+			return false
+		}
+	}
+	return true
+}
+
+// Close releases the frozen chain's memory. The chain must not be
+// used afterwards.
+func (fc *FrozenChain) Close() error {
+	if fc.unmap == nil {
+		return nil
+	}
+	err := fc.unmap(fc.data)
+	fc.data, fc.unmap = nil, nil
+	return err
+}
+
+// Size returns the number of prefixes in the frozen chain.
+func (fc *FrozenChain) Size() int {
+	return fc.prefixes
+}
+
+// u32 returns the uint32 at the given offset.
+func (fc *FrozenChain) u32(offset int) uint32 {
+	return binary.LittleEndian.Uint32(fc.data[offset:])
+}
+
+// word returns the word with the given ID.
+func (fc *FrozenChain) word(id uint32) string {
+	start := fc.u32(fc.wordOffsets + 4*int(id))
+	end := fc.u32(fc.wordOffsets + 4*int(id) + 4)
+	return string(fc.data[fc.blob+int(start) : fc.blob+int(end)])
+}
+
+// wordID returns the ID of a word, and false if the chain doesn't know
+// it.
+func (fc *FrozenChain) wordID(w string) (uint32, bool) {
+	i := sort.Search(fc.words, func(i int) bool {
+		return fc.word(uint32(i)) >= w
+	})
+	if i < fc.words && fc.word(uint32(i)) == w {
+		return uint32(i), true
+	}
+	return 0, false
+}
+
+// prefix returns the offset of the entry in the prefix table for the
+// given prefix of word IDs, and false if there is none.
+func (fc *FrozenChain) prefix(ids []uint32) (int, bool) {
+	entryLen := 4 * (fc.prefixLen + 3)
+	entryIDs := func(i int) []uint32 {
+		entry := make([]uint32, fc.prefixLen)
+		for j := range entry {
+			entry[j] = fc.u32(fc.prefixTable + i*entryLen + 4*j)
+		}
+		return entry
+	}
+	i := sort.Search(fc.prefixes, func(i int) bool {
+		return comparePrefixes(entryIDs(i), ids) >= 0
+	})
+	if i < fc.prefixes && comparePrefixes(entryIDs(i), ids) == 0 {
+		return fc.prefixTable + i*entryLen, true
+	}
+	return 0, false
+}
+
+// NextWord randomly chooses a word to follow the given prefix, as
+// Chain.NextWord does.
+func (fc *FrozenChain) NextWord(p Prefix) string {
+	return fc.nextWord(p).word
+}
+
+// nextWord implements NextWord, additionally returning the details of
+// the choice.
+func (fc *FrozenChain) nextWord(p Prefix) step {
+	for i := 0; i <= fc.prefixLen; i++ {
+		ids := make([]uint32, fc.prefixLen)
+		known := true
+		for j := range ids {
+			if j < i {
+				ids[j] = noWord
+				continue
+			}
+			id, ok := fc.wordID(p[j])
+			if !ok {
+				known = false
+				break
+			}
+			ids[j] = id
+		}
+		if !known {
+			continue
+		}
+		entry, ok := fc.prefix(ids)
+		if !ok {
+			continue
+		}
+
+		first := int(fc.u32(entry + 4*fc.prefixLen))
+		count := int(fc.u32(entry + 4*fc.prefixLen + 4))
+		mass := int(fc.u32(entry + 4*fc.prefixLen + 8))
+		if mass == 0 {
+			continue
+		}
+		n := rand.Intn(mass)
+		for j := 0; j < count; j++ {
+			suffix := fc.suffixTable + 8*(first+j)
+			freq := int(fc.u32(suffix + 4))
+			n -= freq
+			if n < 0 || j == count-1 {
+				result := fc.word(fc.u32(suffix))
+				if i == fc.prefixLen {
+					result = fc.caser().fixCase(p, result)
+				}
+				return step{
+					word:       result,
+					level:      fc.prefixLen - i,
+					mass:       mass,
+					prob:       float64(freq) / float64(mass),
+					key:        strings.Join(p[i:], " "),
+					candidates: count,
+				}
+			}
+		}
+	}
+	return step{}
+}
+
+// caser returns the frozen chain's caser. Learned case isn't frozen,
+// so a chain frozen with RestoreCase generates as with FoldCase.
+func (fc *FrozenChain) caser() caser {
+	if fc.preserveCase {
+		return caser{folding: PreserveCase}
+	}
+	return caser{folding: FoldCase}
+}
+
+// Generate generates text as Chain.Generate does.
+func (fc *FrozenChain) Generate(start string, sentences, maxWords int) string {
+	g := generateFrom(fc.prefixLen, fc.sentenceMarkers, fc.caser(), fc.nextWord, start, sentences, maxWords)
+	return strings.Join(g.words, " ")
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewFrozenChainCorrupt(t *testing.T) {
+	c := NewChain(2)
+	c.Build(strings.NewReader("the cat sat on the mat"))
+	var buf bytes.Buffer
+	if err := c.WriteFrozen(&buf); err != nil {
+		t.Fatal(err)
+	}
+	good := buf.Bytes()
+	fc, err := newFrozenChain(good)
+	if err != nil {
+		t.Fatalf("newFrozenChain of a written chain = %v", err)
+	}
+	fc.NextWord(NewPrefix(2))
+
+	// set returns a copy of the good data with the uint32 at the
+	// given offset replaced
+	set := func(offset int, v uint32) []byte {
+		data := append([]byte(nil), good...)
+		binary.LittleEndian.PutUint32(data[offset:], v)
+		return data
+	}
+	header := func(i int) int { return len(frozenMagic) + 4*i }
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated", good[:len(good)-1]},
+		{"huge word count", set(header(2), ^uint32(0))},
+		{"huge prefix count", set(header(3), ^uint32(0))},
+		{"huge prefix length", set(header(0), ^uint32(0))},
+		{"offset past blob", set(fc.wordOffsets+4, 1<<20)},
+		{"offsets descending", set(fc.wordOffsets+4*fc.words, 0)},
+		{"prefix word ID", set(fc.prefixTable, uint32(fc.words))},
+		{"suffix range", set(fc.prefixTable+4*fc.prefixLen+4, 1000)},
+		{"suffix word ID", set(fc.suffixTable, uint32(fc.words))},
+		{"last suffix word ID", set(len(good)-8, uint32(fc.words))},
+	}
+	for _, test := range tests {
+		_, err := newFrozenChain(test.data)
+		if !errors.Is(err, ErrCorruptModel) {
+			t.Errorf("newFrozenChain with %s = %v, want ErrCorruptModel", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// fuzzy.go optionally matches prefix words a Chain doesn't know with
+// similarly spelled ones it does, so that a typo in a seed ("teh
+// weathr") still leads somewhere topical instead of to whatever the
+// chain says with no context at all.
+
+package markov
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// maxFuzzyMatches is the most known words an unknown prefix word is
+// matched with.
+const maxFuzzyMatches = 5
+
+// SetFuzzy sets the most edits (see stringutil.EditDistance) by which
+// generation may match a prefix word the chain doesn't know with one
+// it does, or turns fuzzy matching off if maxEdits is 0. With fuzzy
+// matching on, when generation finds no suffixes for a prefix
+// containing unknown words (nor, with stemming on, for its stems), it
+// tries the suffixes of the prefixes with each unknown word replaced
+// by the nearest known ones, after trying synonyms (see SetThesaurus)
+// and before trying skip-grams (see SetSkipGrams) or backing off to a
+// shorter prefix. Words no longer than maxEdits+1 letters aren't
+// matched, as they'd match too much.
+// Finding the nearest words means looking through all the chain's
+// prefixes, but only happens while unknown words (usually from the
+// seed) are in the prefix.
+func (c *Chain) SetFuzzy(maxEdits int) {
+	if maxEdits < 0 {
+		maxEdits = 0
+	}
+	c.fuzzy = maxEdits
+}
+
+// Fuzzy returns the most edits by which generation may match unknown
+// prefix words, or 0 if fuzzy matching is off.
+func (c *Chain) Fuzzy() int {
+	return c.fuzzy
+}
+
+// substitutedSuffixes returns the combined suffixes of the prefixes
+// made by replacing each unknown word of a prefix key with its
+// alternatives, or nil if there are none.
+func (c *Chain) substitutedSuffixes(key string, alternatives func(w string) []string) map[string]int {
+	if key == "" {
+		return nil
+	}
+	words := strings.Split(key, " ")
+	choices := make([][]string, len(words))
+	unknown := false
+	for i, w := range words {
+		if c.chain[w] != nil {
+			choices[i] = []string{w}
+			continue
+		}
+		if choices[i] = alternatives(w); choices[i] == nil {
+			return nil
+		}
+		unknown = true
+	}
+	if !unknown {
+		return nil
+	}
+
+	var suffixes map[string]int
+	var visit func(i int, prefix []string)
+	visit = func(i int, prefix []string) {
+		if i == len(choices) {
+			for s, freq := range c.chain[strings.Join(prefix, " ")] {
+				if suffixes == nil {
+					suffixes = make(map[string]int)
+				}
+				suffixes[s] += freq
+			}
+			return
+		}
+		for _, w := range choices[i] {
+			visit(i+1, append(prefix, w))
+		}
+	}
+	visit(0, make([]string, 0, len(words)))
+	return suffixes
+}
+
+// nearestWords returns the known prefix words fewest edits from w,
+// within the chain's limit, most common first, or nil if there are
+// none.
+func (c *Chain) nearestWords(w string) []string {
+	n := utf8.RuneCountInString(w)
+	if n <= c.fuzzy+1 {
+		return nil
+	}
+	best := c.fuzzy + 1
+	var nearest []string
+	for key := range c.chain {
+		if key == "" || strings.Contains(key, " ") {
+			continue
+		}
+		if diff := utf8.RuneCountInString(key) - n; diff > c.fuzzy || -diff > c.fuzzy {
+			continue
+		}
+		d := stringutil.EditDistance(w, key)
+		if d < best {
+			best, nearest = d, nearest[:0]
+		}
+		if d == best {
+			nearest = append(nearest, key)
+		}
+	}
+	mass := func(key string) int {
+		total := 0
+		for _, freq := range c.chain[key] {
+			total += freq
+		}
+		return total
+	}
+	sort.Slice(nearest, func(i, j int) bool {
+		mi, mj := mass(nearest[i]), mass(nearest[j])
+		if mi != mj {
+			return mi > mj
+		}
+		return nearest[i] < nearest[j]
+	})
+	if len(nearest) > maxFuzzyMatches {
+		nearest = nearest[:maxFuzzyMatches]
+	}
+	return nearest
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// header.go frames saved chain files with a header giving the format
+// version and the length and checksum of what follows, so that a
+// truncated or corrupted file is rejected as such, instead of failing
+// somewhere in the JSON or, worse, loading part of a chain.
+
+package markov
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// headerMagic is how every chain file with a header starts.
+var headerMagic = []byte("CLYDEMKV")
+
+// formatVersion is the version of the file format written by Save and
+// SaveCompact. Files with a newer version are refused, so that future
+// formats can be migrated rather than misread.
+const formatVersion = 1
+
+// headerSize is the length of a header: the magic, then the format
+// version, the length of the data, and its CRC-32 (IEEE), big-endian.
+var headerSize = len(headerMagic) + 4 + 8 + 4
+
+// saveModel saves the output of the given encoding function to the
+// named file as in saveWith, preceded by a header.
+func saveModel(filename string, encode func(w io.Writer) error) error {
+	return saveWith(filename, func(w io.Writer) error {
+		var buf bytes.Buffer
+		if err := encode(&buf); err != nil {
+			return err
+		}
+		header := make([]byte, 0, headerSize)
+		header = append(header, headerMagic...)
+		header = binary.BigEndian.AppendUint32(header, formatVersion)
+		header = binary.BigEndian.AppendUint64(header, uint64(buf.Len()))
+		header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(buf.Bytes()))
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		_, err := buf.WriteTo(w)
+		return err
+	})
+}
+
+// hasHeader reports whether buffered input starts with a header,
+// without consuming any of it.
+func hasHeader(br *bufio.Reader) bool {
+	magic, _ := br.Peek(len(headerMagic))
+	return bytes.Equal(magic, headerMagic)
+}
+
+// readHeader reads a header and the data it describes from buffered
+// input, checks the data against it, and returns a reader for the
+// data.
+func readHeader(br *bufio.Reader) (*bufio.Reader, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, corruptf("markov: truncated chain file header")
+	}
+	header = header[len(headerMagic):]
+	version := binary.BigEndian.Uint32(header)
+	length := binary.BigEndian.Uint64(header[4:])
+	sum := binary.BigEndian.Uint32(header[12:])
+	if version > formatVersion {
+		return nil, fmt.Errorf("markov: chain file format version %d is newer than supported version %d", version, formatVersion)
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(br, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(n) < length {
+		return nil, corruptf("markov: truncated chain file (%d of %d bytes)", n, length)
+	}
+	if crc32.ChecksumIEEE(buf.Bytes()) != sum {
+		return nil, corruptf("markov: corrupted chain file (checksum mismatch)")
+	}
+	return bufio.NewReader(&buf), nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// html.go trains a Chain on the visible text of HTML documents, so
+// that web pages and HTML email archives can be used as corpora
+// directly.
+
+package markov
+
+import (
+	"io"
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// BuildHTML reads an HTML document from the provided Reader and adds
+// its visible text to the chain as Build would (see
+// stringutil.HTMLText), with each paragraph, heading, list item, and
+// other block as a separate block of text, so that text doesn't run
+// on from one into the next. It returns the number of words added,
+// and any error reading the document, in which case nothing is added.
+func (c *Chain) BuildHTML(r io.Reader, opts ...BuildOption) (int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	words := 0
+	for _, p := range stringutil.HTMLText(string(data)) {
+		// Reading a string can't fail
+		n, _ := c.build(strings.NewReader(p), opts)
+		words += n
+	}
+	return words, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// import.go brings in state from other Markov chain implementations:
+// the original clyde.pl's brain, and a plain tab-separated table of
+// suffix counts that anything else can be dumped to.
+
+package markov
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ImportTable reads suffix counts from the provided Reader and adds
+// them to the chain. Each line of input has three tab-separated
+// fields: the prefix (zero or more space-separated words), the
+// suffix, and its count, e.g.
+//
+//	i am	a	3
+//
+// Blank lines and lines starting with "#" are ignored. As with Add,
+// the count is added after every tail of the prefix, so a table
+// listing only full-length prefixes imports correctly. Prefixes longer
+// than the chain's prefix length are shortened to their last words;
+// shorter ones only contribute the tails they have. ImportTable
+// returns the number of entries imported; on a malformed line, it
+// returns an error naming the line, and the entries before it remain
+// imported.
+func (c *Chain) ImportTable(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	imported := 0
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 3 || fields[1] == "" {
+			return imported, fmt.Errorf("markov: line %d: expected prefix, suffix, and count separated by tabs", line)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil || count < 1 {
+			return imported, fmt.Errorf("markov: line %d: bad count %q", line, fields[2])
+		}
+
+		words := strings.Fields(fields[0])
+		if len(words) > c.prefixLen {
+			words = words[len(words)-c.prefixLen:]
+		}
+		c.AddWeighted(c.wordsPrefix(words, c.prefixLen), fields[1], count)
+		imported++
+	}
+	return imported, scanner.Err()
+}
+
+// ImportLegacy reads the brain saved by the original clyde.pl (the
+// chain.json that purge.py edits) from the provided Reader and adds
+// its counts to the chain. The brain is a JSON object mapping each prefix (zero or more space-separated
+// words, with "START" and "END" as this package uses them) to an
+// object mapping each suffix to its count; like a Chain, it already
+// holds every tail of each prefix, so each count is added after its
+// own prefix alone. Prefixes longer than the chain's prefix length
+// are skipped, as their tails are in the brain too; a brain with a
+// shorter prefix length imports, but the chain only backs off to what
+// it has. ImportLegacy returns the number of entries imported, or an
+// error if the brain is malformed, in which case nothing is imported.
+func (c *Chain) ImportLegacy(r io.Reader) (int, error) {
+	var brain map[string]map[string]int
+	if err := json.NewDecoder(r).Decode(&brain); err != nil {
+		return 0, jsonError(err)
+	}
+	for key, suffixes := range brain {
+		for s, count := range suffixes {
+			if count < 1 {
+				return 0, corruptf("markov: corrupt brain: bad count %d for %q after %q", count, s, key)
+			}
+		}
+	}
+	imported := 0
+	for key, suffixes := range brain {
+		words := strings.Fields(key)
+		if len(words) > c.prefixLen {
+			continue
+		}
+		key = strings.Join(words, " ")
+		for s, count := range suffixes {
+			c.addKey(key, s, count)
+			imported++
+		}
+	}
+	return imported, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportTable(t *testing.T) {
+	c := NewChain(2)
+	n, err := c.ImportTable(strings.NewReader("# comment\nSTART\thello\t2\nSTART hello\tthere\t1\n"))
+	if err != nil || n != 2 {
+		t.Fatalf("ImportTable = %d, %v; want 2, nil", n, err)
+	}
+	want := map[string]map[string]int{
+		"":            {"hello": 2, "there": 1},
+		"START":       {"hello": 2},
+		"hello":       {"there": 1},
+		"START hello": {"there": 1},
+	}
+	if !reflect.DeepEqual(c.chain, want) {
+		t.Errorf("ImportTable chain = %v, want %v", c.chain, want)
+	}
+}
+
+func TestImportLegacy(t *testing.T) {
+	tests := []struct {
+		brain   string
+		want    map[string]map[string]int
+		wantErr error
+	}{
+		{
+			brain: `{"": {"hi": 1, "END": 1}, "START": {"hi": 1}, "hi": {"END": 1}, "START hi": {"END": 1}}`,
+			want: map[string]map[string]int{
+				"":         {"hi": 1, "END": 1},
+				"START":    {"hi": 1},
+				"hi":       {"END": 1},
+				"START hi": {"END": 1},
+			},
+		},
+		{
+			// A longer prefix length's extra prefixes are skipped
+			brain: `{"a b c": {"d": 1}, "b c": {"d": 1}}`,
+			want:  map[string]map[string]int{"b c": {"d": 1}},
+		},
+		{brain: `{"a": {"b": 0}}`, wantErr: ErrCorruptModel},
+		{brain: `{"a": [1]}`, wantErr: ErrCorruptModel},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		_, err := c.ImportLegacy(strings.NewReader(test.brain))
+		if !errors.Is(err, test.wantErr) || (err == nil) != (test.wantErr == nil) {
+			t.Errorf("ImportLegacy(%s) error = %v, want %v", test.brain, err, test.wantErr)
+			continue
+		}
+		if test.wantErr == nil && !reflect.DeepEqual(c.chain, test.want) {
+			t.Errorf("ImportLegacy(%s) chain = %v, want %v", test.brain, c.chain, test.want)
+		}
+		if test.wantErr != nil && c.Size() != 0 {
+			t.Errorf("ImportLegacy(%s) imported %d prefixes despite error", test.brain, c.Size())
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// inspect.go lets tools look at what a Chain has learned.
+
+package markov
+
+import (
+	"sort"
+	"strings"
+)
+
+// Vocabulary returns every word the chain has learned as a suffix, in
+// sorted order, not including End.
+func (c *Chain) Vocabulary() []string {
+	seen := make(map[string]bool)
+	for _, suffixes := range c.chain {
+		for s := range suffixes {
+			seen[s] = true
+		}
+	}
+	delete(seen, End)
+
+	words := make([]string, 0, len(seen))
+	for w := range seen {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// PrefixLen returns the number of words in the chain's prefixes.
+func (c *Chain) PrefixLen() int {
+	return c.prefixLen
+}
+
+// LongestPrefix returns the number of words in the longest prefix the
+// chain has learned. That's its prefix length, unless it has learned
+// too little to fill a whole prefix, or its counts were decoded into a
+// chain with a different prefix length than they were learned with;
+// check it before trusting a chain from elsewhere.
+func (c *Chain) LongestPrefix() int {
+	longest := 0
+	for key := range c.chain {
+		if key == "" {
+			continue
+		}
+		if n := strings.Count(key, " ") + 1; n > longest {
+			longest = n
+		}
+	}
+	return longest
+}
+
+// Ready reports whether the chain has learned at least minTokens words
+// (counting repeats), i.e. enough to generate more than the odd word
+// or two it happens to know. A chain with a fallback (see SetFallback)
+// is also ready if its fallback is.
+func (c *Chain) Ready(minTokens int) bool {
+	if c.fallback != nil && c.fallback.Ready(minTokens) {
+		return true
+	}
+	n := 0
+	for s, freq := range c.chain[""] {
+		if s != End {
+			n += freq
+		}
+	}
+	return n >= minTokens
+}
+
+// Prefixes calls f with each prefix the chain has learned, as a string
+// of zero to prefixLen lowercase words joined with spaces, in no
+// particular order, until f returns false. The chain must not be
+// modified until Prefixes returns.
+func (c *Chain) Prefixes(f func(prefix string) bool) {
+	for key := range c.chain {
+		if !f(key) {
+			return
+		}
+	}
+}
+
+// Suffixes returns a copy of the map of suffixes to frequencies for
+// the given prefix, which is normalized the way the chain stores
+// prefixes, leaving "START" as it is. It returns nil if the chain
+// doesn't know the prefix.
+func (c *Chain) Suffixes(prefix string) map[string]int {
+	suffixes := c.chain[c.prefixKey(prefix)]
+	if suffixes == nil {
+		return nil
+	}
+	result := make(map[string]int, len(suffixes))
+	for s, freq := range suffixes {
+		result[s] = freq
+	}
+	return result
+}
+
+// prefixKey returns the key the chain stores a space-separated prefix
+// under.
+func (c *Chain) prefixKey(prefix string) string {
+	words := strings.Fields(prefix)
+	return strings.Join(c.wordsPrefix(words, len(words)), " ")
+}
+
+// wordsPrefix returns a Prefix of n words ending with the given words,
+// normalized the way the chain stores prefixes, but leaving "START"
+// as it is rather than lowercasing it like a word.
+func (c *Chain) wordsPrefix(words []string, n int) Prefix {
+	p := make(Prefix, n)
+	for _, w := range words {
+		if w == "START" {
+			copy(p, p[1:])
+			p[len(p)-1] = w
+			continue
+		}
+		c.shift(p, w)
+	}
+	return p
+}
+
+// A WordProb is a possible next word and its probability.
+type WordProb struct {
+	Word string
+	Prob float64
+	// Level is the length of the prefix tail the probability is
+	// conditioned on.
+	Level int
+}
+
+// Distribution returns the probability distribution from which
+// NextWord would choose a word to follow the given prefix, most
+// likely words first, taking the chain's allowlist, blend,
+// temperature, and context limit into account (but not its
+// fallback). As in NextWord, the distribution is that of the longest
+// tail of the prefix with any allowed suffixes, and End is among the
+// possible words. It returns nil if there are no words to choose
+// from.
+func (c *Chain) Distribution(p Prefix) []WordProb {
+	for i := c.skip; i <= c.prefixLen; i++ {
+		key := strings.Join(p[i:], " ")
+		if c.chain[key] == nil {
+			continue
+		}
+
+		var weights map[string]float64
+		var total float64
+		if c.blend != nil {
+			weights, total = c.blendWeights(key)
+		} else {
+			weights = make(map[string]float64)
+			for s, freq := range c.chain[key] {
+				if c.allowed(s) {
+					weights[s] = float64(freq)
+					total += float64(freq)
+				}
+			}
+		}
+		if c.temperature != 0 {
+			total = c.temper(weights)
+		}
+		if total == 0 {
+			continue
+		}
+
+		dist := make([]WordProb, 0, len(weights))
+		for s, w := range weights {
+			dist = append(dist, WordProb{Word: s, Prob: w / total, Level: c.prefixLen - i})
+		}
+		sort.Slice(dist, func(a, b int) bool {
+			if dist[a].Prob != dist[b].Prob {
+				return dist[a].Prob > dist[b].Prob
+			}
+			return dist[a].Word < dist[b].Word
+		})
+		return dist
+	}
+	return nil
+}
+
+// Rough per-entry costs of the chain's maps in bytes, for
+// MemoryEstimate: a string header plus a value, plus Go's map
+// overhead per entry.
+const (
+	mapEntryOverhead = 24
+	stringHeader     = 16
+)
+
+// MemoryEstimate returns a rough estimate of the bytes of memory the
+// chain's learned data takes up, including its tagged sub-chains (see
+// Tag), to help explain a learning bot's memory growth. It counts the
+// words themselves and the maps holding them, not memory shared with
+// other chains or the Go runtime's own slack.
+func (c *Chain) MemoryEstimate() int {
+	total := 0
+	for key, suffixes := range c.chain {
+		total += stringHeader + len(key) + 8 + mapEntryOverhead
+		for s := range suffixes {
+			total += stringHeader + len(s) + 8 + mapEntryOverhead
+		}
+	}
+	for key := range c.updated {
+		total += stringHeader + len(key) + 8 + mapEntryOverhead
+	}
+	total += len(c.sentences) * (8 + 1 + mapEntryOverhead)
+	for _, t := range c.tags {
+		total += t.MemoryEstimate()
+	}
+	return total
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSuffixes(t *testing.T) {
+	c := NewChain(2)
+	c.Build(strings.NewReader("Hello there"))
+	tests := []struct {
+		prefix string
+		want   map[string]int
+	}{
+		{"", map[string]int{"Hello": 1, "there": 1, End: 1}},
+		{"START", map[string]int{"Hello": 1}},
+		{"START  Hello", map[string]int{"there": 1}},
+		{"hello THERE", map[string]int{End: 1}},
+		{"start", nil},
+		{"nowhere", nil},
+	}
+	for _, test := range tests {
+		if got := c.Suffixes(test.prefix); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Suffixes(%q) = %v, want %v", test.prefix, got, test.want)
+		}
+	}
+}
+
+func TestLongestPrefix(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"one", 2},
+		{"one two three", 2},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		c.Build(strings.NewReader(test.text))
+		if got := c.LongestPrefix(); got != test.want {
+			t.Errorf("LongestPrefix after %q = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
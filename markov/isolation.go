@@ -0,0 +1,119 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// isolation.go lets a ChainSet keep text learned from private traffic
+// (direct messages, private channels) apart from public traffic. The
+// set routes private text to chains of its own, or nowhere, and a
+// public chain refuses to fall back on or merge in a private one, so
+// nothing said in private can turn up in a public reply however the
+// chains are used.
+
+package markov
+
+import (
+	"errors"
+	"strings"
+)
+
+// Isolation says how a ChainSet routes private traffic (see
+// ChainSet.Route).
+type Isolation int
+
+const (
+	// Shared routes private traffic to the same chains as public
+	// traffic.
+	Shared Isolation = iota
+	// SeparatePrivate routes private traffic to private chains of
+	// its own, which are never used for public traffic.
+	SeparatePrivate
+	// PublicOnly doesn't route private traffic anywhere, so it isn't
+	// learned at all.
+	PublicOnly
+)
+
+// privatePrefix starts the names private chains are kept under in a
+// ChainSet, and saved under.
+const privatePrefix = "private/"
+
+// ErrPrivate is returned when merging a private chain into a public
+// one.
+var ErrPrivate = errors.New("markov: can't merge a private chain into a public one")
+
+// Private reports whether the chain holds text learned from private
+// traffic (see ChainSet.Route).
+func (c *Chain) Private() bool {
+	return c.private
+}
+
+// SetIsolation sets how the set routes private traffic. The default is
+// Shared.
+func (s *ChainSet) SetIsolation(mode Isolation) {
+	s.isolation = mode
+}
+
+// Isolation returns how the set routes private traffic.
+func (s *ChainSet) Isolation() Isolation {
+	return s.isolation
+}
+
+// Route returns the chain to learn text from the named chain's
+// traffic with, and to generate replies to it with, creating an empty
+// one if there is none. Private traffic is routed according to the
+// set's isolation mode: for SeparatePrivate, to a private chain kept
+// under the same name, and for PublicOnly, nowhere; Route returns nil.
+// All learning and generation on behalf of traffic should go through
+// Route, which is what keeps private traffic isolated.
+func (s *ChainSet) Route(name string, private bool) *Chain {
+	key, ok := s.routeKey(name, private)
+	if !ok {
+		return nil
+	}
+	return s.Chain(key)
+}
+
+// Lookup is like Route, but returns nil instead of creating a chain.
+func (s *ChainSet) Lookup(name string, private bool) *Chain {
+	key, ok := s.routeKey(name, private)
+	if !ok {
+		return nil
+	}
+	return s.chains[key]
+}
+
+// Put adds an existing chain to the set as the one Route returns for
+// the name and kind of traffic, replacing any, e.g. to route to a
+// chain that's saved elsewhere. For private traffic, it does nothing
+// unless the set's isolation mode is SeparatePrivate, as otherwise
+// private traffic has no chains of its own.
+func (s *ChainSet) Put(name string, private bool, c *Chain) {
+	key, ok := s.routeKey(name, private)
+	if !ok || private && s.isolation != SeparatePrivate {
+		return
+	}
+	c.private = isPrivateKey(key)
+	s.chains[key] = c
+}
+
+// routeKey returns the key of the chain that Route returns, and false
+// if it doesn't return one.
+func (s *ChainSet) routeKey(name string, private bool) (string, bool) {
+	if isPrivateKey(name) {
+		// Nothing is routed to a private chain by its key
+		return "", false
+	}
+	if !private || s.isolation == Shared {
+		return name, true
+	}
+	if s.isolation == PublicOnly {
+		return "", false
+	}
+	return privatePrefix + name, true
+}
+
+// isPrivateKey reports whether a chain kept under the given key in a
+// ChainSet is private.
+func isPrivateKey(key string) bool {
+	return strings.HasPrefix(key, privatePrefix)
+}
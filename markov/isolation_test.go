@@ -0,0 +1,114 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		mode    Isolation
+		private bool
+		want    string // key of the chain routed to, or "" for none
+	}{
+		{Shared, false, "general"},
+		{Shared, true, "general"},
+		{SeparatePrivate, false, "general"},
+		{SeparatePrivate, true, "private/general"},
+		{PublicOnly, false, "general"},
+		{PublicOnly, true, ""},
+	}
+	for _, test := range tests {
+		s := NewChainSet(2)
+		s.SetIsolation(test.mode)
+		c := s.Route("general", test.private)
+		switch {
+		case test.want == "" && c != nil:
+			t.Errorf("mode %d: Route(private=%v) = %v, want nil", test.mode, test.private, s.Names())
+		case test.want != "" && c != s.Get(test.want):
+			t.Errorf("mode %d: Route(private=%v) created %v, want %q", test.mode, test.private, s.Names(), test.want)
+		case c != nil && c.Private() != (test.want != "general"):
+			t.Errorf("mode %d: Route(private=%v).Private() = %v", test.mode, test.private, c.Private())
+		}
+		if got := s.Lookup("general", test.private); got != c {
+			t.Errorf("mode %d: Lookup(private=%v) = %p, want %p", test.mode, test.private, got, c)
+		}
+	}
+}
+
+func TestRouteByPrivateKey(t *testing.T) {
+	s := NewChainSet(2)
+	s.SetIsolation(SeparatePrivate)
+	private := s.Route("general", true)
+	for _, p := range []bool{false, true} {
+		if c := s.Route("private/general", p); c != nil {
+			t.Errorf("Route(%q, %v) = %p, want nil (private chain is %p)", "private/general", p, c, private)
+		}
+	}
+}
+
+// TestIsolation checks that text learned from private traffic never
+// turns up in public output, however the chains are combined.
+func TestIsolation(t *testing.T) {
+	s := NewChainSet(2)
+	s.SetIsolation(SeparatePrivate)
+	s.Route("general", false).Build(strings.NewReader("the weather is nice today"))
+	s.Route("general", true).Build(strings.NewReader("my password is hunter2"))
+
+	public := s.Route("general", false)
+	public.SetFallback(s.Route("general", true), 1)
+	if err := public.Merge(s.Route("general", true)); !errors.Is(err, ErrPrivate) {
+		t.Errorf("Merge(private) = %v, want ErrPrivate", err)
+	}
+	for i := 0; i < 100; i++ {
+		if got := public.Generate("my password is", 1, 10); strings.Contains(got, "hunter2") {
+			t.Fatalf("public chain generated %q", got)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "isolation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := s.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewChainSet(2)
+	loaded.SetIsolation(SeparatePrivate)
+	if err := loaded.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if c := loaded.Lookup("general", true); c == nil || !c.Private() {
+		t.Errorf("loaded private chain = %v, want a private chain", c)
+	}
+	if c := loaded.Lookup("general", false); c == nil || c.Private() {
+		t.Errorf("loaded public chain = %v, want a public chain", c)
+	}
+}
+
+func TestPut(t *testing.T) {
+	for _, mode := range []Isolation{Shared, SeparatePrivate, PublicOnly} {
+		s := NewChainSet(2)
+		s.SetIsolation(mode)
+		public, private := NewChain(2), NewChain(2)
+		s.Put("main", false, public)
+		s.Put("main", true, private)
+		if got := s.Lookup("main", false); got != public {
+			t.Errorf("mode %d: public chain replaced", mode)
+		}
+		if mode == SeparatePrivate && (s.Lookup("main", true) != private || !private.Private()) {
+			t.Errorf("mode %d: private chain not routed to", mode)
+		}
+		if public.Private() {
+			t.Errorf("mode %d: public chain marked private", mode)
+		}
+	}
+}
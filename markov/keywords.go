@@ -0,0 +1,77 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// keywords.go picks out what a piece of text is about, by comparing
+// it against everything a Chain has learned.
+
+package markov
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// keywordTrim is the punctuation trimmed from words before they're
+// considered as keywords.
+const keywordTrim = ".,;:!?\"'()[]{}<>*_"
+
+// Keywords returns the distinct words of text that the chain knows,
+// most distinctive first, lowercased and stripped of surrounding
+// punctuation. Words are scored by tf-idf: a word is more distinctive
+// the more often it appears in text and the less often the chain saw
+// it in training. Stopwords (see stringutil.IsStopword) are never
+// keywords.
+func (c *Chain) Keywords(text string) []string {
+	total := 0
+	for _, n := range c.chain[""] {
+		total += n
+	}
+
+	tf := make(map[string]int)
+	var words []string
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		w = strings.Trim(w, keywordTrim)
+		if w == "" || stringutil.IsStopword(w) {
+			continue
+		}
+		if tf[w] == 0 {
+			words = append(words, w)
+		}
+		tf[w]++
+	}
+
+	scores := make(map[string]float64)
+	var keywords []string
+	for _, w := range words {
+		// Every occurrence of a word in training added a suffix
+		// following it
+		count := 0
+		for _, n := range c.chain[w] {
+			count += n
+		}
+		if count == 0 {
+			continue
+		}
+		scores[w] = float64(tf[w]) * math.Log(float64(total+1)/float64(count))
+		keywords = append(keywords, w)
+	}
+	sort.SliceStable(keywords, func(i, j int) bool {
+		return scores[keywords[i]] > scores[keywords[j]]
+	})
+	return keywords
+}
+
+// Keyword returns the most distinctive word of text (see Keywords), or
+// "" if there isn't one, e.g. to seed a reply on the same topic.
+func (c *Chain) Keyword(text string) string {
+	keywords := c.Keywords(text)
+	if len(keywords) == 0 {
+		return ""
+	}
+	return keywords[0]
+}
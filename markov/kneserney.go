@@ -0,0 +1,148 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// kneserney.go implements interpolated Kneser-Ney smoothing, which
+// judges a word's chances after a short prefix by how many different
+// contexts it has followed rather than how often it occurred, so that
+// e.g. "Francisco", which only ever follows "San", isn't offered
+// wherever the chain has to back off.
+
+package markov
+
+import "strings"
+
+// SetKneserNey turns on interpolated Kneser-Ney smoothing with the
+// given discount, which must be strictly between 0 and 1 (0.75 is
+// typical), or turns it off for any other discount, such as 0. Each
+// observed count is reduced by the discount, and the probability freed
+// up is given to the shorter tails of the prefix, which count each
+// suffix once per distinct word it has followed them after (its
+// continuation count) instead of once per occurrence. Score and
+// Perplexity use the smoothed probabilities in place of
+// SetSmoothing's. Generation samples from them too, except that it
+// backs off to a shorter tail at most once per word, and not for the
+// first word of a text: on a corpus where most prefixes were seen
+// once, backing off again would leave most words to the continuation
+// counts of single words, which string together into nonsense. The
+// chain keeps an index of continuation counts, built from the counts
+// when smoothing is turned on and kept up to date as text is added and
+// removed.
+func (c *Chain) SetKneserNey(discount float64) {
+	if discount <= 0 || discount >= 1 {
+		discount = 0
+	}
+	c.discount = discount
+	c.continuations = nil
+	if discount == 0 {
+		return
+	}
+	c.continuations = make(map[string]map[string]int)
+	for key, suffixes := range c.chain {
+		if key == "" {
+			continue
+		}
+		for s := range suffixes {
+			c.addContinuation(key, s)
+		}
+	}
+}
+
+// KneserNey returns the chain's Kneser-Ney discount, or 0 if it
+// doesn't use Kneser-Ney smoothing.
+func (c *Chain) KneserNey() float64 {
+	return c.discount
+}
+
+// addContinuation counts a suffix newly seen after a prefix key as
+// having followed the key's shorter tail in one more context.
+func (c *Chain) addContinuation(key, s string) {
+	shorter := shorterKey(key)
+	if c.continuations[shorter] == nil {
+		c.continuations[shorter] = make(map[string]int)
+	}
+	c.continuations[shorter][s]++
+}
+
+// shorterKey returns a prefix key without its first word.
+func shorterKey(key string) string {
+	if i := strings.IndexByte(key, ' '); i >= 0 {
+		return key[i+1:]
+	}
+	return ""
+}
+
+// knCounts returns the counts Kneser-Ney smoothing uses for the tail
+// of a prefix starting at word i, where first is the index of the
+// longest tail in use: the observed suffix counts for the longest
+// tail, or for one with no longer context (at the start of input),
+// and continuation counts for the others.
+func (c *Chain) knCounts(p Prefix, i, first int, key string) map[string]int {
+	if i > first && p[i-1] != "" {
+		if cont := c.continuations[key]; cont != nil {
+			return cont
+		}
+	}
+	return c.chain[key]
+}
+
+// knBackOff reports whether generation should back off from a tail
+// with the given counts to a shorter one, as it does with the share
+// of probability Kneser-Ney smoothing frees up by discounting.
+func (c *Chain) knBackOff(counts map[string]int) bool {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return c.float64()*float64(total) < c.discount*float64(len(counts))
+}
+
+// chooseKneserNey makes a random choice from a tail's counts (see
+// knCounts), each reduced by the discount, returning the choice and
+// the probability with which it was chosen. If discounting leaves
+// nothing to choose from, it chooses from the counts as they are.
+func (c *Chain) chooseKneserNey(counts map[string]int) (string, float64) {
+	weights := make(map[string]float64, len(counts))
+	mass := 0.0
+	for s, n := range counts {
+		if c.allowed(s) && float64(n) > c.discount {
+			weights[s] = float64(n) - c.discount
+			mass += weights[s]
+		}
+	}
+	if mass == 0 {
+		for s, n := range counts {
+			if c.allowed(s) && n > 0 {
+				weights[s] = float64(n)
+			}
+		}
+	}
+	return c.chooseWeighted(weights)
+}
+
+// knWordProb implements wordProb with Kneser-Ney smoothing.
+func (c *Chain) knWordProb(p Prefix, w string) float64 {
+	prob := 1.0 / float64(len(c.chain[""])+1)
+	for i := c.prefixLen; i >= 0; i-- {
+		if i < c.prefixLen && p[i] == "" {
+			continue
+		}
+		key := strings.Join(p[i:], " ")
+		counts := c.knCounts(p, i, 0, key)
+		if counts == nil {
+			continue
+		}
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		discounted := float64(counts[w]) - c.discount
+		if discounted < 0 {
+			discounted = 0
+		}
+		freed := c.discount * float64(len(counts))
+		prob = (discounted + freed*prob) / float64(total)
+	}
+	return prob
+}
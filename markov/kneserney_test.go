@@ -0,0 +1,87 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// singletonCorpus returns sentences almost none of whose two-word
+// prefixes repeat with the same suffix.
+func singletonCorpus() string {
+	r := rand.New(rand.NewSource(1))
+	adjectives := strings.Fields("big small red blue old young quiet loud happy sad")
+	nouns := strings.Fields("cat dog bird fish horse mouse fox owl bear wolf")
+	verbs := strings.Fields("sees chases likes hears finds follows meets feeds greets watches")
+	var b strings.Builder
+	for i := 0; i < 60; i++ {
+		fmt.Fprintf(&b, "the %s %s %s a %s %s.\n",
+			adjectives[r.Intn(10)], nouns[r.Intn(10)], verbs[r.Intn(10)], adjectives[r.Intn(10)], nouns[r.Intn(10)])
+	}
+	return b.String()
+}
+
+func TestSetKneserNey(t *testing.T) {
+	tests := []struct {
+		discount, want float64
+	}{
+		{0, 0},
+		{0.75, 0.75},
+		{-0.5, 0},
+		{1, 0},
+		{1.5, 0},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		c.SetKneserNey(test.discount)
+		if got := c.KneserNey(); got != test.want {
+			t.Errorf("SetKneserNey(%v): KneserNey() = %v, want %v", test.discount, got, test.want)
+		}
+	}
+}
+
+func TestKneserNeyGenerates(t *testing.T) {
+	text := singletonCorpus()
+	seen := make(map[string]bool)
+	words := strings.Fields(text)
+	for i := 1; i < len(words); i++ {
+		seen[words[i-1]+" "+words[i]] = true
+	}
+	tests := []float64{0.25, 0.5, 0.75, 0.9}
+	for _, discount := range tests {
+		c := NewChain(2)
+		c.Build(strings.NewReader(text))
+		c.SetKneserNey(discount)
+		for i := 0; i < 300; i++ {
+			r := c.GenerateResult("", 1, 20)
+			if r.Text == "" {
+				t.Errorf("SetKneserNey(%v): GenerateResult(\"\", 1, 20) is empty", discount)
+				break
+			}
+			// Backing off once keeps to word pairs from the
+			// corpus
+			words := strings.Fields(r.Text)
+			for j := 1; j < len(words); j++ {
+				if pair := words[j-1] + " " + words[j]; !seen[pair] {
+					t.Errorf("SetKneserNey(%v): GenerateResult(\"\", 1, 20) = %q, with %q not in the corpus", discount, r.Text, pair)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestChooseKneserNeyUndiscounted(t *testing.T) {
+	// A discount of 1 would leave nothing of counts of 1
+	c := NewChain(2)
+	c.discount = 1
+	if got, _ := c.chooseKneserNey(map[string]int{"cat": 1, "dog": 1}); got == "" {
+		t.Errorf("chooseKneserNey with no discounted mass = %q, want a word", got)
+	}
+}
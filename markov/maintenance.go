@@ -0,0 +1,210 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// maintenance.go defines bulk operations for keeping a long-lived
+// Chain's size and content under control.
+
+package markov
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Prune forgets every suffix seen fewer than minCount times after a
+// prefix, and every prefix left with no suffixes. It returns the
+// number of prefixes forgotten.
+func (c *Chain) Prune(minCount int) int {
+	for _, sub := range c.tags {
+		sub.Prune(minCount)
+	}
+	c.dirty = true
+	pruned := 0
+	for key, suffixes := range c.chain {
+		for s, freq := range suffixes {
+			if freq < minCount {
+				c.removeKey(key, s, freq)
+			}
+		}
+		if c.chain[key] == nil {
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// Decay scales every suffix count in the chain by factor (which
+// should be between 0 and 1), rounding down and forgetting suffixes
+// and prefixes whose counts drop to zero, so that old training
+// gradually matters less than new training. It returns the number of
+// prefixes decayed.
+func (c *Chain) Decay(factor float64) int {
+	return c.decayWhere(factor, func(string) bool { return true })
+}
+
+// decayWhere implements Decay for the prefixes for which stale
+// returns true.
+func (c *Chain) decayWhere(factor float64, stale func(key string) bool) int {
+	for _, sub := range c.tags {
+		sub.decayWhere(factor, stale)
+	}
+	c.dirty = true
+	decayed := 0
+	for key, suffixes := range c.chain {
+		if !stale(key) {
+			continue
+		}
+		decayed++
+		for s, freq := range suffixes {
+			c.removeKey(key, s, freq-int(float64(freq)*factor))
+		}
+	}
+	return decayed
+}
+
+// Merge adds all of the suffix counts from another chain, including
+// its tagged sub-corpora, into this one. The chains must use the same
+// prefix length, and a private chain can't be merged into a public one
+// (see Chain.Private).
+func (c *Chain) Merge(other *Chain) error {
+	if other.prefixLen != c.prefixLen {
+		return fmt.Errorf("markov: can't merge chain with prefix length %d into chain with prefix length %d", other.prefixLen, c.prefixLen)
+	}
+	if other.private && !c.private {
+		return ErrPrivate
+	}
+	c.dirty = true
+	for key, suffixes := range other.chain {
+		for s, freq := range suffixes {
+			c.addKey(key, s, freq)
+		}
+	}
+	for tag, sub := range other.tags {
+		c.tagChain(tag).Merge(sub)
+	}
+	return nil
+}
+
+// Remove reads text from the provided Reader and subtracts the counts
+// that Build would have added for it, forgetting suffixes and prefixes
+// whose counts drop to zero, so that a document can be untrained. It
+// returns the number of prefixes forgotten. Counts are never taken
+// below zero, so removing text that was never trained (or was since
+// pruned or decayed) is harmless, but it may also remove counts that
+// other, identical text contributed. The text is removed from every
+// tagged sub-corpus too (see Tag). Hashes recorded by TrackSentences
+// are kept, so the removed text still can't be quoted back verbatim.
+// It also returns the first error reading the text, other than
+// io.EOF, in which case only what was read before it is removed.
+func (c *Chain) Remove(r io.Reader) (int, error) {
+	forgotten := 0
+	err := c.walk(r, func(p Prefix, s string) {
+		for _, sub := range c.tags {
+			sub.subtract(p, s)
+		}
+		forgotten += c.subtract(p, s)
+	})
+	return forgotten, err
+}
+
+// subtract implements Remove for a single suffix, returning the number
+// of prefixes forgotten.
+func (c *Chain) subtract(p Prefix, s string) int {
+	c.dirty = true
+	forgotten := 0
+	for i := 0; i <= c.prefixLen; i++ {
+		if i < c.prefixLen && p[i] == "" {
+			continue
+		}
+		key := strings.Join(p[i:], " ")
+		if c.chain[key] == nil {
+			continue
+		}
+		c.removeKey(key, s, 1)
+		if c.chain[key] == nil {
+			forgotten++
+		}
+	}
+	return forgotten
+}
+
+// Clone returns a deep copy of the chain, including its settings and
+// tagged sub-corpora, which can be used and modified independently of
+// the original; e.g. a snapshot to restore if bulk training goes
+// wrong, or a stable copy to generate from while training continues.
+// A fallback chain (see SetFallback) is shared, not copied.
+func (c *Chain) Clone() *Chain {
+	clone := &Chain{
+		chain:           make(map[string]map[string]int, len(c.chain)),
+		prefixLen:       c.prefixLen,
+		updateBucket:    c.updateBucket,
+		sentenceMarkers: c.sentenceMarkers,
+		urls:            c.urls,
+		mentions:        c.mentions,
+		caseFolding:     c.caseFolding,
+		fallback:        c.fallback,
+		fallbackWeight:  c.fallbackWeight,
+		temperature:     c.temperature,
+		smoothing:       c.smoothing,
+		fuzzy:           c.fuzzy,
+		thesaurus:       c.thesaurus,
+		skip:            c.skip,
+		private:         c.private,
+		dirty:           true,
+	}
+	for key, suffixes := range c.chain {
+		clone.chain[key] = make(map[string]int, len(suffixes))
+		for s, freq := range suffixes {
+			clone.chain[key][s] = freq
+		}
+	}
+	if c.allowlist != nil {
+		clone.allowlist = make(map[string]bool, len(c.allowlist))
+		for w := range c.allowlist {
+			clone.allowlist[w] = true
+		}
+	}
+	if c.updated != nil {
+		clone.updated = make(map[string]int64, len(c.updated))
+		for key, t := range c.updated {
+			clone.updated[key] = t
+		}
+	}
+	if c.cases != nil {
+		clone.cases = make(map[string]map[string]int, len(c.cases))
+		for key, forms := range c.cases {
+			clone.cases[key] = make(map[string]int, len(forms))
+			for form, n := range forms {
+				clone.cases[key][form] = n
+			}
+		}
+	}
+	if c.sentences != nil {
+		clone.sentences = make(map[uint64]bool, len(c.sentences))
+		for h := range c.sentences {
+			clone.sentences[h] = true
+		}
+	}
+	if c.trained != nil {
+		clone.trained = make(map[uint64]bool, len(c.trained))
+		for h := range c.trained {
+			clone.trained[h] = true
+		}
+	}
+	for tag, sub := range c.tags {
+		clone.tagChain(tag).chain = sub.Clone().chain
+	}
+	if c.blend != nil {
+		clone.blend = make(map[string]float64, len(c.blend))
+		for tag, w := range c.blend {
+			clone.blend[tag] = w
+		}
+	}
+	clone.SetStemming(c.stemLang)
+	clone.SetSkipGrams(c.skips != nil)
+	clone.SetKneserNey(c.discount)
+	return clone
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// indexedChain returns a chain with every index on, trained on text.
+func indexedChain(text string) *Chain {
+	c := NewChain(2)
+	c.SetStemming("en")
+	c.SetSkipGrams(true)
+	c.SetKneserNey(0.75)
+	c.Build(strings.NewReader(text))
+	return c
+}
+
+// checkIndexes reports any difference between a chain's indexes and
+// those rebuilt from its counts.
+func checkIndexes(t *testing.T, op string, c *Chain) {
+	t.Helper()
+	want := c.Clone()
+	if !reflect.DeepEqual(c.stems, want.stems) {
+		t.Errorf("after %s, stems = %v, want %v", op, c.stems, want.stems)
+	}
+	if !reflect.DeepEqual(c.skips, want.skips) {
+		t.Errorf("after %s, skips = %v, want %v", op, c.skips, want.skips)
+	}
+	if !reflect.DeepEqual(c.continuations, want.continuations) {
+		t.Errorf("after %s, continuations = %v, want %v", op, c.continuations, want.continuations)
+	}
+}
+
+func TestIndexesMaintained(t *testing.T) {
+	const text = "my password is hunter2. my passwords are secret. the cat sat on the mat. the cat sat on the mat."
+	tests := []struct {
+		op string
+		do func(c *Chain)
+	}{
+		{"Remove", func(c *Chain) { c.Remove(strings.NewReader("my password is hunter2.")) }},
+		{"Prune", func(c *Chain) { c.Prune(2) }},
+		{"Decay", func(c *Chain) { c.Decay(0.5) }},
+		{"DecayStale", func(c *Chain) { c.DecayStale(time.Now().Add(time.Hour), 0.5) }},
+		{"Merge", func(c *Chain) { c.Merge(indexedChain("my password is swordfish.")) }},
+		{"BuildParallel", func(c *Chain) {
+			c.BuildParallel([]io.Reader{strings.NewReader("my password is swordfish."), strings.NewReader("a dog sat.")}, 2)
+		}},
+	}
+	for _, test := range tests {
+		c := indexedChain(text)
+		test.do(c)
+		checkIndexes(t, test.op, c)
+	}
+}
+
+func TestRemoveStemmed(t *testing.T) {
+	c := indexedChain("my password is hunter2.")
+	c.Remove(strings.NewReader("my password is hunter2."))
+	// Without the stem and skip-gram indexes forgetting it too, the
+	// stems of "passwords is" would still lead to "hunter2"
+	for _, seed := range []string{"my password is", "my passwords is", "your password is"} {
+		if got := c.Generate(seed, 1, 10); strings.Contains(got, "hunter2") {
+			t.Errorf("Generate(%q) = %q after removing it", seed, got)
+		}
+	}
+}
+
+func TestBuildParallel(t *testing.T) {
+	texts := []string{
+		"the cat sat on the mat.",
+		"the dog sat on the log.",
+		"The cat ate the dog's dinner.",
+		"a cat and a dog walked into a bar.",
+	}
+	for _, workers := range []int{1, 2, 4} {
+		want := indexedChain("")
+		want.SetCaseFolding(RestoreCase)
+		got := indexedChain("")
+		got.SetCaseFolding(RestoreCase)
+		var readers []io.Reader
+		for _, text := range texts {
+			want.Build(strings.NewReader(text))
+			readers = append(readers, strings.NewReader(text))
+		}
+		got.BuildParallel(readers, workers)
+		if !reflect.DeepEqual(got.chain, want.chain) {
+			t.Errorf("BuildParallel with %d workers built %v, want %v", workers, got.chain, want.chain)
+		}
+		if !reflect.DeepEqual(got.cases, want.cases) {
+			t.Errorf("BuildParallel with %d workers learned cases %v, want %v", workers, got.cases, want.cases)
+		}
+		checkIndexes(t, "BuildParallel", got)
+	}
+}
+
+func TestRemoveSkipGrams(t *testing.T) {
+	c := NewChain(2)
+	c.SetSkipGrams(true)
+	c.Build(strings.NewReader("my password is hunter2."))
+	c.Build(strings.NewReader("the cat sat."))
+	// "password was" was never seen, but its skip-gram "password *"
+	// was, followed by "hunter2"
+	if got := c.skipped("password was"); got["hunter2."] == 0 {
+		t.Fatalf("skipped(%q) = %v before removing, want hunter2.", "password was", got)
+	}
+	c.Remove(strings.NewReader("my password is hunter2."))
+	if got := c.skipped("password was"); got != nil {
+		t.Errorf("skipped(%q) = %v after removing, want nil", "password was", got)
+	}
+	for i := 0; i < 20; i++ {
+		if got := c.Generate("password was", 1, 10); strings.Contains(got, "hunter2") {
+			t.Errorf("Generate(%q) = %q after removing it", "password was", got)
+			break
+		}
+	}
+}
@@ -51,6 +51,7 @@ import (
 	"strings"
 	"encoding/json"
 	"os"
+	"time"
 	"github.com/sdukhovni/clyde-go/stringutil"
 )
 
@@ -60,6 +61,12 @@ import (
 // input/output text.
 type Prefix []string
 
+// End is the suffix recorded after the last word of each block of
+// input text (and of each sentence, when a Chain is built with
+// sentence markers; see Chain.SetSentenceMarkers), so that the chain
+// learns where text tends to stop.
+const End = "END"
+
 // NewPrefix creates a new Prefix ending with the "START" symbol.
 func NewPrefix(prefixLen int) (Prefix) {
 	p := make([]string, prefixLen)
@@ -79,87 +86,407 @@ func (p Prefix) Shift(word string) {
 type Chain struct {
 	chain     map[string]map[string]int
 	prefixLen int
-	stats []int
+	allowlist map[string]bool
+	banned map[string]bool // only set on copies made by GenerateWith
+	rng *rand.Rand // likewise
+	updated map[string]int64
+	updateBucket time.Duration
+	sentenceMarkers bool
+	urls URLPolicy
+	mentions MentionPolicy
+	caseFolding CaseFolding
+	cases map[string]map[string]int
+	stemLang string
+	stems map[string]map[string]int
+	skips map[string]map[string]int // skip-gram counts
+	fuzzy int // most edits to match unknown prefix words by
+	thesaurus Thesaurus
+	sentences map[uint64]bool
+	trained map[uint64]bool
+	tags map[string]*Chain
+	blend map[string]float64
+	fallback *Chain
+	fallbackWeight float64
+	temperature float64
+	smoothing float64
+	discount float64 // for Kneser-Ney smoothing
+	continuations map[string]map[string]int
+	skip int // leading prefix words to ignore when generating
+	private bool // learned from private traffic; see ChainSet.Route
+	dirty bool // changed since last loaded or saved
 }
 
 // NewChain returns a new Chain with prefixes of prefixLen words.
 func NewChain(prefixLen int) *Chain {
-	return &Chain{make(map[string]map[string]int), prefixLen, make([]int, prefixLen+1)}
+	return &Chain{
+		chain:     make(map[string]map[string]int),
+		prefixLen: prefixLen,
+		dirty:     true,
+	}
 }
 
 // Add increments the frequency count for a suffix following each
 // distinct tail of a prefix
 func (c *Chain) Add(p Prefix, s string) {
+	c.AddWeighted(p, s, 1)
+}
+
+// AddWeighted is like Add, but counts the suffix weight times, so that
+// text from an important source can outweigh a larger body of less
+// important text.
+func (c *Chain) AddWeighted(p Prefix, s string, weight int) {
 	for i := 0; i <= c.prefixLen; i++ {
 		if i < c.prefixLen && p[i] == "" {
 			continue
 		}
-		key := strings.Join(p[i:], " ")
-		if c.chain[key] == nil {
-			c.chain[key] = make(map[string]int)
+		c.addKey(strings.Join(p[i:], " "), s, weight)
+	}
+}
+
+// addKey counts a suffix weight times after a single prefix key,
+// keeping the chain's indexes up to date.
+func (c *Chain) addKey(key, s string, weight int) {
+	if c.chain[key] == nil {
+		c.chain[key] = make(map[string]int)
+	}
+	if c.continuations != nil && key != "" && c.chain[key][s] == 0 {
+		c.addContinuation(key, s)
+	}
+	c.chain[key][s] += weight
+	c.touch(key)
+	if c.stems != nil {
+		c.addStemmed(key, s, weight)
+	}
+	if c.skips != nil {
+		c.addSkipGrams(key, s, weight)
+	}
+	c.dirty = true
+}
+
+// removeKey uncounts a suffix n times (at most as many as it was
+// counted) after a single prefix key, forgetting the suffix when its
+// count reaches zero, and the key once it has no suffixes left,
+// keeping the chain's indexes up to date, so that nothing forgotten
+// can still be generated through them.
+func (c *Chain) removeKey(key, s string, n int) {
+	suffixes := c.chain[key]
+	if n > suffixes[s] {
+		n = suffixes[s]
+	}
+	if n <= 0 {
+		return
+	}
+	c.dirty = true
+	suffixes[s] -= n
+	if c.stems != nil {
+		uncount(c.stems, c.stemKey(key), s, n)
+	}
+	if c.skips != nil {
+		for _, skipped := range skipKeys(key) {
+			uncount(c.skips, skipped, s, n)
+		}
+	}
+	if suffixes[s] > 0 {
+		return
+	}
+	delete(suffixes, s)
+	if c.continuations != nil && key != "" {
+		uncount(c.continuations, shorterKey(key), s, 1)
+	}
+	if len(suffixes) == 0 {
+		delete(c.chain, key)
+		delete(c.updated, key)
+	}
+}
+
+// uncount subtracts n from an index's count of a suffix under a key,
+// forgetting the suffix, and the key, as they reach zero.
+func uncount(index map[string]map[string]int, key, s string, n int) {
+	counts := index[key]
+	if counts == nil {
+		return
+	}
+	if counts[s] -= n; counts[s] <= 0 {
+		delete(counts, s)
+	}
+	if len(counts) == 0 {
+		delete(index, key)
+	}
+}
+
+// reindex rebuilds the chain's indexes (see SetStemming, SetSkipGrams,
+// and SetKneserNey), and the case of its words for RestoreCase, from
+// its counts, for after they're replaced wholesale.
+func (c *Chain) reindex() {
+	c.SetStemming(c.stemLang)
+	c.SetSkipGrams(c.skips != nil)
+	c.SetKneserNey(c.discount)
+	if c.caseFolding == RestoreCase {
+		c.learnCases()
+	}
+}
+
+// SetSentenceMarkers sets whether Build should treat every sentence
+// boundary it detects as the end of a block of input: when enabled,
+// the End symbol is added after the last word of each sentence, and
+// the following sentence is added starting from a fresh "START"
+// prefix. Otherwise End is only added after the last word of the
+// input.
+func (c *Chain) SetSentenceMarkers(on bool) {
+	c.sentenceMarkers = on
+}
+
+// A BuildOption modifies how Build adds text to a chain.
+type BuildOption func(*buildOptions)
+
+type buildOptions struct {
+	weight int
+	tag    string
+	dedup  dedupMode
+	progress *progress
+}
+
+// Weight returns a BuildOption that counts every word of the text
+// weight times (see AddWeighted). Weights less than 1 are treated as 1.
+func Weight(weight int) BuildOption {
+	return func(o *buildOptions) {
+		if weight > 1 {
+			o.weight = weight
 		}
-		c.chain[key][s]++
 	}
 }
 
 // Build reads text from the provided Reader and
-// parses it into prefixes and suffixes that are stored in Chain.
-func (c *Chain) Build(r io.Reader) {
+// parses it into prefixes and suffixes that are stored in Chain,
+// returning the number of words added, which leaves out any skipped
+// as already trained on (see DedupLines), and the first error reading
+// the text, other than io.EOF. What was read before an error stays
+// learned.
+func (c *Chain) Build(r io.Reader, opts ...BuildOption) (int, error) {
+	return c.build(r, opts)
+}
+
+// build implements Build.
+func (c *Chain) build(r io.Reader, opts []BuildOption) (int, error) {
+	o := buildOptions{weight: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var counter *countingReader
+	if o.progress != nil {
+		counter = &countingReader{r: r}
+		r = counter
+	}
+	if o.dedup != noDedup {
+		r = c.dedup(r, o.dedup)
+	}
+	var tagged *Chain
+	if o.tag != "" {
+		tagged = c.tagChain(o.tag)
+	}
+	var sentence []string
+	words := 0
+	// report adds the progress since it was last called
+	var reported BuildProgress
+	report := func() {
+		if o.progress != nil {
+			o.progress.add(words-reported.Words, counter.n-reported.Bytes)
+			reported = BuildProgress{Words: words, Bytes: counter.n}
+		}
+	}
+	err := c.walk(r, func(p Prefix, s string) {
+		c.AddWeighted(p, s, o.weight)
+		c.learnCase(p, s)
+		if tagged != nil {
+			tagged.AddWeighted(p, s, o.weight)
+		}
+		if s == End {
+			return
+		}
+		words++
+		if words%progressInterval == 0 {
+			report()
+		}
+		if c.sentences == nil {
+			return
+		}
+		sentence = append(sentence, s)
+		if stringutil.EndsSentence(s) {
+			c.recordSentence(sentence)
+			sentence = sentence[:0]
+		}
+	})
+	c.recordSentence(sentence)
+	report()
+	return words, err
+}
+
+// walk reads text from the provided Reader and calls visit with each
+// prefix and suffix that Build would add to the chain, including End.
+// It returns the first error reading the text, other than io.EOF, and
+// ends the text there.
+func (c *Chain) walk(r io.Reader, visit func(p Prefix, s string)) error {
 	br := bufio.NewReader(r)
 	p := NewPrefix(c.prefixLen)
-	for {
+	inSentence := false
+	var err error
+	for first := true; ; first = false {
 		var s string
-		if _, err := fmt.Fscan(br, &s); err != nil {
+		if _, err = fmt.Fscan(br, &s); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
 			break
 		}
-		c.Add(p, s)
-		p.Shift(s)
+		if s = c.token(s, first); s == "" {
+			continue
+		}
+		visit(p, s)
+		c.shift(p, s)
+		inSentence = true
+		if c.sentenceMarkers && stringutil.EndsSentence(s) {
+			visit(p, End)
+			p = NewPrefix(c.prefixLen)
+			inSentence = false
+		}
 	}
+	if inSentence {
+		visit(p, End)
+	}
+	return err
 }
 
 // NextWord randomly chooses a word to follow the given prefix, using
-// the weights provided by Chain.
+// the weights provided by Chain. It returns End if the chain chooses
+// to stop, or "" if it has no words to choose from.
 func (c *Chain) NextWord(p Prefix) string {
+	return c.nextWord(p).word
+}
+
+// step records a single choice made by nextWord.
+type step struct {
+	word  string
+	level int     // length of the prefix tail used
+	mass  int     // total frequency count of that tail's suffixes
+	prob  float64 // probability with which the word was chosen
+	// key is the prefix tail used, and candidates the number of
+	// suffixes it has, for GenerateTrace
+	key        string
+	candidates int
+}
+
+// nextWord implements NextWord, additionally returning the details of
+// the choice. The word is "" if there was nothing to choose from.
+func (c *Chain) nextWord(p Prefix) step {
+	if c.fallback != nil {
+		return c.nextWordFallback(p)
+	}
+	return c.ownNextWord(p)
+}
+
+// ownNextWord implements nextWord using only this chain's counts.
+func (c *Chain) ownNextWord(p Prefix) step {
 	// Try each tail of the prefix, starting with the longest
-	for i := 0; i <= c.prefixLen; i++ {
+	knBackedOff := false
+	for i := c.skip; i <= c.prefixLen; i++ {
 		key := strings.Join(p[i:], " ")
-		if c.chain[key] == nil {
-			continue
+		suffixes := c.chain[key]
+		inexact := false // suffixes aren't the prefix's own
+		if suffixes == nil && c.stems != nil {
+			// Try the prefixes with the same stems (see
+			// SetStemming)
+			suffixes, inexact = c.stems[c.stemKey(key)], true
 		}
-
-		c.stats[c.prefixLen-i]++
-
-		// Make a random choice weighted by frequency
-		total := 0
-		for _, freq := range c.chain[key] {
-			total += freq
+		if suffixes == nil && c.thesaurus != nil {
+			// Try the prefixes with synonyms for unknown words
+			// (see SetThesaurus)
+			suffixes, inexact = c.substitutedSuffixes(key, c.knownSynonyms), true
+		}
+		if suffixes == nil && c.fuzzy > 0 {
+			// Try the prefixes with unknown words respelled
+			// (see SetFuzzy)
+			suffixes, inexact = c.substitutedSuffixes(key, c.nearestWords), true
+		}
+		if suffixes == nil && c.skips != nil {
+			// Try the prefixes differing in one word (see
+			// SetSkipGrams)
+			suffixes, inexact = c.skipped(key), true
 		}
-		if total == 0 {
+		if suffixes == nil {
 			continue
 		}
-		n := rand.Intn(total)
+
+		mass := 0
+		for _, freq := range suffixes {
+			mass += freq
+		}
+
 		var result string
-		for w, freq := range c.chain[key] {
-			n -= freq
-			if n <= 0 {
-				result = w
-				break
+		var prob float64
+		if c.discount > 0 && !inexact {
+			// Back off with the probability discounting frees
+			// up (see SetKneserNey), but only once, and not
+			// before the first word
+			counts := c.knCounts(p, i, c.skip, key)
+			if key != "" && !knBackedOff && p[c.prefixLen-1] != "START" && c.knBackOff(counts) {
+				knBackedOff = true
+				continue
 			}
+			result, prob = c.chooseKneserNey(counts)
+		} else if key != "" && c.smoothBackOff(mass) {
+			// Leave the share smoothing reserves for unseen
+			// suffixes to the shorter tails (see SetSmoothing)
+			continue
+		} else if c.blend != nil && !inexact {
+			result, prob = c.chooseBlended(key)
+		} else if c.temperature != 0 || c.rng != nil {
+			// chooseTempered chooses in a fixed order, so a
+			// seeded source (see Rand) gives the same text
+			result, prob = c.chooseTempered(suffixes)
+		} else {
+			var total int
+			result, total = c.choose(suffixes)
+			prob = float64(suffixes[result]) / float64(total)
 		}
+		if result == "" {
+			continue
+		}
+
+		level := c.prefixLen - i
 
-		// If we're making an uninformed choice because we
-		// don't recognize the tail word, at least try to get
-		// capitalization right.
 		if key == "" {
-			if stringutil.IsEndOfSentence(p[c.prefixLen-1]) {
-				result = stringutil.Capitalize(result)
-			} else {
-				result = strings.ToLower(result)
-			}
+			result = c.caser().fixCase(p, result)
+		}
+		return step{word: result, level: level, mass: mass, prob: prob, key: key, candidates: len(suffixes)}
+	}
+	return step{}
+}
+
+// choose makes a random choice from a map of suffixes to
+// frequencies, weighted by frequency and restricted to allowed
+// words, returning the choice and the total frequency of the allowed
+// words. The choice is "" if no words are allowed.
+func (c *Chain) choose(suffixes map[string]int) (string, int) {
+	total := 0
+	for w, freq := range suffixes {
+		if c.allowed(w) {
+			total += freq
+		}
+	}
+	if total == 0 {
+		return "", 0
+	}
+	n := c.intn(total)
+	for w, freq := range suffixes {
+		if !c.allowed(w) {
+			continue
+		}
+		n -= freq
+		if n <= 0 {
+			return w, total
 		}
-		return result
 	}
-	return ""
+	return "", 0
 }
 
 // Generate returns a string of at most maxWords words (in addition to
@@ -167,36 +494,175 @@ func (c *Chain) NextWord(p Prefix) string {
 // to generate exactly the requested number of sentences, but may
 // generate fewer if the chain doesn't produce enough
 // sentence-endings, or may generate a single sentence fragment if the
-// chain produces no sentence endings within the word limit.
+// chain produces no sentence endings within the word limit. If the
+// chain produces the End symbol, the text is considered finished and
+// generation stops, unless the chain has sentence markers, in which
+// case only the current sentence is finished and any further
+// sentences are started afresh.
 func (c *Chain) Generate(start string, sentences, maxWords int) string {
+	text, _ := c.GenerateConfidence(start, sentences, maxWords)
+	return text
+}
+
+// confidenceMass is the average suffix count mass at which the
+// chain's counts are considered to contribute half of full
+// confidence.
+const confidenceMass = 4.0
+
+// GenerateConfidence is like Generate, but also returns a confidence
+// value between 0 and 1 for the generated text. Confidence is high
+// when words were chosen using full-length prefixes with plenty of
+// training data behind them, and low when the chain had to back off
+// to shorter prefixes or had only seen a prefix once or twice; it is
+// 0 if no words were generated.
+func (c *Chain) GenerateConfidence(start string, sentences, maxWords int) (string, float64) {
+	g := c.generate(start, sentences, maxWords)
+	return strings.Join(g.words, " "), g.confidence(c.prefixLen)
+}
+
+// confidence returns the confidence in a generation from a chain with
+// the given prefix length, as GenerateConfidence describes.
+func (g generation) confidence(prefixLen int) float64 {
+	if len(g.steps) == 0 {
+		return 0
+	}
+	levelSum, massSum := 0, 0
+	for _, st := range g.steps {
+		levelSum += st.level
+		massSum += st.mass
+	}
+	depth := float64(levelSum) / float64(len(g.steps)*prefixLen)
+	mass := float64(massSum) / float64(len(g.steps))
+	return depth * mass / (mass + confidenceMass)
+}
+
+// generation is the outcome of generate.
+type generation struct {
+	words []string // the seed words followed by the generated words
+	seed  int      // the number of seed words
+	steps []step   // every choice made, including End
+	// pos records, for each step, the number of words in the text
+	// after it was taken
+	pos []int
+	// truncated is set if trailing words were dropped to end the
+	// text on a sentence boundary
+	truncated bool
+	// sentences is the number of sentences completed, and capped is
+	// set if generation stopped at the word limit
+	sentences int
+	capped    bool
+}
+
+// generate implements GenerateConfidence and the other generation
+// functions that need the details of each choice.
+func (c *Chain) generate(start string, sentences, maxWords int) generation {
+	return generateFrom(c.prefixLen, c.sentenceMarkers, c.caser(), c.nextWord, start, sentences, maxWords)
+}
+
+// generateFrom implements generate for any source of words with the
+// given prefix length, which chooses words with next and cases them
+// with cs.
+func generateFrom(prefixLen int, sentenceMarkers bool, cs caser, next func(Prefix) step, start string, sentences, maxWords int) generation {
+	gen := newGenerator(prefixLen, sentenceMarkers, cs, next, start, sentences, maxWords)
+	for {
+		if _, ok := gen.advance(); !ok {
+			break
+		}
+	}
+	return gen.finish(true)
+}
+
+// A generator generates text a step at a time, for generateFrom and
+// Stream.
+type generator struct {
+	prefixLen       int
+	sentenceMarkers bool
+	cs              caser
+	next            func(Prefix) step
+	sentences       int
+	maxWords        int
+
+	p                Prefix
+	g                generation
+	sentenceCount    int
+	sentenceEndIndex int
+	done             bool
+}
+
+// newGenerator returns a generator continuing start, with the same
+// arguments as generateFrom.
+func newGenerator(prefixLen int, sentenceMarkers bool, cs caser, next func(Prefix) step, start string, sentences, maxWords int) *generator {
 	words := strings.Fields(start)
-	p := NewPrefix(c.prefixLen)
-	lastWordsStart := len(words) - c.prefixLen
+	p := NewPrefix(prefixLen)
+	lastWordsStart := len(words) - prefixLen
 	if lastWordsStart < 0 {
 		lastWordsStart = 0
 	}
-	for _,w := range words[lastWordsStart:] {
-		p.Shift(w)
+	for _, w := range words[lastWordsStart:] {
+		cs.shift(p, w)
 	}
+	return &generator{
+		prefixLen:       prefixLen,
+		sentenceMarkers: sentenceMarkers,
+		cs:              cs,
+		next:            next,
+		sentences:       sentences,
+		maxWords:        maxWords,
+		p:               p,
+		g:               generation{words: words, seed: len(words)},
+	}
+}
 
-	sentenceCount := 0
-	sentenceEndIndex := 0
-	for i := 0; i < maxWords && sentenceCount < sentences; i++ {
-		next := c.NextWord(p)
-		if len(next) == 0 {
-			break
+// advance chooses the next word, returning it as added to the text,
+// or "" if the chain chose End. It returns false once the text is
+// finished.
+func (gen *generator) advance() (string, bool) {
+	if gen.done || len(gen.g.steps) >= gen.maxWords || gen.sentenceCount >= gen.sentences {
+		gen.done = true
+		return "", false
+	}
+	g := &gen.g
+	st := gen.next(gen.p)
+	word := st.word
+	if len(word) == 0 {
+		gen.done = true
+		return "", false
+	}
+	g.steps = append(g.steps, st)
+	if word == End {
+		g.pos = append(g.pos, len(g.words))
+		if gen.sentenceEndIndex < len(g.words) {
+			gen.sentenceCount++
+			gen.sentenceEndIndex = len(g.words)
 		}
-		words = append(words, next)
-		p.Shift(next)
-		if stringutil.IsEndOfSentence(next) {
-			sentenceCount++
-			sentenceEndIndex = len(words)
+		if !gen.sentenceMarkers {
+			gen.done = true
 		}
+		gen.p = NewPrefix(gen.prefixLen)
+		return "", true
 	}
-	if sentenceCount < sentences && sentenceEndIndex > 0 {
-		words = words[:sentenceEndIndex]
+	w := gen.cs.restore(gen.p, word)
+	g.words = append(g.words, w)
+	g.pos = append(g.pos, len(g.words))
+	gen.cs.shift(gen.p, word)
+	if stringutil.EndsSentence(word) {
+		gen.sentenceCount++
+		gen.sentenceEndIndex = len(g.words)
 	}
-	return strings.Join(words, " ")
+	return w, true
+}
+
+// finish returns the generation, with any incomplete sentence after
+// the last complete one dropped if truncate is set.
+func (gen *generator) finish(truncate bool) generation {
+	g := gen.g
+	g.sentences = gen.sentenceCount
+	g.capped = len(g.steps) == gen.maxWords && gen.sentenceCount < gen.sentences
+	if truncate && gen.sentenceCount < gen.sentences && gen.sentenceEndIndex > 0 && gen.sentenceEndIndex < len(g.words) {
+		g.words = g.words[:gen.sentenceEndIndex]
+		g.truncated = true
+	}
+	return g
 }
 
 // Load attempts to load a suffix frequency map in JSON format from
@@ -208,26 +674,82 @@ func (c *Chain) Load(filename string) error {
 	}
 	defer f.Close()
 
-	dec := json.NewDecoder(f)
-	err = dec.Decode(&(c.chain))
+	return c.Decode(f)
+}
+
+// Decode reads a suffix frequency map in JSON format, either nested
+// maps as written by Encode or the compact format written by
+// EncodeCompact, from the given Reader to use in Chain. Gzipped input
+// is decompressed transparently, and input saved with a header (see
+// Save) is checked against it before any of it is used.
+func (c *Chain) Decode(r io.Reader) error {
+	br, err := decompress(r)
 	if err != nil {
 		return err
 	}
+	if hasHeader(br) {
+		if br, err = readHeader(br); err != nil {
+			return err
+		}
+	}
+	if isCompact(br) {
+		return c.decodeCompact(br)
+	}
 
+	var chain map[string]map[string]int
+	dec := json.NewDecoder(br)
+	err = dec.Decode(&chain)
+	if err != nil {
+		return jsonError(err)
+	}
+	// Generating after a prefix with a count less than 1 would
+	// panic, so don't use any of it
+	for key, suffixes := range chain {
+		for s, freq := range suffixes {
+			if freq < 1 {
+				return corruptf("markov: corrupt model: bad count %d for %q after %q", freq, s, key)
+			}
+		}
+	}
+	for key, suffixes := range chain {
+		c.chain[key] = suffixes
+	}
+
+	c.reindex()
+	c.dirty = false
 	return nil
 }
 
 // Save saves a chain's suffix frequency map to the given file in JSON
-// format
+// format, compressed with gzip if the filename ends in ".gz". The JSON
+// is preceded by a header with the format version and a checksum, so
+// that Load can reject truncated or corrupted files.
 func (c *Chain) Save(filename string) error {
-	f, err := os.Create(filename)
-	if err != nil {
+	if err := saveModel(filename, c.Encode); err != nil {
 		return err
 	}
-	defer f.Close()
+	c.dirty = false
+	return nil
+}
+
+// Dirty returns a boolean indicating whether the chain has changed
+// since it was last loaded or saved, and so needs saving. New chains
+// are dirty.
+func (c *Chain) Dirty() bool {
+	return c.dirty
+}
 
-	enc := json.NewEncoder(f)
-	err = enc.Encode(c.chain)
+// MarkDirty marks the chain as needing saving, e.g. when it was loaded
+// from elsewhere to replace a chain that's saved.
+func (c *Chain) MarkDirty() {
+	c.dirty = true
+}
+
+// Encode writes a chain's suffix frequency map to the given Writer in
+// JSON format.
+func (c *Chain) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	err := enc.Encode(c.chain)
 	if err != nil {
 		return err
 	}
@@ -239,12 +761,3 @@ func (c *Chain) Save(filename string) error {
 func (c *Chain) Size() int {
 	return len(c.chain)
 }
-
-// Stats returns a histogram of what prefix lengths are being used to
-// generate words. The nth entry in the returned array holds the
-// number of words generated using length-n prefixes.
-func (c *Chain) Stats() []int {
-	retval := make([]int, len(c.stats))
-	copy(retval, c.stats)
-	return retval
-}
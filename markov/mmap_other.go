@@ -0,0 +1,26 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// mmap_other.go reads frozen chain files into memory on systems
+// without mmap.
+
+//go:build !unix
+
+package markov
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// mapFile reads the given file into memory, returning its contents and
+// a function to release them.
+func mapFile(f *os.File) ([]byte, func([]byte) error, error) {
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func([]byte) error { return nil }, nil
+}
@@ -0,0 +1,32 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// mmap_unix.go memory-maps frozen chain files on Unix systems.
+
+//go:build unix
+
+package markov
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the given file into memory read-only, returning its
+// contents and a function to unmap them.
+func mapFile(f *os.File) ([]byte, func([]byte) error, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return nil, nil, errBadFrozen
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, syscall.Munmap, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// novelty.go optionally remembers the sentences a Chain was trained
+// on, so that generated text which just quotes a training sentence
+// back verbatim can be detected and regenerated.
+
+package markov
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// minNovelWords is the number of words a sentence must have before
+// reproducing it counts as quoting; short sentences like "lol, yeah."
+// are fair game.
+const minNovelWords = 4
+
+// TrackSentences starts recording a hash of every sentence added by
+// Build, for use by IsNovel. Sentences are normalized before hashing,
+// ignoring case, punctuation, and spacing, so near-verbatim
+// reproductions are caught too.
+func (c *Chain) TrackSentences() {
+	if c.sentences == nil {
+		c.sentences = make(map[uint64]bool)
+	}
+}
+
+// sentenceHash returns the hash of a normalized sentence, and false if
+// the sentence is too short to be worth tracking.
+func sentenceHash(sentence string) (uint64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) < minNovelWords {
+		return 0, false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(words, " ")))
+	return h.Sum64(), true
+}
+
+// recordSentence records a training sentence, if sentence tracking is
+// enabled.
+func (c *Chain) recordSentence(words []string) {
+	if c.sentences == nil {
+		return
+	}
+	if h, ok := sentenceHash(strings.Join(words, " ")); ok {
+		c.sentences[h] = true
+	}
+}
+
+// IsNovel returns a boolean indicating whether the given text is
+// novel, i.e. doesn't contain any (long enough) sentence that the
+// chain was trained on since it started tracking sentences.
+func (c *Chain) IsNovel(text string) bool {
+	for _, sentence := range stringutil.SplitSentences(text) {
+		if h, ok := sentenceHash(sentence); ok && c.sentences[h] {
+			return false
+		}
+	}
+	return true
+}
+
+// GenerateNovel is like Generate, but makes up to tries attempts to
+// generate novel text (see IsNovel), returning the last attempt if
+// none are novel.
+func (c *Chain) GenerateNovel(start string, sentences, maxWords, tries int) string {
+	var text string
+	for i := 0; i < tries; i++ {
+		text = c.Generate(start, sentences, maxWords)
+		if c.IsNovel(text) {
+			break
+		}
+	}
+	return text
+}
+
+// LoadSentences attempts to load training sentence hashes in JSON
+// format from the given file, and starts tracking sentences.
+func (c *Chain) LoadSentences(filename string) error {
+	c.TrackSentences()
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var hashes []uint64
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&hashes); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		c.sentences[h] = true
+	}
+	return nil
+}
+
+// SaveSentences saves a chain's training sentence hashes to the given
+// file in JSON format.
+func (c *Chain) SaveSentences(filename string) error {
+	hashes := make([]uint64, 0, len(c.sentences))
+	for h := range c.sentences {
+		hashes = append(hashes, h)
+	}
+	return saveAtomic(filename, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(hashes)
+	})
+}
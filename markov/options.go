@@ -0,0 +1,195 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// options.go lets callers of GenerateWith adjust a single generation,
+// e.g. its temperature, without changing the chain's own settings
+// under other users of it.
+
+package markov
+
+import "math/rand"
+
+// Defaults for GenerateWith.
+const (
+	defaultSentences = 1
+	defaultMaxWords  = 50
+)
+
+// A GenerateOption modifies how GenerateWith generates text.
+type GenerateOption func(*generateOptions)
+
+type generateOptions struct {
+	start       string
+	sentences   int
+	maxWords    int
+	temperature float64
+	banned      []string
+	rng         *rand.Rand
+	stop        map[string]bool
+	trace       bool
+	correct     int
+}
+
+// Start returns a GenerateOption continuing the given text, as the
+// start string of Generate does.
+func Start(text string) GenerateOption {
+	return func(o *generateOptions) {
+		o.start = text
+	}
+}
+
+// Sentences returns a GenerateOption setting the number of sentences
+// to generate, 1 by default.
+func Sentences(n int) GenerateOption {
+	return func(o *generateOptions) {
+		o.sentences = n
+	}
+}
+
+// MaxWords returns a GenerateOption setting the most words to
+// generate, 50 by default.
+func MaxWords(n int) GenerateOption {
+	return func(o *generateOptions) {
+		o.maxWords = n
+	}
+}
+
+// Temperature returns a GenerateOption overriding the chain's sampling
+// temperature (see SetTemperature). Temperatures that aren't positive
+// are ignored.
+func Temperature(t float64) GenerateOption {
+	return func(o *generateOptions) {
+		if t > 0 {
+			o.temperature = t
+		}
+	}
+}
+
+// Banned returns a GenerateOption never generating the given words,
+// compared as for SetAllowlist, in addition to any the chain's
+// allowlist leaves out.
+func Banned(words ...string) GenerateOption {
+	return func(o *generateOptions) {
+		o.banned = append(o.banned, words...)
+	}
+}
+
+// Rand returns a GenerateOption choosing words with r instead of the
+// math/rand package's source, e.g. to generate reproducibly from a
+// seeded source. Like r, the option mustn't be used by several
+// goroutines at once.
+func Rand(r *rand.Rand) GenerateOption {
+	return func(o *generateOptions) {
+		o.rng = r
+	}
+}
+
+// StopAt returns a GenerateOption ending the text after any of the
+// given words is generated, as if the chain had produced End there.
+func StopAt(words ...string) GenerateOption {
+	return func(o *generateOptions) {
+		if o.stop == nil {
+			o.stop = make(map[string]bool)
+		}
+		for _, w := range words {
+			o.stop[w] = true
+		}
+	}
+}
+
+// Trace returns a GenerateOption recording how each word was chosen in
+// the result, as GenerateTrace does.
+func Trace() GenerateOption {
+	return func(o *generateOptions) {
+		o.trace = true
+	}
+}
+
+// GenerateWith generates text as GenerateResult does, configured by
+// options, which makes it easier to add knobs without changing every
+// caller. With no options, it generates a sentence of at most 50
+// words from scratch. Options only affect this generation, so other
+// goroutines may generate from the chain at the same time.
+func (c *Chain) GenerateWith(opts ...GenerateOption) GenerationResult {
+	gen, gc, o := c.generatorWith(opts)
+	for {
+		if _, ok := gen.advance(); !ok {
+			break
+		}
+	}
+	return gen.finish(true).result(c.prefixLen, o.trace, gc.wordError)
+}
+
+// generatorWith returns a generator configured by options, along with
+// the copy of the chain it generates from (see withOptions) and the
+// options.
+func (c *Chain) generatorWith(opts []GenerateOption) (*generator, *Chain, generateOptions) {
+	o := generateOptions{sentences: defaultSentences, maxWords: defaultMaxWords}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	gc := c.withOptions(&o)
+	if o.correct > 0 {
+		o.start = c.CorrectSpelling(o.start, o.correct)
+	}
+	next := gc.nextWord
+	if o.stop != nil {
+		stopped := false
+		next = func(p Prefix) step {
+			if stopped {
+				return step{}
+			}
+			st := gc.nextWord(p)
+			stopped = o.stop[st.word]
+			return st
+		}
+	}
+	gen := newGenerator(c.prefixLen, c.sentenceMarkers, c.caser(), next, o.start, o.sentences, o.maxWords)
+	return gen, gc, o
+}
+
+// withOptions returns a shallow copy of the chain, and of its
+// fallback, sampling as the options say. The copy shares the chain's
+// counts, so it must only be used to generate.
+func (c *Chain) withOptions(o *generateOptions) *Chain {
+	gc := *c
+	if o.temperature != 0 {
+		gc.SetTemperature(o.temperature)
+	}
+	if len(o.banned) > 0 {
+		gc.banned = make(map[string]bool, len(c.banned)+len(o.banned))
+		for w := range c.banned {
+			gc.banned[w] = true
+		}
+		for _, w := range o.banned {
+			gc.banned[allowKey(w)] = true
+		}
+	}
+	if o.rng != nil {
+		gc.rng = o.rng
+	}
+	if c.fallback != nil {
+		gc.fallback = c.fallback.withOptions(o)
+	}
+	return &gc
+}
+
+// intn returns a random number in [0,n) from the chain's source of
+// randomness.
+func (c *Chain) intn(n int) int {
+	if c.rng != nil {
+		return c.rng.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+// float64 returns a random number in [0,1) from the chain's source of
+// randomness.
+func (c *Chain) float64() float64 {
+	if c.rng != nil {
+		return c.rng.Float64()
+	}
+	return rand.Float64()
+}
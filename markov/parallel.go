@@ -0,0 +1,142 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// parallel.go trains a Chain on many inputs at once, for corpora too
+// large to train on one word at a time, and generates many texts at
+// once, for bulk jobs like a month of scheduled posts.
+
+package markov
+
+import (
+	"io"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// BuildParallel adds the text from each of the provided Readers to the
+// chain as Build would, using the given number of worker goroutines
+// (or one per CPU, if workers is less than 1). Each worker counts into
+// its own intermediate chain, and the intermediate chains are merged
+// into this one at the end, so the chain is only modified once all the
+// readers are exhausted. With deduplication (see DedupLines), each
+// worker skips what the chain was trained on before and what it has
+// seen itself, but not what other workers have. It returns an error
+// reading one of the readers, other than io.EOF, if there was any; the
+// rest are still read, and everything read before an error is learned.
+func (c *Chain) BuildParallel(readers []io.Reader, workers int, opts ...BuildOption) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(readers) {
+		workers = len(readers)
+	}
+
+	work := make(chan io.Reader)
+	shards := make([]*Chain, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := range shards {
+		shard := NewChain(c.prefixLen)
+		shard.sentenceMarkers = c.sentenceMarkers
+		shard.urls, shard.mentions = c.urls, c.mentions
+		shard.SetCaseFolding(c.caseFolding)
+		if c.sentences != nil {
+			shard.TrackSentences()
+		}
+		if c.trained != nil {
+			shard.trained = make(map[uint64]bool, len(c.trained))
+			for h := range c.trained {
+				shard.trained[h] = true
+			}
+		}
+		shards[i] = shard
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for r := range work {
+				if _, err := shard.Build(r, opts...); err != nil && errs[i] == nil {
+					errs[i] = err
+				}
+			}
+		}(i)
+	}
+	for _, r := range readers {
+		work <- r
+	}
+	close(work)
+	wg.Wait()
+
+	for _, shard := range shards {
+		// Shards always have the same prefix length, so Merge
+		// can't fail
+		c.Merge(shard)
+		for h := range shard.sentences {
+			c.sentences[h] = true
+		}
+		if len(shard.trained) > 0 && c.trained == nil {
+			c.trained = make(map[uint64]bool)
+		}
+		for h := range shard.trained {
+			c.trained[h] = true
+		}
+		for key, forms := range shard.cases {
+			for form, n := range forms {
+				if c.cases[key] == nil {
+					c.cases[key] = make(map[string]int)
+				}
+				c.cases[key][form] += n
+			}
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateBatch generates a text continuing each of the given seeds, as
+// GenerateWith would with the same options and Start(seed), using a
+// worker goroutine per CPU, and returns the results in the same order
+// as the seeds. Generation only reads the chain, so it mustn't be
+// modified until GenerateBatch returns; to keep training it meanwhile,
+// generate from a Clone. With Rand, each text gets its own source,
+// seeded from the given one in turn, so the batch is still
+// reproducible.
+func (c *Chain) GenerateBatch(seeds []string, opts ...GenerateOption) []GenerationResult {
+	var o generateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	seedOpts := make([][]GenerateOption, len(seeds))
+	for i, seed := range seeds {
+		seedOpts[i] = append(opts[:len(opts):len(opts)], Start(seed))
+		if o.rng != nil {
+			seedOpts[i] = append(seedOpts[i], Rand(rand.New(rand.NewSource(o.rng.Int63()))))
+		}
+	}
+
+	results := make([]GenerationResult, len(seeds))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU() && w < len(seeds); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = c.GenerateWith(seedOpts[i]...)
+			}
+		}()
+	}
+	for i := range seeds {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return results
+}
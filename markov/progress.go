@@ -0,0 +1,68 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// progress.go lets callers follow Build through a large corpus, e.g.
+// to show a progress bar.
+
+package markov
+
+import (
+	"io"
+	"sync"
+)
+
+// progressInterval is how many words Build reads between progress
+// reports.
+const progressInterval = 10000
+
+// BuildProgress is how far training has got.
+type BuildProgress struct {
+	// Words is the number of words added.
+	Words int
+	// Bytes is the number of bytes of input read, which runs ahead
+	// of Words, as input is read in blocks.
+	Bytes int64
+}
+
+// progress accumulates the progress of the builds a Progress option is
+// passed to.
+type progress struct {
+	mu    sync.Mutex
+	total BuildProgress
+	f     func(BuildProgress)
+}
+
+// add adds to the progress made and reports it.
+func (p *progress) add(words int, bytes int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total.Words += words
+	p.total.Bytes += bytes
+	p.f(p.total)
+}
+
+// Progress returns a BuildOption that calls f with the progress made
+// every few thousand words and at the end of each Build. The progress
+// is the total over every Build the option is passed to, so one option
+// can follow a whole import, e.g. through corpus.Build, BuildDir, or
+// BuildParallel, whose workers take turns calling f.
+func Progress(f func(BuildProgress)) BuildOption {
+	p := &progress{f: f}
+	return func(o *buildOptions) {
+		o.progress = p
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(b []byte) (int, error) {
+	n, err := cr.r.Read(b)
+	cr.n += int64(n)
+	return n, err
+}
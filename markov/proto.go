@@ -0,0 +1,207 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// proto.go encodes and decodes Chains in the Protocol Buffers wire
+// format described by chain.proto, so that tools in other languages
+// can read and write the same models. The encoding is done by hand,
+// since the schema is small and stable.
+
+package markov
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Protocol Buffers wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errProtoTruncated is returned when protobuf input ends mid-field.
+var errProtoTruncated error = &corruptError{msg: "markov: truncated protobuf input"}
+
+// MarshalBinary encodes the chain's suffix frequency map as a Chain
+// message in the Protocol Buffers wire format (see chain.proto).
+func (c *Chain) MarshalBinary() ([]byte, error) {
+	cc := c.compact()
+	var b []byte
+	b = appendVarintField(b, 1, uint64(cc.PrefixLen))
+	for _, w := range cc.Words {
+		b = appendBytesField(b, 2, []byte(w))
+	}
+	for i, prefix := range cc.Prefixes {
+		var entry []byte
+		if len(prefix) > 0 {
+			var packed []byte
+			for _, id := range prefix {
+				packed = binary.AppendUvarint(packed, uint64(id))
+			}
+			entry = appendBytesField(entry, 1, packed)
+		}
+		counts := cc.Suffixes[i]
+		for j := 0; j < len(counts); j += 2 {
+			var suffix []byte
+			suffix = appendVarintField(suffix, 1, uint64(counts[j]))
+			suffix = appendVarintField(suffix, 2, uint64(counts[j+1]))
+			entry = appendBytesField(entry, 2, suffix)
+		}
+		b = appendBytesField(b, 3, entry)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary replaces the chain's suffix frequency map with one
+// decoded from a Chain message in the Protocol Buffers wire format
+// (see chain.proto). The message's prefix length must match the
+// chain's.
+func (c *Chain) UnmarshalBinary(data []byte) error {
+	cc := compactChain{Format: compactFormat}
+	err := eachField(data, func(field int, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == wireVarint:
+			if v > math.MaxInt32 {
+				return corruptf("markov: bad prefix length %d", v)
+			}
+			cc.PrefixLen = int(v)
+		case field == 2 && wire == wireBytes:
+			cc.Words = append(cc.Words, string(b))
+		case field == 3 && wire == wireBytes:
+			prefix, counts, err := unmarshalEntry(b)
+			if err != nil {
+				return err
+			}
+			cc.Prefixes = append(cc.Prefixes, prefix)
+			cc.Suffixes = append(cc.Suffixes, counts)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return c.loadCompact(cc)
+}
+
+// unmarshalEntry decodes an Entry message into a prefix and a list
+// alternating suffix word IDs and counts.
+func unmarshalEntry(data []byte) ([]int, []int, error) {
+	prefix := []int{}
+	var counts []int
+	err := eachField(data, func(field int, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == wireVarint:
+			// Unpacked repeated field
+			id, err := protoInt(v)
+			if err != nil {
+				return err
+			}
+			prefix = append(prefix, id)
+		case field == 1 && wire == wireBytes:
+			for len(b) > 0 {
+				v, n := binary.Uvarint(b)
+				if n <= 0 {
+					return errProtoTruncated
+				}
+				id, err := protoInt(v)
+				if err != nil {
+					return err
+				}
+				prefix = append(prefix, id)
+				b = b[n:]
+			}
+		case field == 2 && wire == wireBytes:
+			word, count := 0, 0
+			err := eachField(b, func(field int, wire int, v uint64, _ []byte) (err error) {
+				switch {
+				case field == 1 && wire == wireVarint:
+					word, err = protoInt(v)
+				case field == 2 && wire == wireVarint:
+					count, err = protoInt(v)
+				}
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			counts = append(counts, word, count)
+		}
+		return nil
+	})
+	return prefix, counts, err
+}
+
+// protoInt converts a decoded varint to an int, returning an error if
+// it's too large to be a word ID or count.
+func protoInt(v uint64) (int, error) {
+	if v > math.MaxInt32 {
+		return 0, corruptf("markov: protobuf value %d out of range", v)
+	}
+	return int(v), nil
+}
+
+// eachField calls f with the field number and wire type of each field
+// of an encoded message, along with its value: v for varint and fixed
+// fields, b for length-delimited ones.
+func eachField(data []byte, f func(field int, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		data = data[n:]
+		field, wire := int(key>>3), int(key&7)
+
+		var v uint64
+		var b []byte
+		switch wire {
+		case wireVarint:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+		case wireFixed64:
+			if len(data) < 8 {
+				return errProtoTruncated
+			}
+			v, n = binary.LittleEndian.Uint64(data), 8
+		case wireFixed32:
+			if len(data) < 4 {
+				return errProtoTruncated
+			}
+			v, n = uint64(binary.LittleEndian.Uint32(data)), 4
+		case wireBytes:
+			length, ln := binary.Uvarint(data)
+			if ln <= 0 || length > uint64(len(data)-ln) {
+				return errProtoTruncated
+			}
+			b, n = data[ln:ln+int(length)], ln+int(length)
+		default:
+			return corruptf("markov: unsupported protobuf wire type %d", wire)
+		}
+		data = data[n:]
+
+		if err := f(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendVarintField appends a varint field to an encoded message.
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+// appendBytesField appends a length-delimited field to an encoded
+// message.
+func appendBytesField(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
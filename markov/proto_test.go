@@ -0,0 +1,85 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	c := NewChain(2)
+	c.Build(strings.NewReader("the cat sat on the mat"))
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := NewChain(2)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.chain, c.chain) {
+		t.Errorf("UnmarshalBinary(MarshalBinary()) = %v, want %v", decoded.chain, c.chain)
+	}
+}
+
+func TestUnmarshalBinaryCounts(t *testing.T) {
+	// chain builds a Chain message with one word and one entry, for
+	// the empty prefix, with that word and the given count
+	chain := func(count uint64) []byte {
+		var suffix, entry, b []byte
+		suffix = appendVarintField(suffix, 1, 0)
+		suffix = appendVarintField(suffix, 2, count)
+		entry = appendBytesField(entry, 2, suffix)
+		b = appendVarintField(b, 1, 2)
+		b = appendBytesField(b, 2, []byte("word"))
+		return appendBytesField(b, 3, entry)
+	}
+	tests := []struct {
+		count   uint64
+		wantErr bool
+	}{
+		{1, false},
+		{math.MaxInt32, false},
+		{0, true},
+		{math.MaxInt32 + 1, true},
+		{math.MaxUint64, true},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		err := c.UnmarshalBinary(chain(test.count))
+		if test.wantErr && !errors.Is(err, ErrCorruptModel) {
+			t.Errorf("UnmarshalBinary with count %d = %v, want ErrCorruptModel", test.count, err)
+		}
+		if !test.wantErr && (err != nil || c.Suffixes("")["word"] != int(test.count)) {
+			t.Errorf("UnmarshalBinary with count %d = %v, suffixes %v", test.count, err, c.Suffixes(""))
+		}
+	}
+}
+
+func TestDecodeCounts(t *testing.T) {
+	tests := []struct {
+		json    string
+		wantErr bool
+	}{
+		{`{"": {"a": 1}}`, false},
+		{`{"": {"a": 0}}`, true},
+		{`{"": {"a": -3}}`, true},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		err := c.Decode(strings.NewReader(test.json))
+		if test.wantErr != errors.Is(err, ErrCorruptModel) {
+			t.Errorf("Decode(%s) = %v, want corrupt: %v", test.json, err, test.wantErr)
+		}
+		if test.wantErr && c.Size() != 0 {
+			t.Errorf("Decode(%s) kept %d prefixes despite error", test.json, c.Size())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// rank.go generates several candidate outputs from a Chain and ranks
+// them, so that callers can pick the best one instead of the first.
+
+package markov
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// A Ranker scores a candidate text generated by a chain; higher
+// scores are better.
+type Ranker func(c *Chain, text string) float64
+
+// ByFluency ranks texts by their average per-word log-likelihood under
+// the chain (see Score), preferring texts that follow the chain's
+// most well-trodden paths.
+func ByFluency(c *Chain, text string) float64 {
+	words := len(strings.Fields(text))
+	if words == 0 {
+		return math.Inf(-1)
+	}
+	return c.Score(text) / float64(words)
+}
+
+// ByNovelty ranks texts that don't quote a training sentence (see
+// IsNovel) above texts that do.
+func ByNovelty(c *Chain, text string) float64 {
+	if c.IsNovel(text) {
+		return 0
+	}
+	return -1
+}
+
+// ByLength returns a Ranker preferring texts with close to the given
+// number of words.
+func ByLength(target int) Ranker {
+	return func(c *Chain, text string) float64 {
+		return -math.Abs(float64(len(strings.Fields(text)) - target))
+	}
+}
+
+// Scaled returns a Ranker whose scores are those of the given Ranker
+// multiplied by weight.
+func Scaled(weight float64, rank Ranker) Ranker {
+	return func(c *Chain, text string) float64 {
+		return weight * rank(c, text)
+	}
+}
+
+// Combined returns a Ranker whose score is the sum of the given
+// Rankers' scores.
+func Combined(ranks ...Ranker) Ranker {
+	return func(c *Chain, text string) float64 {
+		total := 0.0
+		for _, rank := range ranks {
+			total += rank(c, text)
+		}
+		return total
+	}
+}
+
+// DefaultRanker prefers novel texts, and then fluent ones.
+var DefaultRanker = Combined(ByFluency, Scaled(10, ByNovelty))
+
+// GenerateN generates n candidate texts as in Generate, and returns
+// the distinct candidates ranked best first by the given Ranker (or
+// by DefaultRanker, if rank is nil).
+func (c *Chain) GenerateN(start string, n, sentences, maxWords int, rank Ranker) []string {
+	if rank == nil {
+		rank = DefaultRanker
+	}
+	scores := make(map[string]float64)
+	var candidates []string
+	for i := 0; i < n; i++ {
+		text := c.Generate(start, sentences, maxWords)
+		if _, ok := scores[text]; ok {
+			continue
+		}
+		scores[text] = rank(c, text)
+		candidates = append(candidates, text)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i]] > scores[candidates[j]]
+	})
+	return candidates
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// report.go summarizes what a Chain has learned, to spot a corpus
+// dominated by spam or polluted by junk: a bot that has learned one
+// pasted message a thousand times, or a log full of URLs, shows up as
+// a few prefixes and words with an outsized share of the counts.
+
+package markov
+
+import (
+	"sort"
+	"strings"
+)
+
+// A Tally is a prefix or word and a count of it.
+type Tally struct {
+	Text  string
+	Count int
+}
+
+// A Report summarizes what a chain has learned (see Chain.Report).
+type Report struct {
+	// Vocabulary is the number of distinct words learned, not
+	// counting End.
+	Vocabulary int
+	// Tokens is the number of words learned, counting repeats but
+	// not End.
+	Tokens int
+	// Prefixes is the number of distinct full-length prefixes
+	// learned.
+	Prefixes int
+	// TopPrefixes are the full-length prefixes followed by the most
+	// words, counting repeats, most first.
+	TopPrefixes []Tally
+	// VariedPrefixes are the full-length prefixes followed by the
+	// most distinct words, most first.
+	VariedPrefixes []Tally
+	// TopWords are the words learned most often, most first.
+	TopWords []Tally
+}
+
+// Report returns a summary of what the chain has learned, with the top
+// n prefixes and words of each kind. Full-length prefixes are those of
+// the chain's prefix length; the shorter ones the chain also counts
+// would crowd them out. Ties are broken alphabetically.
+func (c *Chain) Report(n int) Report {
+	var r Report
+	for s, freq := range c.chain[""] {
+		if s != End {
+			r.Vocabulary++
+			r.Tokens += freq
+		}
+	}
+	r.TopWords = topTallies(c.chain[""], n, func(s string) bool { return s != End })
+
+	mass := make(map[string]int)
+	varied := make(map[string]int)
+	for key, suffixes := range c.chain {
+		if key == "" || strings.Count(key, " ")+1 != c.prefixLen {
+			continue
+		}
+		r.Prefixes++
+		for _, freq := range suffixes {
+			mass[key] += freq
+		}
+		varied[key] = len(suffixes)
+	}
+	r.TopPrefixes = topTallies(mass, n, nil)
+	r.VariedPrefixes = topTallies(varied, n, nil)
+	return r
+}
+
+// topTallies returns the n highest counts of a map, highest first,
+// of the keys keep accepts (or all of them, if it's nil), or none if n
+// is negative.
+func topTallies(counts map[string]int, n int, keep func(string) bool) []Tally {
+	if n < 0 {
+		n = 0
+	}
+	tallies := make([]Tally, 0, len(counts))
+	for text, count := range counts {
+		if keep == nil || keep(text) {
+			tallies = append(tallies, Tally{text, count})
+		}
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		if tallies[i].Count != tallies[j].Count {
+			return tallies[i].Count > tallies[j].Count
+		}
+		return tallies[i].Text < tallies[j].Text
+	})
+	if len(tallies) > n {
+		tallies = tallies[:n]
+	}
+	return tallies
+}
@@ -0,0 +1,31 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReportTopWords(t *testing.T) {
+	c := NewChain(2)
+	c.Build(strings.NewReader("the cat and the dog and the bird"))
+	tests := []struct {
+		n    int
+		want []Tally
+	}{
+		{2, []Tally{{"the", 3}, {"and", 2}}},
+		{0, []Tally{}},
+		{-1, []Tally{}},
+		{10, []Tally{{"the", 3}, {"and", 2}, {"bird", 1}, {"cat", 1}, {"dog", 1}}},
+	}
+	for _, test := range tests {
+		if got := c.Report(test.n).TopWords; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Report(%d).TopWords = %v, want %v", test.n, got, test.want)
+		}
+	}
+}
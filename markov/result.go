@@ -0,0 +1,155 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// result.go reports how a generated text came out, for callers that
+// need more than the text, e.g. to tell a fragment cut off at the word
+// limit from a finished sentence, and totals the prefix lengths used
+// over many texts.
+
+package markov
+
+import (
+	"strings"
+	"sync"
+)
+
+// A GenerationResult is a generated text, with details of how it was
+// generated.
+type GenerationResult struct {
+	Text string
+	// Sentences is the number of sentences completed.
+	Sentences int
+	// Words is the number of words generated, not counting the
+	// start string.
+	Words int
+	// Levels is a histogram of the prefix lengths used to choose
+	// each word (and End): the nth entry is the number chosen using
+	// length-n prefixes.
+	Levels []int
+	// Confidence is as GenerateConfidence returns.
+	Confidence float64
+	// Capped is set if generation stopped at the word limit before
+	// completing the requested number of sentences.
+	Capped bool
+	// Trace holds each choice made, in order, if the text was
+	// generated by GenerateTrace.
+	Trace []TraceStep
+	// Err is ErrEmptyChain or ErrUnknownPrefix if the chain had no
+	// word to start with.
+	Err error
+}
+
+// wordError returns the error for when there's no word to generate
+// after a prefix.
+func (fc *FrozenChain) wordError() error {
+	if fc.Size() == 0 {
+		return ErrEmptyChain
+	}
+	return ErrUnknownPrefix
+}
+
+// A TraceStep records how a word was chosen, for diagnosing odd
+// output.
+type TraceStep struct {
+	// Word is the word chosen, or End.
+	Word string
+	// Prefix is the words it was chosen to follow, as the chain
+	// stores them (see Chain.Prefixes), and Level the number of
+	// them: fewer than the chain's prefix length if it had to back
+	// off to a shorter prefix.
+	Prefix string
+	Level  int
+	// Candidates is the number of words the prefix has been
+	// followed by, and Prob the probability the word was chosen
+	// with.
+	Candidates int
+	Prob       float64
+}
+
+// GenerateResult is like Generate, but returns the text with details
+// of how it was generated.
+func (c *Chain) GenerateResult(start string, sentences, maxWords int) GenerationResult {
+	return c.generate(start, sentences, maxWords).result(c.prefixLen, false, c.wordError)
+}
+
+// GenerateTrace is like GenerateResult, but also records how each
+// word was chosen in the result's Trace.
+func (c *Chain) GenerateTrace(start string, sentences, maxWords int) GenerationResult {
+	return c.generate(start, sentences, maxWords).result(c.prefixLen, true, c.wordError)
+}
+
+// GenerateResult generates text as Chain.GenerateResult does.
+func (fc *FrozenChain) GenerateResult(start string, sentences, maxWords int) GenerationResult {
+	g := generateFrom(fc.prefixLen, fc.sentenceMarkers, fc.caser(), fc.nextWord, start, sentences, maxWords)
+	return g.result(fc.prefixLen, false, fc.wordError)
+}
+
+// GenerateTrace generates text as Chain.GenerateTrace does.
+func (fc *FrozenChain) GenerateTrace(start string, sentences, maxWords int) GenerationResult {
+	g := generateFrom(fc.prefixLen, fc.sentenceMarkers, fc.caser(), fc.nextWord, start, sentences, maxWords)
+	return g.result(fc.prefixLen, true, fc.wordError)
+}
+
+// result returns the GenerationResult for a generation from a chain
+// with the given prefix length, with a trace if asked for, and the
+// error from wordErr if there was no word to choose at all.
+func (g generation) result(prefixLen int, trace bool, wordErr func() error) GenerationResult {
+	r := GenerationResult{
+		Text:      strings.Join(g.words, " "),
+		Sentences: g.sentences,
+		Words:     len(g.words) - g.seed,
+		Levels:    make([]int, prefixLen+1),
+		Capped:    g.capped,
+	}
+	r.Confidence = g.confidence(prefixLen)
+	if len(g.steps) == 0 {
+		r.Err = wordErr()
+	}
+	for _, st := range g.steps {
+		r.Levels[st.level]++
+		if trace {
+			r.Trace = append(r.Trace, TraceStep{
+				Word:       st.word,
+				Prefix:     st.key,
+				Level:      st.level,
+				Candidates: st.candidates,
+				Prob:       st.prob,
+			})
+		}
+	}
+	return r
+}
+
+// Stats is a running histogram of the prefix lengths used to generate
+// words, which shows how often generation backs off to shorter
+// contexts. Callers keep one for whatever texts they care about,
+// adding each one's GenerationResult.Levels; chains don't keep one, as
+// generating from a chain is otherwise read-only and may be done
+// concurrently. The zero value is empty, and a Stats may be used
+// concurrently.
+type Stats struct {
+	mu     sync.Mutex
+	levels []int
+}
+
+// Add adds a text's histogram of prefix lengths to s.
+func (s *Stats) Add(levels []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.levels) < len(levels) {
+		s.levels = append(s.levels, 0)
+	}
+	for n, count := range levels {
+		s.levels[n] += count
+	}
+}
+
+// Levels returns the histogram: the nth entry holds the number of
+// words generated using length-n prefixes.
+func (s *Stats) Levels() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.levels...)
+}
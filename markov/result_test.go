@@ -0,0 +1,65 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		adds [][]int
+		want []int
+	}{
+		{nil, nil},
+		{[][]int{{1, 2, 3}}, []int{1, 2, 3}},
+		{[][]int{{1, 2, 3}, {0, 1, 1}}, []int{1, 3, 4}},
+		// Histograms from chains with other prefix lengths
+		{[][]int{{1, 2}, {0, 1, 1, 5}}, []int{1, 3, 1, 5}},
+	}
+	for _, test := range tests {
+		var s Stats
+		for _, levels := range test.adds {
+			s.Add(levels)
+		}
+		if got := s.Levels(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Stats after adding %v = %v, want %v", test.adds, got, test.want)
+		}
+	}
+}
+
+func TestStatsConcurrent(t *testing.T) {
+	c := NewChain(2)
+	c.Build(strings.NewReader("the cat sat on the mat. the dog sat on the log."))
+	var s Stats
+	var wg sync.WaitGroup
+	words := make([]int, 8)
+	for i := range words {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := c.GenerateResult("the", 1, 10)
+			s.Add(r.Levels)
+			for _, n := range r.Levels {
+				words[i] += n
+			}
+		}(i)
+	}
+	wg.Wait()
+	want, got := 0, 0
+	for _, n := range words {
+		want += n
+	}
+	for _, n := range s.Levels() {
+		got += n
+	}
+	if got != want {
+		t.Errorf("Stats counted %d words, want %d", got, want)
+	}
+}
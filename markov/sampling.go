@@ -0,0 +1,170 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// sampling.go defines knobs for how a Chain samples words: a
+// temperature to sharpen or flatten its distributions, a limit on how
+// much of each prefix it pays attention to, and smoothing to give
+// unseen suffixes some probability.
+
+package markov
+
+import (
+	"math"
+	"sort"
+)
+
+// SetTemperature sets the sampling temperature, which must be
+// positive. At 1, words are chosen in proportion to their frequency;
+// lower temperatures favor the most frequent words more strongly, and
+// higher ones flatten the distribution towards uniform, for more
+// surprising text. Passing 0 restores ordinary sampling.
+func (c *Chain) SetTemperature(t float64) {
+	if t == 1 {
+		t = 0
+	}
+	c.temperature = t
+}
+
+// Temperature returns the chain's sampling temperature.
+func (c *Chain) Temperature() float64 {
+	if c.temperature == 0 {
+		return 1
+	}
+	return c.temperature
+}
+
+// SetContext limits generation to using at most the last n words of
+// each prefix, as if the chain had been built with a shorter prefix
+// length, for more creative (and less coherent) text. Passing a
+// negative n, or one at least the chain's prefix length, removes the
+// limit.
+func (c *Chain) SetContext(n int) {
+	if n < 0 || n >= c.prefixLen {
+		c.skip = 0
+		return
+	}
+	c.skip = c.prefixLen - n
+}
+
+// Context returns the number of words of each prefix the chain uses
+// for generation; its prefix length, unless limited by SetContext.
+func (c *Chain) Context() int {
+	return c.prefixLen - c.skip
+}
+
+// SetSmoothing sets the add-k smoothing constant, which must not be
+// negative. With k > 0, each prefix is treated as if every word in
+// the chain's vocabulary had followed it k more times than it did,
+// spread in proportion to how often the word follows the prefix's
+// shorter tails; at k = 1 this is Laplace smoothing, interpolated
+// with the shorter tails rather than with a uniform distribution.
+// Score and Perplexity use the smoothed probabilities, and generation
+// sometimes backs off to a shorter tail on prefixes seen only a few
+// times, so it can choose words it never saw there. Small values,
+// like 0.01, diversify generation only slightly. Passing 0 restores
+// the default, which smooths scores as if each prefix had one extra
+// observation and doesn't change generation. Kneser-Ney smoothing
+// (see SetKneserNey) takes precedence.
+func (c *Chain) SetSmoothing(k float64) {
+	if k < 0 {
+		k = 0
+	}
+	c.smoothing = k
+}
+
+// Smoothing returns the chain's add-k smoothing constant.
+func (c *Chain) Smoothing() float64 {
+	return c.smoothing
+}
+
+// pseudocount returns the number of extra observations smoothing
+// gives each prefix: k for each word in the vocabulary, or 1 if
+// smoothing is off.
+func (c *Chain) pseudocount() float64 {
+	if c.smoothing == 0 {
+		return 1
+	}
+	return c.smoothing * float64(len(c.chain[""]))
+}
+
+// smoothBackOff reports whether generation should ignore a prefix's
+// suffixes, which were seen total times, and back off to a shorter
+// tail, as it does with the share of probability smoothing gives to
+// unseen suffixes.
+func (c *Chain) smoothBackOff(total int) bool {
+	if c.smoothing == 0 {
+		return false
+	}
+	k := c.pseudocount()
+	return c.float64()*(float64(total)+k) < k
+}
+
+// temper applies the chain's temperature to a map of words to
+// sampling weights, in place, and returns their new total. Only the
+// weights' proportions matter, so they're scaled so that the largest
+// is 1 (subtracting the largest log-weight before exponentiating),
+// which keeps low temperatures from overflowing to +Inf.
+func (c *Chain) temper(weights map[string]float64) float64 {
+	total := 0.0
+	if c.temperature == 0 {
+		for _, w := range weights {
+			total += w
+		}
+		return total
+	}
+	maxLog := math.Inf(-1)
+	for _, w := range weights {
+		maxLog = math.Max(maxLog, math.Log(w))
+	}
+	if math.IsInf(maxLog, -1) {
+		return 0
+	}
+	for s, w := range weights {
+		weights[s] = math.Exp((math.Log(w) - maxLog) / c.temperature)
+		total += weights[s]
+	}
+	return total
+}
+
+// chooseTempered makes a random choice from a map of suffixes to
+// frequencies, as choose does, but with the frequencies adjusted by
+// the chain's temperature. It returns the choice and the probability
+// with which it was chosen.
+func (c *Chain) chooseTempered(suffixes map[string]int) (string, float64) {
+	weights := make(map[string]float64, len(suffixes))
+	for s, freq := range suffixes {
+		if c.allowed(s) {
+			weights[s] = float64(freq)
+		}
+	}
+	return c.chooseWeighted(weights)
+}
+
+// chooseWeighted makes a random choice from a map of words to
+// sampling weights, after applying the chain's temperature to them,
+// returning the choice and the probability with which it was chosen,
+// or "" if there's nothing to choose.
+func (c *Chain) chooseWeighted(weights map[string]float64) (string, float64) {
+	total := c.temper(weights)
+	if total == 0 {
+		return "", 0
+	}
+
+	// Iterate in a fixed order so the choice only depends on rand
+	words := make([]string, 0, len(weights))
+	for s := range weights {
+		words = append(words, s)
+	}
+	sort.Strings(words)
+	n := c.float64() * total
+	for _, s := range words {
+		n -= weights[s]
+		if n <= 0 {
+			return s, weights[s] / total
+		}
+	}
+	last := words[len(words)-1]
+	return last, weights[last] / total
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTemper(t *testing.T) {
+	tests := []struct {
+		temperature float64
+		weights     map[string]float64
+		want        map[string]float64 // probabilities
+	}{
+		{1, map[string]float64{"a": 3, "b": 1}, map[string]float64{"a": 0.75, "b": 0.25}},
+		{0.5, map[string]float64{"a": 3, "b": 1}, map[string]float64{"a": 0.9, "b": 0.1}},
+		{2, map[string]float64{"a": 4, "b": 1}, map[string]float64{"a": 2.0 / 3, "b": 1.0 / 3}},
+		// Would overflow to +Inf without scaling
+		{0.01, map[string]float64{"a": 1000, "b": 500}, map[string]float64{"a": 1, "b": 0}},
+		{0.5, map[string]float64{"a": 1, "b": 0}, map[string]float64{"a": 1, "b": 0}},
+		{0.5, map[string]float64{"a": 0}, nil},
+	}
+	for _, test := range tests {
+		c := NewChain(2)
+		c.SetTemperature(test.temperature)
+		total := c.temper(test.weights)
+		if test.want == nil {
+			if total != 0 {
+				t.Errorf("temperature %g: total = %g, want 0", test.temperature, total)
+			}
+			continue
+		}
+		for s, want := range test.want {
+			if got := test.weights[s] / total; math.IsNaN(got) || math.Abs(got-want) > 1e-9 {
+				t.Errorf("temperature %g: P(%s) = %g, want %g", test.temperature, s, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// score.go evaluates how likely a piece of text is under a Chain,
+// which is useful for ranking candidate outputs and for asking
+// whether some text "sounds like" what the chain was trained on.
+
+package markov
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"strings"
+)
+
+// Score returns the natural log-likelihood of the given text under
+// the chain, treating the text as the start of a block of input. The
+// probabilities are smoothed so that words the chain has never seen
+// (or never seen after a given prefix) have a small nonzero
+// probability, and the score is always finite. Longer texts have
+// lower scores, so compare texts of different lengths by average
+// score per word.
+func (c *Chain) Score(text string) float64 {
+	p := NewPrefix(c.prefixLen)
+	score := 0.0
+	for _, w := range strings.Fields(text) {
+		score += math.Log(c.wordProb(p, w))
+		c.shift(p, w)
+	}
+	return score
+}
+
+// Perplexity reads held-out text from the provided Reader and returns
+// the chain's perplexity on it: the exponential of the negated
+// average per-word log-likelihood (see Score). Lower is better; a
+// perplexity of N means the chain is as uncertain about each word as
+// if it were choosing uniformly among N words. The text is treated as
+// a single block of input, as in Build.
+func (c *Chain) Perplexity(r io.Reader) (float64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	p := NewPrefix(c.prefixLen)
+	score := 0.0
+	words := 0
+	for scanner.Scan() {
+		w := scanner.Text()
+		score += math.Log(c.wordProb(p, w))
+		c.shift(p, w)
+		words++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if words == 0 {
+		return 0, errors.New("markov: no words to evaluate perplexity on")
+	}
+	return math.Exp(-score / float64(words)), nil
+}
+
+// wordProb returns the smoothed probability that the word w follows
+// the prefix p. Starting from a uniform distribution over the chain's
+// vocabulary (plus one unknown word), each successively longer tail
+// of the prefix that the chain knows contributes its observed
+// frequencies, reserving a share of probability for the shorter
+// tails in proportion to one extra observation, or to the chain's
+// add-k pseudocounts (see SetSmoothing). Kneser-Ney smoothing (see
+// SetKneserNey) replaces all this.
+func (c *Chain) wordProb(p Prefix, w string) float64 {
+	if c.discount > 0 {
+		return c.knWordProb(p, w)
+	}
+	prob := 1.0 / float64(len(c.chain[""])+1)
+	k := c.pseudocount()
+	for i := c.prefixLen; i >= 0; i-- {
+		if i < c.prefixLen && p[i] == "" {
+			continue
+		}
+		suffixes := c.chain[strings.Join(p[i:], " ")]
+		if suffixes == nil {
+			continue
+		}
+		total := 0
+		for _, freq := range suffixes {
+			total += freq
+		}
+		prob = (float64(suffixes[w]) + k*prob) / (float64(total) + k)
+	}
+	return prob
+}
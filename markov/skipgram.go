@@ -0,0 +1,88 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// skipgram.go optionally counts prefixes with a word skipped, so that
+// when a Chain doesn't know a prefix, it can still continue from what
+// followed prefixes differing in a single word, rather than dropping
+// straight to a shorter prefix.
+
+package markov
+
+import "strings"
+
+// wildcard stands in for a skipped word in skip-gram keys. It can't
+// be a word itself, since words never contain spaces or control
+// characters.
+const wildcard = "\x00"
+
+// SetSkipGrams sets whether the chain keeps skip-gram counts: for each
+// prefix of two or more words, the suffixes of every prefix that
+// matches it in all but one word (other than the first). With
+// skip-grams on, when generation finds no suffixes for a prefix (or,
+// with stemming on, for its stems), it tries the suffixes of those
+// prefixes before backing off to a shorter one, so rare word pairs
+// have more to go on. Like the stem index (see SetStemming), the
+// counts are built from the chain's counts when skip-grams are turned
+// on and kept up to date as text is added and removed.
+func (c *Chain) SetSkipGrams(on bool) {
+	c.skips = nil
+	if !on {
+		return
+	}
+	c.skips = make(map[string]map[string]int)
+	for key, suffixes := range c.chain {
+		for s, freq := range suffixes {
+			c.addSkipGrams(key, s, freq)
+		}
+	}
+}
+
+// SkipGrams reports whether the chain keeps skip-gram counts.
+func (c *Chain) SkipGrams() bool {
+	return c.skips != nil
+}
+
+// addSkipGrams adds a suffix's count to the skip-gram counts under
+// each of a prefix key's skip-grams.
+func (c *Chain) addSkipGrams(key, s string, weight int) {
+	for _, skipped := range skipKeys(key) {
+		if c.skips[skipped] == nil {
+			c.skips[skipped] = make(map[string]int)
+		}
+		c.skips[skipped][s] += weight
+	}
+}
+
+// skipped returns the combined suffixes of a prefix key's skip-grams,
+// or nil if there are none.
+func (c *Chain) skipped(key string) map[string]int {
+	var suffixes map[string]int
+	for _, skipped := range skipKeys(key) {
+		for s, freq := range c.skips[skipped] {
+			if suffixes == nil {
+				suffixes = make(map[string]int)
+			}
+			suffixes[s] += freq
+		}
+	}
+	return suffixes
+}
+
+// skipKeys returns the keys of a prefix key with each word but the
+// first replaced by the wildcard in turn, last word first.
+func skipKeys(key string) []string {
+	words := strings.Split(key, " ")
+	if len(words) < 2 {
+		return nil
+	}
+	keys := make([]string, 0, len(words)-1)
+	for i := len(words) - 1; i > 0; i-- {
+		w := words[i]
+		words[i] = wildcard
+		keys = append(keys, strings.Join(words, " "))
+		words[i] = w
+	}
+	return keys
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// spelling.go corrects near-miss words in text using a Chain's own
+// vocabulary as the dictionary, so that a seed typed in a hurry
+// ("definately") matches the prefixes the chain learned
+// ("definitely").
+
+package markov
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// CorrectSpelling returns text with each word the chain doesn't know
+// as a prefix word replaced by the known word fewest edits away (see
+// stringutil.EditDistance), if that's at most maxEdits and at most a
+// third of the word's length, preferring the most common of equally
+// near words; short words would otherwise match too much. Words are
+// compared without case or surrounding punctuation, both of which
+// are kept. Words are separated by single spaces in the result, as in
+// generated text.
+func (c *Chain) CorrectSpelling(text string, maxEdits int) string {
+	words := strings.Fields(text)
+	if maxEdits <= 0 || len(words) == 0 {
+		return strings.Join(words, " ")
+	}
+	var dict map[string]int
+	p := NewPrefix(1)
+	for i, w := range words {
+		if c.shift(p, w); c.chain[p[0]] != nil {
+			continue
+		}
+		lead, core, trail := splitWord(w)
+		lower := strings.ToLower(core)
+		n := utf8.RuneCountInString(core)
+		limit := maxEdits
+		if limit > n/3 {
+			limit = n / 3
+		}
+		if limit == 0 {
+			continue
+		}
+		if dict == nil {
+			// Only look through the chain once a word isn't
+			// found as it is
+			dict = c.spellingDictionary()
+		}
+		if dict[lower] > 0 {
+			continue
+		}
+		best, bestDist := "", limit+1
+		for known, mass := range dict {
+			if diff := utf8.RuneCountInString(known) - n; diff > limit || -diff > limit {
+				continue
+			}
+			d := stringutil.EditDistance(lower, known)
+			if d < bestDist || (d == bestDist && best != "" && (mass > dict[best] || mass == dict[best] && known < best)) {
+				best, bestDist = known, d
+			}
+		}
+		if best != "" {
+			words[i] = lead + matchCase(core, best) + trail
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// CorrectSeed returns a GenerateOption correcting the spelling of the
+// start text (see CorrectSpelling) before generating from it.
+func CorrectSeed(maxEdits int) GenerateOption {
+	return func(o *generateOptions) {
+		o.correct = maxEdits
+	}
+}
+
+// spellingDictionary returns the chain's prefix words, lowercased and
+// stripped of punctuation, with how often each was followed by
+// something.
+func (c *Chain) spellingDictionary() map[string]int {
+	dict := make(map[string]int)
+	for key, suffixes := range c.chain {
+		if key == "" || key == "START" || strings.Contains(key, " ") {
+			continue
+		}
+		_, core, _ := splitWord(key)
+		if core == "" {
+			continue
+		}
+		core = strings.ToLower(core)
+		for _, freq := range suffixes {
+			dict[core] += freq
+		}
+	}
+	return dict
+}
+
+// splitWord splits a word into its leading punctuation, its core of
+// letters and digits (and whatever's between them), and its trailing
+// punctuation.
+func splitWord(w string) (lead, core, trail string) {
+	isCore := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	start := strings.IndexFunc(w, isCore)
+	if start < 0 {
+		return w, "", ""
+	}
+	end := strings.LastIndexFunc(w, isCore)
+	_, size := utf8.DecodeRuneInString(w[end:])
+	end += size
+	return w[:start], w[start:end], w[end:]
+}
+
+// matchCase returns a lowercase word in the case of the word it
+// replaces: all capitals, capitalized, or lowercase.
+func matchCase(old, word string) string {
+	switch {
+	case utf8.RuneCountInString(old) > 1 && old == strings.ToUpper(old) && old != strings.ToLower(old):
+		return strings.ToUpper(word)
+	case old != "" && unicode.IsUpper([]rune(old)[0]):
+		return stringutil.Capitalize(word)
+	}
+	return word
+}
@@ -0,0 +1,62 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// stem.go optionally lets a Chain match prefixes by their words'
+// stems, so that a small corpus, or one in an inflected language, can
+// continue "she walks" from what followed "she walked".
+
+package markov
+
+import (
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// SetStemming sets the language to stem prefixes in (see
+// stringutil.StemIn), or turns stemming off if lang is "". With
+// stemming on, when generation finds no suffixes for a prefix, it
+// tries the suffixes of every prefix with the same stems before
+// backing off to a shorter prefix. Suffixes are still the words as
+// they were trained. The chain keeps an index of its counts by stems,
+// built from the counts when stemming is turned on and kept up to date
+// as text is added and removed (see Remove, Prune, and Decay).
+func (c *Chain) SetStemming(lang string) {
+	c.stemLang = lang
+	c.stems = nil
+	if lang == "" {
+		return
+	}
+	c.stems = make(map[string]map[string]int)
+	for key, suffixes := range c.chain {
+		for s, freq := range suffixes {
+			c.addStemmed(key, s, freq)
+		}
+	}
+}
+
+// addStemmed adds a suffix's count to the stem index under a prefix
+// key's stems.
+func (c *Chain) addStemmed(key, s string, weight int) {
+	stemmed := c.stemKey(key)
+	if c.stems[stemmed] == nil {
+		c.stems[stemmed] = make(map[string]int)
+	}
+	c.stems[stemmed][s] += weight
+}
+
+// stemKey returns a prefix key with each word replaced by its stem.
+func (c *Chain) stemKey(key string) string {
+	if key == "" {
+		return key
+	}
+	words := strings.Split(key, " ")
+	for i, w := range words {
+		if w != "START" {
+			words[i] = stringutil.StemIn(c.stemLang, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// story.go generates long-form stories from a Chain, with chapters,
+// paragraphs, and a consistent cast of characters.
+
+package markov
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// StoryConfig describes the shape of a story to generate.
+type StoryConfig struct {
+	// Topic seeds the first paragraph of the story.
+	Topic string
+	// Chapters is the number of chapters; if it's more than one,
+	// each chapter gets a generated header.
+	Chapters int
+	// Paragraphs is the number of paragraphs per chapter.
+	Paragraphs int
+	// Sentences and MaxWords bound the length of each paragraph,
+	// as in Generate.
+	Sentences int
+	MaxWords  int
+	// Cast is a list of character names. If it is non-empty, names
+	// the chain generates are consistently replaced with names
+	// from the cast, in order of appearance, so that the story
+	// follows the same characters throughout.
+	Cast []string
+}
+
+// storyTitleWords is the maximum number of words in a chapter title.
+const storyTitleWords = 4
+
+// GenerateStory generates a story as described by the given
+// configuration. Paragraphs are separated by blank lines. Each
+// paragraph after the first starts afresh from the "START" symbol, so
+// with a chain trained on paragraph-sized blocks of input, paragraphs
+// begin and end the way the training text's did.
+func (c *Chain) GenerateStory(conf StoryConfig) string {
+	var paragraphs []string
+	seed := conf.Topic
+	for ch := 1; ch <= conf.Chapters; ch++ {
+		if conf.Chapters > 1 {
+			paragraphs = append(paragraphs, fmt.Sprintf("Chapter %d: %s", ch, c.storyTitle()))
+		}
+		for i := 0; i < conf.Paragraphs; i++ {
+			if paragraph := c.Generate(seed, conf.Sentences, conf.MaxWords); paragraph != "" {
+				paragraphs = append(paragraphs, paragraph)
+			}
+			seed = ""
+		}
+	}
+	story := strings.Join(paragraphs, "\n\n")
+	if len(conf.Cast) > 0 {
+		story = castNames(story, conf.Cast)
+	}
+	return story
+}
+
+// storyTitle generates a short title, in title case.
+func (c *Chain) storyTitle() string {
+	words := strings.Fields(c.Generate("", 1, storyTitleWords))
+	for i, w := range words {
+		words[i] = stringutil.Capitalize(strings.TrimFunc(w, unicode.IsPunct))
+	}
+	return strings.Join(words, " ")
+}
+
+// castNames consistently replaces the names in a story (capitalized
+// words that don't start a sentence, paragraph, or header) with names
+// from the cast, in order of first appearance. Names beyond the size
+// of the cast are left alone.
+func castNames(story string, cast []string) string {
+	roles := make(map[string]string)
+	var paragraphs []string
+	for _, paragraph := range strings.Split(story, "\n\n") {
+		words := strings.Fields(paragraph)
+		header := strings.HasPrefix(paragraph, "Chapter ")
+		for i, w := range words {
+			name := strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) })
+			runes := []rune(name)
+			if len(runes) < 2 || !unicode.IsUpper(runes[0]) || !unicode.IsLower(runes[1]) {
+				continue
+			}
+			// Capitalized words at the start of a sentence
+			// might not be names, but might be names we've
+			// already cast
+			atStart := header || i == 0 || stringutil.EndsSentence(words[i-1])
+			role, ok := roles[name]
+			if !ok {
+				if atStart || len(roles) >= len(cast) {
+					continue
+				}
+				role = cast[len(roles)]
+				roles[name] = role
+			}
+			words[i] = strings.Replace(w, name, role, 1)
+		}
+		paragraphs = append(paragraphs, strings.Join(words, " "))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// stream.go generates text a word at a time, so that frontends can
+// send it as it's generated, e.g. for a typing effect, and stop when
+// they like.
+
+package markov
+
+// A WordStream generates text a word at a time (see Chain.Stream).
+type WordStream struct {
+	gen       *generator
+	prefixLen int
+	trace     bool
+	wordErr   func() error
+}
+
+// Stream returns a stream of the words of a text generated as
+// GenerateWith would with the same options, except that the stream
+// can't take back words it has returned, so text that runs into the
+// word limit ends with an incomplete sentence instead of being cut
+// back to the last complete one. Words are only generated as Next is
+// called, so the chain mustn't be modified until the caller is done
+// with the stream, but a stream can simply be abandoned.
+func (c *Chain) Stream(opts ...GenerateOption) *WordStream {
+	gen, gc, o := c.generatorWith(opts)
+	return &WordStream{gen: gen, prefixLen: c.prefixLen, trace: o.trace, wordErr: gc.wordError}
+}
+
+// Next returns the next word of the text, or false if the text is
+// finished. The words of the start string (see Start) aren't
+// returned.
+func (s *WordStream) Next() (string, bool) {
+	for {
+		w, ok := s.gen.advance()
+		if !ok {
+			return "", false
+		}
+		if w != "" {
+			return w, true
+		}
+	}
+}
+
+// Result returns the text streamed so far, including the start string,
+// and the details of how it was generated, as GenerateWith does.
+func (s *WordStream) Result() GenerationResult {
+	return s.gen.finish(false).result(s.prefixLen, s.trace, s.wordErr)
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// structured.go generates text along with a structured account of how
+// it was generated, suitable for encoding as JSON for downstream
+// tooling.
+
+package markov
+
+import (
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// A Token is a single word of structured output.
+type Token struct {
+	Word string `json:"word"`
+	// Seed is set if the word came from the start string rather
+	// than from the chain, in which case Prob and Level are zero.
+	Seed bool `json:"seed,omitempty"`
+	// Prob is the probability with which the chain chose the word.
+	Prob float64 `json:"prob,omitempty"`
+	// Level is the length of the prefix tail the chain used to
+	// choose the word; less than the chain's prefix length means
+	// the chain had to back off to a shorter prefix.
+	Level int `json:"level,omitempty"`
+}
+
+// Structured is generated text with the details of its generation.
+type Structured struct {
+	Text   string  `json:"text"`
+	Tokens []Token `json:"tokens"`
+	// Sentences are the [start, end) indices in Tokens of each
+	// sentence; the last may be a fragment.
+	Sentences [][2]int `json:"sentences"`
+	// Ended is set if the chain chose to stop, rather than running
+	// out of sentences, words, or suffixes.
+	Ended bool `json:"ended"`
+	// Filters lists the filters that shaped the output: "allowlist"
+	// if the chain's vocabulary is restricted (see SetAllowlist),
+	// and "truncate" if a trailing sentence fragment was dropped.
+	Filters []string `json:"filters,omitempty"`
+}
+
+// GenerateStructured is like Generate, but returns the generated text
+// along with its tokens, their probabilities and backoff levels, and
+// its sentence boundaries.
+func (c *Chain) GenerateStructured(start string, sentences, maxWords int) Structured {
+	g := c.generate(start, sentences, maxWords)
+	res := Structured{Text: strings.Join(g.words, " ")}
+	for i := 0; i < g.seed; i++ {
+		res.Tokens = append(res.Tokens, Token{Word: g.words[i], Seed: true})
+	}
+	// With sentence markers, End can finish a sentence that has
+	// no punctuation
+	ends := make(map[int]bool)
+	for i, st := range g.steps {
+		if g.pos[i] > len(g.words) {
+			break
+		}
+		if st.word == End {
+			ends[len(res.Tokens)] = true
+			continue
+		}
+		res.Tokens = append(res.Tokens, Token{Word: st.word, Prob: st.prob, Level: st.level})
+	}
+	if n := len(g.steps); n > 0 && !g.truncated && g.steps[n-1].word == End {
+		res.Ended = true
+	}
+
+	sentenceStart := 0
+	for i, t := range res.Tokens {
+		if stringutil.EndsSentence(t.Word) || ends[i+1] {
+			res.Sentences = append(res.Sentences, [2]int{sentenceStart, i + 1})
+			sentenceStart = i + 1
+		}
+	}
+	if sentenceStart < len(res.Tokens) {
+		res.Sentences = append(res.Sentences, [2]int{sentenceStart, len(res.Tokens)})
+	}
+
+	if c.allowlist != nil {
+		res.Filters = append(res.Filters, "allowlist")
+	}
+	if g.truncated {
+		res.Filters = append(res.Filters, "truncate")
+	}
+	return res
+}
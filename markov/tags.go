@@ -0,0 +1,142 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// tags.go lets a Chain keep separate counts for tagged sub-corpora,
+// and blend them with chosen weights at generation time, so one chain
+// can produce a stylistic mix of several sources.
+
+package markov
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+)
+
+// Tag returns a BuildOption that additionally records the text's
+// counts under the given source tag, for use by SetBlend. The text is
+// still added to the chain itself as usual.
+func Tag(tag string) BuildOption {
+	return func(o *buildOptions) {
+		o.tag = tag
+	}
+}
+
+// tagChain returns the sub-chain holding a tag's counts, creating it
+// if necessary.
+func (c *Chain) tagChain(tag string) *Chain {
+	if c.tags == nil {
+		c.tags = make(map[string]*Chain)
+	}
+	if c.tags[tag] == nil {
+		c.tags[tag] = NewChain(c.prefixLen)
+	}
+	return c.tags[tag]
+}
+
+// Tags returns the chain's source tags, in sorted order.
+func (c *Chain) Tags() []string {
+	tags := make([]string, 0, len(c.tags))
+	for tag := range c.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// SetBlend makes generation draw from the chain's tagged sub-corpora
+// instead of its combined counts, mixed according to the given
+// weights, e.g. {"general": 0.7, "shakespeare": 0.3}. Each tag's
+// suffix probabilities are weighted, not its raw counts, so a small
+// corpus gets its share however large the others are. Untagged text
+// and tags missing from weights don't contribute; when no weighted tag
+// knows a prefix, the chain backs off to a shorter one as usual.
+// Passing an empty map restores ordinary generation.
+func (c *Chain) SetBlend(weights map[string]float64) {
+	if len(weights) == 0 {
+		c.blend = nil
+		return
+	}
+	c.blend = make(map[string]float64)
+	for tag, w := range weights {
+		if w > 0 {
+			c.blend[tag] = w
+		}
+	}
+}
+
+// chooseBlended makes a random choice of a suffix to follow the given
+// prefix key, weighted by the blend of the tags' suffix probabilities
+// and restricted to allowed words. It returns the choice and the
+// probability with which it was chosen, or "" if no weighted tag
+// knows the key.
+func (c *Chain) chooseBlended(key string) (string, float64) {
+	weights, _ := c.blendWeights(key)
+	return c.chooseWeighted(weights)
+}
+
+// blendWeights returns the blended weight of each allowed suffix of the
+// given prefix key, and their total.
+func (c *Chain) blendWeights(key string) (map[string]float64, float64) {
+	weights := make(map[string]float64)
+	total := 0.0
+	for tag, w := range c.blend {
+		sub := c.tags[tag]
+		if sub == nil {
+			continue
+		}
+		suffixes := sub.chain[key]
+		mass := 0
+		for s, freq := range suffixes {
+			if c.allowed(s) {
+				mass += freq
+			}
+		}
+		if mass == 0 {
+			continue
+		}
+		for s, freq := range suffixes {
+			if c.allowed(s) {
+				weight := w * float64(freq) / float64(mass)
+				weights[s] += weight
+				total += weight
+			}
+		}
+	}
+	return weights, total
+}
+
+// LoadTags attempts to load tagged sub-corpus counts in JSON format
+// from the given file.
+func (c *Chain) LoadTags(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var tags map[string]map[string]map[string]int
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&tags); err != nil {
+		return err
+	}
+	for tag, counts := range tags {
+		c.tagChain(tag).chain = counts
+	}
+	return nil
+}
+
+// SaveTags saves a chain's tagged sub-corpus counts to the given file
+// in JSON format.
+func (c *Chain) SaveTags(filename string) error {
+	tags := make(map[string]map[string]map[string]int)
+	for tag, sub := range c.tags {
+		tags[tag] = sub.chain
+	}
+	return saveAtomic(filename, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(tags)
+	})
+}
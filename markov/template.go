@@ -0,0 +1,194 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// template.go mines frequent sentence skeletons from a corpus and
+// fills them in using a Chain. On small corpora, plain chaining tends
+// to wander off into word salad; following a skeleton that real
+// sentences share keeps the output closer to grammatical.
+
+package markov
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// Slot marks a position in a Template to be filled by generation.
+const Slot = "_"
+
+// templateVocab is the number of most common words in a corpus that
+// are kept verbatim when mining templates; all other words become
+// slots.
+const templateVocab = 100
+
+// Template is a sentence skeleton: a list of words, some of which are
+// Slots, along with the number of sentences in the mined corpus that
+// shared it.
+type Template struct {
+	Words []string
+	Count int
+}
+
+// String returns the template's words joined with spaces.
+func (t Template) String() string {
+	return strings.Join(t.Words, " ")
+}
+
+// MineTemplates reads text from the provided Reader, splits it into
+// sentences, and returns the skeletons shared by at least minCount
+// sentences, most frequent first. A skeleton keeps the corpus's most
+// common words (compared case-insensitively) and replaces every other
+// word with a Slot; skeletons with no slots or no fixed words are
+// discarded.
+func MineTemplates(r io.Reader, minCount int) ([]Template, error) {
+	text, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sentences := stringutil.SplitSentences(string(text))
+
+	// Find the most common words
+	freqs := make(map[string]int)
+	for _, s := range sentences {
+		for _, w := range strings.Fields(s) {
+			freqs[strings.ToLower(w)]++
+		}
+	}
+	var vocab []string
+	for w := range freqs {
+		vocab = append(vocab, w)
+	}
+	sort.Slice(vocab, func(i, j int) bool {
+		if freqs[vocab[i]] != freqs[vocab[j]] {
+			return freqs[vocab[i]] > freqs[vocab[j]]
+		}
+		return vocab[i] < vocab[j]
+	})
+	fixed := make(map[string]bool)
+	for i := 0; i < len(vocab) && i < templateVocab; i++ {
+		fixed[vocab[i]] = true
+	}
+
+	// Count skeletons, keeping the capitalization of the first
+	// sentence seen for each
+	templates := make(map[string]*Template)
+	for _, s := range sentences {
+		var words []string
+		slots := 0
+		for _, w := range strings.Fields(s) {
+			if fixed[strings.ToLower(w)] {
+				words = append(words, w)
+			} else {
+				words = append(words, Slot)
+				slots++
+			}
+		}
+		if slots == 0 || slots == len(words) {
+			continue
+		}
+		key := strings.ToLower(strings.Join(words, " "))
+		if templates[key] == nil {
+			templates[key] = &Template{Words: words}
+		}
+		templates[key].Count++
+	}
+
+	var result []Template
+	for _, t := range templates {
+		if t.Count >= minCount {
+			result = append(result, *t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].String() < result[j].String()
+	})
+	return result, nil
+}
+
+// FillTemplate generates a sentence following the given template,
+// filling each Slot with a word chosen by the chain. When a slot is
+// followed by a fixed word, it prefers words that the chain has seen
+// leading into that fixed word. It returns "" if some slot can't be
+// filled.
+func (c *Chain) FillTemplate(t Template) string {
+	p := NewPrefix(c.prefixLen)
+	var words []string
+	for i, w := range t.Words {
+		if w != Slot {
+			words = append(words, w)
+			c.shift(p, w)
+			continue
+		}
+		next := ""
+		if i+1 < len(t.Words) && t.Words[i+1] != Slot {
+			next = c.chooseLeadingTo(p, t.Words[i+1])
+		}
+		if next == "" {
+			next = c.NextWord(p)
+		}
+		if next == "" || next == End {
+			return ""
+		}
+		words = append(words, c.caser().restore(p, next))
+		c.shift(p, next)
+	}
+	return strings.Join(words, " ")
+}
+
+// GenerateFromTemplates picks one of the given templates at random,
+// weighted by how often it occurred in the mined corpus, and fills it
+// in. It tries a few templates before giving up and returning "".
+func (c *Chain) GenerateFromTemplates(templates []Template) string {
+	total := 0
+	for _, t := range templates {
+		total += t.Count
+	}
+	if total == 0 {
+		return ""
+	}
+	for try := 0; try < 5; try++ {
+		n := rand.Intn(total)
+		for _, t := range templates {
+			n -= t.Count
+			if n < 0 {
+				if s := c.FillTemplate(t); s != "" {
+					return s
+				}
+				break
+			}
+		}
+	}
+	return ""
+}
+
+// chooseLeadingTo randomly chooses a word to follow the given prefix
+// such that the chain has seen the target word follow it, weighting
+// each candidate by how often both transitions occurred. It returns
+// "" if there is no such word.
+func (c *Chain) chooseLeadingTo(p Prefix, target string) string {
+	for i := 0; i <= c.prefixLen; i++ {
+		suffixes := c.chain[strings.Join(p[i:], " ")]
+		candidates := make(map[string]int)
+		for s, freq := range suffixes {
+			q := append(Prefix(nil), p...)
+			c.shift(q, s)
+			if n := c.chain[strings.Join(q, " ")][target]; n > 0 {
+				candidates[s] = freq * n
+			}
+		}
+		if result, _ := c.choose(candidates); result != "" {
+			return result
+		}
+	}
+	return ""
+}
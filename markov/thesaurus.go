@@ -0,0 +1,129 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// thesaurus.go optionally consults a list of synonyms for prefix words
+// a Chain doesn't know, so that a seed about an "automobile" can
+// continue from what the chain learned about cars. A small English
+// list is built in; programs can plug in their own.
+
+package markov
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"strings"
+	"sync"
+)
+
+// A Thesaurus provides synonyms for words (see SetThesaurus).
+type Thesaurus interface {
+	// Synonyms returns words with about the same meaning as a
+	// lowercase word, most interchangeable first, or nil if it knows
+	// of none.
+	Synonyms(word string) []string
+}
+
+// A SynonymList is a Thesaurus listing the synonyms of each lowercase
+// word.
+type SynonymList map[string][]string
+
+// Synonyms returns the listed synonyms of a word.
+func (l SynonymList) Synonyms(word string) []string {
+	return l[strings.ToLower(word)]
+}
+
+// ReadSynonyms reads a SynonymList from r: groups of words with about
+// the same meaning, one group per line, separated by commas, with "#"
+// comments. Each word in a group is a synonym of the others, and a
+// word in several groups is a synonym of the words in all of them.
+func ReadSynonyms(r io.Reader) (SynonymList, error) {
+	l := make(SynonymList)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var group []string
+		for _, w := range strings.Split(line, ",") {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+				group = append(group, w)
+			}
+		}
+		for _, w := range group {
+			for _, syn := range group {
+				if syn != w && !contains(l[w], syn) {
+					l[w] = append(l[w], syn)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+//go:embed thesaurus.txt
+var builtinSynonyms string
+
+var builtinThesaurus SynonymList
+var builtinThesaurusOnce sync.Once
+
+// BuiltinThesaurus returns a small thesaurus of common English words,
+// e.g. "automobile" and "car". It's shared, so it mustn't be
+// modified.
+func BuiltinThesaurus() SynonymList {
+	builtinThesaurusOnce.Do(func() {
+		builtinThesaurus, _ = ReadSynonyms(strings.NewReader(builtinSynonyms))
+	})
+	return builtinThesaurus
+}
+
+// SetThesaurus sets a thesaurus to consult for prefix words the chain
+// doesn't know, or turns it off if t is nil. With a thesaurus, when
+// generation finds no suffixes for a prefix containing unknown words
+// (nor, with stemming on, for its stems), it tries the suffixes of the
+// prefixes with each unknown word replaced by its synonyms that the
+// chain knows, before trying fuzzy matching (see SetFuzzy) and
+// skip-grams (see SetSkipGrams) or backing off to a shorter prefix.
+// Words are looked up without their surrounding punctuation, which is
+// kept on their synonyms. The thesaurus is consulted by generation
+// from any goroutine, so it must be safe for concurrent use.
+func (c *Chain) SetThesaurus(t Thesaurus) {
+	c.thesaurus = t
+}
+
+// knownSynonyms returns the synonyms of a prefix word that the chain
+// knows, as prefix words, or nil if there are none.
+func (c *Chain) knownSynonyms(w string) []string {
+	lead, core, trail := splitWord(w)
+	if core == "" {
+		return nil
+	}
+	var known []string
+	p := NewPrefix(1)
+	for _, syn := range c.thesaurus.Synonyms(strings.ToLower(core)) {
+		if strings.Contains(syn, " ") {
+			continue
+		}
+		c.shift(p, lead+syn+trail)
+		if c.chain[p[0]] != nil && !contains(known, p[0]) {
+			known = append(known, p[0])
+		}
+	}
+	return known
+}
+
+// contains reports whether a list of words contains w.
+func contains(words []string, w string) bool {
+	for _, x := range words {
+		if x == w {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// tokens.go controls how Build treats links and mentions of other
+// users, so that generated text doesn't send people to dead or
+// mangled links or ping random users.
+
+package markov
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A URLPolicy is what Build does with URLs in its input.
+type URLPolicy int
+
+const (
+	// KeepURLs learns URLs like any other word.
+	KeepURLs URLPolicy = iota
+	// DropURLs leaves URLs out.
+	DropURLs
+	// PlaceholderURLs learns URLPlaceholder in place of each URL,
+	// so that generated text shows where links would go.
+	PlaceholderURLs
+)
+
+// URLPlaceholder is the word learned in place of URLs with
+// PlaceholderURLs.
+const URLPlaceholder = "<url>"
+
+var urlPolicyNames = []string{"keep", "drop", "placeholder"}
+
+// String returns the policy's name.
+func (p URLPolicy) String() string {
+	if p < 0 || int(p) >= len(urlPolicyNames) {
+		return fmt.Sprintf("URLPolicy(%d)", int(p))
+	}
+	return urlPolicyNames[p]
+}
+
+// ParseURLPolicy returns the URL policy with the given name ("keep",
+// "drop", or "placeholder").
+func ParseURLPolicy(name string) (URLPolicy, error) {
+	for i, n := range urlPolicyNames {
+		if strings.EqualFold(name, n) {
+			return URLPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("markov: unknown URL policy %q", name)
+}
+
+// A MentionPolicy is what Build does with mentions of users, like
+// "@alice", and with nick prefixes addressing a message to someone,
+// like "alice: ".
+type MentionPolicy int
+
+const (
+	// KeepMentions learns mentions like any other word.
+	KeepMentions MentionPolicy = iota
+	// StripMentions learns mentions without their "@", so they no
+	// longer ping anyone, and leaves out nick prefixes.
+	StripMentions
+	// DropMentions leaves out mentions and nick prefixes.
+	DropMentions
+)
+
+var mentionPolicyNames = []string{"keep", "strip", "drop"}
+
+// String returns the policy's name.
+func (p MentionPolicy) String() string {
+	if p < 0 || int(p) >= len(mentionPolicyNames) {
+		return fmt.Sprintf("MentionPolicy(%d)", int(p))
+	}
+	return mentionPolicyNames[p]
+}
+
+// ParseMentionPolicy returns the mention policy with the given name
+// ("keep", "strip", or "drop").
+func ParseMentionPolicy(name string) (MentionPolicy, error) {
+	for i, n := range mentionPolicyNames {
+		if strings.EqualFold(name, n) {
+			return MentionPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("markov: unknown mention policy %q", name)
+}
+
+// urlWord matches words that are URLs, possibly in brackets or
+// quotes, capturing any punctuation after them.
+var urlWord = regexp.MustCompile(`^[(<"'\[]*(?i:[a-z][a-z0-9+.-]*://|www\.)[^\s]*?([)>"'\].,;:!?]*)$`)
+
+// mentionWord matches mentions, capturing the name mentioned and any
+// punctuation after it.
+var mentionWord = regexp.MustCompile(`^@([\w.-]*\w)(\W*)$`)
+
+// nickPrefix matches a word addressing a message to a nick, e.g.
+// "alice:" or "<alice>".
+var nickPrefix = regexp.MustCompile(`^(@?[\w\[\]{}^|` + "`" + `.-]+:|<[^<>\s]+>)$`)
+
+// SetURLPolicy sets what Build does with URLs in its input.
+func (c *Chain) SetURLPolicy(p URLPolicy) {
+	c.urls = p
+}
+
+// SetMentionPolicy sets what Build does with mentions of users and
+// nick prefixes in its input.
+func (c *Chain) SetMentionPolicy(p MentionPolicy) {
+	c.mentions = p
+}
+
+// token returns what Build learns for a word of its input, according
+// to the chain's URL and mention policies, or "" to leave it out.
+// first is set for the first word of the input, where nick prefixes
+// go. The word END is escaped, so it isn't taken for End.
+func (c *Chain) token(s string, first bool) string {
+	return escapeEnd(c.policyToken(s, first))
+}
+
+// escapeEnd escapes a learned word that would be taken for End with a
+// backslash. Words that already look escaped get another one, so that
+// unescapeEnd always undoes it.
+func escapeEnd(word string) string {
+	if strings.TrimLeft(word, `\`) == End {
+		return `\` + word
+	}
+	return word
+}
+
+// unescapeEnd undoes escapeEnd for a generated word.
+func unescapeEnd(word string) string {
+	if word != End && strings.TrimLeft(word, `\`) == End {
+		return word[1:]
+	}
+	return word
+}
+
+// policyToken implements token, before escaping.
+func (c *Chain) policyToken(s string, first bool) string {
+	if c.urls != KeepURLs {
+		if m := urlWord.FindStringSubmatch(s); m != nil {
+			if c.urls == DropURLs {
+				return ""
+			}
+			// Keep the punctuation after the URL, but not the
+			// brackets or quotes around it
+			return URLPlaceholder + strings.Trim(m[1], `)>"']`)
+		}
+	}
+	if c.mentions != KeepMentions {
+		if first && nickPrefix.MatchString(s) {
+			return ""
+		}
+		if m := mentionWord.FindStringSubmatch(s); m != nil {
+			if c.mentions == DropMentions {
+				return ""
+			}
+			return m[1] + m[2]
+		}
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package markov
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeEnd(t *testing.T) {
+	tests := []struct {
+		word, escaped string
+	}{
+		{"end", "end"},
+		{"END", `\END`},
+		{`\END`, `\\END`},
+		{`\end`, `\end`},
+		{"ENDS", "ENDS"},
+	}
+	for _, test := range tests {
+		if got := escapeEnd(test.word); got != test.escaped {
+			t.Errorf("escapeEnd(%q) = %q, want %q", test.word, got, test.escaped)
+		}
+		if got := unescapeEnd(test.escaped); got != test.word {
+			t.Errorf("unescapeEnd(%q) = %q, want %q", test.escaped, got, test.word)
+		}
+	}
+}
+
+func TestLearnedEnd(t *testing.T) {
+	c := NewChain(2)
+	c.Build(strings.NewReader("THE END"))
+	if got := c.Generate("", 1, 10); got != "THE END" {
+		t.Errorf("Generate = %q, want %q", got, "THE END")
+	}
+}
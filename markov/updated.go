@@ -0,0 +1,101 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// updated.go optionally tracks when each prefix in a Chain was last
+// trained, so that callers can ask what the chain learned recently
+// and decay only the regions of the chain that have gone stale.
+
+package markov
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// TrackUpdates starts recording the time at which each prefix is
+// updated by Add. Times are rounded down to a multiple of bucket
+// (e.g. time.Hour), which keeps saved timestamps small and coarse; a
+// bucket of zero or less records times to the second. Prefixes
+// trained before tracking started have no recorded time.
+func (c *Chain) TrackUpdates(bucket time.Duration) {
+	if bucket < time.Second {
+		bucket = time.Second
+	}
+	c.updateBucket = bucket
+	if c.updated == nil {
+		c.updated = make(map[string]int64)
+	}
+}
+
+// touch records that the given prefix key was just updated, if update
+// tracking is enabled.
+func (c *Chain) touch(key string) {
+	if c.updated == nil {
+		return
+	}
+	c.updated[key] = time.Now().Truncate(c.updateBucket).Unix()
+}
+
+// LastUpdated returns the (bucketed) time at which the given prefix
+// was last updated, and false if no update time is known for it.
+func (c *Chain) LastUpdated(prefix string) (time.Time, bool) {
+	t, ok := c.updated[prefix]
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(t, 0), true
+}
+
+// UpdatedSince returns a sorted list of prefixes last updated at or
+// after the given time.
+func (c *Chain) UpdatedSince(since time.Time) []string {
+	var prefixes []string
+	for key, t := range c.updated {
+		if t >= since.Unix() {
+			prefixes = append(prefixes, key)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// DecayStale scales the suffix counts of every prefix that hasn't been
+// updated since the given time by factor (which should be between 0
+// and 1), rounding down and forgetting suffixes and prefixes whose
+// counts drop to zero. Prefixes with no known update time count as
+// stale. It returns the number of prefixes decayed.
+func (c *Chain) DecayStale(before time.Time, factor float64) int {
+	return c.decayWhere(factor, func(key string) bool {
+		t, ok := c.updated[key]
+		return !ok || t < before.Unix()
+	})
+}
+
+// LoadUpdates attempts to load prefix update times in JSON format
+// from the given file, and starts tracking updates with the given
+// bucket size.
+func (c *Chain) LoadUpdates(filename string, bucket time.Duration) error {
+	c.TrackUpdates(bucket)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	return dec.Decode(&(c.updated))
+}
+
+// SaveUpdates saves a chain's prefix update times to the given file
+// in JSON format.
+func (c *Chain) SaveUpdates(filename string) error {
+	return saveAtomic(filename, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(c.updated)
+	})
+}
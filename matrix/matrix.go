@@ -0,0 +1,217 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// matrix is a bot frontend (see package bot) for Matrix: it joins the
+// rooms it's invited to and passes on their messages. Since Matrix
+// bridges reach IRC, Slack, and more, one bot can cover rooms on those
+// networks indirectly. Encrypted rooms aren't supported.
+
+package matrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/bot"
+)
+
+// syncTimeout is how long the homeserver may hold each sync request
+// open waiting for events.
+const syncTimeout = 30 * time.Second
+
+// retryDelay is how long to wait after a failed sync before retrying.
+const retryDelay = 10 * time.Second
+
+// Bot is a Matrix client implementing bot.Frontend. Its channels are
+// room IDs.
+type Bot struct {
+	mu         sync.Mutex
+	homeserver string
+	userID     string
+	token      string
+	client     *http.Client
+	since      string
+	txn        int64
+	messages   chan bot.Message
+}
+
+// NewBot returns a Bot that logs in to the given homeserver (e.g.
+// "https://matrix.example.org") as the given user (e.g.
+// "@clyde:example.org") with an access token. Users address it by the
+// user's localpart ("clyde").
+func NewBot(homeserver, userID, token string) *Bot {
+	return &Bot{
+		homeserver: strings.TrimRight(homeserver, "/"),
+		userID:     userID,
+		token:      token,
+		client:     &http.Client{Timeout: syncTimeout + 30*time.Second},
+		txn:        time.Now().UnixNano(),
+		messages:   make(chan bot.Message),
+	}
+}
+
+// Identity implements bot.Frontend.
+func (b *Bot) Identity() bot.Identity {
+	name := strings.TrimPrefix(strings.SplitN(b.userID, ":", 2)[0], "@")
+	return bot.Identity{Network: "matrix", ID: b.userID, Name: name}
+}
+
+// Messages implements bot.Frontend.
+func (b *Bot) Messages() <-chan bot.Message {
+	return b.messages
+}
+
+// syncResponse is the part of a sync response the bot uses.
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+// event is the part of a room event the bot uses.
+type event struct {
+	Type    string `json:"type"`
+	Sender  string `json:"sender"`
+	Content struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+	} `json:"content"`
+}
+
+// Run syncs with the homeserver, passing on messages and accepting
+// invitations, until stop is closed, and then closes the Messages
+// channel. Messages sent before Run was called are ignored, so
+// restarting the bot doesn't relearn them, but invitations received
+// while it wasn't running are still accepted.
+func (b *Bot) Run(stop <-chan struct{}) {
+	defer close(b.messages)
+	first := true
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		timeout := syncTimeout
+		if first {
+			timeout = 0
+		}
+		resp, err := b.sync(timeout)
+		if err != nil {
+			log.Printf("Matrix sync error: %v", err)
+			select {
+			case <-stop:
+				return
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+		b.since = resp.NextBatch
+		b.handle(resp, first)
+		first = false
+	}
+}
+
+// sync fetches the events since the last sync.
+func (b *Bot) sync(timeout time.Duration) (*syncResponse, error) {
+	q := url.Values{}
+	q.Set("timeout", strconv.FormatInt(int64(timeout/time.Millisecond), 10))
+	if b.since != "" {
+		q.Set("since", b.since)
+	}
+	var resp syncResponse
+	err := b.call("GET", "/_matrix/client/v3/sync?"+q.Encode(), nil, &resp)
+	return &resp, err
+}
+
+// handle joins rooms the bot's been invited to, and passes on new
+// messages, unless the sync is the initial one, whose timelines are
+// only history.
+func (b *Bot) handle(resp *syncResponse, initial bool) {
+	for room := range resp.Rooms.Invite {
+		log.Printf("Joining %s", room)
+		if err := b.call("POST", "/_matrix/client/v3/join/"+url.PathEscape(room), struct{}{}, nil); err != nil {
+			log.Printf("Error joining %s: %v", room, err)
+		}
+	}
+
+	if initial {
+		return
+	}
+	for room, joined := range resp.Rooms.Join {
+		for _, ev := range joined.Timeline.Events {
+			if ev.Type != "m.room.message" || ev.Sender == b.userID {
+				continue
+			}
+			if ev.Content.MsgType != "m.text" && ev.Content.MsgType != "m.emote" {
+				continue
+			}
+			b.messages <- bot.Message{Channel: room, Sender: ev.Sender, Text: ev.Content.Body}
+		}
+	}
+}
+
+// Send sends a text message to a room. It implements bot.Frontend.
+func (b *Bot) Send(room, text string) error {
+	b.mu.Lock()
+	b.txn++
+	txn := strconv.FormatInt(b.txn, 10)
+	b.mu.Unlock()
+
+	msg := map[string]string{"msgtype": "m.text", "body": text}
+	return b.call("PUT", fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s", url.PathEscape(room), txn), msg, nil)
+}
+
+// call makes an authenticated request to the homeserver, sending in
+// (if non-nil) and decoding the response into out (if non-nil).
+func (b *Bot) call(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, b.homeserver+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("matrix: %s %s: %s: %s", method, strings.SplitN(path, "?", 2)[0], resp.Status, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
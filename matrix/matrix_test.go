@@ -0,0 +1,70 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunInitialSync(t *testing.T) {
+	syncs := []string{
+		// The initial sync: an invitation, and old history
+		`{"next_batch": "1", "rooms": {
+			"invite": {"!new:example.org": {}},
+			"join": {"!old:example.org": {"timeline": {"events": [
+				{"type": "m.room.message", "sender": "@alice:example.org", "content": {"msgtype": "m.text", "body": "old news"}}
+			]}}}}}`,
+		`{"next_batch": "2", "rooms": {
+			"join": {"!old:example.org": {"timeline": {"events": [
+				{"type": "m.room.message", "sender": "@clyde:example.org", "content": {"msgtype": "m.text", "body": "my own"}},
+				{"type": "m.room.message", "sender": "@alice:example.org", "content": {"msgtype": "m.text", "body": "fresh news"}}
+			]}}}}}`,
+	}
+	var mu sync.Mutex
+	var joined []string
+	n := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/sync"):
+			if n < len(syncs) {
+				fmt.Fprint(w, syncs[n])
+			} else {
+				fmt.Fprintf(w, `{"next_batch": "%d"}`, n+1)
+			}
+			n++
+		case strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/join/"):
+			joined = append(joined, strings.TrimPrefix(r.URL.Path, "/_matrix/client/v3/join/"))
+			fmt.Fprint(w, `{}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	b := NewBot(server.URL, "@clyde:example.org", "token")
+	stop := make(chan struct{})
+	go b.Run(stop)
+	msg := <-b.Messages()
+	close(stop)
+	for range b.Messages() {
+	}
+
+	if msg.Text != "fresh news" || msg.Channel != "!old:example.org" {
+		t.Errorf("first message = %q in %s, want %q in %s", msg.Text, msg.Channel, "fresh news", "!old:example.org")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(joined) != 1 || joined[0] != "!new:example.org" {
+		t.Errorf("joined %q, want the room invited to before the bot started", joined)
+	}
+}
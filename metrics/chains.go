@@ -0,0 +1,48 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// chains.go exports gauges describing a set of chains, shared by
+// everything that serves one.
+
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// ChainGauges returns gauges for the number of chains in a set, the
+// size of each chain, and the number of words generated using each
+// length of prefix, as totalled in stats, which shows how often
+// generation backs off to shorter contexts. The gauges read the chains
+// through do, which should call its argument with the chains while
+// nothing else is using them, like bot.Core.Do. Gauge names start with
+// prefix.
+func ChainGauges(prefix string, do func(f func(chains *markov.ChainSet)), stats *markov.Stats) []Metric {
+	return []Metric{
+		NewGauge(prefix+"chains", "Number of chains.", func() float64 {
+			var n int
+			do(func(chains *markov.ChainSet) { n = chains.Len() })
+			return float64(n)
+		}),
+		NewLabeledGauge(prefix+"chain_prefixes", "Number of prefixes stored in each chain.", "chain", func() map[string]float64 {
+			sizes := make(map[string]float64)
+			do(func(chains *markov.ChainSet) {
+				chains.Each(func(name string, c *markov.Chain) {
+					sizes[name] = float64(c.Size())
+				})
+			})
+			return sizes
+		}),
+		NewLabeledGauge(prefix+"generated_words", "Number of words generated, by length of prefix used.", "context", func() map[string]float64 {
+			words := make(map[string]float64)
+			for n, count := range stats.Levels() {
+				words[strconv.Itoa(n)] = float64(count)
+			}
+			return words
+		}),
+	}
+}
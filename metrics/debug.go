@@ -0,0 +1,89 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// debug.go serves the Go runtime's profiles and a dump of a set of
+// chains' sizes, to answer why a learning bot's memory keeps growing.
+
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sort"
+
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// A ChainDebug describes one chain in a DebugReport.
+type ChainDebug struct {
+	Name     string `json:"name"`
+	Prefixes int    `json:"prefixes"`
+	// Bytes is the chain's estimated memory use (see
+	// markov.Chain.MemoryEstimate).
+	Bytes int `json:"bytes"`
+}
+
+// A DebugReport is the reply to GET /debug/chains: every chain,
+// largest first, and the Go runtime's view of the process's memory.
+type DebugReport struct {
+	Chains []ChainDebug `json:"chains"`
+	// ChainBytes is the total estimated memory use of the chains.
+	ChainBytes int `json:"chainBytes"`
+	// HeapAlloc, HeapInuse, and Sys are as in runtime.MemStats.
+	HeapAlloc  uint64 `json:"heapAlloc"`
+	HeapInuse  uint64 `json:"heapInuse"`
+	Sys        uint64 `json:"sys"`
+	NumGC      uint32 `json:"numGC"`
+	Goroutines int    `json:"goroutines"`
+}
+
+// DebugHandler returns an http.Handler serving the net/http/pprof
+// profiles under /debug/pprof/ and a DebugReport as JSON at
+// /debug/chains, reading the chains through do as in ChainGauges.
+// Profiles can reveal the process's data, so the handler should only
+// be served to trusted clients.
+func DebugHandler(do func(f func(chains *markov.ChainSet))) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/chains", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Debug(do))
+	})
+	return mux
+}
+
+// Debug returns a DebugReport for the chains read through do, as in
+// ChainGauges.
+func Debug(do func(f func(chains *markov.ChainSet))) DebugReport {
+	var report DebugReport
+	do(func(chains *markov.ChainSet) {
+		chains.Each(func(name string, c *markov.Chain) {
+			report.Chains = append(report.Chains, ChainDebug{
+				Name:     name,
+				Prefixes: c.Size(),
+				Bytes:    c.MemoryEstimate(),
+			})
+		})
+	})
+	sort.Slice(report.Chains, func(i, j int) bool {
+		return report.Chains[i].Bytes > report.Chains[j].Bytes
+	})
+	for _, c := range report.Chains {
+		report.ChainBytes += c.Bytes
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	report.HeapAlloc, report.HeapInuse, report.Sys = m.HeapAlloc, m.HeapInuse, m.Sys
+	report.NumGC = m.NumGC
+	report.Goroutines = runtime.NumGoroutine()
+	return report
+}
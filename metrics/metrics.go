@@ -0,0 +1,245 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// metrics exports counters, gauges, and histograms in the Prometheus
+// text exposition format, so that long-running bots and servers can be
+// monitored without linking the Prometheus client library.
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are histogram bucket upper bounds suited to latencies
+// in seconds, from a millisecond to ten seconds.
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// A Metric is a counter, gauge, or histogram that a Registry exports.
+type Metric interface {
+	// Name returns the metric's name.
+	Name() string
+	// write writes the metric's samples in the text format.
+	write(w *bufio.Writer)
+}
+
+// A Counter is a count that only goes up, like the number of messages
+// learned. Its methods may be called concurrently.
+type Counter struct {
+	name, help string
+	n          uint64
+}
+
+// NewCounter returns a Counter with the given name and help text.
+func NewCounter(name, help string) *Counter {
+	return &Counter{name: name, help: help}
+}
+
+// Name returns the counter's name.
+func (c *Counter) Name() string {
+	return c.name
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.n, n)
+}
+
+// Value returns the counter's current value.
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.n)
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	fmt.Fprintf(w, "%s %d\n", c.name, c.Value())
+}
+
+// A Gauge is a value that can go up and down, like the size of a
+// chain, computed by a function whenever the metrics are exported. The
+// function returns the value for each value of the gauge's label; a
+// gauge without a label should return a single value under "".
+type Gauge struct {
+	name, help, label string
+	f                 func() map[string]float64
+}
+
+// NewGauge returns a Gauge with the given name and help text whose
+// value is computed by f.
+func NewGauge(name, help string, f func() float64) *Gauge {
+	return &Gauge{name: name, help: help, f: func() map[string]float64 {
+		return map[string]float64{"": f()}
+	}}
+}
+
+// NewLabeledGauge returns a Gauge with the given name and help text
+// whose values for each value of the given label are computed by f.
+func NewLabeledGauge(name, help, label string, f func() map[string]float64) *Gauge {
+	return &Gauge{name: name, help: help, label: label, f: f}
+}
+
+// Name returns the gauge's name.
+func (g *Gauge) Name() string {
+	return g.name
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	values := g.f()
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	writeHeader(w, g.name, g.help, "gauge")
+	for _, k := range keys {
+		if g.label == "" {
+			fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(values[k]))
+		} else {
+			fmt.Fprintf(w, "%s{%s=%s} %s\n", g.name, g.label, quote(k), formatFloat(values[k]))
+		}
+	}
+}
+
+// A Histogram counts observations, like generation latencies, in
+// buckets. Its methods may be called concurrently.
+type Histogram struct {
+	name, help string
+	mu         sync.Mutex
+	buckets    []float64
+	counts     []uint64
+	sum        float64
+	count      uint64
+}
+
+// NewHistogram returns a Histogram with the given name and help text,
+// counting observations up to each of the given bucket upper bounds
+// (or DefaultBuckets, if buckets is nil).
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+// Name returns the histogram's name.
+func (h *Histogram) Name() string {
+	return h.name
+}
+
+// Observe records an observation.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Buckets are cumulative, so count v in every bucket it fits in
+	for i := len(h.buckets) - 1; i >= 0 && v <= h.buckets[i]; i-- {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	sum, count := h.sum, h.count
+	h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for i, b := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=%s} %d\n", h.name, quote(formatFloat(b)), counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, formatFloat(sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, count)
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric.
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// quote quotes a label value.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// formatFloat formats a sample value.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// A Registry is an http.Handler exporting a set of metrics, in the
+// order they were registered, for Prometheus to scrape.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []Metric
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds metrics to the registry. It panics if a metric with
+// the same name is already registered, since Prometheus would reject
+// the export.
+func (r *Registry) Register(metrics ...Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range metrics {
+		for _, old := range r.metrics {
+			if old.Name() == m.Name() {
+				panic("metrics: duplicate metric " + m.Name())
+			}
+		}
+		r.metrics = append(r.metrics, m)
+	}
+}
+
+// ServeHTTP implements http.Handler, writing the registry's metrics in
+// the text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	metrics := append([]Metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	bw.Flush()
+}
@@ -0,0 +1,159 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// moderation keeps configured terms (profanity, slurs, names that
+// shouldn't come up) out of what a bot says, whatever it learned.
+// It works on generated text, after the fact, so it also catches
+// terms that a chain puts together from innocent pieces.
+
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// A Strategy is what a Filter does with sentences containing its
+// terms.
+type Strategy int
+
+const (
+	// Drop removes the sentences.
+	Drop Strategy = iota
+	// Regenerate generates the text again, and drops any sentences
+	// still containing terms after a few tries.
+	Regenerate
+	// Mask replaces the terms with asterisks.
+	Mask
+)
+
+var strategyNames = []string{"drop", "regenerate", "mask"}
+
+// String returns the strategy's name.
+func (s Strategy) String() string {
+	if s < 0 || int(s) >= len(strategyNames) {
+		return fmt.Sprintf("Strategy(%d)", int(s))
+	}
+	return strategyNames[s]
+}
+
+// ParseStrategy returns the strategy with the given name ("drop",
+// "regenerate", or "mask").
+func ParseStrategy(name string) (Strategy, error) {
+	for i, n := range strategyNames {
+		if strings.EqualFold(name, n) {
+			return Strategy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("moderation: unknown strategy %q", name)
+}
+
+// regenerateTries is the number of times Regenerate generates text
+// again before giving up and dropping sentences.
+const regenerateTries = 3
+
+// Filter finds configured terms in text, matching whole words
+// case-insensitively, and handles them according to a Strategy. A nil
+// Filter has no terms, so callers with an optional filter can use it
+// without checking.
+type Filter struct {
+	Strategy Strategy
+	rex      *regexp.Regexp
+}
+
+// NewFilter returns a Filter for the given terms, which may be single
+// words or phrases.
+func NewFilter(terms []string, strategy Strategy) *Filter {
+	var quoted []string
+	for _, t := range terms {
+		if words := strings.Fields(t); len(words) > 0 {
+			for i, w := range words {
+				words[i] = regexp.QuoteMeta(w)
+			}
+			quoted = append(quoted, strings.Join(words, "\\s+"))
+		}
+	}
+	f := &Filter{Strategy: strategy}
+	if len(quoted) > 0 {
+		f.rex = regexp.MustCompile("(?i)\\b(" + strings.Join(quoted, "|") + ")\\b")
+	}
+	return f
+}
+
+// LoadFilter returns a Filter for the terms in a file, one per line,
+// ignoring blank lines and lines starting with "#".
+func LoadFilter(filename string, strategy Strategy) (*Filter, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			terms = append(terms, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewFilter(terms, strategy), nil
+}
+
+// Matches returns a boolean indicating whether text contains any of
+// the filter's terms.
+func (f *Filter) Matches(text string) bool {
+	return f != nil && f.rex != nil && f.rex.MatchString(text)
+}
+
+// Apply returns text with the sentences containing the filter's terms
+// handled according to its strategy. For Regenerate, regenerate is
+// called to generate replacement text; if it's nil, Apply drops
+// sentences instead. The result is "" if nothing acceptable is left.
+func (f *Filter) Apply(text string, regenerate func() string) string {
+	if !f.Matches(text) {
+		return text
+	}
+	switch f.Strategy {
+	case Mask:
+		return f.rex.ReplaceAllStringFunc(text, func(term string) string {
+			return strings.Map(func(r rune) rune {
+				if r == ' ' || r == '\t' || r == '\n' {
+					return r
+				}
+				return '*'
+			}, term)
+		})
+	case Regenerate:
+		if regenerate != nil {
+			for i := 0; i < regenerateTries; i++ {
+				if text = regenerate(); !f.Matches(text) {
+					return text
+				}
+			}
+		}
+	}
+	return f.drop(text)
+}
+
+// drop returns text without the sentences containing the filter's
+// terms.
+func (f *Filter) drop(text string) string {
+	var kept []string
+	for _, sentence := range stringutil.SplitSentences(text) {
+		if !f.Matches(sentence) {
+			kept = append(kept, sentence)
+		}
+	}
+	return strings.Join(kept, " ")
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package moderation
+
+import "testing"
+
+func TestFilterApply(t *testing.T) {
+	terms := []string{"darn", "heck no"}
+	tests := []struct {
+		strategy   Strategy
+		text       string
+		regenerate string // "" for no regenerate function
+		want       string
+	}{
+		{Drop, "Hello there.", "", "Hello there."},
+		{Drop, "Oh darn. Hello there.", "", "Hello there."},
+		{Drop, "Darn it!", "", ""},
+		{Drop, "Darning socks.", "", "Darning socks."},
+		{Mask, "Oh darn. Heck  no!", "", "Oh ****. ****  **!"},
+		{Regenerate, "Oh darn.", "Oh well.", "Oh well."},
+		{Regenerate, "Oh darn. Fine.", "Still darn. Okay.", "Okay."},
+		{Regenerate, "Oh darn. Fine.", "", "Fine."},
+	}
+	for _, tt := range tests {
+		f := NewFilter(terms, tt.strategy)
+		var regenerate func() string
+		if tt.regenerate != "" {
+			regenerate = func() string { return tt.regenerate }
+		}
+		if got := f.Apply(tt.text, regenerate); got != tt.want {
+			t.Errorf("%v: Apply(%q) = %q, want %q", tt.strategy, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNilFilter(t *testing.T) {
+	var f *Filter
+	if f.Matches("darn") {
+		t.Errorf("nil filter matched")
+	}
+	if got := f.Apply("darn", nil); got != "darn" {
+		t.Errorf("nil filter Apply = %q, want the text unchanged", got)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	s, err := NewScrubber([]string{"email", "secret"}, []string{`\bACME-\d{6}\b`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text, want string
+	}{
+		{"mail me at sam@example.org", "mail me at [email]"},
+		{"my password is hunter2", "my [secret]"},
+		{"ticket ACME-123456 is open", "ticket [redacted] is open"},
+		{"nothing to see here", "nothing to see here"},
+	}
+	for _, test := range tests {
+		if got := s.Scrub(test.text); got != test.want {
+			t.Errorf("Scrub(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+
+	var none *Scrubber
+	if got := none.Scrub("sam@example.org"); got != "sam@example.org" {
+		t.Errorf("nil scrubber Scrub = %q, want the text unchanged", got)
+	}
+}
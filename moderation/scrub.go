@@ -0,0 +1,102 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// scrub.go masks personal information and secrets in text before a
+// bot learns it, as a chain can repeat anything it was trained on.
+
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ScrubKinds are the kinds of information a Scrubber knows how to
+// find: email addresses, phone numbers, and secrets such as API
+// tokens, private keys, and passwords.
+var ScrubKinds = []string{"email", "phone", "secret"}
+
+// scrubPatterns match each kind of information in ScrubKinds.
+var scrubPatterns = map[string][]*regexp.Regexp{
+	"email": {
+		regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9-]+(\.[a-z0-9-]+)*\.[a-z]{2,}\b`),
+	},
+	"phone": {
+		// International numbers, and numbers in groups like
+		// "(555) 123-4567" or "020 7946 0958", but not dates
+		regexp.MustCompile(`\+\d{1,3}([ .-]?\(?\d{1,4}\)?){2,5}\d\b`),
+		regexp.MustCompile(`(\(\d{2,4}\) ?|\b\d{2,4}[ .-])\d{3,4}[ .-]\d{3,4}\b`),
+	},
+	"secret": {
+		regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(-----END [A-Z ]*PRIVATE KEY-----|$)`),
+		// AWS access keys, GitHub, Slack, and Stripe tokens, and
+		// JSON Web Tokens
+		regexp.MustCompile(`\b(AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{30,}|xox[abposr]-[A-Za-z0-9-]{10,}|[sr]k_(live|test)_[A-Za-z0-9]{16,}|eyJ[\w-]+\.eyJ[\w-]+\.[\w-]+)`),
+		// Values given to something that sounds secret
+		regexp.MustCompile(`(?i)\b(password|passwd|pwd|secret|token|api[_-]?key)(\s*[:=]\s*|\s+is\s+)\S+`),
+	},
+}
+
+// secretWord matches words that might be random tokens, which are
+// masked if they mix letters and digits.
+var secretWord = regexp.MustCompile(`\b[A-Za-z0-9+/_=-]{32,}`)
+
+// A Scrubber masks kinds of personal information and secrets in text,
+// replacing each with a placeholder like "[email]", and anything
+// matching extra patterns with "[redacted]". A nil Scrubber masks
+// nothing, so callers with an optional scrubber can use it without
+// checking.
+type Scrubber struct {
+	kinds    []string
+	patterns []*regexp.Regexp
+}
+
+// NewScrubber returns a Scrubber for the given kinds of information
+// (see ScrubKinds) and extra regular expressions.
+func NewScrubber(kinds []string, patterns []string) (*Scrubber, error) {
+	s := &Scrubber{}
+	for _, kind := range kinds {
+		kind = strings.ToLower(kind)
+		if scrubPatterns[kind] == nil {
+			return nil, fmt.Errorf("moderation: unknown kind of information %q", kind)
+		}
+		s.kinds = append(s.kinds, kind)
+	}
+	for _, p := range patterns {
+		rex, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("moderation: %v", err)
+		}
+		s.patterns = append(s.patterns, rex)
+	}
+	return s, nil
+}
+
+// Scrub returns text with the scrubber's kinds of information masked.
+func (s *Scrubber) Scrub(text string) string {
+	if s == nil {
+		return text
+	}
+	for _, kind := range s.kinds {
+		mask := "[" + kind + "]"
+		for _, rex := range scrubPatterns[kind] {
+			text = rex.ReplaceAllLiteralString(text, mask)
+		}
+		if kind == "secret" {
+			text = secretWord.ReplaceAllStringFunc(text, func(w string) string {
+				if strings.IndexFunc(w, unicode.IsDigit) >= 0 && strings.IndexFunc(w, unicode.IsLetter) >= 0 {
+					return mask
+				}
+				return w
+			})
+		}
+	}
+	for _, rex := range s.patterns {
+		text = rex.ReplaceAllLiteralString(text, "[redacted]")
+	}
+	return text
+}
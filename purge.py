@@ -4,18 +4,41 @@
 # should be provided on stdin followed by EOF.
 
 import json
+import struct
 import sys
+import zlib
 
 prefix_len = 2
 
+# Chains saved by Clyde start with a header (see markov/header.go): the
+# magic, then the format version, the length of the JSON that follows,
+# and its CRC-32, big-endian. Chains saved before the header was added
+# are bare JSON.
+magic = b"CLYDEMKV"
+header = struct.Struct(">IQI")
+format_version = 1
+
 words = sys.stdin.read().split()
 
-with open("chain.json") as f:
-    chain = json.load(f)
+with open("chain.json", "rb") as f:
+    data = f.read()
+
+framed = data.startswith(magic)
+if framed:
+    version, length, crc = header.unpack_from(data, len(magic))
+    if version > format_version:
+        sys.exit("chain.json format version {0} is newer than supported version {1}".format(version, format_version))
+    data = data[len(magic) + header.size:]
+    if len(data) < length or zlib.crc32(data[:length]) != crc:
+        sys.exit("chain.json is truncated or corrupted")
+    data = data[:length]
+
+chain = json.loads(data)
 
 ngram = [""]*(prefix_len-1) + ["START"]
 
-for word in words:
+# Clyde also learns an END symbol after the last word of each message
+for word in words + ["END"]:
     for i in range(prefix_len + 1):
         if i < prefix_len and ngram[i] == "":
             continue
@@ -34,5 +57,8 @@ for word in words:
                     chain[key][word] -= 1
     ngram = ngram[1:] + [word]
 
-with open("chain.json", "w") as f:
-    json.dump(chain, f)
+data = json.dumps(chain).encode()
+with open("chain.json", "wb") as f:
+    if framed:
+        f.write(magic + header.pack(format_version, len(data), zlib.crc32(data)))
+    f.write(data)
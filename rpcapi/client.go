@@ -0,0 +1,186 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// client.go calls the Clyde service of clyde.proto, from Go programs
+// that would rather not link the gRPC libraries either.
+
+package rpcapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Client calls the Clyde service at a base URL.
+type Client struct {
+	base   string
+	client *http.Client
+	token  string
+}
+
+// NewClient returns a Client calling the service at the given base
+// URL, e.g. "http://localhost:8044", using the given http.Client, which
+// must speak HTTP/2. If client is nil, a client is used that speaks
+// HTTP/2 over TLS for https URLs, and unencrypted HTTP/2 with prior
+// knowledge for http URLs, as gRPC servers expect.
+func NewClient(baseURL string, client *http.Client) *Client {
+	if client == nil {
+		var protocols http.Protocols
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		client = &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+	}
+	return &Client{base: strings.TrimRight(baseURL, "/"), client: client}
+}
+
+// SetToken sets the token to send with Train calls (see
+// httpapi.Server.SetTrainToken).
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// Generate generates text from a chain.
+func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	resp := new(GenerateResponse)
+	if err := c.unary(ctx, "Generate", req.marshal(), resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Train trains a chain, creating it if it doesn't exist.
+func (c *Client) Train(ctx context.Context, req *TrainRequest) (*TrainResponse, error) {
+	resp := new(TrainResponse)
+	if err := c.unary(ctx, "Train", req.marshal(), resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GenerateStream generates text from a chain, returning a stream of
+// its tokens as the server sends them. The stream must be closed.
+func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest) (*TokenStream, error) {
+	resp, err := c.call(ctx, "GenerateStream", req.marshal())
+	if err != nil {
+		return nil, err
+	}
+	return &TokenStream{resp: resp}, nil
+}
+
+// A TokenStream is the stream of tokens returned by GenerateStream.
+type TokenStream struct {
+	resp *http.Response
+	err  error
+}
+
+// Recv returns the next token of the stream, or io.EOF once the call
+// has finished successfully.
+func (s *TokenStream) Recv() (*Token, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	msg, err := readFrame(s.resp.Body)
+	if err == nil {
+		t := new(Token)
+		if err = t.unmarshal(msg); err == nil {
+			return t, nil
+		}
+	}
+	if err == io.EOF {
+		// The trailers are only available at the end of the body
+		if err = status(s.resp); err == nil {
+			err = io.EOF
+		}
+	}
+	s.err = err
+	s.resp.Body.Close()
+	return nil, err
+}
+
+// Close ends the stream, abandoning any tokens not yet received.
+func (s *TokenStream) Close() error {
+	return s.resp.Body.Close()
+}
+
+// unary makes a call with a single request and response message.
+func (c *Client) unary(ctx context.Context, method string, req []byte, resp interface{ unmarshal([]byte) error }) error {
+	r, err := c.call(ctx, method, req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	msg, err := readFrame(r.Body)
+	if err == io.EOF {
+		if err = status(r); err == nil {
+			err = errorf(Internal, "no response message")
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	// Read to the end for the trailers
+	if _, err := io.Copy(io.Discard, r.Body); err != nil {
+		return err
+	}
+	if err := status(r); err != nil {
+		return err
+	}
+	return resp.unmarshal(msg)
+}
+
+// call starts a call, returning the response once its headers arrive.
+func (c *Client) call(ctx context.Context, method string, req []byte) (*http.Response, error) {
+	var body bytes.Buffer
+	if err := writeFrame(&body, req); err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, "POST", c.base+servicePath+method, &body)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/grpc")
+	r.Header.Set("TE", "trailers")
+	if c.token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errorf(Unknown, "HTTP status %s", resp.Status)
+	}
+	// A call that fails at once has its status in the headers
+	if resp.Header.Get("Grpc-Status") != "" {
+		if err := status(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// status returns the error for a finished call's status, or nil if it
+// succeeded.
+func status(resp *http.Response) error {
+	code, msg := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if code == "" {
+		code, msg = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return errorf(Unknown, "bad grpc-status %q", code)
+	}
+	if Code(n) == OK {
+		return nil
+	}
+	return &Error{Code: Code(n), Message: decodeMessage(msg)}
+}
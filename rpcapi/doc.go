@@ -0,0 +1,15 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// rpcapi serves text generation and training over gRPC, as described
+// by clyde.proto, mirroring the HTTP API of package httpapi, with
+// streaming generation. It speaks the gRPC wire protocol with only the
+// standard library, so clyde-go doesn't depend on the protobuf and
+// gRPC libraries: Server is an http.Handler to serve over HTTP/2, and
+// Client calls it (or any other implementation of the service), using
+// Go 1.24's support for unencrypted HTTP/2. Code for other languages
+// can be generated from clyde.proto as usual.
+
+package rpcapi
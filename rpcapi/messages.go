@@ -0,0 +1,178 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// messages.go defines the messages of clyde.proto as Go types; see
+// clyde.proto for what their fields mean.
+
+package rpcapi
+
+import "math"
+
+// A GenerateRequest asks for text generated from a chain.
+type GenerateRequest struct {
+	Chain       string
+	Seed        string
+	Sentences   uint32
+	MaxWords    uint32
+	Temperature float64
+	Context     uint32
+}
+
+// A Token is a single word of generated text; see markov.Token.
+type Token struct {
+	Word  string
+	Seed  bool
+	Prob  float64
+	Level uint32
+}
+
+// A GenerateResponse is generated text and its tokens.
+type GenerateResponse struct {
+	Text   string
+	Tokens []Token
+	Ended  bool
+}
+
+// A TrainRequest asks for a chain to learn some text.
+type TrainRequest struct {
+	Chain  string
+	Text   string
+	Weight uint32
+	Tag    string
+}
+
+// A TrainResponse reports what a chain learned.
+type TrainResponse struct {
+	Words uint32
+	Size  uint32
+}
+
+func (m *GenerateRequest) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Chain)
+	b = appendString(b, 2, m.Seed)
+	b = appendVarint(b, 3, uint64(m.Sentences))
+	b = appendVarint(b, 4, uint64(m.MaxWords))
+	b = appendDouble(b, 5, m.Temperature)
+	b = appendVarint(b, 6, uint64(m.Context))
+	return b
+}
+
+func (m *GenerateRequest) unmarshal(data []byte) error {
+	return eachField(data, func(field, wire int, v uint64, b []byte) (err error) {
+		switch {
+		case field == 1 && wire == wireBytes:
+			m.Chain = string(b)
+		case field == 2 && wire == wireBytes:
+			m.Seed = string(b)
+		case field == 3 && wire == wireVarint:
+			m.Sentences, err = uint32Field(v)
+		case field == 4 && wire == wireVarint:
+			m.MaxWords, err = uint32Field(v)
+		case field == 5 && wire == wireFixed64:
+			m.Temperature = math.Float64frombits(v)
+		case field == 6 && wire == wireVarint:
+			m.Context, err = uint32Field(v)
+		}
+		return err
+	})
+}
+
+func (m *Token) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Word)
+	b = appendBool(b, 2, m.Seed)
+	b = appendDouble(b, 3, m.Prob)
+	b = appendVarint(b, 4, uint64(m.Level))
+	return b
+}
+
+func (m *Token) unmarshal(data []byte) error {
+	return eachField(data, func(field, wire int, v uint64, b []byte) (err error) {
+		switch {
+		case field == 1 && wire == wireBytes:
+			m.Word = string(b)
+		case field == 2 && wire == wireVarint:
+			m.Seed = v != 0
+		case field == 3 && wire == wireFixed64:
+			m.Prob = math.Float64frombits(v)
+		case field == 4 && wire == wireVarint:
+			m.Level, err = uint32Field(v)
+		}
+		return err
+	})
+}
+
+func (m *GenerateResponse) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Text)
+	for i := range m.Tokens {
+		b = appendBytes(b, 2, m.Tokens[i].marshal())
+	}
+	b = appendBool(b, 3, m.Ended)
+	return b
+}
+
+func (m *GenerateResponse) unmarshal(data []byte) error {
+	return eachField(data, func(field, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == wireBytes:
+			m.Text = string(b)
+		case field == 2 && wire == wireBytes:
+			var t Token
+			if err := t.unmarshal(b); err != nil {
+				return err
+			}
+			m.Tokens = append(m.Tokens, t)
+		case field == 3 && wire == wireVarint:
+			m.Ended = v != 0
+		}
+		return nil
+	})
+}
+
+func (m *TrainRequest) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Chain)
+	b = appendString(b, 2, m.Text)
+	b = appendVarint(b, 3, uint64(m.Weight))
+	b = appendString(b, 4, m.Tag)
+	return b
+}
+
+func (m *TrainRequest) unmarshal(data []byte) error {
+	return eachField(data, func(field, wire int, v uint64, b []byte) (err error) {
+		switch {
+		case field == 1 && wire == wireBytes:
+			m.Chain = string(b)
+		case field == 2 && wire == wireBytes:
+			m.Text = string(b)
+		case field == 3 && wire == wireVarint:
+			m.Weight, err = uint32Field(v)
+		case field == 4 && wire == wireBytes:
+			m.Tag = string(b)
+		}
+		return err
+	})
+}
+
+func (m *TrainResponse) marshal() []byte {
+	var b []byte
+	b = appendVarint(b, 1, uint64(m.Words))
+	b = appendVarint(b, 2, uint64(m.Size))
+	return b
+}
+
+func (m *TrainResponse) unmarshal(data []byte) error {
+	return eachField(data, func(field, wire int, v uint64, b []byte) (err error) {
+		switch {
+		case field == 1 && wire == wireVarint:
+			m.Words, err = uint32Field(v)
+		case field == 2 && wire == wireVarint:
+			m.Size, err = uint32Field(v)
+		}
+		return err
+	})
+}
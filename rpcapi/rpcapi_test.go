@@ -0,0 +1,128 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package rpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sdukhovni/clyde-go/httpapi"
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// newTestServer serves a Server over unencrypted HTTP/2, as
+// clyde-serve does, returning a client for it.
+func newTestServer(t *testing.T, token string) *Client {
+	api := httpapi.NewServer(markov.NewChainSet(2))
+	api.SetTrainToken(token)
+	ts := httptest.NewUnstartedServer(NewServer(api))
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return NewClient(ts.URL, nil)
+}
+
+func TestService(t *testing.T) {
+	c := newTestServer(t, "secret")
+	ctx := context.Background()
+
+	_, err := c.Train(ctx, &TrainRequest{Chain: "c", Text: "the cat sat."})
+	var status *Error
+	if !errors.As(err, &status) || status.Code != Unauthenticated {
+		t.Errorf("Train without token = %v, want Unauthenticated", err)
+	}
+	c.SetToken("secret")
+	trained, err := c.Train(ctx, &TrainRequest{Chain: "c", Text: "the cat sat."})
+	if err != nil || trained.Words != 3 {
+		t.Fatalf("Train = %+v, %v; want 3 words", trained, err)
+	}
+
+	resp, err := c.Generate(ctx, &GenerateRequest{Chain: "c", Seed: "the"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "the cat sat." || len(resp.Tokens) != 3 || !resp.Tokens[0].Seed || resp.Tokens[1].Prob != 1 {
+		t.Errorf("Generate = %+v", resp)
+	}
+
+	stream, err := c.GenerateStream(ctx, &GenerateRequest{Chain: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var words []string
+	for {
+		tok, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		words = append(words, tok.Word)
+	}
+	if got := strings.Join(words, " "); got != "the cat sat." {
+		t.Errorf("GenerateStream = %q, want %q", got, "the cat sat.")
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	c := newTestServer(t, "")
+	ctx := context.Background()
+	tests := []struct {
+		req  GenerateRequest
+		code Code
+	}{
+		{GenerateRequest{Chain: "missing"}, NotFound},
+		{GenerateRequest{Chain: "missing", MaxWords: 100000}, InvalidArgument},
+	}
+	for _, test := range tests {
+		_, err := c.Generate(ctx, &test.req)
+		var status *Error
+		if !errors.As(err, &status) || status.Code != test.code {
+			t.Errorf("Generate(%+v) = %v, want %v", test.req, err, test.code)
+		}
+		stream, err := c.GenerateStream(ctx, &test.req)
+		if err == nil {
+			_, err = stream.Recv()
+			stream.Close()
+		}
+		if !errors.As(err, &status) || status.Code != test.code {
+			t.Errorf("GenerateStream(%+v) = %v, want %v", test.req, err, test.code)
+		}
+	}
+}
+
+func TestMessages(t *testing.T) {
+	req := GenerateRequest{Chain: "c", Seed: "hi", Sentences: 2, MaxWords: 30, Temperature: 0.5, Context: 1}
+	var req2 GenerateRequest
+	if err := req2.unmarshal(req.marshal()); err != nil || req2 != req {
+		t.Errorf("GenerateRequest round trip = %+v, %v; want %+v", req2, err, req)
+	}
+	resp := GenerateResponse{Text: "hi there", Tokens: []Token{{Word: "hi", Seed: true}, {Word: "there", Prob: 0.25, Level: 2}}, Ended: true}
+	var resp2 GenerateResponse
+	if err := resp2.unmarshal(resp.marshal()); err != nil || !reflect.DeepEqual(resp2, resp) {
+		t.Errorf("GenerateResponse round trip = %+v, %v; want %+v", resp2, err, resp)
+	}
+	if err := req2.unmarshal([]byte{0x0a, 0x05, 'a'}); err == nil {
+		t.Errorf("unmarshal of a truncated message succeeded")
+	}
+}
+
+func TestStatusMessage(t *testing.T) {
+	for _, msg := range []string{"", "plain", "100% sure", "café\nnext"} {
+		if got := decodeMessage(encodeMessage(msg)); got != msg {
+			t.Errorf("decodeMessage(encodeMessage(%q)) = %q", msg, got)
+		}
+	}
+}
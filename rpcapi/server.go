@@ -0,0 +1,185 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// server.go serves the Clyde service of clyde.proto, by translating
+// its calls into calls on an httpapi.Server, so both APIs share the
+// same chains, limits, authorization, and metrics.
+
+package rpcapi
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sdukhovni/clyde-go/httpapi"
+	"github.com/sdukhovni/clyde-go/markov"
+)
+
+// servicePath is the path prefix of the service's methods.
+const servicePath = "/clyde.rpcapi.Clyde/"
+
+// Server is an http.Handler serving the Clyde service of clyde.proto.
+// gRPC clients require HTTP/2, so it must be served by an http.Server
+// with HTTP/2 enabled: over TLS, or unencrypted (see
+// http.Server.Protocols) where clients connect with prior knowledge.
+// Train calls require the same authorization as POST /train does on
+// the httpapi.Server, with the token sent as "authorization" metadata.
+type Server struct {
+	api *httpapi.Server
+}
+
+// NewServer returns a Server serving calls with the given
+// httpapi.Server, which may also be serving HTTP requests.
+func NewServer(api *httpapi.Server) *Server {
+	return &Server{api: api}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	var err error
+	switch strings.TrimPrefix(r.URL.Path, servicePath) {
+	case "Generate":
+		err = s.generate(w, r)
+	case "GenerateStream":
+		err = s.generateStream(w, r)
+	case "Train":
+		err = s.train(w, r)
+	default:
+		err = errorf(Unimplemented, "unknown method %s", r.URL.Path)
+	}
+	finish(w, err)
+}
+
+// finish ends a call with the status for err, in the trailers.
+func finish(w http.ResponseWriter, err error) {
+	status := &Error{Code: OK}
+	if err != nil && !errors.As(err, &status) {
+		status = &Error{Code: Internal, Message: err.Error()}
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(status.Code)))
+	if status.Message != "" {
+		w.Header().Set("Grpc-Message", encodeMessage(status.Message))
+	}
+}
+
+// readRequest reads the single request message of a call.
+func readRequest(r *http.Request, m interface{ unmarshal([]byte) error }) error {
+	msg, err := readFrame(r.Body)
+	if err == io.EOF {
+		return errorf(InvalidArgument, "missing request message")
+	}
+	if err == nil {
+		err = m.unmarshal(msg)
+	}
+	if err != nil {
+		return errorf(InvalidArgument, "%v", err)
+	}
+	return nil
+}
+
+// apiError converts an error from the httpapi.Server to its status.
+func apiError(err error) error {
+	switch {
+	case errors.Is(err, httpapi.ErrBadRequest):
+		return errorf(InvalidArgument, "%v", err)
+	case errors.Is(err, httpapi.ErrNoChain):
+		return errorf(NotFound, "%v", err)
+	}
+	return err
+}
+
+// generate serves a Generate call.
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) error {
+	res, err := s.generateResult(r)
+	if err != nil {
+		return err
+	}
+	resp := GenerateResponse{Text: res.Text, Ended: res.Ended}
+	for _, t := range res.Tokens {
+		resp.Tokens = append(resp.Tokens, token(t))
+	}
+	return writeFrame(w, resp.marshal())
+}
+
+// generateStream serves a GenerateStream call, sending each token as
+// its own message. The whole text is generated first, so the chain
+// isn't held up by a slow client.
+func (s *Server) generateStream(w http.ResponseWriter, r *http.Request) error {
+	res, err := s.generateResult(r)
+	if err != nil {
+		return err
+	}
+	flusher, _ := w.(http.Flusher)
+	for _, t := range res.Tokens {
+		t := token(t)
+		if err := writeFrame(w, t.marshal()); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// generateResult reads a GenerateRequest and generates the text it
+// asks for.
+func (s *Server) generateResult(r *http.Request) (markov.Structured, error) {
+	var req GenerateRequest
+	if err := readRequest(r, &req); err != nil {
+		return markov.Structured{}, err
+	}
+	res, err := s.api.Generate(httpapi.GenerateRequest{
+		Chain:       req.Chain,
+		Seed:        req.Seed,
+		Sentences:   int(req.Sentences),
+		MaxWords:    int(req.MaxWords),
+		Temperature: req.Temperature,
+		Context:     int(req.Context),
+	})
+	return res, apiError(err)
+}
+
+// token converts a generated token to its message.
+func token(t markov.Token) Token {
+	return Token{Word: t.Word, Seed: t.Seed, Prob: t.Prob, Level: uint32(t.Level)}
+}
+
+// train serves a Train call.
+func (s *Server) train(w http.ResponseWriter, r *http.Request) error {
+	if !s.api.Authorized(r) {
+		log.Printf("Rejected unauthorized training call from %s", r.RemoteAddr)
+		return errorf(Unauthenticated, "unauthorized")
+	}
+	var req TrainRequest
+	if err := readRequest(r, &req); err != nil {
+		return err
+	}
+	res, err := s.api.Train(r.Context(), httpapi.TrainRequest{
+		Chain:  req.Chain,
+		Text:   req.Text,
+		Weight: int(req.Weight),
+		Tag:    req.Tag,
+	})
+	if err != nil {
+		if r.Context().Err() != nil {
+			return errorf(Canceled, "%v", err)
+		}
+		return apiError(err)
+	}
+	resp := TrainResponse{Words: uint32(res.Words), Size: uint32(res.Size)}
+	return writeFrame(w, resp.marshal())
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// status.go defines the gRPC status codes the service uses, and how
+// errors carry them between Server and Client.
+
+package rpcapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Code is a gRPC status code.
+type Code int
+
+// The status codes the service returns. Client may also see others
+// from other servers.
+const (
+	OK              Code = 0
+	Canceled        Code = 1
+	Unknown         Code = 2
+	InvalidArgument Code = 3
+	NotFound        Code = 5
+	Unimplemented   Code = 12
+	Internal        Code = 13
+	Unauthenticated Code = 16
+)
+
+var codeNames = map[Code]string{
+	OK:              "OK",
+	Canceled:        "Canceled",
+	Unknown:         "Unknown",
+	InvalidArgument: "InvalidArgument",
+	NotFound:        "NotFound",
+	Unimplemented:   "Unimplemented",
+	Internal:        "Internal",
+	Unauthenticated: "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Code(%d)", int(c))
+}
+
+// An Error is a call's failure status, as returned by Client's
+// methods.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpcapi: %v: %s", e.Code, e.Message)
+}
+
+// errorf returns an Error with a formatted message.
+func errorf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// encodeMessage percent-encodes a status message for the grpc-message
+// header, as the gRPC protocol specifies.
+func encodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// decodeMessage undoes encodeMessage, leaving anything malformed as
+// it is.
+func decodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if msg[i] == '%' && i+2 < len(msg) {
+			if c, err := strconv.ParseUint(msg[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(msg[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// address.go recognizes messages addressed to someone by name, the
+// way people address each other in chat rooms: "clyde: hi", "@clyde
+// hi", or "what do you think, clyde?".
+
+package stringutil
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Address reports whether text addresses the given name (with or
+// without a leading "@"), case-insensitively. Text addresses a name if
+// it starts with the name followed by a colon or comma ("clyde: hi",
+// "Clyde, hi"), mentions it with an "@" anywhere ("hi @clyde"), or
+// mentions it anywhere in a question ("right, clyde?"). If the address
+// starts the text, the rest of the text is returned, e.g. to seed a
+// reply; otherwise the returned text is "".
+func Address(text, name string) (string, bool) {
+	name = strings.TrimPrefix(name, "@")
+	if name == "" {
+		return "", false
+	}
+	quoted := regexp.QuoteMeta(name)
+
+	prefix := regexp.MustCompile("(?i)^\\s*(@" + quoted + "\\b[:,]?|" + quoted + "\\s*[:,])\\s*")
+	if loc := prefix.FindStringIndex(text); loc != nil {
+		return strings.TrimSpace(text[loc[1]:]), true
+	}
+	if regexp.MustCompile("(?i)@" + quoted + "\\b").MatchString(text) {
+		return "", true
+	}
+	mention := regexp.MustCompile("(?i)(^|\\W)" + quoted + "\\b")
+	if strings.Contains(text, "?") && mention.MatchString(text) {
+		return "", true
+	}
+	return "", false
+}
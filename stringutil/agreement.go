@@ -0,0 +1,226 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// agreement.go fixes the most jarring agreement errors in generated
+// text, like "they is", "he were", or "a apples". There's no
+// part-of-speech tagger here; the rules only fire on closed classes of
+// words (pronouns, auxiliary verbs, and articles) whose role is
+// unambiguous enough to fix safely.
+
+package stringutil
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Agreement is how aggressively FixAgreement rewrites text.
+type Agreement int
+
+const (
+	// AgreementOff leaves text alone.
+	AgreementOff Agreement = iota
+	// AgreementConservative fixes pronoun/auxiliary verb agreement
+	// and "a"/"an" before a word.
+	AgreementConservative
+	// AgreementAggressive additionally singularizes plural nouns
+	// after "a" or "an", which can mangle irregular words.
+	AgreementAggressive
+)
+
+// person is the grammatical person and number of a subject pronoun.
+type person int
+
+const (
+	firstSingular person = iota
+	thirdSingular
+	plural // also covers "you"
+)
+
+var subjectPronouns = map[string]person{
+	"i":    firstSingular,
+	"he":   thirdSingular,
+	"she":  thirdSingular,
+	"it":   thirdSingular,
+	"you":  plural,
+	"we":   plural,
+	"they": plural,
+}
+
+// auxiliaries maps each form of an auxiliary verb to its forms for
+// each person.
+var auxiliaries = map[string][3]string{}
+
+func init() {
+	for _, forms := range [][3]string{
+		{"am", "is", "are"},
+		{"was", "was", "were"},
+		{"have", "has", "have"},
+		{"do", "does", "do"},
+		{"don't", "doesn't", "don't"},
+		{"haven't", "hasn't", "haven't"},
+		{"wasn't", "wasn't", "weren't"},
+	} {
+		for _, form := range forms {
+			auxiliaries[form] = forms
+		}
+	}
+	// "isn't" and "aren't" share a row, but there's no "amn't"
+	auxiliaries["isn't"] = [3]string{"", "isn't", "aren't"}
+	auxiliaries["aren't"] = [3]string{"", "isn't", "aren't"}
+}
+
+// subjunctives are words after which "I were" or "he were" is
+// correct, as in "if I were you".
+var subjunctives = map[string]bool{
+	"if": true, "as": true, "wish": true, "though": true,
+}
+
+// causatives are words after which a pronoun is the object of the
+// causative rather than the subject of the next verb, as in "let it
+// have its way".
+var causatives = map[string]bool{
+	"let": true, "make": true, "made": true, "help": true, "helped": true,
+	"watch": true, "watched": true, "see": true, "saw": true,
+}
+
+// infinitiveMarkers are words after which a pronoun is followed by a
+// bare infinitive rather than a verb agreeing with it, as in "did he
+// have" or "can she do".
+var infinitiveMarkers = map[string]bool{
+	"do": true, "does": true, "did": true, "will": true, "would": true,
+	"can": true, "could": true, "shall": true, "should": true,
+	"may": true, "might": true, "must": true, "to": true,
+}
+
+// anExceptions and aExceptions are prefixes of words whose article
+// isn't given by their first letter.
+var anExceptions = []string{"hour", "honest", "honor", "honour", "heir"}
+var aExceptions = []string{"uni", "use", "usu", "one", "once", "eu", "ewe"}
+
+// FixAgreement returns its input with agreement errors fixed, to the
+// given level of aggressiveness.
+func FixAgreement(s string, level Agreement) string {
+	if level == AgreementOff {
+		return s
+	}
+	spans := nonSpace.FindAllStringIndex(s, -1)
+	words := make([]string, len(spans))
+	for i, span := range spans {
+		words[i] = s[span[0]:span[1]]
+	}
+	changed := false
+	for i := 0; i+1 < len(words); i++ {
+		// Punctuation after a word means the next word belongs to
+		// another clause
+		if strings.TrimRightFunc(words[i], unicode.IsPunct) != words[i] {
+			continue
+		}
+		w := core(words[i])
+		next := core(words[i+1])
+		var fixed string
+		if p, ok := subjectPronouns[w]; ok {
+			forms, ok := auxiliaries[next]
+			if !ok || forms[p] == "" || forms[p] == next {
+				continue
+			}
+			if i > 0 && next == "were" && p != plural && subjunctives[core(words[i-1])] {
+				continue
+			}
+			if i > 0 && (causatives[core(words[i-1])] || infinitiveMarkers[core(words[i-1])]) {
+				continue
+			}
+			fixed = forms[p]
+		} else if w == "a" || w == "an" {
+			noun := next
+			if level >= AgreementAggressive {
+				noun = singular(next)
+				if noun != next {
+					words[i+1] = replaceCore(words[i+1], next, noun)
+					changed = true
+				}
+			}
+			if article := indefiniteArticle(noun); article != w {
+				words[i] = replaceCore(words[i], w, article)
+				changed = true
+			}
+			continue
+		} else {
+			continue
+		}
+		words[i+1] = replaceCore(words[i+1], next, fixed)
+		changed = true
+	}
+	if !changed {
+		return s
+	}
+	// Put the words back, keeping the original spacing
+	var b strings.Builder
+	last := 0
+	for i, span := range spans {
+		b.WriteString(s[last:span[0]])
+		b.WriteString(words[i])
+		last = span[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+var nonSpace = regexp.MustCompile("\\S+")
+
+// core returns a word lowercased, with surrounding punctuation (other
+// than apostrophes within it) removed.
+func core(w string) string {
+	return strings.TrimFunc(strings.ToLower(w), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// replaceCore replaces the core of a word with another, keeping its
+// punctuation and initial capital.
+func replaceCore(w, old, new string) string {
+	i := strings.Index(strings.ToLower(w), old)
+	if i < 0 {
+		return w
+	}
+	if unicode.IsUpper([]rune(w[i:])[0]) {
+		new = Capitalize(new)
+	}
+	return w[:i] + new + w[i+len(old):]
+}
+
+// indefiniteArticle returns the indefinite article for a word,
+// accounting for some common words whose spelling is misleading.
+func indefiniteArticle(w string) string {
+	for _, prefix := range anExceptions {
+		if strings.HasPrefix(w, prefix) {
+			return "an"
+		}
+	}
+	for _, prefix := range aExceptions {
+		if strings.HasPrefix(w, prefix) {
+			return "a"
+		}
+	}
+	return Article(w)
+}
+
+// singular makes a rough guess at the singular form of a plural noun,
+// returning its input if it doesn't look plural.
+func singular(w string) string {
+	switch {
+	case len(w) < 4 || !strings.HasSuffix(w, "s"):
+		return w
+	case strings.HasSuffix(w, "ss") || strings.HasSuffix(w, "us") || strings.HasSuffix(w, "is"):
+		return w
+	case strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "sses") || strings.HasSuffix(w, "xes") ||
+		strings.HasSuffix(w, "ches") || strings.HasSuffix(w, "shes"):
+		return w[:len(w)-2]
+	}
+	return w[:len(w)-1]
+}
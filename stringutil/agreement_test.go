@@ -0,0 +1,44 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package stringutil
+
+import "testing"
+
+func TestFixAgreement(t *testing.T) {
+	tests := []struct {
+		text  string
+		level Agreement
+		want  string
+	}{
+		{"they is here", AgreementOff, "they is here"},
+		{"they is here", AgreementConservative, "they are here"},
+		{"He were late.", AgreementConservative, "He was late."},
+		{"I is  hungry", AgreementConservative, "I am  hungry"},
+		{"She have it", AgreementConservative, "She has it"},
+		{"if I were you", AgreementConservative, "if I were you"},
+		{"let it have its way", AgreementConservative, "let it have its way"},
+		{"did he have a plan?", AgreementConservative, "did he have a plan?"},
+		{"Does she have one", AgreementConservative, "Does she have one"},
+		{"why would it do that", AgreementConservative, "why would it do that"},
+		{"you can't make it do that", AgreementConservative, "you can't make it do that"},
+		{"I want to have it", AgreementConservative, "I want to have it"},
+		{"we must do it", AgreementConservative, "we must do it"},
+		{"he is, they is", AgreementConservative, "he is, they are"},
+		{"he. Were you", AgreementConservative, "he. Were you"},
+		{"a apple", AgreementConservative, "an apple"},
+		{"an unicorn", AgreementConservative, "a unicorn"},
+		{"a hour", AgreementConservative, "an hour"},
+		{"a apples", AgreementConservative, "an apples"},
+		{"a apples", AgreementAggressive, "an apple"},
+		{"a boxes", AgreementAggressive, "a box"},
+		{"a glass", AgreementAggressive, "a glass"},
+	}
+	for _, test := range tests {
+		if got := FixAgreement(test.text, test.level); got != test.want {
+			t.Errorf("FixAgreement(%q, %d) = %q, want %q", test.text, test.level, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// html.go extracts the visible text of HTML documents, such as web
+// pages and HTML email, for use as training text.
+
+package stringutil
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlHidden are the elements whose contents aren't displayed as text.
+var htmlHidden = []string{"head", "script", "style", "noscript", "template", "svg", "math", "iframe", "object", "select"}
+
+// htmlHiddenElements match each hidden element with its contents, as
+// Go's regular expressions can't match a closing tag to its opener.
+var htmlHiddenElements []*regexp.Regexp
+
+func init() {
+	for _, name := range htmlHidden {
+		htmlHiddenElements = append(htmlHiddenElements, regexp.MustCompile(`(?is)<`+name+`\b[^>]*>.*?</`+name+`\s*>`))
+	}
+}
+
+// htmlComment matches comments, CDATA sections, and declarations like
+// "<!DOCTYPE html>".
+var htmlComment = regexp.MustCompile(`(?s)<!--.*?-->|<!\[CDATA\[.*?\]\]>|<![^>]*>|<\?[^>]*>`)
+
+// htmlTag matches a start or end tag, capturing its name.
+var htmlTag = regexp.MustCompile(`</?([a-zA-Z][a-zA-Z0-9-]*)(\s[^>]*)?/?>`)
+
+// htmlBlocks are the elements that display on lines of their own, and
+// so separate paragraphs of text.
+var htmlBlocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"body": true, "caption": true, "dd": true, "div": true, "dl": true,
+	"dt": true, "fieldset": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"html": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "td": true,
+	"th": true, "tr": true, "ul": true,
+}
+
+// HTMLText returns the visible text of an HTML document or fragment,
+// split into paragraphs at block elements such as paragraphs,
+// headings, list items, and table cells, with whitespace collapsed.
+// Scripts, styles, comments, and the document's head are removed, and
+// character references like "&amp;" are decoded. It doesn't need
+// well-formed HTML, but doesn't try to render it exactly as a browser
+// would either.
+func HTMLText(s string) []string {
+	s = htmlComment.ReplaceAllString(s, "")
+	for _, rex := range htmlHiddenElements {
+		s = rex.ReplaceAllString(s, " ")
+	}
+	s = htmlTag.ReplaceAllStringFunc(s, func(tag string) string {
+		name := strings.ToLower(htmlTag.FindStringSubmatch(tag)[1])
+		switch {
+		case htmlBlocks[name]:
+			return "\x00"
+		case name == "br":
+			return " "
+		}
+		return ""
+	})
+
+	var paragraphs []string
+	for _, p := range strings.Split(s, "\x00") {
+		p = strings.Join(strings.Fields(html.UnescapeString(p)), " ")
+		if p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// language.go guesses what language a message is in, cheaply enough
+// to run on every message a bot receives: by its script, or for text
+// in the Latin alphabet, by which language's stopwords it uses most.
+
+package stringutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scriptLanguages are the languages guessed for text mostly in scripts
+// other than Latin, in order of precedence: Japanese mixes kana with
+// Chinese characters, which alone are taken for Chinese.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// DetectLanguage guesses the language of text, returning its ISO 639-1
+// code (e.g. "en", "fr", "ja"), or "" if it can't tell, as for short
+// messages without any stopwords, like "lol". Text in the Latin
+// alphabet is told apart by stopwords, so only languages with stopword
+// lists are detected (see StopwordLanguages and LoadStopwords); text
+// in another script is taken to be in the main language written in it,
+// e.g. Russian for Cyrillic.
+func DetectLanguage(text string) string {
+	latin, other := 0, make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				other[s.lang]++
+				break
+			}
+		}
+	}
+	if latin == 0 && len(other) == 0 {
+		return ""
+	}
+	nonLatin := 0
+	for _, n := range other {
+		nonLatin += n
+	}
+	if nonLatin > latin {
+		// Kana marks Japanese however many Chinese characters there
+		// are
+		if other["ja"] > 0 {
+			return "ja"
+		}
+		best := ""
+		for _, s := range scriptLanguages {
+			if best == "" || other[s.lang] > other[best] {
+				best = s.lang
+			}
+		}
+		return best
+	}
+
+	stopwordsOnce.Do(loadBuiltinStopwords)
+	stopwordsMu.RLock()
+	defer stopwordsMu.RUnlock()
+	counts := make(map[string]int)
+	for _, w := range strings.Fields(text) {
+		key := stopwordKey(w)
+		for lang, set := range stopwords {
+			if set[key] {
+				counts[lang]++
+			}
+		}
+	}
+	// Only guess a language with more stopwords than any other
+	best, tied := "", false
+	for lang, n := range counts {
+		switch {
+		case best == "" || n > counts[best]:
+			best, tied = lang, false
+		case n == counts[best]:
+			tied = true
+		}
+	}
+	if tied {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,48 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// spelling.go measures how far apart the spellings of words are, for
+// matching words despite typos.
+
+package stringutil
+
+// EditDistance returns the Levenshtein distance between two strings:
+// the fewest single-character insertions, deletions, and
+// substitutions that turn one into the other. Characters are runes,
+// not bytes.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// row holds the distances from a prefix of ra to each prefix of
+	// rb
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		diag := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			next := min3(row[j]+1, row[j-1]+1, diag+cost)
+			diag = row[j]
+			row[j] = next
+		}
+	}
+	return row[len(rb)]
+}
+
+// min3 returns the least of three ints.
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// stem.go reduces words to rough stems by stripping common inflectional
+// suffixes, so that e.g. "walked", "walking", and "walks" can be
+// treated alike. It's far cruder than a real stemmer, but needs no
+// dictionary.
+
+package stringutil
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minStem is the fewest letters a stem is left with.
+const minStem = 3
+
+// stemSuffixes are the suffixes stripped from words in each language,
+// longest first, with what to replace them with, if anything.
+var stemSuffixes = map[string][][2]string{
+	"en": {
+		{"ations", "ate"}, {"ation", "ate"}, {"ingly", ""}, {"ments", ""},
+		{"ment", ""}, {"ness", ""}, {"edly", ""}, {"ings", ""}, {"ies", "y"},
+		{"ied", "y"}, {"ing", ""}, {"ers", ""}, {"est", ""}, {"ed", ""},
+		{"er", ""}, {"ly", ""}, {"es", ""}, {"s", ""},
+	},
+	"fr": {
+		{"ements", ""}, {"ations", ""}, {"ement", ""}, {"ation", ""},
+		{"euses", ""}, {"euse", ""}, {"eux", ""}, {"ées", ""}, {"ent", ""},
+		{"ée", ""}, {"és", ""}, {"er", ""}, {"ez", ""}, {"es", ""}, {"é", ""},
+		{"e", ""}, {"s", ""}, {"x", ""},
+	},
+	"de": {
+		{"ungen", ""}, {"heiten", ""}, {"keiten", ""}, {"heit", ""},
+		{"keit", ""}, {"ung", ""}, {"ern", ""}, {"em", ""}, {"en", ""},
+		{"er", ""}, {"es", ""}, {"e", ""}, {"s", ""}, {"n", ""},
+	},
+	"es": {
+		{"aciones", ""}, {"amente", ""}, {"ación", ""}, {"iendo", ""},
+		{"mente", ""}, {"ando", ""}, {"ados", ""}, {"adas", ""},
+		{"idos", ""}, {"idas", ""}, {"ado", ""}, {"ada", ""}, {"ido", ""},
+		{"ida", ""}, {"ar", ""}, {"er", ""}, {"ir", ""}, {"es", ""},
+		{"as", ""}, {"os", ""}, {"a", ""}, {"o", ""}, {"s", ""},
+	},
+}
+
+// Stem returns the rough stem of a word in DefaultLanguage, lowercased,
+// keeping any punctuation around it.
+func Stem(w string) string {
+	return StemIn(DefaultLanguage, w)
+}
+
+// StemIn is like Stem, for the given language ("en", "fr", "de", or
+// "es"). Words in other languages are only lowercased.
+func StemIn(lang, w string) string {
+	w = strings.ToLower(w)
+	start := strings.IndexFunc(w, unicode.IsLetter)
+	end := strings.LastIndexFunc(w, unicode.IsLetter)
+	if start < 0 {
+		return w
+	}
+	_, size := utf8.DecodeRuneInString(w[end:])
+	end += size
+	core := w[start:end]
+	for _, s := range stemSuffixes[lang] {
+		stem := strings.TrimSuffix(core, s[0])
+		if stem == core || utf8.RuneCountInString(stem) < minStem {
+			continue
+		}
+		// "glass" isn't a plural
+		if s[0] == "s" && strings.HasSuffix(stem, "s") {
+			break
+		}
+		core = stem + s[1]
+		break
+	}
+	return w[:start] + core + w[end:]
+}
@@ -0,0 +1,156 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// stopwords.go knows the common words ("the", "of", "and") that say
+// little about what a message is about, in a few languages. The
+// built-in lists are embedded from the stopwords directory; programs
+// can add their own words or load more languages.
+
+package stringutil
+
+import (
+	"bufio"
+	"embed"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the language of the stopwords IsStopword and
+// FilterStopwords use.
+const DefaultLanguage = "en"
+
+//go:embed stopwords/*.txt
+var stopwordFiles embed.FS
+
+// stopwordTrim is the punctuation trimmed from words before they're
+// looked up.
+const stopwordTrim = ".,;:!?\"'()[]{}<>*_"
+
+var stopwordsMu sync.RWMutex
+var stopwordsOnce sync.Once
+
+// stopwords maps each language to its set of stopwords.
+var stopwords map[string]map[string]bool
+
+// loadBuiltinStopwords loads the embedded stopword lists.
+func loadBuiltinStopwords() {
+	stopwords = make(map[string]map[string]bool)
+	entries, _ := stopwordFiles.ReadDir("stopwords")
+	for _, e := range entries {
+		f, err := stopwordFiles.Open(path.Join("stopwords", e.Name()))
+		if err != nil {
+			continue
+		}
+		readStopwords(strings.TrimSuffix(e.Name(), ".txt"), f)
+		f.Close()
+	}
+}
+
+// readStopwords adds the stopwords in r, one per line, with "#"
+// comments, to a language's list.
+func readStopwords(lang string, r io.Reader) error {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	addStopwords(lang, words)
+	return nil
+}
+
+// addStopwords implements AddStopwords, with the lists already
+// loaded.
+func addStopwords(lang string, words []string) {
+	stopwordsMu.Lock()
+	defer stopwordsMu.Unlock()
+	set := stopwords[lang]
+	if set == nil {
+		set = make(map[string]bool)
+		stopwords[lang] = set
+	}
+	for _, w := range words {
+		set[stopwordKey(w)] = true
+	}
+}
+
+// stopwordKey returns the normalized form of a word for looking it up.
+func stopwordKey(w string) string {
+	return strings.Trim(strings.ToLower(w), stopwordTrim)
+}
+
+// AddStopwords adds words to a language's stopwords, creating the
+// language's list if there isn't one.
+func AddStopwords(lang string, words ...string) {
+	stopwordsOnce.Do(loadBuiltinStopwords)
+	addStopwords(lang, words)
+}
+
+// LoadStopwords adds the stopwords in a file, one per line, with "#"
+// comments, to a language's stopwords.
+func LoadStopwords(lang, filename string) error {
+	stopwordsOnce.Do(loadBuiltinStopwords)
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return readStopwords(lang, f)
+}
+
+// StopwordLanguages returns the languages with stopword lists, in
+// sorted order.
+func StopwordLanguages() []string {
+	stopwordsOnce.Do(loadBuiltinStopwords)
+	stopwordsMu.RLock()
+	defer stopwordsMu.RUnlock()
+	var langs []string
+	for lang := range stopwords {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// IsStopword returns a boolean indicating whether a word, ignoring case
+// and surrounding punctuation, is a stopword in DefaultLanguage.
+func IsStopword(w string) bool {
+	return IsStopwordIn(DefaultLanguage, w)
+}
+
+// IsStopwordIn is like IsStopword, for the given language. No words
+// are stopwords in languages without a list.
+func IsStopwordIn(lang, w string) bool {
+	stopwordsOnce.Do(loadBuiltinStopwords)
+	stopwordsMu.RLock()
+	defer stopwordsMu.RUnlock()
+	return stopwords[lang][stopwordKey(w)]
+}
+
+// FilterStopwords returns the words that aren't stopwords in
+// DefaultLanguage, in order.
+func FilterStopwords(words []string) []string {
+	return FilterStopwordsIn(DefaultLanguage, words)
+}
+
+// FilterStopwordsIn is like FilterStopwords, for the given language.
+func FilterStopwordsIn(lang string, words []string) []string {
+	var filtered []string
+	for _, w := range words {
+		if !IsStopwordIn(lang, w) {
+			filtered = append(filtered, w)
+		}
+	}
+	return filtered
+}
@@ -11,6 +11,7 @@ package stringutil
 import (
 	"fmt"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 	"regexp"
 )
@@ -38,6 +39,19 @@ func BreakLines(s string, maxLine int) string {
 	return strings.Join(lines, "\n")
 }
 
+// BreakParagraphs breaks each blank-line-separated paragraph of s into
+// lines as in BreakLines, keeping the paragraphs separated by blank
+// lines.
+func BreakParagraphs(s string, maxLine int) string {
+	var paragraphs []string
+	for _, p := range strings.Split(s, "\n\n") {
+		if strings.TrimSpace(p) != "" {
+			paragraphs = append(paragraphs, BreakLines(p, maxLine))
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
 var endOfSentence = regexp.MustCompile("[\\.\\?!]['\"]?$")
 
 // IsEndOfSentence returns a boolean indicating whether a word ends
@@ -94,3 +108,121 @@ var syl = regexp.MustCompile("/[aeiouy]+/")
 func SylCount(s string) int {
 	return len(syl.FindAllString(strings.ToLower(s), 0))
 }
+
+// abbreviations is a set of common lowercased abbreviations that end
+// with a period but don't usually end a sentence.
+var abbreviations = map[string]bool{
+	"mr.": true, "mrs.": true, "ms.": true, "dr.": true, "prof.": true,
+	"sr.": true, "jr.": true, "st.": true, "mt.": true, "vs.": true,
+	"e.g.": true, "i.e.": true, "cf.": true, "approx.": true,
+	"vol.": true, "fig.": true, "dept.": true,
+	"jan.": true, "feb.": true, "mar.": true, "apr.": true, "jun.": true,
+	"jul.": true, "aug.": true, "sep.": true, "sept.": true, "oct.": true,
+	"nov.": true, "dec.": true,
+}
+
+// initials matches dotted initials and initialisms like "J." or
+// "U.S.". Only capitals count, so that sentences ending in "a." or
+// "i." still end.
+var initials = regexp.MustCompile("^(\\p{Lu}\\.)+$")
+
+// sentenceEnd matches sentence-ending punctuation, possibly followed
+// by closing quotes or parentheses.
+var sentenceEnd = regexp.MustCompile("[\\.\\?!]+['\"\\)”’]*$")
+
+// ambiguousEnd matches sentence-ending punctuation that often
+// doesn't end a sentence: ellipses, and punctuation inside quotes.
+var ambiguousEnd = regexp.MustCompile("(\\.\\.+|[\\.\\?!]+['\"”’]+)\\)?$")
+
+// IsAbbreviation returns a boolean indicating whether a word is a
+// common abbreviation or initial whose trailing period doesn't end a
+// sentence.
+func IsAbbreviation(w string) bool {
+	w = strings.TrimLeft(w, "'\"(“‘")
+	// "I." is more likely the end of a sentence than an initial
+	return abbreviations[strings.ToLower(w)] || (initials.MatchString(w) && w != "I.")
+}
+
+// EndsSentence returns a boolean indicating whether a word ends a
+// sentence: it ends with sentence-ending punctuation (possibly inside
+// closing quotes or parentheses) and isn't an abbreviation.
+func EndsSentence(w string) bool {
+	return sentenceEnd.MatchString(w) && !IsAbbreviation(w)
+}
+
+// SplitSentences splits text into sentences, each returned with its
+// whitespace normalized to single spaces. An ellipsis or a closing
+// quote only ends a sentence if the word after it is capitalized.
+func SplitSentences(text string) []string {
+	words := strings.Fields(text)
+	var sentences []string
+	var sentence []string
+	for i, w := range words {
+		sentence = append(sentence, w)
+		if !EndsSentence(w) {
+			continue
+		}
+		if ambiguousEnd.MatchString(w) && i+1 < len(words) {
+			next := []rune(strings.TrimLeft(words[i+1], "'\"(“‘"))
+			if len(next) == 0 || !unicode.IsUpper(next[0]) {
+				continue
+			}
+		}
+		sentences = append(sentences, strings.Join(sentence, " "))
+		sentence = nil
+	}
+	if len(sentence) > 0 {
+		sentences = append(sentences, strings.Join(sentence, " "))
+	}
+	return sentences
+}
+
+var quoteReplacer = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"“", "\"", "”", "\"", "„", "\"", "‟", "\"", "″", "\"",
+	"«", "\"", "»", "\"", "‹", "'", "›", "'",
+)
+
+// NormalizeQuotes replaces typographic ("smart") quotes, primes, and
+// guillemets with ASCII quotes.
+func NormalizeQuotes(s string) string {
+	return quoteReplacer.Replace(s)
+}
+
+var dashReplacer = strings.NewReplacer(
+	"—", "--", "―", "--", "–", "-", "‒", "-", "‐", "-", "‑", "-",
+	"−", "-", "…", "...",
+)
+
+// NormalizeDashes replaces em dashes with "--", other dashes, hyphens
+// and minus signs with "-", and ellipses with "...".
+func NormalizeDashes(s string) string {
+	return dashReplacer.Replace(s)
+}
+
+// NormalizeFullWidth replaces full-width and ideographic punctuation
+// (as copied from CJK text and some input methods) and unusual
+// spaces with their ASCII equivalents. Full-width letters and digits
+// are left alone.
+func NormalizeFullWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '！' && r <= '～' && (unicode.IsPunct(r) || unicode.IsSymbol(r)):
+			return r - '！' + '!'
+		case r == '。':
+			return '.'
+		case r == '、':
+			return ','
+		case r == '\u3000' || r == '\u00a0' || r == '\u202f':
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// NormalizePunctuation applies NormalizeQuotes, NormalizeDashes, and
+// NormalizeFullWidth, so that text copied from word processors and
+// the like uses the same punctuation as text typed at a terminal.
+func NormalizePunctuation(s string) string {
+	return NormalizeFullWidth(NormalizeDashes(NormalizeQuotes(s)))
+}
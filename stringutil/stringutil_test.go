@@ -0,0 +1,63 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package stringutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Hello there", []string{"Hello there"}},
+		{"Hello.  How are you?  Fine!", []string{"Hello.", "How are you?", "Fine!"}},
+		{"No. It isn't.", []string{"No.", "It isn't."}},
+		{"Mr. Smith went to Washington. He left.", []string{"Mr. Smith went to Washington.", "He left."}},
+		{"See e.g. the manual. Then stop.", []string{"See e.g. the manual.", "Then stop."}},
+		{"J. R. R. Tolkien wrote it. Really.", []string{"J. R. R. Tolkien wrote it.", "Really."}},
+		{"He moved to the U.S. last year.", []string{"He moved to the U.S. last year."}},
+		{"I got an a. Then a b.", []string{"I got an a.", "Then a b."}},
+		{"It was i. Then you.", []string{"It was i.", "Then you."}},
+		{"It was I. Then you.", []string{"It was I.", "Then you."}},
+		{"Well... maybe. Or not.", []string{"Well... maybe.", "Or not."}},
+		{"Well... Maybe.", []string{"Well...", "Maybe."}},
+		{`He said "stop!" and left. Then "Go!" She went.`, []string{`He said "stop!" and left.`, `Then "Go!"`, "She went."}},
+		{"(It ended.) Then more.", []string{"(It ended.)", "Then more."}},
+	}
+	for _, test := range tests {
+		if got := SplitSentences(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitSentences(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestEndsSentence(t *testing.T) {
+	tests := []struct {
+		word string
+		want bool
+	}{
+		{"end.", true},
+		{"end", false},
+		{"why?", true},
+		{"stop!\"", true},
+		{"Mr.", false},
+		{"no.", true},
+		{"J.", false},
+		{"U.S.", false},
+		{"a.", true},
+		{"i.", true},
+		{"I.", true},
+	}
+	for _, test := range tests {
+		if got := EndsSentence(test.word); got != test.want {
+			t.Errorf("EndsSentence(%q) = %v, want %v", test.word, got, test.want)
+		}
+	}
+}
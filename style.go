@@ -0,0 +1,49 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// style.go lets Clyde talk in the style of a particular user, using a
+// small chain trained only on that user's public messages.
+
+package clyde
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/zephyr-im/zephyr-go"
+)
+
+// userStyleSize is the number of prefixes a user's style model needs
+// before Clyde stops mixing in his main chain; smaller models lean on
+// the main chain proportionally more.
+const userStyleSize = 2000
+
+// userStyleMinSize is the number of prefixes a user's style model
+// needs before Clyde will imitate them at all.
+const userStyleMinSize = 20
+
+// userKey normalizes a username or nick for use as the key of a style
+// model.
+func userKey(user string) string {
+	user = strings.ToLower(strings.Split(user, "@")[0])
+	return strings.TrimRight(user, "_`|")
+}
+
+var styleOf = standardBehavior("clyde.? (talk|speak|say something) (like|in the style of) (?P<person>[^\\s\\.\\?!]+)",
+	[]string{"person"},
+	false,
+	func(c *Clyde, r zephyr.MessageReaderResult, kvs map[string]string) string {
+		key := userKey(kvs["person"])
+		if c.optOut[key] {
+			return fmt.Sprintf("%s asked me not to learn from them, sorry.", kvs["person"])
+		}
+		chain := c.userChains.Get(key)
+		if chain == nil || chain.Size() < userStyleMinSize {
+			return fmt.Sprintf("I haven't heard enough from %s to do a good impression.", kvs["person"])
+		}
+		defer chain.SetFallbackUntil(c.chain, userStyleSize)()
+		return chain.Generate("", sentenceCounts[rand.Intn(len(sentenceCounts))], maxWords)
+	})
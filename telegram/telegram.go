@@ -0,0 +1,258 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// telegram is a bot frontend (see package bot) for Telegram, receiving
+// updates from the Bot API by long polling or by webhook. It can also
+// answer inline queries with generated completions of whatever the
+// user has typed.
+
+package telegram
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/bot"
+)
+
+// apiBase is the base URL of the Bot API.
+const apiBase = "https://api.telegram.org"
+
+// pollTimeout is how long Telegram may hold each getUpdates request
+// open waiting for updates.
+const pollTimeout = 30 * time.Second
+
+// retryDelay is how long to wait after a failed poll before retrying.
+const retryDelay = 10 * time.Second
+
+// inlineResults is the number of completions offered for an inline
+// query.
+const inlineResults = 3
+
+// Bot is a Telegram bot implementing bot.Frontend. Its channels are
+// chat IDs. Messages in private chats and replies to the bot's own
+// messages are addressed to it, and users can mention it by username.
+type Bot struct {
+	// Complete, if set, is called to answer inline queries with up
+	// to n completions of the query text, e.g. bot.Core.Complete.
+	Complete func(text string, n int) []string
+
+	token    string
+	username string
+	secret   string
+	client   *http.Client
+	offset   int64
+	messages chan bot.Message
+	stopOnce sync.Once
+}
+
+// NewBot returns a Bot authenticating with the given token, whose
+// username (without the "@") is given.
+func NewBot(token, username string) *Bot {
+	return &Bot{
+		token:    token,
+		username: strings.TrimPrefix(username, "@"),
+		client:   &http.Client{Timeout: pollTimeout + 30*time.Second},
+		messages: make(chan bot.Message),
+	}
+}
+
+// Identity implements bot.Frontend.
+func (b *Bot) Identity() bot.Identity {
+	return bot.Identity{Network: "telegram", Name: "@" + b.username}
+}
+
+// Messages implements bot.Frontend.
+func (b *Bot) Messages() <-chan bot.Message {
+	return b.messages
+}
+
+// Stop closes the Messages channel, once no more updates are coming.
+func (b *Bot) Stop() {
+	b.stopOnce.Do(func() { close(b.messages) })
+}
+
+// update is the part of a Telegram update the bot uses.
+type update struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID   int64  `json:"id"`
+			Type string `json:"type"`
+		} `json:"chat"`
+		From struct {
+			ID int64 `json:"id"`
+		} `json:"from"`
+		Text           string `json:"text"`
+		ReplyToMessage *struct {
+			From *struct {
+				Username string `json:"username"`
+			} `json:"from"`
+		} `json:"reply_to_message"`
+	} `json:"message"`
+	InlineQuery *struct {
+		ID    string `json:"id"`
+		Query string `json:"query"`
+	} `json:"inline_query"`
+}
+
+// Poll receives updates by long polling until stop is closed, and
+// then calls Stop. It can't be used while a webhook is set.
+func (b *Bot) Poll(stop <-chan struct{}) {
+	defer b.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		var updates []update
+		err := b.call("getUpdates", map[string]interface{}{
+			"offset":          b.offset,
+			"timeout":         int(pollTimeout / time.Second),
+			"allowed_updates": []string{"message", "inline_query"},
+		}, &updates)
+		if err != nil {
+			log.Printf("Telegram poll error: %v", err)
+			select {
+			case <-stop:
+				return
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+		for _, u := range updates {
+			b.offset = u.UpdateID + 1
+			b.handle(u)
+		}
+	}
+}
+
+// SetWebhook asks Telegram to deliver updates to the given URL, which
+// must be served by the bot's ServeHTTP, instead of by polling. If
+// secret is set, Telegram presents it with every update, and the bot
+// rejects updates without it. Setting an empty URL removes the
+// webhook, so updates can be polled again.
+func (b *Bot) SetWebhook(webhook, secret string) error {
+	b.secret = secret
+	params := map[string]interface{}{
+		"url":             webhook,
+		"allowed_updates": []string{"message", "inline_query"},
+	}
+	if secret != "" {
+		params["secret_token"] = secret
+	}
+	return b.call("setWebhook", params, nil)
+}
+
+// ServeHTTP receives updates delivered to a webhook (see SetWebhook).
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	got := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if b.secret != "" && subtle.ConstantTimeCompare([]byte(got), []byte(b.secret)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var u update
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b.handle(u)
+}
+
+// handle handles an update.
+func (b *Bot) handle(u update) {
+	switch {
+	case u.Message != nil && u.Message.Text != "":
+		m := u.Message
+		addressed := m.Chat.Type == "private" ||
+			(m.ReplyToMessage != nil && m.ReplyToMessage.From != nil && strings.EqualFold(m.ReplyToMessage.From.Username, b.username))
+		b.messages <- bot.Message{
+			Channel:   strconv.FormatInt(m.Chat.ID, 10),
+			Sender:    strconv.FormatInt(m.From.ID, 10),
+			Text:      m.Text,
+			Addressed: addressed,
+		}
+	case u.InlineQuery != nil && b.Complete != nil:
+		results := []interface{}{}
+		for i, text := range b.Complete(u.InlineQuery.Query, inlineResults) {
+			results = append(results, map[string]interface{}{
+				"type":                  "article",
+				"id":                    strconv.Itoa(i),
+				"title":                 text,
+				"input_message_content": map[string]string{"message_text": text},
+			})
+		}
+		err := b.call("answerInlineQuery", map[string]interface{}{
+			"inline_query_id": u.InlineQuery.ID,
+			"results":         results,
+			"cache_time":      0,
+		}, nil)
+		if err != nil {
+			log.Printf("Error answering inline query: %v", err)
+		}
+	}
+}
+
+// Send sends a message to a chat. It implements bot.Frontend.
+func (b *Bot) Send(chat, text string) error {
+	return b.call("sendMessage", map[string]interface{}{"chat_id": chat, "text": text}, nil)
+}
+
+// apiResponse is the envelope of every Bot API response.
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// call calls a Bot API method with the given parameters, decoding its
+// result into out (if non-nil).
+func (b *Bot) call(method string, params interface{}, out interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Post(fmt.Sprintf("%s/bot%s/%s", apiBase, b.token, method), "application/json", bytes.NewReader(data))
+	if err != nil {
+		// The URL contains the token, so don't report it
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		return fmt.Errorf("telegram: %s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var r apiResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return fmt.Errorf("telegram: %s: %s", method, resp.Status)
+	}
+	if !r.OK {
+		return fmt.Errorf("telegram: %s: %s", method, r.Description)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, out)
+}
@@ -0,0 +1,189 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// watermark keeps a registry of keyed hashes of text that a bot has
+// said, so that its operator can later verify whether a given piece
+// of text was really generated by their bot. Only hashes are stored,
+// keyed with a secret, so the registry doesn't reveal what was said
+// and can't be used by anyone without the key to check guesses.
+
+package watermark
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/sdukhovni/clyde-go/stringutil"
+)
+
+// KeySize is the size in bytes of a registry key.
+const KeySize = 32
+
+// minSentenceWords is the number of words a sentence must have to be
+// registered on its own, in addition to the full text it was part of.
+const minSentenceWords = 4
+
+// hashSize is the number of bytes of each hash that are kept.
+const hashSize = 16
+
+// Registry records keyed hashes of text.
+type Registry struct {
+	key    []byte
+	hashes map[string]bool
+}
+
+// NewRegistry returns an empty Registry using the given secret key.
+func NewRegistry(key []byte) *Registry {
+	return &Registry{key, make(map[string]bool)}
+}
+
+// LoadKey reads a registry key from the given file, creating the file
+// with a new random key if it doesn't exist.
+func LoadKey(filename string) ([]byte, error) {
+	key, err := ioutil.ReadFile(filename)
+	if err == nil {
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	key, err = NewKey()
+	if err != nil {
+		return nil, err
+	}
+	return key, ioutil.WriteFile(filename, key, 0600)
+}
+
+// NewKey returns a new random registry key.
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// normalize normalizes text so that line breaking, spacing, case, and
+// punctuation don't affect its hash.
+func normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+func (r *Registry) hash(text string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(normalize(text)))
+	return hex.EncodeToString(mac.Sum(nil)[:hashSize])
+}
+
+// Record registers a piece of text, along with each of its sentences
+// that is long enough to be distinctive.
+func (r *Registry) Record(text string) {
+	if normalize(text) == "" {
+		return
+	}
+	r.hashes[r.hash(text)] = true
+	for _, sentence := range stringutil.SplitSentences(text) {
+		if len(strings.Fields(normalize(sentence))) >= minSentenceWords {
+			r.hashes[r.hash(sentence)] = true
+		}
+	}
+}
+
+// Verify returns a boolean indicating whether the given text, or any
+// long enough sentence in it, was registered.
+func (r *Registry) Verify(text string) bool {
+	if normalize(text) != "" && r.hashes[r.hash(text)] {
+		return true
+	}
+	for _, sentence := range stringutil.SplitSentences(text) {
+		if len(strings.Fields(normalize(sentence))) >= minSentenceWords && r.hashes[r.hash(sentence)] {
+			return true
+		}
+	}
+	return false
+}
+
+// Strip returns text without the parts that were registered: "" if
+// the whole text was, or else all but its long enough sentences that
+// were (as in Verify), with whitespace normalized to single spaces. A
+// bot can learn from what's left without reinforcing its own output.
+func (r *Registry) Strip(text string) string {
+	if normalize(text) != "" && r.hashes[r.hash(text)] {
+		return ""
+	}
+	var kept []string
+	for _, sentence := range stringutil.SplitSentences(text) {
+		if len(strings.Fields(normalize(sentence))) < minSentenceWords || !r.hashes[r.hash(sentence)] {
+			kept = append(kept, sentence)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// Size returns the number of hashes in the registry.
+func (r *Registry) Size() int {
+	return len(r.hashes)
+}
+
+// Load attempts to load registered hashes in JSON format from the
+// given file.
+func (r *Registry) Load(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var hashes []string
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&hashes); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		r.hashes[h] = true
+	}
+	return nil
+}
+
+// Save saves the registered hashes to the given file in JSON format.
+// The file is written under a temporary name and renamed into place,
+// so that a crash or a concurrent backup never sees it half-written.
+func (r *Registry) Save(filename string) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	hashes := make([]string, 0, len(r.hashes))
+	for h := range r.hashes {
+		hashes = append(hashes, h)
+	}
+	enc := json.NewEncoder(f)
+	err = enc.Encode(hashes)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
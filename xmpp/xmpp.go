@@ -0,0 +1,397 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+//
+// xmpp is a bot frontend (see package bot) for XMPP (Jabber) servers:
+// it joins multi-user chat rooms and accepts direct messages. Only the
+// parts of XMPP a bot needs are implemented: STARTTLS, SASL PLAIN
+// authentication, resource binding, presence, and messages.
+
+package xmpp
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sdukhovni/clyde-go/bot"
+)
+
+// XML namespaces used in the XMPP handshake.
+const (
+	nsClient  = "jabber:client"
+	nsStream  = "http://etherx.jabber.org/streams"
+	nsTLS     = "urn:ietf:params:xml:ns:xmpp-tls"
+	nsSASL    = "urn:ietf:params:xml:ns:xmpp-sasl"
+	nsBind    = "urn:ietf:params:xml:ns:xmpp-bind"
+	nsMUC     = "http://jabber.org/protocol/muc"
+	nsSession = "urn:ietf:params:xml:ns:xmpp-session"
+)
+
+// dialTimeout limits how long connecting to the server may take.
+const dialTimeout = 30 * time.Second
+
+// Bot is an XMPP client implementing bot.Frontend. Its channels are
+// the bare JIDs of rooms and correspondents; direct messages are
+// addressed to the bot, and users can address it by nickname in rooms.
+//
+// Senders are the bare JIDs of correspondents, and of room occupants
+// whose real JIDs the room reveals to the bot, as non-anonymous rooms
+// do. Otherwise they're occupant JIDs ("room@conference.example.org/nick"),
+// which anyone can take by picking the nickname, so they should never
+// be given roles.
+type Bot struct {
+	// TLSConfig, if set, is used to secure the connection, e.g. to
+	// trust a self-hosted server's private CA. Its ServerName
+	// defaults to the JID's domain.
+	TLSConfig *tls.Config
+
+	mu       sync.Mutex
+	user     string
+	domain   string
+	password string
+	nick     string
+	conn     net.Conn
+	dec      *xml.Decoder
+	wmu      sync.Mutex // held while writing to conn
+	jid      string     // full JID assigned by the server
+	rooms    map[string]bool
+	// occupants maps the occupant JIDs of rooms' occupants to their
+	// real bare JIDs, where known
+	occupants map[string]string
+	messages  chan bot.Message
+}
+
+// NewBot returns a Bot that logs in as the given JID (e.g.
+// "clyde@example.org") with a password, and uses the given nickname in
+// rooms.
+func NewBot(jid, password, nick string) (*Bot, error) {
+	parts := strings.SplitN(bare(jid), "@", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("xmpp: bad JID %q", jid)
+	}
+	return &Bot{
+		user:      parts[0],
+		domain:    parts[1],
+		password:  password,
+		nick:      nick,
+		rooms:     make(map[string]bool),
+		occupants: make(map[string]string),
+		messages:  make(chan bot.Message),
+	}, nil
+}
+
+// bare returns a JID without its resource.
+func bare(jid string) string {
+	return strings.SplitN(jid, "/", 2)[0]
+}
+
+// Identity implements bot.Frontend.
+func (b *Bot) Identity() bot.Identity {
+	return bot.Identity{Network: "xmpp", ID: b.user + "@" + b.domain, Name: b.nick}
+}
+
+// Messages implements bot.Frontend.
+func (b *Bot) Messages() <-chan bot.Message {
+	return b.messages
+}
+
+// Connect connects and logs in to the given server address
+// ("host:port"), or to port 5222 of the JID's domain if addr is "".
+func (b *Bot) Connect(addr string) error {
+	if addr == "" {
+		addr = net.JoinHostPort(b.domain, "5222")
+	}
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return err
+	}
+	b.conn = conn
+	if err := b.handshake(); err != nil {
+		conn.Close()
+		return err
+	}
+	log.Printf("Connected to XMPP as %s", b.jid)
+	return nil
+}
+
+// handshake secures the stream, authenticates, and binds a resource.
+func (b *Bot) handshake() error {
+	// Require TLS before sending the password
+	if _, err := b.openStream(); err != nil {
+		return err
+	}
+	if err := b.write("<starttls xmlns='%s'/>", nsTLS); err != nil {
+		return err
+	}
+	if err := b.expect("proceed"); err != nil {
+		return err
+	}
+	conf := &tls.Config{}
+	if b.TLSConfig != nil {
+		conf = b.TLSConfig.Clone()
+	}
+	if conf.ServerName == "" {
+		conf.ServerName = b.domain
+	}
+	tlsConn := tls.Client(b.conn, conf)
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	b.conn = tlsConn
+
+	if _, err := b.openStream(); err != nil {
+		return err
+	}
+	creds := base64.StdEncoding.EncodeToString([]byte("\x00" + b.user + "\x00" + b.password))
+	if err := b.write("<auth xmlns='%s' mechanism='PLAIN'>%s</auth>", nsSASL, creds); err != nil {
+		return err
+	}
+	if err := b.expect("success"); err != nil {
+		return errors.New("xmpp: authentication failed")
+	}
+
+	features, err := b.openStream()
+	if err != nil {
+		return err
+	}
+	if err := b.write("<iq type='set' id='bind'><bind xmlns='%s'><resource>%s</resource></bind></iq>", nsBind, escape(b.nick)); err != nil {
+		return err
+	}
+	var bind struct {
+		Type string `xml:"type,attr"`
+		JID  string `xml:"bind>jid"`
+	}
+	if err := b.next("iq", &bind); err != nil {
+		return err
+	}
+	if bind.Type != "result" {
+		return errors.New("xmpp: resource binding failed")
+	}
+	b.jid = bind.JID
+
+	// Old servers require a session to be established
+	if features.Session != nil {
+		if err := b.write("<iq type='set' id='session'><session xmlns='%s'/></iq>", nsSession); err != nil {
+			return err
+		}
+		if err := b.next("iq", nil); err != nil {
+			return err
+		}
+	}
+	return b.write("<presence/>")
+}
+
+// streamFeatures is the part of a stream's features the bot uses.
+type streamFeatures struct {
+	Session *struct{} `xml:"session"`
+}
+
+// openStream opens a new XML stream, as required at the start and
+// after STARTTLS and authentication, returning its features.
+func (b *Bot) openStream() (*streamFeatures, error) {
+	err := b.write("<?xml version='1.0'?><stream:stream to='%s' xmlns='%s' xmlns:stream='%s' version='1.0'>",
+		escape(b.domain), nsClient, nsStream)
+	if err != nil {
+		return nil, err
+	}
+	b.dec = xml.NewDecoder(b.conn)
+	for {
+		t, err := b.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Space == nsStream && se.Name.Local == "stream" {
+			break
+		}
+	}
+	var features streamFeatures
+	if err := b.next("features", &features); err != nil {
+		return nil, err
+	}
+	return &features, nil
+}
+
+// next reads the next top-level element of the stream, which must have
+// the given name, into v (if non-nil).
+func (b *Bot) next(name string, v interface{}) error {
+	for {
+		t, err := b.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local != name {
+				b.dec.Skip()
+				return fmt.Errorf("xmpp: expected <%s>, got <%s>", name, t.Name.Local)
+			}
+			if v == nil {
+				return b.dec.Skip()
+			}
+			return b.dec.DecodeElement(v, &t)
+		case xml.EndElement:
+			return io.EOF
+		}
+	}
+}
+
+// expect reads the next top-level element, which must have the given
+// name.
+func (b *Bot) expect(name string) error {
+	return b.next(name, nil)
+}
+
+// write writes formatted XML to the stream.
+func (b *Bot) write(format string, args ...interface{}) error {
+	b.wmu.Lock()
+	defer b.wmu.Unlock()
+	_, err := fmt.Fprintf(b.conn, format, args...)
+	return err
+}
+
+// escape escapes text for inclusion in XML.
+func escape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// Join joins a multi-user chat room, given its bare JID (e.g.
+// "chat@conference.example.org"), without fetching its history.
+func (b *Bot) Join(room string) error {
+	b.mu.Lock()
+	b.rooms[bare(room)] = true
+	b.mu.Unlock()
+	return b.write("<presence to='%s/%s'><x xmlns='%s'><history maxstanzas='0'/></x></presence>",
+		escape(bare(room)), escape(b.nick), nsMUC)
+}
+
+// stanza is the part of a message stanza the bot uses.
+type stanza struct {
+	From  string    `xml:"from,attr"`
+	Type  string    `xml:"type,attr"`
+	Body  string    `xml:"body"`
+	Delay *struct{} `xml:"urn:xmpp:delay delay"`
+}
+
+// presence is the part of a presence stanza the bot uses: the real JID
+// of a room's occupant, if the room reveals it.
+type presence struct {
+	From string `xml:"from,attr"`
+	Type string `xml:"type,attr"`
+	User *struct {
+		Item struct {
+			JID string `xml:"jid,attr"`
+		} `xml:"item"`
+	} `xml:"http://jabber.org/protocol/muc#user x"`
+}
+
+// Run passes on incoming messages until the connection is closed or
+// fails, and then closes the Messages channel.
+func (b *Bot) Run() error {
+	defer close(b.messages)
+	for {
+		t, err := b.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "presence" {
+				var p presence
+				if err := b.dec.DecodeElement(&p, &t); err != nil {
+					return err
+				}
+				b.handlePresence(p)
+				continue
+			}
+			if t.Name.Local != "message" {
+				b.dec.Skip()
+				continue
+			}
+			var s stanza
+			if err := b.dec.DecodeElement(&s, &t); err != nil {
+				return err
+			}
+			b.handle(s)
+		case xml.EndElement:
+			// The server closed the stream
+			return io.EOF
+		}
+	}
+}
+
+// handle passes on a message stanza.
+func (b *Bot) handle(s stanza) {
+	if s.Body == "" || s.Delay != nil {
+		return
+	}
+	switch s.Type {
+	case "groupchat":
+		room := bare(s.From)
+		if s.From == room+"/"+b.nick {
+			return
+		}
+		// Only trust real JIDs from the room's presence, since
+		// occupants can put anything in their messages
+		b.mu.Lock()
+		sender := b.occupants[s.From]
+		b.mu.Unlock()
+		if sender == "" {
+			sender = s.From
+		}
+		b.messages <- bot.Message{Channel: room, Sender: sender, Text: s.Body}
+	case "chat", "normal", "":
+		// Ignore people writing privately from a room, who may
+		// not even be on this server
+		b.mu.Lock()
+		fromRoom := b.rooms[bare(s.From)]
+		b.mu.Unlock()
+		if fromRoom {
+			return
+		}
+		b.messages <- bot.Message{Channel: bare(s.From), Sender: bare(s.From), Text: s.Body, Addressed: true}
+	}
+}
+
+// handlePresence keeps track of the real JIDs of rooms' occupants.
+func (b *Bot) handlePresence(p presence) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.rooms[bare(p.From)] {
+		return
+	}
+	if p.Type == "unavailable" || p.User == nil || p.User.Item.JID == "" {
+		delete(b.occupants, p.From)
+		return
+	}
+	b.occupants[p.From] = bare(p.User.Item.JID)
+}
+
+// Send sends a message to a room or correspondent. It implements
+// bot.Frontend.
+func (b *Bot) Send(channel, text string) error {
+	b.mu.Lock()
+	typ := "chat"
+	if b.rooms[channel] {
+		typ = "groupchat"
+	}
+	b.mu.Unlock()
+	return b.write("<message to='%s' type='%s'><body>%s</body></message>", escape(channel), typ, escape(text))
+}
+
+// Close closes the stream and the connection.
+func (b *Bot) Close() error {
+	b.write("<presence type='unavailable'/></stream:stream>")
+	return b.conn.Close()
+}
@@ -0,0 +1,61 @@
+// Copyright 2016 Sam Dukhovni <dukhovni@mit.edu>
+//
+// Licensed under the MIT License
+// (https://opensource.org/licenses/MIT)
+
+package xmpp
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestSenders(t *testing.T) {
+	const room = "chat@conference.example.org"
+	tests := []struct {
+		stanzas string
+		want    string
+	}{
+		// Non-anonymous rooms reveal occupants' real JIDs
+		{`<presence from='chat@conference.example.org/sam'><x xmlns='http://jabber.org/protocol/muc#user'><item jid='sam@example.org/laptop' role='participant'/></x></presence>
+		<message from='chat@conference.example.org/sam' type='groupchat'><body>hi</body></message>`,
+			"sam@example.org"},
+		// Anonymous ones don't, leaving the occupant JID
+		{`<presence from='chat@conference.example.org/sam'><x xmlns='http://jabber.org/protocol/muc#user'><item role='participant'/></x></presence>
+		<message from='chat@conference.example.org/sam' type='groupchat'><body>hi</body></message>`,
+			"chat@conference.example.org/sam"},
+		// A real JID in the message itself is ignored
+		{`<message from='chat@conference.example.org/sam' type='groupchat'><x xmlns='http://jabber.org/protocol/muc#user'><item jid='owner@example.org'/></x><body>hi</body></message>`,
+			"chat@conference.example.org/sam"},
+		// A nickname's real JID is forgotten when its occupant leaves
+		{`<presence from='chat@conference.example.org/sam'><x xmlns='http://jabber.org/protocol/muc#user'><item jid='owner@example.org/laptop'/></x></presence>
+		<presence from='chat@conference.example.org/sam' type='unavailable'><x xmlns='http://jabber.org/protocol/muc#user'><item jid='owner@example.org/laptop'/></x></presence>
+		<message from='chat@conference.example.org/sam' type='groupchat'><body>hi</body></message>`,
+			"chat@conference.example.org/sam"},
+		// Presence from anything but a joined room is ignored
+		{`<presence from='other@conference.example.org/sam'><x xmlns='http://jabber.org/protocol/muc#user'><item jid='owner@example.org'/></x></presence>
+		<message from='chat@conference.example.org/sam' type='groupchat'><body>hi</body></message>`,
+			"chat@conference.example.org/sam"},
+		{`<message from='sam@example.org/laptop' type='chat'><body>hi</body></message>`,
+			"sam@example.org"},
+	}
+	for _, test := range tests {
+		b, err := NewBot("clyde@example.org", "", "clyde")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.rooms[room] = true
+		b.dec = xml.NewDecoder(strings.NewReader("<stream>" + test.stanzas + "</stream>"))
+		b.dec.Token()
+		go b.Run()
+		m, ok := <-b.Messages()
+		if !ok {
+			t.Errorf("%s: no message", test.stanzas)
+			continue
+		}
+		if m.Sender != test.want {
+			t.Errorf("%s: Sender = %q, want %q", test.stanzas, m.Sender, test.want)
+		}
+	}
+}